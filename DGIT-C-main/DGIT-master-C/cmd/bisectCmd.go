@@ -0,0 +1,539 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"dgit/internal/bisect"
+	"dgit/internal/log"
+	"dgit/internal/merge"
+	"dgit/internal/refs"
+	"dgit/internal/restore"
+	"dgit/internal/staging"
+
+	"github.com/spf13/cobra"
+)
+
+// BisectCmd finds the commit that broke an asset by binary search
+var BisectCmd = &cobra.Command{
+	Use:   "bisect",
+	Short: "Find the commit that introduced a problem by binary search",
+	Long: `Binary-search the history between a known good and a known bad version.
+
+DGit restores the version halfway between them; you check it and mark it
+good or bad, and the range halves until only the first bad commit is left.
+Versions are restored into the working tree (HEAD is detached meanwhile),
+or into a temporary directory with --temp-dir so your working tree is left
+alone.
+
+'dgit bisect run' automates the check with a script. Its exit code decides:
+0 means good, 125 means the version cannot be tested (skip), 1-127 means
+bad and anything else aborts. The script runs inside the restored version
+with DGIT_BISECT_COMMIT and DGIT_BISECT_DIR set.
+
+Examples:
+  dgit bisect start                   # Start bisecting
+  dgit bisect bad                     # The current version is bad
+  dgit bisect good v12                # Version 12 was still fine
+  dgit bisect start HEAD v12          # Same as the three commands above
+  dgit bisect start --temp-dir HEAD v12
+  dgit bisect run ./check-export.sh   # Let a script decide
+  dgit bisect skip                    # The current version cannot be tested
+  dgit bisect reset                   # Finish and return to the original branch`,
+	Args: cobra.NoArgs,
+	Run:  runBisectStatus,
+}
+
+var bisectStartCmd = &cobra.Command{
+	Use:   "start [bad [good...]]",
+	Short: "Start bisecting, optionally marking the bad and good commits",
+	Run:   runBisectStart,
+}
+
+var bisectGoodCmd = &cobra.Command{
+	Use:   "good [revision...]",
+	Short: "Mark revisions as good (default: the version under test)",
+	Run: func(cmd *cobra.Command, args []string) {
+		runBisectMark(args, "good")
+	},
+}
+
+var bisectBadCmd = &cobra.Command{
+	Use:   "bad [revision]",
+	Short: "Mark a revision as bad (default: the version under test)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runBisectMark(args, "bad")
+	},
+}
+
+var bisectSkipCmd = &cobra.Command{
+	Use:   "skip [revision...]",
+	Short: "Mark revisions as untestable (default: the version under test)",
+	Run: func(cmd *cobra.Command, args []string) {
+		runBisectMark(args, "skip")
+	},
+}
+
+var bisectResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "End bisection and return to the original HEAD",
+	Args:  cobra.NoArgs,
+	Run:   runBisectReset,
+}
+
+var bisectRunCmd = &cobra.Command{
+	Use:   "run <script> [args...]",
+	Short: "Bisect automatically by running a script on each version",
+	Args:  cobra.MinimumNArgs(1),
+	// Flags after the script name belong to the script
+	DisableFlagParsing: true,
+	Run:                runBisectRun,
+}
+
+func init() {
+	bisectStartCmd.Flags().Bool("temp-dir", false, "Restore versions into a temporary directory instead of the working tree")
+
+	BisectCmd.AddCommand(bisectStartCmd)
+	BisectCmd.AddCommand(bisectGoodCmd)
+	BisectCmd.AddCommand(bisectBadCmd)
+	BisectCmd.AddCommand(bisectSkipCmd)
+	BisectCmd.AddCommand(bisectResetCmd)
+	BisectCmd.AddCommand(bisectRunCmd)
+}
+
+// runBisectStart records the starting point and marks any commits given
+func runBisectStart(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	bisectManager := bisect.NewBisectManager(dgitDir)
+	logManager := log.NewLogManager(dgitDir)
+	useTempDir, _ := cmd.Flags().GetBool("temp-dir")
+
+	if bisectManager.InProgress() {
+		exitWithError("a bisection is already in progress", "Use 'dgit bisect reset' to end it first")
+	}
+	if merge.NewMergeManager(dgitDir).InProgress() {
+		exitWithError("a merge is in progress", "Finish it with 'dgit merge --continue' or 'dgit merge --abort'")
+	}
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head == nil {
+		exitWithError("no commits to bisect", "Create some commits first")
+	}
+	branch, err := refs.NewRefManager(dgitDir).CurrentBranch()
+	if err != nil {
+		printError(fmt.Sprintf("reading HEAD: %v", err))
+		os.Exit(1)
+	}
+
+	if !useTempDir {
+		checkBisectSafety(dgitDir, head)
+	}
+
+	state := &bisect.BisectState{
+		OriginalHead:   head.Hash,
+		OriginalBranch: branch,
+		StartedAt:      time.Now(),
+	}
+	if useTempDir {
+		dir, err := os.MkdirTemp("", "dgit-bisect-*")
+		if err != nil {
+			printError(fmt.Sprintf("creating temp directory: %v", err))
+			os.Exit(1)
+		}
+		state.WorkDir = dir
+	}
+
+	for i, arg := range args {
+		c, err := findTargetCommit(logManager, arg)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if i == 0 {
+			state.Bad = c.Hash
+		} else {
+			state.Good = append(state.Good, c.Hash)
+		}
+	}
+
+	if err := bisectManager.SaveState(state); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if state.WorkDir != "" {
+		printInfo(fmt.Sprintf("Versions will be restored into %s", state.WorkDir))
+	}
+	advanceBisect(dgitDir, bisectManager, state)
+}
+
+// runBisectMark marks revisions good, bad or skipped and moves on to the next version
+func runBisectMark(args []string, term string) {
+	dgitDir := checkDgitRepository()
+	bisectManager := bisect.NewBisectManager(dgitDir)
+	state := loadBisectState(bisectManager)
+	logManager := log.NewLogManager(dgitDir)
+
+	var hashes []string
+	for _, arg := range args {
+		c, err := findTargetCommit(logManager, arg)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		hashes = append(hashes, c.Hash)
+	}
+	if len(hashes) == 0 {
+		hashes = []string{bisectCurrent(logManager, state)}
+	}
+
+	for _, hash := range hashes {
+		switch term {
+		case "bad":
+			state.Bad = hash
+		case "good":
+			state.Good = appendUnique(state.Good, hash)
+		case "skip":
+			state.Skipped = appendUnique(state.Skipped, hash)
+		}
+	}
+	state.FirstBad = ""
+
+	if err := bisectManager.SaveState(state); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	advanceBisect(dgitDir, bisectManager, state)
+}
+
+// runBisectRun tests versions with a script until the first bad commit is found
+func runBisectRun(cmd *cobra.Command, args []string) {
+	if args[0] == "-h" || args[0] == "--help" {
+		cmd.Help()
+		return
+	}
+
+	dgitDir := checkDgitRepository()
+	bisectManager := bisect.NewBisectManager(dgitDir)
+	state := loadBisectState(bisectManager)
+	logManager := log.NewLogManager(dgitDir)
+
+	if state.Bad == "" || len(state.Good) == 0 {
+		exitWithError("bisect run needs a bad and a good commit",
+			"Mark them with 'dgit bisect bad' and 'dgit bisect good <revision>'")
+	}
+
+	for state.FirstBad == "" {
+		current := bisectCurrent(logManager, state)
+		dir := state.WorkDir
+		if dir == "" {
+			dir = filepath.Dir(dgitDir)
+		}
+
+		fmt.Printf("running %s\n", strings.Join(args, " "))
+		script := bisectScript(args)
+		script.Dir = dir
+		script.Stdout = os.Stdout
+		script.Stderr = os.Stderr
+		script.Env = append(os.Environ(), "DGIT_BISECT_COMMIT="+current, "DGIT_BISECT_DIR="+dir)
+
+		code := 0
+		if err := script.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				printError(fmt.Sprintf("running %s: %v", args[0], err))
+				os.Exit(1)
+			}
+			code = exitErr.ExitCode()
+		}
+
+		switch {
+		case code == 0:
+			state.Good = appendUnique(state.Good, current)
+		case code == 125:
+			state.Skipped = appendUnique(state.Skipped, current)
+		case code > 0 && code < 128:
+			state.Bad = current
+		default:
+			exitWithError(fmt.Sprintf("bisect run aborted: %s exited with code %d", args[0], code),
+				"Exit codes from 128 up stop the run; use 1-127 for bad and 125 for skip")
+		}
+		if err := bisectManager.SaveState(state); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if !advanceBisect(dgitDir, bisectManager, state) {
+			return
+		}
+	}
+}
+
+// runBisectReset ends the bisection and returns to where it started
+func runBisectReset(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	bisectManager := bisect.NewBisectManager(dgitDir)
+	state := loadBisectState(bisectManager)
+	logManager := log.NewLogManager(dgitDir)
+	refManager := refs.NewRefManager(dgitDir)
+
+	original, err := logManager.GetCommitByHash(state.OriginalHead)
+	if err != nil {
+		printError(fmt.Sprintf("loading %s: %v", state.OriginalHead, err))
+		os.Exit(1)
+	}
+
+	if state.WorkDir != "" {
+		if err := os.RemoveAll(state.WorkDir); err != nil {
+			printWarning(fmt.Sprintf("removing %s: %v", state.WorkDir, err))
+		}
+	} else {
+		if state.Current != "" && state.Current != original.Hash {
+			current, err := logManager.GetCommitByHash(state.Current)
+			if err != nil {
+				printError(fmt.Sprintf("loading %s: %v", state.Current, err))
+				os.Exit(1)
+			}
+			if _, err := restore.NewRestoreManager(dgitDir).CheckoutCommit(current, original, true); err != nil {
+				printError(fmt.Sprintf("restoring %s: %v", original.Hash[:8], err))
+				os.Exit(1)
+			}
+			if err := refManager.RecordHeadMove(current.Hash, original.Hash, "bisect", "reset"); err != nil {
+				printWarning(fmt.Sprintf("Failed to update reflog: %v", err))
+			}
+		}
+		// HEAD is detached while bisecting, even when the last step tested the original commit
+		if state.OriginalBranch != "" {
+			err = refManager.SetHeadToBranch(state.OriginalBranch)
+		} else {
+			err = refManager.DetachHead(original.Hash)
+		}
+		if err != nil {
+			printError(fmt.Sprintf("updating HEAD: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if err := bisectManager.ClearState(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if state.OriginalBranch != "" {
+		printSuccess(fmt.Sprintf("Bisection ended; back on branch '%s' at %s (v%d)", state.OriginalBranch, original.Hash[:8], original.Version))
+	} else {
+		printSuccess(fmt.Sprintf("Bisection ended; HEAD at %s (v%d)", original.Hash[:8], original.Version))
+	}
+}
+
+// runBisectStatus summarizes the bisection in progress
+func runBisectStatus(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	bisectManager := bisect.NewBisectManager(dgitDir)
+	if !bisectManager.InProgress() {
+		fmt.Println("Not bisecting.")
+		printSuggestion("Start with 'dgit bisect start'")
+		return
+	}
+	state := loadBisectState(bisectManager)
+
+	fmt.Printf("Bisecting since %s\n", state.StartedAt.Format("2006-01-02 15:04"))
+	if state.Bad != "" {
+		fmt.Printf("  %s %s\n", red("bad: "), state.Bad[:8])
+	}
+	for _, hash := range state.Good {
+		fmt.Printf("  %s %s\n", green("good:"), hash[:8])
+	}
+	for _, hash := range state.Skipped {
+		fmt.Printf("  %s %s\n", yellow("skip:"), hash[:8])
+	}
+	if state.Current != "" {
+		fmt.Printf("Testing %s", state.Current[:8])
+		if state.WorkDir != "" {
+			fmt.Printf(" in %s", state.WorkDir)
+		}
+		fmt.Println()
+	}
+	if state.FirstBad != "" {
+		fmt.Printf("First bad commit: %s\n", state.FirstBad[:8])
+	}
+}
+
+// advanceBisect restores the next version to test, or reports the result
+// It returns true while there are versions left to test
+func advanceBisect(dgitDir string, bisectManager *bisect.BisectManager, state *bisect.BisectState) bool {
+	if state.Bad == "" || len(state.Good) == 0 {
+		switch {
+		case state.Bad == "" && len(state.Good) == 0:
+			printInfo("Waiting for a bad and a good commit")
+		case state.Bad == "":
+			printInfo("Waiting for a bad commit; mark one with 'dgit bisect bad [revision]'")
+		default:
+			printInfo("Waiting for a good commit; mark one with 'dgit bisect good <revision>'")
+		}
+		return false
+	}
+
+	step, err := bisectManager.NextStep(state)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if step.FirstBad != nil {
+		state.FirstBad = step.FirstBad.Hash
+		if err := bisectManager.SaveState(state); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		printFirstBad(step.FirstBad)
+		printSuggestion("Use 'dgit bisect reset' to return to where you started")
+		return false
+	}
+	if step.Next == nil {
+		printWarning("There are only skipped commits left to test; the first bad commit is one of:")
+		for _, c := range step.Skipped {
+			fmt.Printf("  %s (v%d) %s\n", c.Hash[:8], c.Version, c.Message)
+		}
+		return false
+	}
+
+	if err := checkoutBisectVersion(dgitDir, state, step.Next); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := bisectManager.SaveState(state); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Bisecting: %d version(s) left to test after this (roughly %d step(s))\n", step.Remaining, step.Steps)
+	printCyan(fmt.Sprintf("[%s] (v%d) %s", step.Next.Hash[:8], step.Next.Version, step.Next.Message))
+	return true
+}
+
+// checkoutBisectVersion restores a version into the temp directory or the working tree
+func checkoutBisectVersion(dgitDir string, state *bisect.BisectState, target *log.Commit) error {
+	logManager := log.NewLogManager(dgitDir)
+	if state.WorkDir != "" {
+		if err := bisect.NewBisectManager(dgitDir).Extract(target, state.WorkDir); err != nil {
+			return fmt.Errorf("restoring %s into %s: %v", target.Hash[:8], state.WorkDir, err)
+		}
+		state.Current = target.Hash
+		return nil
+	}
+
+	previous, err := logManager.GetHeadCommit()
+	if err != nil {
+		return fmt.Errorf("resolving HEAD: %v", err)
+	}
+	result, err := restore.NewRestoreManager(dgitDir).CheckoutCommit(previous, target, true)
+	if err != nil {
+		for file, fileErr := range result.ErrorFiles {
+			printWarning(fmt.Sprintf("%s: %v", file, fileErr))
+		}
+		return fmt.Errorf("checking out %s: %v", target.Hash[:8], err)
+	}
+
+	refManager := refs.NewRefManager(dgitDir)
+	if err := refManager.DetachHead(target.Hash); err != nil {
+		return fmt.Errorf("updating HEAD: %v", err)
+	}
+	if err := refManager.RecordHeadMove(previous.Hash, target.Hash, "bisect", fmt.Sprintf("checkout v%d", target.Version)); err != nil {
+		printWarning(fmt.Sprintf("Failed to update reflog: %v", err))
+	}
+	state.Current = target.Hash
+	return nil
+}
+
+// printFirstBad reports the commit that introduced the problem
+func printFirstBad(c *log.Commit) {
+	printBold(fmt.Sprintf("%s is the first bad commit", c.Hash))
+	fmt.Printf("Version: v%d\n", c.Version)
+	fmt.Printf("Author:  %s\n", c.Author)
+	fmt.Printf("Date:    %s\n", c.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Printf("\n    %s\n\n", c.Message)
+}
+
+// loadBisectState exits unless a bisection is in progress
+func loadBisectState(bisectManager *bisect.BisectManager) *bisect.BisectState {
+	if !bisectManager.InProgress() {
+		exitWithError("not bisecting", "Start with 'dgit bisect start'")
+	}
+	state, err := bisectManager.LoadState()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	return state
+}
+
+// bisectCurrent returns the version under test, which is HEAD before the first step
+func bisectCurrent(logManager *log.LogManager, state *bisect.BisectState) string {
+	if state.Current != "" {
+		return state.Current
+	}
+	head, err := logManager.GetHeadCommit()
+	if err != nil || head == nil {
+		printError("no version is being tested; give a revision explicitly")
+		os.Exit(1)
+	}
+	return head.Hash
+}
+
+// checkBisectSafety exits when restoring versions would overwrite local work
+func checkBisectSafety(dgitDir string, head *log.Commit) {
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+	if !stagingArea.IsEmpty() {
+		exitWithError("you have staged changes; commit them before bisecting",
+			"Commit or stash them first, or use --temp-dir to leave the working tree alone")
+	}
+
+	changes, err := getWorkingTreeChanges(dgitDir, head)
+	if err != nil {
+		printError(fmt.Sprintf("checking working tree: %v", err))
+		os.Exit(1)
+	}
+	if len(changes.ModifiedFiles) > 0 || len(changes.DeletedFiles) > 0 {
+		printError("your local changes would be overwritten by bisecting:")
+		for _, file := range changes.ModifiedFiles {
+			fmt.Fprintf(os.Stderr, "  modified: %s\n", file.Path)
+		}
+		for _, file := range changes.DeletedFiles {
+			fmt.Fprintf(os.Stderr, "  deleted:  %s\n", file.Path)
+		}
+		exitWithError("", "Commit or stash them first, or use --temp-dir to leave the working tree alone")
+	}
+}
+
+// bisectScript builds the command for 'bisect run'; a single argument is run through the shell
+func bisectScript(args []string) *exec.Cmd {
+	if len(args) > 1 {
+		return exec.Command(args[0], args[1:]...)
+	}
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", args[0])
+	}
+	return exec.Command("sh", "-c", args[0])
+}
+
+// appendUnique appends a hash unless it is already listed
+func appendUnique(hashes []string, hash string) []string {
+	for _, h := range hashes {
+		if h == hash {
+			return hashes
+		}
+	}
+	return append(hashes, hash)
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"dgit/internal/log"
+	"dgit/internal/refs"
+
+	"github.com/spf13/cobra"
+)
+
+// BranchCmd lists, creates and deletes branches
+var BranchCmd = &cobra.Command{
+	Use:   "branch [name] [start_point]",
+	Short: "List, create, or delete branches",
+	Long: `Manage independent lines of design work.
+
+Branches are stored under .dgit/refs/heads and point at commit hashes.
+Each branch numbers its own versions, so v3 on one branch and v3 on
+another are different commits. A version number always refers to the
+current branch; a version that is not on it is only accepted when a
+single other commit has it, and otherwise dgit lists the candidate hashes.
+
+Examples:
+  dgit branch                     # List branches
+  dgit branch feature/logo        # Create a branch at HEAD
+  dgit branch hotfix v2           # Create a branch at version 2
+  dgit branch -d feature/logo     # Delete a merged branch
+  dgit branch -D experiment       # Delete a branch even if unmerged`,
+	Args: cobra.MaximumNArgs(2),
+	Run:  runBranch,
+}
+
+func init() {
+	BranchCmd.Flags().BoolP("delete", "d", false, "Delete a branch that is merged into HEAD")
+	BranchCmd.Flags().BoolP("force-delete", "D", false, "Delete a branch even if it is not merged")
+}
+
+// runBranch dispatches between listing, creating and deleting branches
+func runBranch(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	refManager := refs.NewRefManager(dgitDir)
+	logManager := log.NewLogManager(dgitDir)
+
+	deleteFlag, _ := cmd.Flags().GetBool("delete")
+	forceDelete, _ := cmd.Flags().GetBool("force-delete")
+
+	switch {
+	case deleteFlag || forceDelete:
+		if len(args) != 1 {
+			printError("branch deletion requires exactly one branch name")
+			os.Exit(1)
+		}
+		deleteBranch(refManager, logManager, args[0], forceDelete)
+	case len(args) == 0:
+		listBranches(refManager, logManager)
+	default:
+		startPoint := ""
+		if len(args) > 1 {
+			startPoint = args[1]
+		}
+		createBranch(refManager, logManager, args[0], startPoint)
+	}
+}
+
+// listBranches prints all branches, marking the current one
+func listBranches(refManager *refs.RefManager, logManager *log.LogManager) {
+	branches, err := refManager.ListBranches()
+	if err != nil {
+		printError(fmt.Sprintf("listing branches: %v", err))
+		os.Exit(1)
+	}
+
+	if refManager.IsDetached() {
+		if head, err := logManager.GetHeadCommit(); err == nil && head != nil {
+			fmt.Printf("* %s\n", yellow(fmt.Sprintf("(HEAD detached at %s)", head.Hash[:8])))
+		}
+	}
+
+	if len(branches) == 0 {
+		if current, err := refManager.CurrentBranch(); err == nil && current != "" {
+			fmt.Printf("* %s (no commits yet)\n", green(current))
+		}
+		return
+	}
+
+	nameWidth := 0
+	for _, branch := range branches {
+		if len(branch.Name) > nameWidth {
+			nameWidth = len(branch.Name)
+		}
+	}
+
+	for _, branch := range branches {
+		summary := ""
+		if commit, err := logManager.GetCommitByHash(branch.Hash); err == nil {
+			summary = fmt.Sprintf("%s (v%d) %s", commit.Hash[:8], commit.Version, commit.Message)
+		}
+
+		name := fmt.Sprintf("%-*s", nameWidth, branch.Name)
+		if branch.Current {
+			fmt.Printf("* %s  %s\n", green(name), summary)
+		} else {
+			fmt.Printf("  %s  %s\n", name, summary)
+		}
+	}
+}
+
+// createBranch creates a branch at HEAD or at the given start point without switching to it
+func createBranch(refManager *refs.RefManager, logManager *log.LogManager, name, startPoint string) {
+	target, err := resolveStartPoint(logManager, startPoint)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if err := refManager.CreateBranch(name, target.Hash); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	var startPoints []string
+	if startPoint != "" {
+		startPoints = []string{startPoint}
+	}
+	recordBranchCreation(refManager, name, target, startPoints)
+
+	printSuccess(fmt.Sprintf("Created branch '%s' at %s (v%d)", name, target.Hash[:8], target.Version))
+	printInfo(fmt.Sprintf("Use 'dgit switch %s' to start working on it", name))
+}
+
+// deleteBranch removes a branch, refusing unmerged branches unless forced
+func deleteBranch(refManager *refs.RefManager, logManager *log.LogManager, name string, force bool) {
+	tip, err := refManager.ReadBranch(name)
+	if err != nil {
+		printError(fmt.Sprintf("branch '%s' not found", name))
+		os.Exit(1)
+	}
+
+	if !force {
+		merged, err := isMergedIntoHead(logManager, tip)
+		if err != nil {
+			printError(fmt.Sprintf("checking branch '%s': %v", name, err))
+			os.Exit(1)
+		}
+		if !merged {
+			exitWithError(fmt.Sprintf("branch '%s' is not fully merged", name),
+				fmt.Sprintf("Use 'dgit branch -D %s' to delete it anyway", name))
+		}
+	}
+
+	if err := refManager.DeleteBranch(name); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	printSuccess(fmt.Sprintf("Deleted branch '%s' (was %s)", name, tip[:min(8, len(tip))]))
+}
+
+// recordBranchCreation starts the reflog of a new branch
+func recordBranchCreation(refManager *refs.RefManager, name string, target *log.Commit, startPoint []string) {
+	from := "HEAD"
+	if len(startPoint) > 0 {
+		from = startPoint[0]
+	}
+	if err := refManager.RecordBranchUpdate(name, "", target.Hash, "branch", "created from "+from); err != nil {
+		printWarning(fmt.Sprintf("Failed to update reflog: %v", err))
+	}
+}
+
+// resolveStartPoint returns the commit a new branch should point at, defaulting to HEAD
+func resolveStartPoint(logManager *log.LogManager, startPoint string) (*log.Commit, error) {
+	if startPoint == "" {
+		head, err := logManager.GetHeadCommit()
+		if err != nil {
+			return nil, fmt.Errorf("resolving HEAD: %v", err)
+		}
+		if head == nil {
+			return nil, fmt.Errorf("no commits yet; create a commit before branching")
+		}
+		return head, nil
+	}
+
+	target, err := findTargetCommit(logManager, startPoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start point '%s': %v", startPoint, err)
+	}
+	return target, nil
+}
+
+// isMergedIntoHead reports whether a commit is reachable from HEAD, including through merges
+func isMergedIntoHead(logManager *log.LogManager, hash string) (bool, error) {
+	head, err := logManager.GetHeadCommit()
+	if err != nil || head == nil {
+		return false, err
+	}
+	return logManager.IsAncestor(hash, head.Hash)
+}
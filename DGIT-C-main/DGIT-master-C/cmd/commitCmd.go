@@ -7,6 +7,7 @@ import (
 	"strings"
 	
 	"dgit/internal/commit"
+	"dgit/internal/merge"
 	"dgit/internal/staging"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +23,7 @@ Examples:
   dgit commit "Logo design completed"
   dgit commit -m "Updated color scheme to brand guidelines"
   dgit commit                       # Opens editor for commit message
+  dgit commit --amend -m "Fixed typo" # Replace the latest commit
 
 The commit will:
 - Create a snapshot (ZIP) of all staged files
@@ -36,6 +38,7 @@ The commit will:
 func init() {
 	// Add -m flag for commit message (similar to git)
 	CommitCmd.Flags().StringP("message", "m", "", "Commit message")
+	CommitCmd.Flags().Bool("amend", false, "Replace the latest commit with its changes plus the staged files")
 }
 
 // runCommit executes the commit command functionality
@@ -58,8 +61,16 @@ func runCommit(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// A merge in progress is finished with 'dgit merge --continue' so it records both parents
+	if merge.NewMergeManager(dgitDir).InProgress() {
+		exitWithError("a merge is in progress", "Run 'dgit merge --continue' to commit the merge")
+	}
+
+	// Amending may only change the message, so it does not need staged files
+	amend, _ := cmd.Flags().GetBool("amend")
+
 	// Check if there are any files to commit
-	if stagingArea.IsEmpty() {
+	if stagingArea.IsEmpty() && !amend {
 		fmt.Println("No files staged for commit.")
 		fmt.Println("   Use 'dgit add <files>' to stage files for commit.")
 		os.Exit(1)
@@ -73,7 +84,7 @@ func runCommit(cmd *cobra.Command, args []string) {
 	} else if msgFlag, _ := cmd.Flags().GetString("message"); msgFlag != "" {
 		// Message provided via -m flag
 		message = msgFlag
-	} else {
+	} else if !amend {
 		// Interactive input for commit message
 		fmt.Print("Enter commit message: ")
 		reader := bufio.NewReader(os.Stdin)
@@ -93,6 +104,7 @@ func runCommit(cmd *cobra.Command, args []string) {
 
 	// Get staged files for processing
 	stagedFiles := stagingArea.GetStagedFiles()
+	removedFiles := stagingArea.GetRemovedFiles()
 	
 	// Display DGit-style commit progress messages
 	fmt.Printf("Creating commit with %d design files...\n", len(stagedFiles))
@@ -101,7 +113,14 @@ func runCommit(cmd *cobra.Command, args []string) {
 	
 	// Create the actual commit with metadata and snapshot
 	commitManager := commit.NewCommitManager(dgitDir)
-	newCommit, err := commitManager.CreateCommit(message, stagedFiles)
+	var newCommit *commit.Commit
+	var err error
+	if amend {
+		// An empty message keeps the message of the amended commit
+		newCommit, err = commitManager.AmendCommit(message, stagedFiles, removedFiles)
+	} else {
+		newCommit, err = commitManager.CreateCommit(message, stagedFiles, removedFiles)
+	}
 	if err != nil {
 		printError(fmt.Sprintf("creating commit: %v", err))
 		os.Exit(1)
@@ -114,8 +133,12 @@ func runCommit(cmd *cobra.Command, args []string) {
 
 	// Display DGit-style success message with commit details
 	fmt.Printf("\n")
-	printGreen(fmt.Sprintf("Created commit %s", newCommit.Hash[:8]))
-	fmt.Printf("%s\n", message)
+	if amend {
+		printGreen(fmt.Sprintf("Amended commit %s", newCommit.Hash[:8]))
+	} else {
+		printGreen(fmt.Sprintf("Created commit %s", newCommit.Hash[:8]))
+	}
+	fmt.Printf("%s\n", newCommit.Message)
 	printCyan(fmt.Sprintf("Author: %s", newCommit.Author))
 	
 	// Show design-specific file details (unique to DGit!)
@@ -154,6 +177,16 @@ func runCommit(cmd *cobra.Command, args []string) {
 			fmt.Printf("   %s\n", fileName)
 		}
 	}
+	renamedFrom := make(map[string]bool)
+	for newPath, oldPath := range newCommit.Renames {
+		renamedFrom[oldPath] = true
+		printYellow(fmt.Sprintf("   renamed: %s -> %s", oldPath, newPath))
+	}
+	for _, path := range removedFiles {
+		if !renamedFrom[path] {
+			printYellow(fmt.Sprintf("   removed: %s", path))
+		}
+	}
 	
 	printGreen(fmt.Sprintf("Snapshot: %s", newCommit.SnapshotZip))
 	printBold("Ready for collaboration!")
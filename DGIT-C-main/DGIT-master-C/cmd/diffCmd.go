@@ -0,0 +1,469 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dgit/internal/commit"
+	"dgit/internal/diff"
+	"dgit/internal/log"
+	"dgit/internal/scanner/figma"
+	"dgit/internal/scanner/illustrator"
+
+	"github.com/spf13/cobra"
+)
+
+// DiffCmd compares the working tree, the staging area and commits
+var DiffCmd = &cobra.Command{
+	Use:   "diff [revision [revision] | from..to] [-- paths]",
+	Short: "Show changes between commits, the staging area and the working tree",
+	Long: `Show which design files were added, removed, renamed or modified, and for
+modified files what changed inside them: canvas dimensions, color mode,
+layer and artboard counts. For PSD files every layer change is listed:
+added and deleted layers, layers moved in the stack, and changes of pixels,
+opacity, blend mode, visibility, name or position.
+
+What is compared depends on the arguments:
+  dgit diff                  # Working tree against the staging area
+  dgit diff --staged         # Staging area against HEAD
+  dgit diff --staged v3      # Staging area against a revision
+  dgit diff v3               # Working tree against a revision
+  dgit diff v3 v7            # Two revisions
+  dgit diff main..feature    # Same as 'dgit diff main feature'
+
+Examples:
+  dgit diff -- hero.psd      # Limit the comparison to files or directories
+  dgit diff --stat HEAD~1    # Only list files and size changes
+  dgit diff --name-only v3   # Only list file names
+  dgit diff --json v3 v7     # Machine-readable output
+  dgit diff --visual v3 v7 hero.psd
+
+With --visual the merged images of modified PSD files are rendered and
+compared pixel by pixel. The old and new versions, a side-by-side image and
+a heatmap of the changed pixels are written as PNG files, and the share of
+the canvas that changed is reported with its bounding box.`,
+	Args: cobra.ArbitraryArgs,
+	Run:  runDiff,
+}
+
+func init() {
+	DiffCmd.Flags().Bool("staged", false, "Compare the staging area with HEAD or a revision")
+	DiffCmd.Flags().Bool("cached", false, "Synonym for --staged")
+	DiffCmd.Flags().Bool("name-only", false, "Show only the names of changed files")
+	DiffCmd.Flags().Bool("stat", false, "Show changed files with their size changes")
+	DiffCmd.Flags().Bool("json", false, "Output in JSON format")
+	DiffCmd.Flags().Bool("visual", false, "Render modified PSD files and compare their pixels")
+	DiffCmd.Flags().String("output", "", "Directory for the --visual PNG files (default: a new temp directory)")
+}
+
+// runDiff compares two versions of the repository and prints the differences
+func runDiff(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	logManager := log.NewLogManager(dgitDir)
+	diffManager := diff.NewDiffManager(dgitDir)
+
+	staged, _ := cmd.Flags().GetBool("staged")
+	cached, _ := cmd.Flags().GetBool("cached")
+	staged = staged || cached
+	nameOnly, _ := cmd.Flags().GetBool("name-only")
+	stat, _ := cmd.Flags().GetBool("stat")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	visual, _ := cmd.Flags().GetBool("visual")
+	outDir, _ := cmd.Flags().GetString("output")
+	if visual && (nameOnly || stat) {
+		exitWithError("--visual cannot be combined with --name-only or --stat", "")
+	}
+
+	revisions, paths := splitDiffArgs(logManager, args, cmd.ArgsLenAtDash())
+	for i, path := range paths {
+		paths[i] = filepath.ToSlash(repoRelativePath(filepath.Dir(dgitDir), path))
+	}
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("failed to read HEAD: %v", err))
+		os.Exit(1)
+	}
+	from, to, err := diffSides(diffManager, logManager, head, revisions, staged)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	result, err := diffManager.Compare(from, to, paths, !nameOnly && !stat)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if visual {
+		addVisualDiffs(diffManager, from, to, result, outDir)
+	}
+
+	switch {
+	case jsonOutput:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	case nameOnly:
+		for _, file := range result.Files {
+			fmt.Println(file.Path)
+		}
+	case stat:
+		printDiffStat(result)
+	default:
+		printDiff(result)
+	}
+}
+
+// splitDiffArgs separates revisions from paths; without "--" the first argument that is not a revision starts the paths
+func splitDiffArgs(logManager *log.LogManager, args []string, dash int) ([]string, []string) {
+	if dash >= 0 {
+		return args[:dash], args[dash:]
+	}
+	for i, arg := range args {
+		if _, err := logManager.ResolveRange(arg); err != nil {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+// diffSides picks the two sides to compare from the revisions given and --staged
+func diffSides(diffManager *diff.DiffManager, logManager *log.LogManager, head *log.Commit, revisions []string, staged bool) (*diff.Side, *diff.Side, error) {
+	commitSide := func(revision string) (*diff.Side, error) {
+		target, err := findTargetCommit(logManager, revision)
+		if err != nil {
+			return nil, err
+		}
+		return diffManager.CommitSide(target), nil
+	}
+
+	if len(revisions) > 2 {
+		return nil, nil, fmt.Errorf("too many revisions; compare at most two")
+	}
+	if staged {
+		if len(revisions) > 1 {
+			return nil, nil, fmt.Errorf("--staged compares the staging area with a single revision")
+		}
+		index, err := diffManager.StagingSide(head)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(revisions) == 1 {
+			from, err := commitSide(revisions[0])
+			return from, index, err
+		}
+		if head == nil {
+			return diff.EmptySide(), index, nil
+		}
+		return diffManager.CommitSide(head), index, nil
+	}
+
+	switch {
+	case len(revisions) == 2:
+		from, err := commitSide(revisions[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		to, err := commitSide(revisions[1])
+		return from, to, err
+	case len(revisions) == 1 && strings.Contains(revisions[0], ".."):
+		r, err := logManager.ResolveRange(revisions[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		return diffManager.CommitSide(r.From), diffManager.CommitSide(r.To), nil
+	}
+
+	index, err := diffManager.StagingSide(head)
+	if err != nil {
+		return nil, nil, err
+	}
+	workTree := diffManager.WorkTreeSide(index)
+	if len(revisions) == 1 {
+		from, err := commitSide(revisions[0])
+		return from, workTree, err
+	}
+	return index, workTree, nil
+}
+
+// addVisualDiffs renders the pixel comparison of every modified or renamed PSD in the result
+func addVisualDiffs(diffManager *diff.DiffManager, from, to *diff.Side, result *diff.Result, outDir string) {
+	for i := range result.Files {
+		file := &result.Files[i]
+		if (file.Status != diff.StatusModified && file.Status != diff.StatusRenamed) || !strings.EqualFold(filepath.Ext(file.Path), ".psd") {
+			continue
+		}
+		if oldHash := from.Files[file.OldPath].Hash; file.OldPath != "" && oldHash != "" && oldHash == to.Files[file.Path].Hash {
+			continue // A pure rename
+		}
+		if outDir == "" {
+			dir, err := os.MkdirTemp("", "dgit-visual-*")
+			if err != nil {
+				printError(fmt.Sprintf("failed to create output directory: %v", err))
+				os.Exit(1)
+			}
+			outDir = dir
+		}
+		visual, err := diffManager.VisualCompare(from, to, file, outDir)
+		if err != nil {
+			if file.Error == "" {
+				file.Error = err.Error()
+			}
+			continue
+		}
+		file.Visual = visual
+	}
+}
+
+// printDiff prints each changed file with its metadata and layer changes
+func printDiff(result *diff.Result) {
+	if len(result.Files) == 0 {
+		fmt.Printf("No differences between %s and %s\n", result.From, result.To)
+		return
+	}
+
+	printBold(fmt.Sprintf("Changes from %s to %s", result.From, result.To))
+	for _, file := range result.Files {
+		fmt.Println()
+		switch file.Status {
+		case diff.StatusAdded:
+			fmt.Printf("%s %s (%s)\n", green("added:   "), file.Path, formatDiffSize(file.NewSize))
+		case diff.StatusDeleted:
+			fmt.Printf("%s %s (%s)\n", red("deleted: "), file.Path, formatDiffSize(file.OldSize))
+		case diff.StatusRenamed:
+			fmt.Printf("%s %s -> %s\n", cyan("renamed: "), file.OldPath, file.Path)
+		default:
+			fmt.Printf("%s %s (%s -> %s)\n", yellow("modified:"), file.Path, formatDiffSize(file.OldSize), formatDiffSize(file.NewSize))
+		}
+
+		for _, change := range file.MetadataChanges {
+			fmt.Printf("    %s: %s -> %s\n", metadataFieldNames[change.Field], change.Old, change.New)
+		}
+		if file.Layers != nil {
+			printLayerDiff(file.Layers)
+		}
+		if file.Document != nil {
+			printDocumentDiff(file.Document)
+		}
+		if file.Nodes != nil {
+			printNodeDiff(file.Nodes)
+		}
+		if file.Visual != nil {
+			printVisualDiff(file.Visual)
+		}
+		if file.Error != "" {
+			printWarning(fmt.Sprintf("could not analyze %s: %s", file.Path, file.Error))
+		}
+	}
+}
+
+// printVisualDiff prints the changed area of a PSD and where its PNG files were written
+func printVisualDiff(visual *diff.VisualDiff) {
+	if visual.Bounds == nil {
+		fmt.Println("    pixels: no visible change")
+	} else {
+		b := visual.Bounds
+		fmt.Printf("    pixels: %.2f%% changed (%d px) within %dx%d at (%d, %d)\n",
+			visual.ChangedPercent, visual.ChangedPixels, b.Width, b.Height, b.X, b.Y)
+	}
+	fmt.Printf("    old:          %s\n", visual.OldImage)
+	fmt.Printf("    new:          %s\n", visual.NewImage)
+	fmt.Printf("    side by side: %s\n", visual.SideBySide)
+	fmt.Printf("    heatmap:      %s\n", visual.Heatmap)
+}
+
+// metadataFieldNames are the display names of diff.MetadataChange fields
+var metadataFieldNames = map[string]string{
+	"dimensions":  "dimensions",
+	"color_mode":  "color mode",
+	"layers":      "layer count",
+	"artboards":   "artboards",
+	"components":  "components",
+	"instances":   "component instances",
+	"text_layers": "text layers",
+	"assets":      "linked assets",
+	"meshes":      "meshes",
+	"vertices":    "vertices",
+	"polygons":    "polygons",
+	"materials":   "materials",
+	"textures":    "textures",
+	"animations":  "animation stacks",
+}
+
+// printLayerDiff prints the layer changes of a PSD, one layer per line
+func printLayerDiff(analysis *commit.ChangeAnalysis) {
+	fmt.Printf("    layers: %s\n", analysis.ChangesSummary)
+	for _, change := range analysis.AddedLayers {
+		fmt.Printf("      %s %s\n", green("+"), change.LayerName)
+	}
+	for _, change := range analysis.DeletedLayers {
+		fmt.Printf("      %s %s\n", red("-"), change.LayerName)
+	}
+	for _, change := range analysis.MovedLayers {
+		fmt.Printf("      %s %s (%s)\n", cyan("↕"), change.LayerName, describeLayerProperty("order", change.PropertyChanges["order"]))
+	}
+	for _, change := range analysis.ChangedLayers {
+		var details []string
+		if change.OldHash != change.NewHash {
+			details = append(details, "pixels changed")
+		}
+		properties := make([]string, 0, len(change.PropertyChanges))
+		for property := range change.PropertyChanges {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		for _, property := range properties {
+			details = append(details, describeLayerProperty(property, change.PropertyChanges[property]))
+		}
+		fmt.Printf("      %s %s (%s)\n", yellow("~"), change.LayerName, strings.Join(details, ", "))
+	}
+}
+
+// printDocumentDiff prints the artboard, layer, font, swatch and image changes of an AI file
+func printDocumentDiff(changes *illustrator.DocumentChanges) {
+	fmt.Printf("    document: %s\n", changes.Summary)
+	for _, artboard := range changes.ArtboardsAdded {
+		fmt.Printf("      %s artboard %d %s\n", green("+"), artboard.Index, formatArtboardBounds(artboard.Bounds))
+	}
+	for _, artboard := range changes.ArtboardsRemoved {
+		fmt.Printf("      %s artboard %d %s\n", red("-"), artboard.Index, formatArtboardBounds(artboard.Bounds))
+	}
+	for _, change := range changes.ArtboardsResized {
+		fmt.Printf("      %s artboard %d %s -> %s\n", yellow("~"), change.Index, formatArtboardBounds(change.Old), formatArtboardBounds(change.New))
+	}
+	for _, layer := range changes.LayersAdded {
+		fmt.Printf("      %s layer %s\n", green("+"), layer)
+	}
+	for _, layer := range changes.LayersRemoved {
+		fmt.Printf("      %s layer %s\n", red("-"), layer)
+	}
+	for _, rename := range changes.LayersRenamed {
+		fmt.Printf("      %s layer %s -> %s\n", cyan("→"), rename.Old, rename.New)
+	}
+	for _, layer := range changes.LayersReordered {
+		fmt.Printf("      %s layer %s (stack position)\n", cyan("↕"), layer)
+	}
+	for _, font := range changes.FontsAdded {
+		fmt.Printf("      %s font %s\n", green("+"), font)
+	}
+	for _, font := range changes.FontsRemoved {
+		fmt.Printf("      %s font %s\n", red("-"), font)
+	}
+	for _, swatch := range changes.SwatchesAdded {
+		fmt.Printf("      %s %s\n", green("+"), describeSwatch(swatch))
+	}
+	for _, swatch := range changes.SwatchesRemoved {
+		fmt.Printf("      %s %s\n", red("-"), describeSwatch(swatch))
+	}
+	for _, change := range changes.SwatchesChanged {
+		fmt.Printf("      %s %s -> %s\n", yellow("~"), describeSwatch(change.Old), change.New.Value)
+	}
+	for _, image := range changes.ImagesAdded {
+		fmt.Printf("      %s image %dx%d\n", green("+"), image.Width, image.Height)
+	}
+	for _, image := range changes.ImagesRemoved {
+		fmt.Printf("      %s image %dx%d\n", red("-"), image.Width, image.Height)
+	}
+}
+
+// printNodeDiff prints the node changes of a Figma file, one node per line
+func printNodeDiff(changes *figma.NodeChanges) {
+	fmt.Printf("    nodes: %s\n", changes.Summary)
+	for _, node := range changes.Added {
+		fmt.Printf("      %s %s\n", green("+"), describeNode(node))
+	}
+	for _, node := range changes.Removed {
+		fmt.Printf("      %s %s\n", red("-"), describeNode(node))
+	}
+	for _, change := range changes.Renamed {
+		fmt.Printf("      %s %s %s -> %s\n", cyan("→"), strings.ToLower(change.New.Type), change.Old.Name, change.New.Name)
+	}
+	for _, change := range changes.Resized {
+		fmt.Printf("      %s %s %gx%g -> %gx%g\n", yellow("~"), describeNode(change.New),
+			change.Old.Width, change.Old.Height, change.New.Width, change.New.Height)
+	}
+	for _, change := range changes.TextChanged {
+		fmt.Printf("      %s %s %q -> %q\n", yellow("~"), describeNode(change.New), change.Old.Text, change.New.Text)
+	}
+	for _, change := range changes.Moved {
+		fmt.Printf("      %s %s (new parent)\n", cyan("↕"), describeNode(change.New))
+	}
+}
+
+// describeNode formats a Figma node with its type
+func describeNode(node figma.Node) string {
+	return fmt.Sprintf("%s %s", strings.ToLower(node.Type), node.Name)
+}
+
+// formatArtboardBounds formats artboard bounds as size and origin in points
+func formatArtboardBounds(bounds [4]float64) string {
+	return fmt.Sprintf("%gx%g pt at (%g, %g)", bounds[2]-bounds[0], bounds[3]-bounds[1], bounds[0], bounds[1])
+}
+
+// describeSwatch formats a swatch with its type and color values
+func describeSwatch(swatch illustrator.Swatch) string {
+	text := fmt.Sprintf("%s swatch %s", swatch.Type, swatch.Name)
+	if swatch.Value != "" {
+		text += " " + swatch.Value
+	}
+	return text
+}
+
+// describeLayerProperty formats an {old, new} property change of a layer
+func describeLayerProperty(property string, value interface{}) string {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return property
+	}
+	name := strings.ReplaceAll(property, "_", " ")
+	if property == "order" {
+		name = "stack position"
+	}
+	return fmt.Sprintf("%s %v -> %v", name, values["old"], values["new"])
+}
+
+// printDiffStat prints one line per changed file and a summary
+func printDiffStat(result *diff.Result) {
+	counts := make(map[string]int)
+	for _, file := range result.Files {
+		counts[file.Status]++
+		name := file.Path
+		if file.OldPath != "" {
+			name = file.OldPath + " -> " + file.Path
+		}
+		fmt.Printf(" %-48s | %-8s %s\n", name, file.Status, formatSizeChange(file.OldSize, file.NewSize))
+	}
+	fmt.Printf(" %d file(s) changed: %d added, %d deleted, %d modified, %d renamed\n", len(result.Files),
+		counts[diff.StatusAdded], counts[diff.StatusDeleted], counts[diff.StatusModified], counts[diff.StatusRenamed])
+}
+
+// formatSizeChange formats the difference between two file sizes
+func formatSizeChange(oldSize, newSize int64) string {
+	delta := newSize - oldSize
+	switch {
+	case delta > 0:
+		return green("+" + formatDiffSize(delta))
+	case delta < 0:
+		return red("-" + formatDiffSize(-delta))
+	}
+	return "±0 B"
+}
+
+// formatDiffSize formats a byte count for display
+func formatDiffSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -3,15 +3,18 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"dgit/internal/log"
+	"dgit/internal/refs"
 
 	"github.com/spf13/cobra"
 )
 
 // LogCmd shows commit history with design-specific metadata
 var LogCmd = &cobra.Command{
-	Use:   "log",
+	Use:   "log [revision | from..to] [-- path]",
 	Short: "Show commit history",
 	Long: `Display the commit history showing:
 - Commit hashes and messages
@@ -19,28 +22,79 @@ var LogCmd = &cobra.Command{
 - File counts and metadata summaries
 
 Examples:
-  dgit log                    # Show all commits
+  dgit log                    # Show commits on the current branch
+  dgit log feature/logo       # Show commits on another branch
+  dgit log client-friday      # Show history up to a tag
+  dgit log v3..v7             # Show commits after v3 up to v7
+  dgit log client-friday..    # Show commits since a tag
+  dgit log HEAD~5..HEAD~2     # Revision expressions work on both sides
+  dgit log @{yesterday}..     # Show commits made since yesterday
   dgit log --oneline          # Show compact format
-  dgit log -n 5               # Show last 5 commits`,
-	Run: runLog,
+  dgit log -n 5               # Show last 5 commits
+  dgit log -- hero.psd        # Show commits that touched a file, following renames
+  dgit log v2.. -- hero.psd   # Same, limited to a range
+  dgit log --graph --oneline  # Draw branches and merges as ASCII art
+  dgit log --graph --all      # Include every branch
+
+Without --graph or --all, log follows the first parent of each commit, which
+is the line of history the branch was built on. With them, the whole DAG is
+listed in topological order: every commit before its parents, and commits
+brought in by a merge as one block.
+
+With a path, each commit shows the file's dimensions, layer count and color
+mode at that point, followed by a table of how they evolved.`,
+	Args: cobra.ArbitraryArgs,
+	Run:  runLog,
 }
 
 func init() {
 	LogCmd.Flags().BoolP("oneline", "o", false, "Show commits in compact one-line format")
 	LogCmd.Flags().IntP("number", "n", 0, "Limit the number of commits to show")
+	LogCmd.Flags().Bool("graph", false, "Draw the branch and merge structure as ASCII art")
+	LogCmd.Flags().Bool("all", false, "Show the history of every branch and tag, not just HEAD")
 }
 
 // runLog displays commit history with design-specific information
-func runLog(cmd *cobra.Command, _ []string) {
+func runLog(cmd *cobra.Command, args []string) {
 	dgitDir := checkDgitRepository()
 	logManager := log.NewLogManager(dgitDir)
+	refManager := refs.NewRefManager(dgitDir)
+
+	revisions, paths := splitLogArgs(logManager, args, cmd.ArgsLenAtDash())
+	if len(revisions) > 1 {
+		exitWithError("too many revisions", "Use a single revision or a from..to range")
+	}
+	if len(paths) > 1 {
+		exitWithError("only one path can be followed at a time", "Run 'dgit log -- <path>' for each file")
+	}
+
+	graph, _ := cmd.Flags().GetBool("graph")
+	all, _ := cmd.Flags().GetBool("all")
+	if all && len(revisions) > 0 {
+		exitWithError("--all cannot be combined with a revision", "Use 'dgit log --all' or 'dgit log <revision>'")
+	}
+	if (graph || all) && len(paths) > 0 {
+		exitWithError("--graph and --all cannot be combined with a path", "Run 'dgit log -- <path>' without them")
+	}
 
-	commits, err := logManager.GetCommitHistory()
+	var commits []*log.Commit
+	var err error
+	if graph || all {
+		commits, err = resolveTopoLog(logManager, refManager, revisions, all)
+	} else if len(revisions) > 0 {
+		commits, err = resolveLogRange(logManager, revisions[0])
+	} else {
+		commits, err = logManager.GetCommitHistory()
+	}
 	if err != nil {
 		printError(fmt.Sprintf("loading commit history: %v", err))
 		os.Exit(1)
 	}
 
+	if len(commits) == 0 && len(revisions) > 0 {
+		fmt.Println("No commits in range.")
+		return
+	}
 	if len(commits) == 0 {
 		fmt.Println("No commits yet.")
 		printInfo("Use 'dgit add' and 'dgit commit' to create your first commit.")
@@ -50,39 +104,326 @@ func runLog(cmd *cobra.Command, _ []string) {
 	oneline, _ := cmd.Flags().GetBool("oneline")
 	number, _ := cmd.Flags().GetInt("number")
 
+	if len(paths) > 0 {
+		path := filepath.ToSlash(repoRelativePath(filepath.Dir(dgitDir), paths[0]))
+		history := logManager.FileHistory(commits, path)
+		if number > 0 && number < len(history) {
+			history = history[:number]
+		}
+		printFileHistory(history, path, collectRefDecorations(refManager), oneline)
+		return
+	}
+
 	if number > 0 && number < len(commits) {
 		commits = commits[:number]
 	}
 
+	decorations := collectRefDecorations(refManager)
+
 	fmt.Printf("Commit History (%d commits)\n\n", len(commits))
 
-	for i, c := range commits {
+	if graph {
+		printLogGraph(logManager, commits, decorations, oneline)
+	} else {
+		for i, c := range commits {
+			for _, line := range logEntryLines(logManager, c, decorations, oneline) {
+				fmt.Println(line)
+			}
+			if !oneline && i < len(commits)-1 {
+				fmt.Println()
+			}
+		}
+	}
+
+	fmt.Printf("\nTotal: %d commits in history\n", len(commits))
+}
+
+// logEntryLines formats a commit for the log, as one line or as a full entry
+func logEntryLines(logManager *log.LogManager, c *log.Commit, decorations map[string][]string, oneline bool) []string {
+	decoration := ""
+	if names := decorations[c.Hash]; len(names) > 0 {
+		decoration = " [" + strings.Join(names, ", ") + "]"
+	}
+	if oneline {
+		return []string{fmt.Sprintf("%s (v%d)%s %s", c.Hash[:8], c.Version, decoration, c.Message)}
+	}
+
+	lines := []string{fmt.Sprintf("commit %s (v%d)%s", c.Hash[:12], c.Version, decoration)}
+	if len(c.Parents) > 1 {
+		parents := make([]string, len(c.Parents))
+		for i, parent := range c.Parents {
+			parents[i] = parent[:min(8, len(parent))]
+		}
+		lines = append(lines, "Merge: "+strings.Join(parents, " "))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Author: %s", c.Author),
+		fmt.Sprintf("Date: %s", c.Timestamp.Format("Mon Jan 2 15:04:05 2006")),
+		"",
+		fmt.Sprintf("    %s", c.Message))
+
+	if c.FilesCount > 0 {
+		files := fmt.Sprintf("    Files: %d", c.FilesCount)
+		if c.SnapshotZip != "" {
+			files += fmt.Sprintf(" (snapshot: %s)", c.SnapshotZip)
+		}
+		lines = append(lines, files)
+
+		summary := logManager.GenerateCommitSummary(c)
+		if summary != fmt.Sprintf("[v%d] %s (%d files)", c.Version, c.Message, c.FilesCount) {
+			lines = append(lines, fmt.Sprintf("    %s", summary))
+		}
+	}
+
+	renames := logManager.CommitRenames(c)
+	for _, newPath := range sortedRenameTargets(renames) {
+		lines = append(lines, fmt.Sprintf("    Renamed: %s -> %s", renames[newPath], newPath))
+	}
+	return lines
+}
+
+// printLogGraph prints commits next to ASCII lanes showing branches and merges
+func printLogGraph(logManager *log.LogManager, commits []*log.Commit, decorations map[string][]string, oneline bool) {
+	rows := log.BuildGraph(commits)
+	for i, row := range rows {
+		if row.Commit == nil {
+			fmt.Println(row.Graph)
+			continue
+		}
+		lines := logEntryLines(logManager, row.Commit, decorations, oneline)
+		fmt.Printf("%s %s\n", yellow(row.Graph), lines[0])
+		for _, line := range lines[1:] {
+			fmt.Println(strings.TrimRight(row.Padding+" "+line, " "))
+		}
+		if !oneline && i < len(rows)-1 {
+			fmt.Println(row.Padding)
+		}
+	}
+}
+
+// splitLogArgs separates revisions from the path to follow
+// Without "--", a single argument naming an existing or tracked file is treated as a path
+func splitLogArgs(logManager *log.LogManager, args []string, dash int) ([]string, []string) {
+	if dash >= 0 {
+		return args[:dash], args[dash:]
+	}
+	if len(args) != 1 {
+		return args, nil
+	}
+	if _, err := logManager.ResolveRange(args[0]); err == nil {
+		return args, nil
+	}
+	if fileExists(args[0]) {
+		return nil, args
+	}
+	if head, err := logManager.GetHeadCommit(); err == nil && head != nil {
+		relPath := filepath.ToSlash(repoRelativePath(filepath.Dir(logManager.DgitDir), args[0]))
+		if _, tracked := head.TrackedFiles()[relPath]; tracked {
+			return nil, args
+		}
+	}
+	return args, nil
+}
+
+// printFileHistory prints the commits that touched a file and how its metadata evolved
+func printFileHistory(history []*log.FileRevision, path string, decorations map[string][]string, oneline bool) {
+	if len(history) == 0 {
+		fmt.Printf("No commits touched %s.\n", path)
+		return
+	}
+
+	fmt.Printf("History of %s (%d commits)\n\n", path, len(history))
+	for i, revision := range history {
+		c := revision.Commit
+		decoration := ""
+		if names := decorations[c.Hash]; len(names) > 0 {
+			decoration = " [" + strings.Join(names, ", ") + "]"
+		}
+
+		change := revision.Change + ": " + revision.Path
+		if revision.OldPath != "" {
+			change = fmt.Sprintf("renamed: %s -> %s", revision.OldPath, revision.Path)
+		}
+		details := fileMetadataDetails(revision.Metadata)
+
 		if oneline {
-			fmt.Printf("%s (v%d) %s\n", c.Hash[:8], c.Version, c.Message)
+			fmt.Printf("%s (v%d)%s %s  [%s]\n", c.Hash[:8], c.Version, decoration, strings.SplitN(c.Message, "\n", 2)[0], change)
+			continue
+		}
+		fmt.Printf("commit %s (v%d)%s\n", c.Hash[:12], c.Version, decoration)
+		fmt.Printf("Author: %s\n", c.Author)
+		fmt.Printf("Date: %s\n", c.Timestamp.Format("Mon Jan 2 15:04:05 2006"))
+		fmt.Printf("\n    %s\n\n", c.Message)
+		if details != "" {
+			fmt.Printf("    %s (%s)\n", change, details)
 		} else {
-			fmt.Printf("commit %s (v%d)\n", c.Hash[:12], c.Version)
-			fmt.Printf("Author: %s\n", c.Author)
-			fmt.Printf("Date: %s\n", c.Timestamp.Format("Mon Jan 2 15:04:05 2006"))
-			fmt.Printf("\n    %s\n", c.Message)
-
-			if c.FilesCount > 0 {
-				fmt.Printf("    Files: %d", c.FilesCount)
-				if c.SnapshotZip != "" {
-					fmt.Printf(" (snapshot: %s)", c.SnapshotZip)
-				}
-				fmt.Println()
+			fmt.Printf("    %s\n", change)
+		}
+		if i < len(history)-1 {
+			fmt.Println()
+		}
+	}
 
-				summary := logManager.GenerateCommitSummary(c)
-				if summary != fmt.Sprintf("[v%d] %s (%d files)", c.Version, c.Message, c.FilesCount) {
-					fmt.Printf("    %s\n", summary)
-				}
-			}
+	printMetadataEvolution(history)
+}
 
-			if i < len(commits)-1 {
-				fmt.Println()
+// printMetadataEvolution prints a table of the file's metadata from oldest to newest
+// followed by compact summaries such as "layers: 12 → 15 → 14" for values that changed
+func printMetadataEvolution(history []*log.FileRevision) {
+	fmt.Printf("\nMetadata evolution:\n")
+	fmt.Printf("  %-8s %-9s %-12s %-7s %-10s %s\n", "VERSION", "CHANGE", "DIMENSIONS", "LAYERS", "COLOR", "PATH")
+
+	fields := []string{"dimensions", "layers", "color_mode"}
+	series := make(map[string][]string)
+	for i := len(history) - 1; i >= 0; i-- {
+		revision := history[i]
+		values := make(map[string]string)
+		for _, field := range fields {
+			values[field] = metadataValue(revision.Metadata, field)
+			if values[field] == "" {
+				continue
+			}
+			if n := len(series[field]); n == 0 || series[field][n-1] != values[field] {
+				series[field] = append(series[field], values[field])
 			}
 		}
+		fmt.Printf("  %-8s %-9s %-12s %-7s %-10s %s\n", fmt.Sprintf("v%d", revision.Commit.Version), revision.Change,
+			orDash(values["dimensions"]), orDash(values["layers"]), orDash(values["color_mode"]), revision.Path)
 	}
 
-	fmt.Printf("\nTotal: %d commits in history\n", len(commits))
+	labels := map[string]string{"dimensions": "dimensions", "layers": "layers", "color_mode": "color mode"}
+	for _, field := range fields {
+		if len(series[field]) > 1 {
+			fmt.Printf("  %s: %s\n", labels[field], strings.Join(series[field], " → "))
+		}
+	}
+}
+
+// fileMetadataDetails formats dimensions, layer count and color mode for display
+func fileMetadataDetails(meta map[string]interface{}) string {
+	var details []string
+	if dimensions := metadataValue(meta, "dimensions"); dimensions != "" {
+		details = append(details, dimensions)
+	}
+	if layers := metadataValue(meta, "layers"); layers != "" {
+		details = append(details, layers+" layers")
+	}
+	if colorMode := metadataValue(meta, "color_mode"); colorMode != "" {
+		details = append(details, colorMode)
+	}
+	return strings.Join(details, ", ")
+}
+
+// metadataValue formats a stored metadata field, returning "" when it is missing or unknown
+func metadataValue(meta map[string]interface{}, field string) string {
+	switch value := meta[field].(type) {
+	case string:
+		if value != "Unknown" {
+			return value
+		}
+	case float64:
+		if value > 0 {
+			return fmt.Sprintf("%.0f", value)
+		}
+	case int:
+		if value > 0 {
+			return fmt.Sprintf("%d", value)
+		}
+	}
+	return ""
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// resolveLogRange returns the history selected by a revision or a "from..to" range
+// A range lists commits reachable from 'to' that are not reachable from 'from'; either side defaults to HEAD
+func resolveLogRange(logManager *log.LogManager, spec string) ([]*log.Commit, error) {
+	revisionRange, err := logManager.ResolveRange(spec)
+	if err != nil {
+		return nil, err
+	}
+	return logManager.RangeCommits(revisionRange)
+}
+
+// resolveTopoLog lists the whole DAG behind HEAD, a revision or range, or every ref, in topological order
+func resolveTopoLog(logManager *log.LogManager, refManager *refs.RefManager, revisions []string, all bool) ([]*log.Commit, error) {
+	var tips, exclude []string
+	switch {
+	case all:
+		if head, err := refManager.ResolveHead(); err == nil && head != "" {
+			tips = append(tips, head)
+		}
+		branches, err := refManager.ListBranches()
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range branches {
+			tips = append(tips, branch.Hash)
+		}
+		tags, err := refManager.ListTags()
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			tips = append(tips, tag.Target)
+		}
+	case len(revisions) > 0:
+		revisionRange, err := logManager.ResolveRange(revisions[0])
+		if err != nil {
+			return nil, err
+		}
+		tips = []string{revisionRange.To.Hash}
+		if revisionRange.From != nil {
+			exclude = []string{revisionRange.From.Hash}
+		}
+	default:
+		head, err := refManager.ResolveHead()
+		if err != nil {
+			return nil, err
+		}
+		if head == "" {
+			return nil, nil
+		}
+		tips = []string{head}
+	}
+	return logManager.GetTopoHistory(tips, exclude)
+}
+
+// collectRefDecorations maps commit hashes to the branch and tag names pointing at them
+// The checked-out branch is shown as "HEAD -> name", a detached HEAD as "HEAD"
+func collectRefDecorations(refManager *refs.RefManager) map[string][]string {
+	decorations := make(map[string][]string)
+
+	branches, err := refManager.ListBranches()
+	if err != nil {
+		return decorations
+	}
+
+	if refManager.IsDetached() {
+		if head, err := refManager.ResolveHead(); err == nil && head != "" {
+			decorations[head] = append(decorations[head], "HEAD")
+		}
+	}
+
+	for _, branch := range branches {
+		if branch.Current {
+			// Keep the checked-out branch first
+			decorations[branch.Hash] = append([]string{"HEAD -> " + branch.Name}, decorations[branch.Hash]...)
+		} else {
+			decorations[branch.Hash] = append(decorations[branch.Hash], branch.Name)
+		}
+	}
+
+	if tags, err := refManager.ListTags(); err == nil {
+		for _, tag := range tags {
+			decorations[tag.Target] = append(decorations[tag.Target], "tag: "+tag.Name)
+		}
+	}
+	return decorations
 }
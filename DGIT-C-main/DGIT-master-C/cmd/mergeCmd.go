@@ -0,0 +1,506 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dgit/internal/commit"
+	"dgit/internal/log"
+	"dgit/internal/merge"
+	"dgit/internal/refs"
+	"dgit/internal/restore"
+	"dgit/internal/staging"
+
+	"github.com/spf13/cobra"
+)
+
+// MergeCmd merges another branch or revision into the current branch
+var MergeCmd = &cobra.Command{
+	Use:   "merge [branch_or_revision]",
+	Short: "Merge another branch into the current branch",
+	Long: `Combine the work of another branch with the current branch.
+
+DGit performs a three-way merge against the nearest common ancestor:
+- Files changed on only one side are taken automatically
+- Files changed on both sides are conflicts
+- PSD files changed on both sides are merged layer by layer when each side
+  changed different layers
+
+Conflicts are resolved per file by keeping one side, or by staging your own
+version with 'dgit add'. Finish with 'dgit merge --continue'.
+
+Examples:
+  dgit merge feature/logo                 # Merge a branch
+  dgit merge feature/logo -m "Bring in logo"
+  dgit merge --theirs hero.psd            # Resolve a conflict with their version
+  dgit merge --ours hero.psd              # Resolve a conflict with our version
+  dgit merge --continue                   # Create the merge commit
+  dgit merge --abort                      # Give up and restore the previous state`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runMerge,
+}
+
+func init() {
+	MergeCmd.Flags().StringP("message", "m", "", "Message for the merge commit")
+	MergeCmd.Flags().Bool("no-ff", false, "Create a merge commit even when a fast-forward is possible")
+	MergeCmd.Flags().StringSlice("ours", nil, "Resolve conflicted files by keeping our version")
+	MergeCmd.Flags().StringSlice("theirs", nil, "Resolve conflicted files by taking their version")
+	MergeCmd.Flags().Bool("continue", false, "Create the merge commit once all conflicts are resolved")
+	MergeCmd.Flags().Bool("abort", false, "Abort the merge in progress and restore the previous state")
+}
+
+// runMerge dispatches between starting, resolving, continuing and aborting a merge
+func runMerge(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	mergeManager := merge.NewMergeManager(dgitDir)
+
+	ours, _ := cmd.Flags().GetStringSlice("ours")
+	theirs, _ := cmd.Flags().GetStringSlice("theirs")
+	continueFlag, _ := cmd.Flags().GetBool("continue")
+	abortFlag, _ := cmd.Flags().GetBool("abort")
+
+	switch {
+	case abortFlag:
+		abortMerge(dgitDir, mergeManager)
+	case len(ours) > 0 || len(theirs) > 0:
+		resolveConflicts(dgitDir, mergeManager, ours, theirs)
+	case continueFlag:
+		continueMerge(dgitDir, mergeManager)
+	case len(args) == 1:
+		message, _ := cmd.Flags().GetString("message")
+		noFF, _ := cmd.Flags().GetBool("no-ff")
+		startMerge(dgitDir, mergeManager, args[0], message, noFF)
+	default:
+		if mergeManager.InProgress() {
+			printMergeStatus(mergeManager)
+			return
+		}
+		exitWithError("nothing to merge", "Usage: dgit merge <branch>")
+	}
+}
+
+// startMerge merges a branch or revision into HEAD
+func startMerge(dgitDir string, mergeManager *merge.MergeManager, name, message string, noFF bool) {
+	if mergeManager.InProgress() {
+		exitWithError("a merge is already in progress",
+			"Resolve it with 'dgit merge --continue' or cancel it with 'dgit merge --abort'")
+	}
+
+	logManager := log.NewLogManager(dgitDir)
+	refManager := refs.NewRefManager(dgitDir)
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head == nil {
+		exitWithError("cannot merge into a branch without commits", "Create a commit first")
+	}
+
+	theirs, err := findTargetCommit(logManager, name)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if upToDate, err := logManager.IsAncestor(theirs.Hash, head.Hash); err != nil {
+		printError(fmt.Sprintf("reading history: %v", err))
+		os.Exit(1)
+	} else if upToDate {
+		fmt.Println("Already up to date.")
+		return
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+	checkMergeSafety(dgitDir, stagingArea, head, theirs)
+
+	fastForward, err := logManager.IsAncestor(head.Hash, theirs.Hash)
+	if err != nil {
+		printError(fmt.Sprintf("reading history: %v", err))
+		os.Exit(1)
+	}
+	if fastForward && !noFF {
+		fastForwardMerge(dgitDir, refManager, head, theirs, name)
+		return
+	}
+
+	base, err := logManager.FindMergeBase(head.Hash, theirs.Hash)
+	if err != nil {
+		printError(fmt.Sprintf("finding merge base: %v", err))
+		os.Exit(1)
+	}
+	if base == nil {
+		printWarning("the histories share no common commit; every file is treated as added")
+	}
+
+	fmt.Printf("Merging %s (v%d) into %s (v%d)...\n", theirs.Hash[:8], theirs.Version, describeHead(refManager, head), head.Version)
+	result, err := mergeManager.MergeTrees(base, head, theirs)
+	if err != nil {
+		printError(fmt.Sprintf("merging: %v", err))
+		os.Exit(1)
+	}
+
+	if message == "" {
+		message = defaultMergeMessage(refManager, name)
+	}
+	state := &merge.MergeState{
+		Ours:       head.Hash,
+		Theirs:     theirs.Hash,
+		TheirsName: name,
+		Message:    message,
+		Tree:       result.Tree,
+		Conflicts:  result.Conflicts,
+		StartedAt:  time.Now(),
+	}
+	if base != nil {
+		state.Base = base.Hash
+	}
+
+	workTree := filepath.Dir(dgitDir)
+	for _, path := range result.FromTheirs {
+		if err := writeCommitFile(mergeManager, theirs, workTree, path); err != nil {
+			printError(fmt.Sprintf("updating %s: %v", path, err))
+			os.Exit(1)
+		}
+		fmt.Printf("  %s %s\n", green("updated:"), path)
+	}
+	for _, path := range result.Removed {
+		if err := os.Remove(filepath.Join(workTree, path)); err != nil && !os.IsNotExist(err) {
+			printWarning(fmt.Sprintf("removing %s: %v", path, err))
+		}
+		fmt.Printf("  %s %s\n", yellow("removed:"), path)
+	}
+	for _, layerMerge := range result.LayerMerged {
+		if err := os.WriteFile(filepath.Join(workTree, layerMerge.Path), layerMerge.Content, 0644); err != nil {
+			printError(fmt.Sprintf("writing merged %s: %v", layerMerge.Path, err))
+			os.Exit(1)
+		}
+		state.LayerMerged = append(state.LayerMerged, layerMerge.Path)
+		fmt.Printf("  %s %s (layers: ours %s; theirs %s)\n", cyan("layer-merged:"), layerMerge.Path,
+			joinOrNone(layerMerge.OursChanged), joinOrNone(layerMerge.TheirsChanged))
+	}
+
+	if len(result.Conflicts) > 0 {
+		if err := mergeManager.SaveState(state); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println()
+		printError(fmt.Sprintf("automatic merge failed; %d file(s) in conflict:", len(result.Conflicts)))
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(os.Stderr, "  %s %s (%s)\n", red("conflict:"), conflict.Path, conflict.Reason)
+		}
+		printSuggestion("Resolve each file with 'dgit merge --ours <file>' or 'dgit merge --theirs <file>',")
+		fmt.Fprintln(os.Stderr, "   or stage your own version with 'dgit add <file>', then run 'dgit merge --continue'")
+		os.Exit(1)
+	}
+
+	commitMerge(dgitDir, mergeManager, state)
+}
+
+// fastForwardMerge moves the current branch forward to a descendant commit
+func fastForwardMerge(dgitDir string, refManager *refs.RefManager, head, theirs *log.Commit, name string) {
+	result, err := restore.NewRestoreManager(dgitDir).CheckoutCommit(head, theirs, false)
+	if err != nil {
+		for file, fileErr := range result.ErrorFiles {
+			printWarning(fmt.Sprintf("%s: %v", file, fileErr))
+		}
+		printError(fmt.Sprintf("checking out %s: %v", theirs.Hash[:8], err))
+		os.Exit(1)
+	}
+	if err := refManager.UpdateHead(theirs.Hash); err != nil {
+		printError(fmt.Sprintf("updating HEAD: %v", err))
+		os.Exit(1)
+	}
+	if err := refManager.RecordRefUpdate(head.Hash, theirs.Hash, "merge", fmt.Sprintf("%s: fast-forward", name)); err != nil {
+		printWarning(fmt.Sprintf("Failed to update reflog: %v", err))
+	}
+
+	fmt.Printf("Updating %s..%s\n", head.Hash[:8], theirs.Hash[:8])
+	printSuccess(fmt.Sprintf("Fast-forward to %s (v%d): updated %d file(s), removed %d file(s)",
+		theirs.Hash[:8], theirs.Version, len(result.RestoredFiles), len(result.RemovedFiles)))
+}
+
+// resolveConflicts records per-file resolutions for the merge in progress
+func resolveConflicts(dgitDir string, mergeManager *merge.MergeManager, ours, theirs []string) {
+	state, err := mergeManager.LoadState()
+	if err != nil {
+		exitWithError(err.Error(), "Start a merge with 'dgit merge <branch>'")
+	}
+
+	logManager := log.NewLogManager(dgitDir)
+	workTree := filepath.Dir(dgitDir)
+
+	resolutions := make(map[string]string)
+	for _, file := range ours {
+		resolutions[file] = merge.ResolutionOurs
+	}
+	for _, file := range theirs {
+		if resolutions[file] != "" {
+			printError(fmt.Sprintf("%s cannot be resolved with both --ours and --theirs", file))
+			os.Exit(1)
+		}
+		resolutions[file] = merge.ResolutionTheirs
+	}
+
+	for file, resolution := range resolutions {
+		path := repoRelativePath(workTree, file)
+		conflict := state.Conflict(path)
+		if conflict == nil {
+			printError(fmt.Sprintf("%s is not in conflict", file))
+			os.Exit(1)
+		}
+
+		sideHash := state.Ours
+		if resolution == merge.ResolutionTheirs {
+			sideHash = state.Theirs
+		}
+		side, err := logManager.GetCommitByHash(sideHash)
+		if err != nil {
+			printError(fmt.Sprintf("loading %s side: %v", resolution, err))
+			os.Exit(1)
+		}
+
+		// Take the chosen side's version, including its removal of the file
+		if entry, exists := side.TrackedFiles()[path]; exists {
+			if err := writeCommitFile(mergeManager, side, workTree, path); err != nil {
+				printError(fmt.Sprintf("writing %s: %v", path, err))
+				os.Exit(1)
+			}
+			state.Tree[path] = entry
+		} else {
+			if err := os.Remove(filepath.Join(workTree, path)); err != nil && !os.IsNotExist(err) {
+				printError(fmt.Sprintf("removing %s: %v", path, err))
+				os.Exit(1)
+			}
+			delete(state.Tree, path)
+		}
+		conflict.Resolution = resolution
+		printSuccess(fmt.Sprintf("Resolved %s using %s version", path, resolution))
+	}
+
+	if err := mergeManager.SaveState(state); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if remaining := state.Unresolved(); len(remaining) > 0 {
+		printInfo(fmt.Sprintf("%d conflict(s) remaining: %s", len(remaining), strings.Join(remaining, ", ")))
+	} else {
+		printInfo("All conflicts resolved. Run 'dgit merge --continue' to create the merge commit")
+	}
+}
+
+// continueMerge creates the merge commit once every conflict is resolved
+func continueMerge(dgitDir string, mergeManager *merge.MergeManager) {
+	state, err := mergeManager.LoadState()
+	if err != nil {
+		exitWithError(err.Error(), "Start a merge with 'dgit merge <branch>'")
+	}
+
+	// Files staged by hand count as resolved with the staged content
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+	staged := make(map[string]bool)
+	for _, file := range stagingArea.GetStagedFiles() {
+		staged[file.Path] = true
+	}
+
+	var unresolved []string
+	for _, path := range state.Unresolved() {
+		if !staged[path] {
+			unresolved = append(unresolved, path)
+		}
+	}
+	if len(unresolved) > 0 {
+		printError("cannot continue: unresolved conflicts remain:")
+		for _, path := range unresolved {
+			fmt.Fprintf(os.Stderr, "  %s %s\n", red("conflict:"), path)
+		}
+		printSuggestion("Use 'dgit merge --ours <file>', 'dgit merge --theirs <file>', or 'dgit add <file>'")
+		os.Exit(1)
+	}
+
+	commitMerge(dgitDir, mergeManager, state)
+}
+
+// commitMerge stores layer-merged and hand-resolved files and records the merge commit
+func commitMerge(dgitDir string, mergeManager *merge.MergeManager, state *merge.MergeState) {
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+	workTree := filepath.Dir(dgitDir)
+	for _, path := range state.LayerMerged {
+		if err := stagingArea.AddFile(filepath.Join(workTree, path)); err != nil {
+			printError(fmt.Sprintf("staging merged %s: %v", path, err))
+			os.Exit(1)
+		}
+	}
+
+	commitManager := commit.NewCommitManager(dgitDir)
+	mergeCommit, err := commitManager.CreateMergeCommit(state.Message, stagingArea.GetStagedFiles(), state.Tree, state.Theirs)
+	if err != nil {
+		printError(fmt.Sprintf("creating merge commit: %v", err))
+		os.Exit(1)
+	}
+
+	if err := stagingArea.ClearStaging(); err != nil {
+		printWarning(fmt.Sprintf("failed to clear staging area: %v", err))
+	}
+	if err := mergeManager.ClearState(); err != nil {
+		printWarning(err.Error())
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Merge commit %s (v%d) created", mergeCommit.Hash[:8], mergeCommit.Version))
+	fmt.Println(state.Message)
+}
+
+// abortMerge restores the working tree to the commit the merge started from
+func abortMerge(dgitDir string, mergeManager *merge.MergeManager) {
+	state, err := mergeManager.LoadState()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	ours, err := log.NewLogManager(dgitDir).GetCommitByHash(state.Ours)
+	if err != nil {
+		printError(fmt.Sprintf("loading %s: %v", state.Ours, err))
+		os.Exit(1)
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err == nil {
+		stagingArea.ClearStaging()
+	}
+
+	// The merged tree covers every file the merge may have written
+	merged := &log.Commit{Hash: "merge", Tree: state.Tree}
+	if _, err := restore.NewRestoreManager(dgitDir).CheckoutCommit(merged, ours, true); err != nil {
+		printError(fmt.Sprintf("restoring %s: %v", ours.Hash[:8], err))
+		os.Exit(1)
+	}
+	if err := mergeManager.ClearState(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	printSuccess(fmt.Sprintf("Merge aborted; working tree restored to %s (v%d)", ours.Hash[:8], ours.Version))
+}
+
+// printMergeStatus summarizes the merge in progress
+func printMergeStatus(mergeManager *merge.MergeManager) {
+	state, err := mergeManager.LoadState()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("Merging %s into %s\n", state.TheirsName, state.Ours[:8])
+	for _, conflict := range state.Conflicts {
+		if conflict.Resolution != "" {
+			fmt.Printf("  %s %s (%s)\n", green("resolved:"), conflict.Path, conflict.Resolution)
+		} else {
+			fmt.Printf("  %s %s (%s)\n", red("conflict:"), conflict.Path, conflict.Reason)
+		}
+	}
+}
+
+// checkMergeSafety exits when the merge could overwrite staged work or local changes
+func checkMergeSafety(dgitDir string, stagingArea *staging.StagingArea, head, theirs *log.Commit) {
+	if !stagingArea.IsEmpty() {
+		exitWithError("you have staged changes; commit them before merging", "Run 'dgit commit' first")
+	}
+
+	changes, err := getWorkingTreeChanges(dgitDir, head)
+	if err != nil {
+		printError(fmt.Sprintf("checking working tree: %v", err))
+		os.Exit(1)
+	}
+	if len(changes.ModifiedFiles) > 0 || len(changes.DeletedFiles) > 0 {
+		printError("your local changes would be overwritten by merge:")
+		for _, file := range changes.ModifiedFiles {
+			fmt.Fprintf(os.Stderr, "  modified: %s\n", file.Path)
+		}
+		for _, file := range changes.DeletedFiles {
+			fmt.Fprintf(os.Stderr, "  deleted:  %s\n", file.Path)
+		}
+		exitWithError("", "Commit or restore them before merging")
+	}
+
+	theirsFiles := theirs.TrackedFiles()
+	for _, file := range changes.UntrackedFiles {
+		if _, ok := theirsFiles[file.Path]; ok {
+			exitWithError(fmt.Sprintf("untracked file %s would be overwritten by merge", file.Path),
+				"Move or add it first")
+		}
+	}
+}
+
+// writeCommitFile writes a file's content from a commit into the working tree
+func writeCommitFile(mergeManager *merge.MergeManager, c *log.Commit, workTree, path string) error {
+	data, err := mergeManager.ReadFile(c, path)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(workTree, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0644)
+}
+
+// defaultMergeMessage describes the merge in the style "Merge branch 'x' into y"
+func defaultMergeMessage(refManager *refs.RefManager, name string) string {
+	subject := fmt.Sprintf("Merge '%s'", name)
+	if refManager.BranchExists(name) {
+		subject = fmt.Sprintf("Merge branch '%s'", name)
+	}
+	if current, err := refManager.CurrentBranch(); err == nil && current != "" && current != refs.DefaultBranch {
+		subject += " into " + current
+	}
+	return subject
+}
+
+// describeHead names the current branch, or the short hash when detached
+func describeHead(refManager *refs.RefManager, head *log.Commit) string {
+	if current, err := refManager.CurrentBranch(); err == nil && current != "" {
+		return current
+	}
+	return head.Hash[:8]
+}
+
+// repoRelativePath converts a user-supplied path to the repository-relative form used in trees
+func repoRelativePath(workTree, path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	relPath, err := filepath.Rel(workTree, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// joinOrNone joins names for display, or returns "none"
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
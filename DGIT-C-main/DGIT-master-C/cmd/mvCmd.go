@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dgit/internal/log"
+	"dgit/internal/scanner"
+	"dgit/internal/staging"
+
+	"github.com/spf13/cobra"
+)
+
+// MvCmd moves or renames a tracked file and records the rename for the next commit
+var MvCmd = &cobra.Command{
+	Use:   "mv <source> <destination>",
+	Short: "Move or rename a tracked file",
+	Long: `Move or rename a tracked design file and stage the rename.
+
+The next commit records the file under its new path together with the path
+it came from, so 'dgit log' can follow its history across the rename. When
+the destination is an existing directory the file keeps its name.
+
+Examples:
+  dgit mv logo.ai brand/logo.ai
+  dgit mv hero.psd hero-v2.psd
+  dgit mv -f draft.psd final.psd    # Overwrite an existing destination`,
+	Args: cobra.ExactArgs(2),
+	Run:  runMv,
+}
+
+func init() {
+	MvCmd.Flags().BoolP("force", "f", false, "Overwrite the destination if it exists")
+}
+
+// runMv moves the file on disk and stages the removal of the old path and the new path
+func runMv(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	workTree := filepath.Dir(dgitDir)
+	force, _ := cmd.Flags().GetBool("force")
+
+	srcRel := filepath.ToSlash(repoRelativePath(workTree, args[0]))
+	srcAbs := filepath.Join(workTree, srcRel)
+	dstAbs, err := filepath.Abs(args[1])
+	if err != nil {
+		printError(fmt.Sprintf("resolving %s: %v", args[1], err))
+		os.Exit(1)
+	}
+	if info, err := os.Stat(dstAbs); err == nil && info.IsDir() {
+		dstAbs = filepath.Join(dstAbs, filepath.Base(srcAbs))
+	}
+	dstRel := filepath.ToSlash(repoRelativePath(workTree, dstAbs))
+
+	info, err := os.Stat(srcAbs)
+	if err != nil {
+		exitWithError(fmt.Sprintf("cannot move '%s': file not found", args[0]), "")
+	}
+	if info.IsDir() {
+		exitWithError(fmt.Sprintf("cannot move '%s': directories are not supported", args[0]), "Move the files inside it one by one")
+	}
+	if !scanner.IsDesignFile(dstAbs) {
+		exitWithError(fmt.Sprintf("'%s' is not a design file name", args[1]), "Keep a supported extension such as .psd or .ai")
+	}
+	if _, err := os.Stat(dstAbs); err == nil && !force {
+		exitWithError(fmt.Sprintf("destination '%s' already exists", dstRel), "Use --force to overwrite it")
+	}
+
+	isTracked := false
+	head, err := log.NewLogManager(dgitDir).GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head != nil {
+		_, isTracked = head.TrackedFiles()[srcRel]
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+	isStaged := stagingArea.HasFile(srcAbs)
+	if !isTracked && !isStaged {
+		exitWithError(fmt.Sprintf("'%s' is not tracked", srcRel), fmt.Sprintf("Use 'dgit add %s' first", srcRel))
+	}
+
+	// A file moved again before committing keeps the path it was committed under
+	renamedFrom := srcRel
+	if isStaged {
+		for _, file := range stagingArea.GetStagedFiles() {
+			if file.AbsolutePath == srcAbs && file.RenamedFrom != "" {
+				renamedFrom = file.RenamedFrom
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstAbs), 0755); err != nil {
+		printError(fmt.Sprintf("creating %s: %v", filepath.Dir(dstRel), err))
+		os.Exit(1)
+	}
+	if err := os.Rename(srcAbs, dstAbs); err != nil {
+		printError(fmt.Sprintf("moving %s: %v", srcRel, err))
+		os.Exit(1)
+	}
+
+	if isStaged {
+		if err := stagingArea.RemoveFile(srcAbs); err != nil {
+			printWarning(err.Error())
+		}
+	}
+	if isTracked {
+		stagingArea.StageRemoval(srcRel)
+	}
+	if err := stagingArea.AddFile(dstAbs); err != nil {
+		printError(fmt.Sprintf("staging %s: %v", dstRel, err))
+		os.Exit(1)
+	}
+	if isTracked || renamedFrom != srcRel {
+		if err := stagingArea.MarkRenamed(dstAbs, renamedFrom); err != nil {
+			printWarning(err.Error())
+		}
+	}
+	if err := stagingArea.SaveStaging(); err != nil {
+		printError(fmt.Sprintf("saving staging area: %v", err))
+		os.Exit(1)
+	}
+
+	printSuccess(fmt.Sprintf("Renamed %s -> %s", srcRel, dstRel))
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"dgit/internal/log"
+	"dgit/internal/refs"
+
+	"github.com/spf13/cobra"
+)
+
+// ReflogCmd shows where HEAD and branches have pointed over time
+var ReflogCmd = &cobra.Command{
+	Use:   "reflog [HEAD | branch]",
+	Short: "Show the history of HEAD and branch movements",
+	Long: `Show every change of HEAD or a branch, newest first: commits, amends,
+merges, resets, switches and bisect checkouts. Each entry records the old
+and new commit, the operation and when it happened.
+
+Entries can be used in revision expressions, which makes it possible to get
+back commits that are no longer on any branch, e.g. after a reset or amend:
+HEAD@{2} is where HEAD was two moves ago and main@{1} is the previous tip of
+main.
+
+Examples:
+  dgit reflog                 # Changes of HEAD
+  dgit reflog main            # Changes of the main branch
+  dgit reflog -n 5            # The latest five entries
+  dgit show HEAD@{1}          # Inspect the commit before the last move
+  dgit reset --hard HEAD@{1}  # Undo the last reset or amend`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runReflog,
+}
+
+func init() {
+	ReflogCmd.Flags().IntP("number", "n", 0, "Limit the number of entries to show")
+	ReflogCmd.Flags().Bool("json", false, "Output in JSON format")
+}
+
+// runReflog prints the log of a reference, newest entry first
+func runReflog(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	refManager := refs.NewRefManager(dgitDir)
+	logManager := log.NewLogManager(dgitDir)
+	limit, _ := cmd.Flags().GetInt("number")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	name := "HEAD"
+	if len(args) == 1 {
+		name = args[0]
+	}
+	ref, err := refManager.ReflogRef(name)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	entries, err := refManager.ReadReflog(ref)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	// Newest first, as numbered by <ref>@{N}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No reflog entries for %s\n", ref)
+		return
+	}
+
+	label := strings.TrimPrefix(ref, "refs/heads/")
+	for i, entry := range entries {
+		version := ""
+		if c, err := logManager.GetCommitByHash(entry.NewHash); err == nil {
+			version = fmt.Sprintf(" (v%d)", c.Version)
+		}
+		description := entry.Operation
+		if entry.Message != "" {
+			description += ": " + firstLine(entry.Message)
+		}
+		fmt.Printf("%s%s %s@{%d}: %s %s\n", yellow(shortHash(entry.NewHash)), version, label, i,
+			description, cyan(entry.Timestamp.Format("(2006-01-02 15:04:05)")))
+	}
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if hash == "" {
+		return "--------"
+	}
+	return hash[:min(8, len(hash))]
+}
+
+// firstLine returns the first line of a message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
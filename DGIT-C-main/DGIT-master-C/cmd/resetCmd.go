@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"dgit/internal/log"
+	"dgit/internal/merge"
+	"dgit/internal/refs"
+	"dgit/internal/restore"
+	"dgit/internal/staging"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// ResetCmd unstages files or moves HEAD to another commit
+var ResetCmd = &cobra.Command{
+	Use:   "reset [--soft | --mixed | --hard] [version_or_hash] [-- files...]",
+	Short: "Unstage files or move HEAD to another commit",
+	Long: `Undo staging or move the current branch to another commit.
+
+Modes:
+  dgit reset [files]        Unstage files (all files when none are given)
+  dgit reset --soft <rev>   Move HEAD only; staging and working tree are kept
+  dgit reset --mixed <rev>  Move HEAD and clear the staging area (default)
+  dgit reset --hard <rev>   Move HEAD and overwrite the working tree with <rev>
+
+--hard discards local modifications, and moving HEAD backwards can leave
+commits unreachable from any branch. In a terminal you are asked to confirm
+these cases; use --yes to skip the prompt. Without a terminal, such as when
+run from a script, these resets are refused unless --yes is given.
+
+Examples:
+  dgit reset                     # Unstage everything
+  dgit reset hero.psd            # Unstage a file
+  dgit reset HEAD -- hero.psd    # Same, with an explicit separator
+  dgit reset --soft v4           # Move HEAD to version 4, keep changes
+  dgit reset --hard v4           # Return the working tree to version 4`,
+	Run: runReset,
+}
+
+// UnstageCmd removes files from the staging area
+var UnstageCmd = &cobra.Command{
+	Use:   "unstage [files...]",
+	Short: "Remove files from the staging area",
+	Long: `Remove files from the staging area without touching the working tree.
+Without arguments every staged file is unstaged. Same as 'dgit reset [files]'.
+
+Examples:
+  dgit unstage hero.psd
+  dgit unstage`,
+	Run: func(cmd *cobra.Command, args []string) {
+		unstageFiles(checkDgitRepository(), args)
+	},
+}
+
+func init() {
+	ResetCmd.Flags().Bool("soft", false, "Move HEAD only")
+	ResetCmd.Flags().Bool("mixed", false, "Move HEAD and clear the staging area")
+	ResetCmd.Flags().Bool("hard", false, "Move HEAD and overwrite the working tree")
+	ResetCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
+
+// runReset dispatches between unstaging files and moving HEAD
+func runReset(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+
+	soft, _ := cmd.Flags().GetBool("soft")
+	mixed, _ := cmd.Flags().GetBool("mixed")
+	hard, _ := cmd.Flags().GetBool("hard")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	modes := 0
+	for _, set := range []bool{soft, mixed, hard} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		printError("--soft, --mixed and --hard are mutually exclusive")
+		os.Exit(1)
+	}
+
+	target, paths := splitResetArgs(dgitDir, args, cmd.ArgsLenAtDash())
+
+	if len(paths) > 0 {
+		if modes > 0 {
+			exitWithError("cannot use --soft, --mixed or --hard with paths", "Use 'dgit reset <files>' to unstage files")
+		}
+		if target != "" && target != "HEAD" {
+			exitWithError("files can only be unstaged relative to HEAD",
+				fmt.Sprintf("Use 'dgit restore %s <files>' to bring back older versions", target))
+		}
+		unstageFiles(dgitDir, paths)
+		return
+	}
+
+	if target == "" {
+		target = "HEAD"
+	}
+	// A mixed reset to HEAD only empties the staging area
+	if target == "HEAD" && !soft && !hard {
+		unstageFiles(dgitDir, nil)
+		return
+	}
+
+	mode := "mixed"
+	switch {
+	case soft:
+		mode = "soft"
+	case hard:
+		mode = "hard"
+	}
+	resetHead(dgitDir, target, mode, yes)
+}
+
+// splitResetArgs separates the target revision from file paths
+// Without "--", an argument naming an existing or staged file is treated as a path
+func splitResetArgs(dgitDir string, args []string, dash int) (string, []string) {
+	if dash >= 0 {
+		target := ""
+		if dash > 0 {
+			target = args[0]
+		}
+		return target, args[dash:]
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	stagingArea.LoadStaging()
+	isPath := func(arg string) bool {
+		if _, err := os.Stat(arg); err == nil {
+			return true
+		}
+		return stagingArea.HasFile(arg) || stagingArea.IsRemoved(repoRelativePath(stagingArea.WorkTreeRoot(), arg))
+	}
+
+	if isPath(args[0]) {
+		return "", args
+	}
+	return args[0], args[1:]
+}
+
+// unstageFiles removes the given files (or everything) from the staging area
+func unstageFiles(dgitDir string, paths []string) {
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+
+	if len(paths) == 0 {
+		count := stagingArea.GetFileCount()
+		if count == 0 {
+			fmt.Println("Nothing to unstage.")
+			return
+		}
+		if err := stagingArea.ClearStaging(); err != nil {
+			printError(fmt.Sprintf("clearing staging area: %v", err))
+			os.Exit(1)
+		}
+		printSuccess(fmt.Sprintf("Unstaged %d file(s)", count))
+		return
+	}
+
+	failed := false
+	for _, path := range paths {
+		if err := stagingArea.RemoveFile(path); err != nil {
+			printWarning(err.Error())
+			failed = true
+			continue
+		}
+		fmt.Printf("  %s %s\n", yellow("unstaged:"), path)
+	}
+	if err := stagingArea.SaveStaging(); err != nil {
+		printError(fmt.Sprintf("saving staging area: %v", err))
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// resetHead moves the current branch (or detached HEAD) to a commit
+func resetHead(dgitDir, targetRef, mode string, yes bool) {
+	logManager := log.NewLogManager(dgitDir)
+	refManager := refs.NewRefManager(dgitDir)
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head == nil {
+		exitWithError("no commits yet", "Use 'dgit reset' to unstage files")
+	}
+
+	target, err := findTargetCommit(logManager, targetRef)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+
+	// Collect what would be lost so the user can confirm
+	var warnings []string
+	if lost := unreachableAfterReset(dgitDir, logManager, refManager, head, target); lost > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d commit(s) will no longer be reachable from any branch or tag", lost))
+	}
+	if mode == "hard" {
+		if !stagingArea.IsEmpty() {
+			warnings = append(warnings, fmt.Sprintf("%d staged file(s) will be unstaged and overwritten", stagingArea.GetFileCount()))
+		}
+		changes, err := getWorkingTreeChanges(dgitDir, head)
+		if err != nil {
+			printError(fmt.Sprintf("checking working tree: %v", err))
+			os.Exit(1)
+		}
+		if n := len(changes.ModifiedFiles) + len(changes.DeletedFiles); n > 0 {
+			warnings = append(warnings, fmt.Sprintf("local changes to %d file(s) will be discarded", n))
+		}
+	}
+	if len(warnings) > 0 && !confirmReset(warnings, yes) {
+		fmt.Println("Reset cancelled.")
+		os.Exit(1)
+	}
+
+	if mode == "hard" {
+		if err := stagingArea.ClearStaging(); err != nil {
+			printError(fmt.Sprintf("clearing staging area: %v", err))
+			os.Exit(1)
+		}
+		result, err := restore.NewRestoreManager(dgitDir).CheckoutCommit(head, target, true)
+		if err != nil {
+			for file, fileErr := range result.ErrorFiles {
+				printWarning(fmt.Sprintf("%s: %v", file, fileErr))
+			}
+			printError(fmt.Sprintf("checking out %s: %v", target.Hash[:8], err))
+			os.Exit(1)
+		}
+		if err := merge.NewMergeManager(dgitDir).ClearState(); err != nil {
+			printWarning(err.Error())
+		}
+	} else if mode == "mixed" {
+		if err := stagingArea.ClearStaging(); err != nil {
+			printError(fmt.Sprintf("clearing staging area: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if target.Hash != head.Hash {
+		if err := refManager.UpdateHead(target.Hash); err != nil {
+			printError(fmt.Sprintf("updating HEAD: %v", err))
+			os.Exit(1)
+		}
+		if err := refManager.RecordRefUpdate(head.Hash, target.Hash, "reset", fmt.Sprintf("%s: moving to %s", mode, targetRef)); err != nil {
+			printWarning(fmt.Sprintf("failed to record reflog: %v", err))
+		}
+	}
+
+	printSuccess(fmt.Sprintf("HEAD is now at %s (v%d) %s", target.Hash[:8], target.Version, strings.SplitN(target.Message, "\n", 2)[0]))
+}
+
+// unreachableAfterReset counts commits on the current line that no ref keeps reachable once HEAD moves
+func unreachableAfterReset(dgitDir string, logManager *log.LogManager, refManager *refs.RefManager, head, target *log.Commit) int {
+	kept, err := logManager.GetAncestors(target.Hash)
+	if err != nil {
+		return 0
+	}
+
+	current, _ := refManager.CurrentBranch()
+	var tips []string
+	if branches, err := refManager.ListBranches(); err == nil {
+		for _, branch := range branches {
+			if branch.Name != current {
+				tips = append(tips, branch.Hash)
+			}
+		}
+	}
+	if tags, err := refManager.ListTags(); err == nil {
+		for _, tag := range tags {
+			tips = append(tips, tag.Target)
+		}
+	}
+	for _, tip := range tips {
+		if ancestors, err := logManager.GetAncestors(tip); err == nil {
+			for hash := range ancestors {
+				kept[hash] = true
+			}
+		}
+	}
+
+	lost, err := logManager.GetAncestors(head.Hash)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for hash := range lost {
+		if !kept[hash] {
+			count++
+		}
+	}
+	return count
+}
+
+// confirmReset asks before a destructive reset, refusing when there is no terminal to ask in
+// Non-interactive callers such as the desktop UI confirm on their side and pass --yes
+func confirmReset(warnings []string, yes bool) bool {
+	for _, warning := range warnings {
+		printWarning(warning)
+	}
+	if yes {
+		return true
+	}
+	if fd := os.Stdin.Fd(); !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+		printError("cannot ask for confirmation: standard input is not a terminal")
+		printSuggestion("Use --yes to reset without confirmation")
+		return false
+	}
+
+	fmt.Print("Continue? [y/N]: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"dgit/internal/log"
+	"dgit/internal/refs"
+	"dgit/internal/staging"
+)
+
+// writeCommits records a first-parent chain of commits, oldest first
+func writeCommits(t *testing.T, dgitDir string, hashes ...string) map[string]*log.Commit {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dgitDir, "commits"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	commits := make(map[string]*log.Commit)
+	parent := ""
+	for i, hash := range hashes {
+		c := &log.Commit{Hash: hash, Message: hash, Version: i + 1, ParentHash: parent}
+		if parent != "" {
+			c.Parents = []string{parent}
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dgitDir, "commits", hash+".json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		commits[hash] = c
+		parent = hash
+	}
+	return commits
+}
+
+func TestUnreachableAfterReset(t *testing.T) {
+	cases := []struct {
+		name     string
+		head     string
+		target   string
+		branches map[string]string
+		tags     map[string]string
+		want     int
+	}{
+		{"back two commits", "c3", "c1", nil, nil, 2},
+		{"same commit", "c3", "c3", nil, nil, 0},
+		{"forward", "c2", "c3", nil, nil, 0},
+		{"kept by another branch", "c3", "c1", map[string]string{"backup": "c3"}, nil, 0},
+		{"partly kept by a tag", "c3", "c1", nil, map[string]string{"client-review": "c2"}, 1},
+	}
+	for _, c := range cases {
+		dgitDir := filepath.Join(t.TempDir(), ".dgit")
+		commits := writeCommits(t, dgitDir, "c1", "c2", "c3")
+		refManager := refs.NewRefManager(dgitDir)
+		refManager.SetHeadToBranch(refs.DefaultBranch)
+		refManager.WriteBranch(refs.DefaultBranch, c.head)
+		for name, hash := range c.branches {
+			refManager.WriteBranch(name, hash)
+		}
+		for name, hash := range c.tags {
+			if err := refManager.CreateTag(&refs.Tag{Name: name, Target: hash}, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got := unreachableAfterReset(dgitDir, log.NewLogManager(dgitDir), refManager, commits[c.head], commits[c.target])
+		if got != c.want {
+			t.Errorf("%s: %d unreachable, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSplitResetArgs(t *testing.T) {
+	workTree := t.TempDir()
+	dgitDir := filepath.Join(workTree, ".dgit")
+	hero := filepath.Join(workTree, "hero.psd")
+	if err := os.WriteFile(hero, []byte("psd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stagingArea := staging.NewStagingArea(dgitDir)
+	stagingArea.StageRemoval("old.ai")
+	if err := stagingArea.SaveStaging(); err != nil {
+		t.Fatal(err)
+	}
+	removed := filepath.Join(workTree, "old.ai")
+
+	cases := []struct {
+		args   []string
+		dash   int
+		target string
+		paths  []string
+	}{
+		{nil, -1, "", nil},
+		{[]string{"v4"}, -1, "v4", []string{}},
+		{[]string{hero}, -1, "", []string{hero}},
+		{[]string{removed}, -1, "", []string{removed}},
+		{[]string{"HEAD", hero}, -1, "HEAD", []string{hero}},
+		{[]string{"HEAD", "hero.psd"}, 1, "HEAD", []string{"hero.psd"}},
+		{[]string{"v4"}, 0, "", []string{"v4"}},
+	}
+	for _, c := range cases {
+		target, paths := splitResetArgs(dgitDir, c.args, c.dash)
+		if target != c.target || !reflect.DeepEqual(paths, c.paths) {
+			t.Errorf("%v (dash %d): got %q, %v; want %q, %v", c.args, c.dash, target, paths, c.target, c.paths)
+		}
+	}
+}
@@ -3,8 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
 
 	"dgit/internal/log"
 	"dgit/internal/restore"
@@ -22,6 +20,10 @@ If no files are specified, all files from that commit will be restored.
 Examples:
   dgit restore 1                  # Restore all files from version 1
   dgit restore c3a5f7b8           # Restore all files from commit hash
+  dgit restore feature/logo       # Restore all files from the tip of a branch
+  dgit restore client-friday      # Restore all files from a tag
+  dgit restore HEAD~2             # Restore all files from two commits ago
+  dgit restore main@{yesterday}   # Restore all files as main was a day ago
   dgit restore 2 my_design.psd    # Restore specific file from version 2
   dgit restore 2 designs/         # Restore directory from version 2
 
@@ -62,7 +64,7 @@ func runRestore(cmd *cobra.Command, args []string) {
 	if len(filesToRestore) == 0 {
 		fmt.Printf("Restoring all files from commit %s (v%d)\n", targetCommit.Hash[:8], targetCommit.Version)
 		fmt.Printf("\"%s\"\n", targetCommit.Message)
-		fmt.Printf("Files: %d\n\n", targetCommit.FilesCount)
+		fmt.Printf("Files: %d\n\n", len(targetCommit.TrackedFiles()))
 	} else {
 		fmt.Printf("Restoring %d specific files from commit %s (v%d)\n", len(filesToRestore), targetCommit.Hash[:8], targetCommit.Version)
 		fmt.Printf("\"%s\"\n", targetCommit.Message)
@@ -76,44 +78,14 @@ func runRestore(cmd *cobra.Command, args []string) {
 	}
 }
 
-// findTargetCommit finds a commit by hash or version number
+// findTargetCommit resolves a revision expression such as HEAD~2, a branch, a tag, a hash or a version
 func findTargetCommit(logManager *log.LogManager, commitRef string) (*log.Commit, error) {
-	var targetCommit *log.Commit
-	var err error
-
-	isHashCandidate := false
-	if len(commitRef) >= 4 && len(commitRef) <= 64 {
-		isHashCandidate = true
-		for _, r := range commitRef {
-			if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')) {
-				isHashCandidate = false
-				break
-			}
-		}
-	}
-
-	if isHashCandidate {
-		targetCommit, err = logManager.GetCommitByHash(commitRef)
-		if err == nil && targetCommit != nil {
-			return targetCommit, nil
-		}
-	}
-
-	strippedCommitRef := strings.TrimPrefix(commitRef, "v")
-	version, err := strconv.Atoi(strippedCommitRef)
-	if err == nil {
-		targetCommit, err = logManager.GetCommit(version)
-		if err == nil && targetCommit != nil {
-			return targetCommit, nil
-		}
-	}
-
-	return nil, fmt.Errorf("commit '%s' not found", commitRef)
+	return logManager.ResolveRevision(commitRef)
 }
 
 // performRestore performs the actual file restoration
 func performRestore(restoreManager *restore.RestoreManager, targetCommit *log.Commit, filesToRestore []string) error {
-	commitRef := fmt.Sprintf("v%d", targetCommit.Version)
+	commitRef := targetCommit.Hash[:8]
 
 	err := restoreManager.RestoreFilesFromCommit(commitRef, filesToRestore, targetCommit)
 
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dgit/internal/commit"
+	"dgit/internal/log"
+	"dgit/internal/merge"
+	"dgit/internal/staging"
+
+	"github.com/spf13/cobra"
+)
+
+// RevertCmd undoes a commit by recording a new commit with the inverse changes
+var RevertCmd = &cobra.Command{
+	Use:   "revert <version_or_hash>",
+	Short: "Undo a commit with a new commit",
+	Long: `Create a new commit that undoes the changes made by an earlier commit.
+
+The changes are computed against the commit's parent (the first parent for
+merge commits) and applied on top of HEAD. Files the commit added are
+removed, removed files come back, and modified files return to their
+previous content. PSDs changed again since then are reverted layer by layer
+when the later changes touched other layers.
+
+The working tree must be clean: revert refuses to run when there are staged
+or unstaged modifications.
+
+Examples:
+  dgit revert v5                          # Undo version 5
+  dgit revert a1b2c3d4                    # Undo a commit by hash
+  dgit revert HEAD -m "Back out new logo" # Undo the latest commit with a custom message`,
+	Args: cobra.ExactArgs(1),
+	Run:  runRevert,
+}
+
+func init() {
+	RevertCmd.Flags().StringP("message", "m", "", "Message for the revert commit")
+}
+
+// runRevert applies the inverse of a commit to the working tree and commits it
+func runRevert(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	logManager := log.NewLogManager(dgitDir)
+	mergeManager := merge.NewMergeManager(dgitDir)
+
+	if mergeManager.InProgress() {
+		exitWithError("a merge is in progress", "Finish it with 'dgit merge --continue' or 'dgit merge --abort'")
+	}
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head == nil {
+		exitWithError("no commits to revert", "Create a commit first")
+	}
+
+	target, err := findTargetCommit(logManager, args[0])
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	// An empty tree stands in for the parent of the first commit
+	parent := &log.Commit{}
+	if target.ParentHash != "" {
+		parent, err = logManager.GetCommitByHash(target.ParentHash)
+		if err != nil {
+			printError(fmt.Sprintf("loading parent of %s: %v", target.Hash[:8], err))
+			os.Exit(1)
+		}
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+	checkRevertSafety(dgitDir, stagingArea, head)
+
+	// Reverting is a three-way merge from the reverted commit towards its parent
+	result, err := mergeManager.MergeTrees(target, head, parent)
+	if err != nil {
+		printError(fmt.Sprintf("computing revert: %v", err))
+		os.Exit(1)
+	}
+
+	if len(result.Conflicts) > 0 {
+		printError(fmt.Sprintf("cannot revert %s (v%d): files were changed again since then:", target.Hash[:8], target.Version))
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(os.Stderr, "  %s %s (%s)\n", red("conflict:"), conflict.Path, conflict.Reason)
+		}
+		printSuggestion(fmt.Sprintf("Use 'dgit restore %s <file>' to bring back individual files", parent.Hash[:min(8, len(parent.Hash))]))
+		os.Exit(1)
+	}
+	if len(result.FromTheirs) == 0 && len(result.Removed) == 0 && len(result.LayerMerged) == 0 {
+		printInfo(fmt.Sprintf("Nothing to revert: the changes of %s (v%d) are no longer present", target.Hash[:8], target.Version))
+		return
+	}
+
+	fmt.Printf("Reverting %s (v%d) \"%s\"...\n", target.Hash[:8], target.Version, target.Message)
+	workTree := filepath.Dir(dgitDir)
+	for _, path := range result.FromTheirs {
+		if err := writeCommitFile(mergeManager, parent, workTree, path); err != nil {
+			printError(fmt.Sprintf("restoring %s: %v", path, err))
+			os.Exit(1)
+		}
+		fmt.Printf("  %s %s\n", green("restored:"), path)
+	}
+	for _, path := range result.Removed {
+		if err := os.Remove(filepath.Join(workTree, path)); err != nil && !os.IsNotExist(err) {
+			printWarning(fmt.Sprintf("removing %s: %v", path, err))
+		}
+		fmt.Printf("  %s %s\n", yellow("removed:"), path)
+	}
+	for _, layerMerge := range result.LayerMerged {
+		absPath := filepath.Join(workTree, layerMerge.Path)
+		if err := os.WriteFile(absPath, layerMerge.Content, 0644); err != nil {
+			printError(fmt.Sprintf("writing %s: %v", layerMerge.Path, err))
+			os.Exit(1)
+		}
+		if err := stagingArea.AddFile(absPath); err != nil {
+			printError(fmt.Sprintf("staging %s: %v", layerMerge.Path, err))
+			os.Exit(1)
+		}
+		fmt.Printf("  %s %s (layers: %s)\n", cyan("layer-reverted:"), layerMerge.Path, joinOrNone(layerMerge.TheirsChanged))
+	}
+
+	message, _ := cmd.Flags().GetString("message")
+	if message == "" {
+		message = fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s (v%d).", strings.SplitN(target.Message, "\n", 2)[0], target.Hash, target.Version)
+	}
+
+	revertCommit, err := commit.NewCommitManager(dgitDir).CreateCommitWithTree(message, stagingArea.GetStagedFiles(), result.Tree)
+	if err != nil {
+		printError(fmt.Sprintf("creating revert commit: %v", err))
+		os.Exit(1)
+	}
+	if err := stagingArea.ClearStaging(); err != nil {
+		printWarning(fmt.Sprintf("failed to clear staging area: %v", err))
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Created commit %s (v%d)", revertCommit.Hash[:8], revertCommit.Version))
+	fmt.Println(strings.SplitN(message, "\n", 2)[0])
+}
+
+// checkRevertSafety exits when the working tree has staged or unstaged modifications
+func checkRevertSafety(dgitDir string, stagingArea *staging.StagingArea, head *log.Commit) {
+	if !stagingArea.IsEmpty() {
+		exitWithError("you have staged changes; commit them before reverting", "Run 'dgit commit' first")
+	}
+
+	changes, err := getWorkingTreeChanges(dgitDir, head)
+	if err != nil {
+		printError(fmt.Sprintf("checking working tree: %v", err))
+		os.Exit(1)
+	}
+	if len(changes.ModifiedFiles) > 0 || len(changes.DeletedFiles) > 0 {
+		printError("your working tree has unstaged modifications:")
+		for _, file := range changes.ModifiedFiles {
+			fmt.Fprintf(os.Stderr, "  modified: %s\n", file.Path)
+		}
+		for _, file := range changes.DeletedFiles {
+			fmt.Fprintf(os.Stderr, "  deleted:  %s\n", file.Path)
+		}
+		exitWithError("", "Commit or restore them before reverting")
+	}
+}
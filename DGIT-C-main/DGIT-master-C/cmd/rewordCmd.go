@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"dgit/internal/log"
+	"dgit/internal/refs"
+
+	"github.com/spf13/cobra"
+)
+
+// RewordCmd changes the message of an existing commit
+var RewordCmd = &cobra.Command{
+	Use:   "reword <version_or_hash> [message]",
+	Short: "Change the message of a commit",
+	Long: `Change only the message of a commit.
+
+The commit keeps its hash, its files and its compressed storage, so branches,
+tags and later commits are unaffected. The original message is recorded in
+the reflog (.dgit/logs/HEAD).
+
+Examples:
+  dgit reword HEAD "Final logo for ACME"  # Fix the latest message
+  dgit reword v3 -m "Hero banner, round 2"
+  dgit reword a1b2c3d4                     # Prompt for the new message`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  runReword,
+}
+
+func init() {
+	RewordCmd.Flags().StringP("message", "m", "", "New commit message")
+}
+
+// runReword replaces a commit's message in place
+func runReword(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	logManager := log.NewLogManager(dgitDir)
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head == nil {
+		exitWithError("no commits to reword", "Create a commit first")
+	}
+
+	target, err := findTargetCommit(logManager, args[0])
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	message, _ := cmd.Flags().GetString("message")
+	if len(args) > 1 {
+		message = args[1]
+	}
+	if message == "" {
+		fmt.Printf("Current message: %s\n", target.Message)
+		fmt.Print("Enter new commit message: ")
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			printError(fmt.Sprintf("reading commit message: %v", err))
+			os.Exit(1)
+		}
+		message = strings.TrimSpace(input)
+	}
+	if message == "" {
+		printError("commit message cannot be empty")
+		os.Exit(1)
+	}
+	if message == target.Message {
+		printInfo("Message unchanged")
+		return
+	}
+
+	if err := logManager.SetCommitMessage(target.Hash, message); err != nil {
+		printError(fmt.Sprintf("rewording %s: %v", target.Hash[:8], err))
+		os.Exit(1)
+	}
+	if err := refs.NewRefManager(dgitDir).RecordRefUpdate(target.Hash, target.Hash, "reword", message); err != nil {
+		printWarning(fmt.Sprintf("failed to record reflog: %v", err))
+	}
+
+	printSuccess(fmt.Sprintf("Reworded %s (v%d)", target.Hash[:8], target.Version))
+	fmt.Printf("  was: %s\n", strings.SplitN(target.Message, "\n", 2)[0])
+	fmt.Printf("  now: %s\n", strings.SplitN(message, "\n", 2)[0])
+}
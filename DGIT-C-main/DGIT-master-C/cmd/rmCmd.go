@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dgit/internal/log"
+	"dgit/internal/staging"
+	"dgit/internal/status"
+
+	"github.com/spf13/cobra"
+)
+
+// RmCmd removes tracked files from the working tree and the next commit
+var RmCmd = &cobra.Command{
+	Use:   "rm <files...>",
+	Short: "Remove files from the working tree and stop tracking them",
+	Long: `Remove tracked files and stage the removal for the next commit.
+
+Files with local modifications are refused unless --force is given, so
+unsaved design work is not lost by accident. With --cached the file stays
+on disk and only stops being tracked.
+
+Examples:
+  dgit rm old-logo.ai              # Delete and stop tracking
+  dgit rm --cached exports/hero.psd # Stop tracking but keep the file`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runRm,
+}
+
+func init() {
+	RmCmd.Flags().Bool("cached", false, "Only stop tracking; keep the file in the working tree")
+	RmCmd.Flags().BoolP("force", "f", false, "Remove files even when they have local modifications")
+}
+
+// runRm stages removals and deletes the files
+func runRm(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	workTree := filepath.Dir(dgitDir)
+	cached, _ := cmd.Flags().GetBool("cached")
+	force, _ := cmd.Flags().GetBool("force")
+
+	tracked := map[string]log.TreeEntry{}
+	head, err := log.NewLogManager(dgitDir).GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head != nil {
+		tracked = head.TrackedFiles()
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, arg := range args {
+		relPath := filepath.ToSlash(repoRelativePath(workTree, arg))
+		absPath := filepath.Join(workTree, relPath)
+		entry, isTracked := tracked[relPath]
+		isStaged := stagingArea.HasFile(absPath)
+
+		if !isTracked && !isStaged {
+			printWarning(fmt.Sprintf("'%s' is not tracked", arg))
+			failed = true
+			continue
+		}
+		if !cached && !force && hasLocalChanges(absPath, entry, isTracked, isStaged) {
+			printWarning(fmt.Sprintf("'%s' has local modifications; use --cached to keep it or --force to discard them", arg))
+			failed = true
+			continue
+		}
+
+		if isStaged {
+			if err := stagingArea.RemoveFile(absPath); err != nil {
+				printWarning(err.Error())
+			}
+		}
+		if isTracked {
+			stagingArea.StageRemoval(relPath)
+		}
+		if !cached {
+			if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+				printWarning(fmt.Sprintf("removing %s: %v", arg, err))
+				failed = true
+			}
+		}
+		fmt.Printf("  %s %s\n", yellow("rm"), relPath)
+	}
+
+	if err := stagingArea.SaveStaging(); err != nil {
+		printError(fmt.Sprintf("saving staging area: %v", err))
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// hasLocalChanges reports whether removing a file would lose content that is not committed
+func hasLocalChanges(absPath string, entry log.TreeEntry, isTracked, isStaged bool) bool {
+	if isStaged || !isTracked {
+		return true
+	}
+	hash, err := status.CalculateFileHash(absPath)
+	if err != nil {
+		// Already deleted from disk
+		return false
+	}
+	return entry.Hash != "" && hash != entry.Hash
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dgit/internal/log"
+	"dgit/internal/status"
+)
+
+func TestHasLocalChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logo.ai")
+	if err := os.WriteFile(path, []byte("logo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := status.CalculateFileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		path      string
+		entry     log.TreeEntry
+		tracked   bool
+		staged    bool
+		hasChange bool
+	}{
+		{"committed content", path, log.TreeEntry{Hash: hash}, true, false, false},
+		{"modified", path, log.TreeEntry{Hash: "other"}, true, false, true},
+		{"legacy entry without a hash", path, log.TreeEntry{}, true, false, false},
+		{"already deleted", path + ".missing", log.TreeEntry{Hash: hash}, true, false, false},
+		{"staged", path, log.TreeEntry{Hash: hash}, true, true, true},
+		{"untracked", path, log.TreeEntry{}, false, false, true},
+	}
+	for _, c := range cases {
+		if got := hasLocalChanges(c.path, c.entry, c.tracked, c.staged); got != c.hasChange {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.hasChange)
+		}
+	}
+}
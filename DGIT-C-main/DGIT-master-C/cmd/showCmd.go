@@ -4,18 +4,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 
 	"dgit/internal/log"
+	"dgit/internal/refs"
 	"dgit/internal/scanner"
+	"dgit/internal/thumbnail"
 
 	"github.com/spf13/cobra"
 )
 
 // ShowCmd represents the show command for detailed information display
 var ShowCmd = &cobra.Command{
-	Use:   "show <target>",
+	Use:   "show <target> | --thumbnail [revision] <file>",
 	Short: "Show detailed information",
 	Long: `Show detailed information about files or commits.
 
@@ -23,8 +25,16 @@ Examples:
   dgit show design.psd        # Detailed file analysis
   dgit show dfb6ae0          # Commit information
   dgit show v1               # Version information
-  dgit show --name-only v1   # List files in commit`,
-	Args: cobra.ExactArgs(1),
+  dgit show HEAD~2           # Two commits before HEAD
+  dgit show main@{yesterday} # Where main was a day ago
+  dgit show client-friday    # Tag annotation and tagged commit
+  dgit show --name-only v1   # List files in commit
+  dgit show --thumbnail v3 hero.psd  # Preview image of a file version
+
+With --thumbnail the path of a JPEG preview of the file as of the revision
+(HEAD by default) is printed. Previews of PSD and AI files are stored when
+they are committed; older versions are extracted once and then cached.`,
+	Args: cobra.RangeArgs(1, 2),
 	Run:  runShow,
 }
 
@@ -32,20 +42,68 @@ func init() {
 	ShowCmd.Flags().Bool("name-only", false, "Show only file names (for commits)")
 	ShowCmd.Flags().Bool("layers", false, "Show layer information (for design files)")
 	ShowCmd.Flags().Bool("json", false, "Output in JSON format") // 추가 필요
+	ShowCmd.Flags().Bool("thumbnail", false, "Show the preview image of a file at a revision")
 }
 
 // runShow executes the show command for files or commits
 func runShow(cmd *cobra.Command, args []string) {
 	target := args[0]
 	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if showThumbnail, _ := cmd.Flags().GetBool("thumbnail"); showThumbnail {
+		showFileThumbnail(args, jsonOutput)
+		return
+	}
+	if len(args) != 1 {
+		exitWithError("show takes a single file or revision", "Use 'dgit show --thumbnail <revision> <file>' for file previews")
+	}
 
-	if isFilePath(target) {
+	if isFilePath(target) && !isRefName(target) {
 		showFileDetails(target, cmd)
 	} else {
 		showCommitDetails(target, cmd, jsonOutput) // 파라미터 추가
 	}
 }
 
+// showFileThumbnail prints where the preview of a file version is stored
+func showFileThumbnail(args []string, jsonOutput bool) {
+	dgitDir := checkDgitRepository()
+	logManager := log.NewLogManager(dgitDir)
+
+	revision, file := "HEAD", args[0]
+	if len(args) == 2 {
+		revision, file = args[0], args[1]
+	}
+	path := filepath.ToSlash(repoRelativePath(filepath.Dir(dgitDir), file))
+
+	target, err := findTargetCommit(logManager, revision)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	thumb, err := thumbnail.NewThumbnailManager(dgitDir).ForCommitFile(target, path)
+	if err != nil {
+		printError(fmt.Sprintf("no thumbnail for %s in v%d: %v", path, target.Version, err))
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		result := map[string]interface{}{
+			"file":      path,
+			"commit":    target.Hash,
+			"version":   target.Version,
+			"thumbnail": thumb,
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println(thumb.Path)
+}
+
 // showFileDetails displays comprehensive file analysis
 func showFileDetails(filePath string, cmd *cobra.Command) {
 	if !fileExists(filePath) {
@@ -76,13 +134,17 @@ func showCommitDetails(commitRef string, cmd *cobra.Command, jsonOutput bool) {
 	dgitDir := checkDgitRepository()
 	logManager := log.NewLogManager(dgitDir)
 
-	commit, err := findCommit(logManager, commitRef)
+	commit, err := findTargetCommit(logManager, commitRef)
 	if err != nil {
-		printError(fmt.Sprintf("commit '%s' not found", commitRef))
+		printError(err.Error())
 		os.Exit(1)
 	}
 
 	nameOnly, _ := cmd.Flags().GetBool("name-only")
+	if !nameOnly && !jsonOutput {
+		printTagAnnotation(refs.NewRefManager(dgitDir), commitRef)
+	}
+
 	if nameOnly {
 		printCommitFileNames(commit, jsonOutput) // 파라미터 추가
 	} else {
@@ -112,7 +174,10 @@ func printFileDetails(fileInfo *scanner.DetailedFileInfo, cmd *cobra.Command) {
 		fmt.Printf("\nLayers: %d\n", fileInfo.Layers)
 
 		showLayers, _ := cmd.Flags().GetBool("layers")
-		if showLayers && len(fileInfo.LayerNames) > 0 {
+		if showLayers && len(fileInfo.LayerTree) > 0 {
+			fmt.Println("Layer structure:")
+			printLayerTree(fileInfo.LayerTree, 1)
+		} else if showLayers && len(fileInfo.LayerNames) > 0 {
 			fmt.Println("Layer structure:")
 			for i, layerName := range fileInfo.LayerNames {
 				fmt.Printf("  %d. %s\n", i+1, layerName)
@@ -123,10 +188,96 @@ func printFileDetails(fileInfo *scanner.DetailedFileInfo, cmd *cobra.Command) {
 	if fileInfo.Artboards > 1 {
 		fmt.Printf("Artboards: %d\n", fileInfo.Artboards)
 	}
+	printDocumentStructure(fileInfo)
+	printSceneContents(fileInfo)
 
 	fmt.Printf("\nAnalysis completed\n")
 }
 
+// printDocumentStructure displays the pages, artboards and shared elements of multi-page documents
+func printDocumentStructure(fileInfo *scanner.DetailedFileInfo) {
+	if len(fileInfo.Pages) > 0 {
+		fmt.Printf("\nPages: %d\n", len(fileInfo.Pages))
+		for _, page := range fileInfo.Pages {
+			fmt.Printf("  - %s\n", page)
+		}
+	}
+	if len(fileInfo.ArtboardList) > 0 {
+		fmt.Println("\nArtboard sizes:")
+		for _, artboard := range fileInfo.ArtboardList {
+			location := ""
+			if artboard.Page != "" {
+				location = fmt.Sprintf(" (%s)", artboard.Page)
+			}
+			fmt.Printf("  - %s: %.0fx%.0f%s\n", artboard.Name, artboard.Width, artboard.Height, location)
+		}
+	}
+	if len(fileInfo.Components) > 0 {
+		fmt.Printf("\nSymbols/components: %d\n", len(fileInfo.Components))
+		for _, component := range fileInfo.Components {
+			fmt.Printf("  - %s\n", component)
+		}
+	}
+	if fileInfo.Instances > 0 {
+		fmt.Printf("Instances: %d\n", fileInfo.Instances)
+	}
+	if len(fileInfo.Styles) > 0 {
+		fmt.Printf("Shared styles: %d\n", len(fileInfo.Styles))
+	}
+	if len(fileInfo.Images) > 0 {
+		fmt.Printf("Embedded images: %d\n", len(fileInfo.Images))
+	}
+	if len(fileInfo.Assets) > 0 {
+		fmt.Printf("\nLinked assets: %d\n", len(fileInfo.Assets))
+		for _, asset := range fileInfo.Assets {
+			fmt.Printf("  - %s\n", asset)
+		}
+	}
+	if len(fileInfo.Texts) > 0 {
+		fmt.Printf("\nText layers: %d\n", len(fileInfo.Texts))
+		for _, text := range fileInfo.Texts {
+			fmt.Printf("  - %q\n", text)
+		}
+	}
+}
+
+// printSceneContents displays the scenes, meshes, materials and animations of 3D files
+func printSceneContents(fileInfo *scanner.DetailedFileInfo) {
+	if len(fileInfo.Scenes) > 0 {
+		fmt.Printf("\nScenes: %d\n", len(fileInfo.Scenes))
+		for _, scene := range fileInfo.Scenes {
+			fmt.Printf("  - %s\n", scene)
+		}
+		fmt.Printf("Objects: %d\n", fileInfo.Objects)
+	}
+	if len(fileInfo.Meshes) > 0 {
+		fmt.Printf("\nMeshes: %d\n", len(fileInfo.Meshes))
+		for _, mesh := range fileInfo.Meshes {
+			fmt.Printf("  - %s: %d vertices, %d faces\n", mesh.Name, mesh.Vertices, mesh.Faces)
+		}
+	}
+	if len(fileInfo.Materials) > 0 {
+		fmt.Printf("\nMaterials: %d\n", len(fileInfo.Materials))
+		for _, material := range fileInfo.Materials {
+			fmt.Printf("  - %s\n", material)
+		}
+	}
+	if len(fileInfo.Animations) > 0 {
+		fmt.Printf("\nAnimation stacks: %d\n", len(fileInfo.Animations))
+		for _, animation := range fileInfo.Animations {
+			fmt.Printf("  - %s\n", animation)
+		}
+	}
+}
+
+// printLayerTree displays a layer tree indented by depth, with each layer's type
+func printLayerTree(layers []scanner.LayerNode, depth int) {
+	for _, layer := range layers {
+		fmt.Printf("%s%s [%s]\n", strings.Repeat("  ", depth), layer.Name, layer.Type)
+		printLayerTree(layer.Children, depth+1)
+	}
+}
+
 // printCommitDetails displays comprehensive commit information
 func printCommitDetails(commit *log.Commit, jsonOutput bool) {
 	if jsonOutput {
@@ -220,26 +371,39 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
-func findCommit(logManager *log.LogManager, commitRef string) (*log.Commit, error) {
-	// Try by hash first
-	commit, err := logManager.GetCommitByHash(commitRef)
-	if err == nil {
-		return commit, nil
+// isRefName reports whether a target is a revision expression rather than a file
+// such as a branch or tag name with a slash, or HEAD@{2026-10-01 14:00}
+func isRefName(target string) bool {
+	if fileExists(target) {
+		return false
 	}
-
-	// Try by version number
-	if version, parseErr := parseVersion(commitRef); parseErr == nil {
-		return logManager.GetCommit(version)
+	dgitDir := findDgitDirectory()
+	if dgitDir == "" {
+		return false
 	}
-
-	return nil, fmt.Errorf("commit not found")
+	_, err := log.NewLogManager(dgitDir).ResolveRevision(target)
+	return err == nil
 }
 
-func parseVersion(versionStr string) (int, error) {
-	if strings.HasPrefix(versionStr, "v") {
-		versionStr = strings.TrimPrefix(versionStr, "v")
+// printTagAnnotation prints the tagger and message when the reference is an annotated tag
+func printTagAnnotation(refManager *refs.RefManager, ref string) {
+	if refManager.BranchExists(ref) || !refManager.TagExists(ref) {
+		return
+	}
+	tag, err := refManager.ReadTag(ref)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("tag %s\n", tag.Name)
+	if tag.Tagger != "" {
+		fmt.Printf("Tagger: %s\n", tag.Tagger)
+	}
+	fmt.Printf("Date: %s\n", tag.Timestamp.Format("Mon Jan 2 15:04:05 2006"))
+	if tag.Message != "" {
+		fmt.Printf("\n    %s\n", tag.Message)
 	}
-	return strconv.Atoi(versionStr)
+	fmt.Println()
 }
 
 func getFileTypeDescription(fileType string) string {
@@ -251,6 +415,9 @@ func getFileTypeDescription(fileType string) string {
 		"xd":       "Adobe XD Document",
 		"afdesign": "Affinity Designer File",
 		"afphoto":  "Affinity Photo File",
+		"blend":    "Blender Scene",
+		"fbx":      "FBX 3D Scene",
+		"obj":      "Wavefront OBJ Model",
 	}
 
 	if desc, exists := descriptions[fileType]; exists {
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"dgit/internal/log"
+	"dgit/internal/merge"
+	"dgit/internal/refs"
+	"dgit/internal/staging"
+	"dgit/internal/stash"
+
+	"github.com/spf13/cobra"
+)
+
+// StashCmd shelves local changes so the working tree can return to HEAD
+var StashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Shelve local changes and restore them later",
+	Long: `Save modified tracked files and the staging area, then reset them to HEAD.
+
+Stash entries are kept in the object store (.dgit/objects/stash) and are
+numbered from newest to oldest: stash@{0} is the latest.
+
+Examples:
+  dgit stash                          # Same as 'dgit stash push'
+  dgit stash push -m "hero retouch"   # Shelve changes with a description
+  dgit stash list                     # Show stash entries
+  dgit stash pop                      # Re-apply the latest entry and drop it
+  dgit stash apply stash@{1}          # Re-apply an entry but keep it
+  dgit stash drop stash@{0}           # Delete an entry`,
+	Args: cobra.NoArgs,
+	Run:  runStashPush,
+}
+
+var stashPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Save local changes to a new stash entry",
+	Args:  cobra.NoArgs,
+	Run:   runStashPush,
+}
+
+var stashPopCmd = &cobra.Command{
+	Use:   "pop [stash]",
+	Short: "Re-apply a stash entry and remove it",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runStashApply(args, true)
+	},
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply [stash]",
+	Short: "Re-apply a stash entry and keep it",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runStashApply(args, false)
+	},
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stash entries",
+	Args:  cobra.NoArgs,
+	Run:   runStashList,
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop [stash]",
+	Short: "Delete a stash entry",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   runStashDrop,
+}
+
+func init() {
+	StashCmd.Flags().StringP("message", "m", "", "Description of the stash entry")
+	stashPushCmd.Flags().StringP("message", "m", "", "Description of the stash entry")
+
+	StashCmd.AddCommand(stashPushCmd)
+	StashCmd.AddCommand(stashPopCmd)
+	StashCmd.AddCommand(stashApplyCmd)
+	StashCmd.AddCommand(stashListCmd)
+	StashCmd.AddCommand(stashDropCmd)
+}
+
+// runStashPush saves local changes and resets them to HEAD
+func runStashPush(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	if merge.NewMergeManager(dgitDir).InProgress() {
+		exitWithError("a merge is in progress", "Finish it with 'dgit merge --continue' or 'dgit merge --abort'")
+	}
+
+	head, err := log.NewLogManager(dgitDir).GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	if head == nil {
+		exitWithError("cannot stash without an initial commit", "Create a commit first")
+	}
+
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+
+	changes, err := getWorkingTreeChanges(dgitDir, head)
+	if err != nil {
+		printError(fmt.Sprintf("checking working tree: %v", err))
+		os.Exit(1)
+	}
+	var changed []string
+	for _, file := range changes.ModifiedFiles {
+		changed = append(changed, file.Path)
+	}
+	for _, file := range changes.DeletedFiles {
+		changed = append(changed, file.Path)
+	}
+	if len(changed) == 0 && stagingArea.IsEmpty() {
+		fmt.Println("No local changes to save")
+		return
+	}
+
+	branch, _ := refs.NewRefManager(dgitDir).CurrentBranch()
+	message, _ := cmd.Flags().GetString("message")
+
+	entry, err := stash.NewStashManager(dgitDir).Push(message, branch, head, changed, stagingArea)
+	if err != nil {
+		printError(fmt.Sprintf("saving stash: %v", err))
+		os.Exit(1)
+	}
+
+	printSuccess(fmt.Sprintf("Saved working directory and staging area: %s", entry.Message))
+	for _, file := range entry.Files {
+		fmt.Printf("  %-9s %s\n", file.Status+":", file.Path)
+	}
+}
+
+// runStashApply re-applies a stash entry, dropping it afterwards when pop is set
+func runStashApply(args []string, pop bool) {
+	dgitDir := checkDgitRepository()
+	stashManager := stash.NewStashManager(dgitDir)
+
+	ref := ""
+	if len(args) > 0 {
+		ref = args[0]
+	}
+	entry, index, err := stashManager.Get(ref)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	head, err := log.NewLogManager(dgitDir).GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+	stagingArea := staging.NewStagingArea(dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		printError(fmt.Sprintf("loading staging area: %v", err))
+		os.Exit(1)
+	}
+
+	result, err := stashManager.Apply(entry, head, stagingArea)
+	if err != nil {
+		printError(fmt.Sprintf("applying stash@{%d}: %v", index, err))
+		os.Exit(1)
+	}
+	if len(result.Conflicts) > 0 {
+		printError(fmt.Sprintf("cannot apply stash@{%d}; conflicts:", index))
+		paths := make([]string, 0, len(result.Conflicts))
+		for path := range result.Conflicts {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(os.Stderr, "  %s %s (%s)\n", red("conflict:"), path, result.Conflicts[path])
+		}
+		printSuggestion("Commit or restore the conflicting files, then try again. The stash entry was kept.")
+		os.Exit(1)
+	}
+
+	for _, path := range result.Restored {
+		fmt.Printf("  %s %s\n", green("restored:"), path)
+	}
+	for _, path := range result.Removed {
+		fmt.Printf("  %s %s\n", yellow("removed:"), path)
+	}
+
+	if pop {
+		if err := stashManager.Drop(entry); err != nil {
+			printWarning(fmt.Sprintf("applied, but failed to drop stash@{%d}: %v", index, err))
+			return
+		}
+		printSuccess(fmt.Sprintf("Applied and dropped stash@{%d} (%s)", index, entry.ID))
+	} else {
+		printSuccess(fmt.Sprintf("Applied stash@{%d} (%s)", index, entry.ID))
+	}
+}
+
+// runStashList prints stash entries newest first
+func runStashList(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	entries, err := stash.NewStashManager(dgitDir).List()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	for i, entry := range entries {
+		var staged string
+		if len(entry.Staged) > 0 {
+			staged = " +staged"
+		}
+		fmt.Printf("stash@{%d}: %s (%d file(s)%s, %s)\n", i, entry.Message, len(entry.Files), staged,
+			entry.CreatedAt.Format("2006-01-02 15:04"))
+	}
+}
+
+// runStashDrop deletes a stash entry
+func runStashDrop(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	stashManager := stash.NewStashManager(dgitDir)
+
+	ref := ""
+	if len(args) > 0 {
+		ref = args[0]
+	}
+	entry, index, err := stashManager.Get(ref)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := stashManager.Drop(entry); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	printSuccess(fmt.Sprintf("Dropped stash@{%d} (%s: %s)", index, entry.ID, strings.SplitN(entry.Message, "\n", 2)[0]))
+}
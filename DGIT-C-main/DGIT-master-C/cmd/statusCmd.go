@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"dgit/internal/bisect"
 	"dgit/internal/log"
+	"dgit/internal/refs"
 	"dgit/internal/scanner"
 	"dgit/internal/staging"
 	"dgit/internal/status"
@@ -42,43 +45,47 @@ func runStatus(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	currentVersion := logManager.GetCurrentVersion()
+	lastCommit, err := logManager.GetHeadCommit()
+	if err != nil {
+		printWarning(fmt.Sprintf("Failed to load last commit: %v", err))
+	}
+
+	printBranchStatus(dgitDir, lastCommit)
+	currentVersion := 0
+	if lastCommit != nil {
+		currentVersion = lastCommit.Version
+	}
 	fmt.Printf("On version %d\n\n", currentVersion+1)
 
 	if !stagingArea.IsEmpty() {
 		fmt.Println("Changes to be committed:")
-		printStatusStagingInfo(stagingArea)
+		printStatusStagingInfo(stagingArea, stagedRenames(logManager, stagingArea, lastCommit))
 		fmt.Println()
 	} else {
 		fmt.Println("No changes staged for commit.")
 		fmt.Println()
 	}
 
-	currentWorkDir, _ := os.Getwd()
-	currentDirFiles := scanCurrentDirectory(currentWorkDir)
+	workTreeRoot := stagingArea.WorkTreeRoot()
+	currentDirFiles := scanCurrentDirectory(workTreeRoot)
 
-	result, err := statusManager.CompareWithCommit(currentVersion, currentDirFiles)
+	result, err := statusManager.CompareWithCommit(lastCommit, currentDirFiles)
 	if err != nil {
 		printWarning(fmt.Sprintf("Failed to compare with last commit: %v", err))
 		return
 	}
 
-	var lastCommit *log.Commit
-	if currentVersion > 0 {
-		lastCommit, err = logManager.GetCommit(currentVersion)
-		if err != nil {
-			printWarning(fmt.Sprintf("Failed to load last commit for metadata comparison: %v", err))
-		}
-	}
-
 	result.ModifiedFiles = filterStagedFiles(result.ModifiedFiles, stagingArea)
 	result.UntrackedFiles = filterStagedFiles(result.UntrackedFiles, stagingArea)
 	result.DeletedFiles = filterStagedFiles(result.DeletedFiles, stagingArea)
 
+	// A deleted file that reappears under another path is reported as a rename
+	renames := detectWorkingTreeRenames(logManager, lastCommit, result, currentDirFiles, workTreeRoot)
+
 	if len(result.ModifiedFiles) > 0 {
 		fmt.Println("Changes not staged for commit:")
 		for _, fileStatus := range result.ModifiedFiles {
-			metadataSummary := getMetadataChangeSummary(fileStatus.Path, lastCommit, currentWorkDir)
+			metadataSummary := getMetadataChangeSummary(fileStatus.Path, lastCommit, workTreeRoot)
 			fmt.Printf("  modified: %s%s\n", fileStatus.Path, metadataSummary)
 		}
 		fmt.Println()
@@ -86,6 +93,15 @@ func runStatus(cmd *cobra.Command, args []string) {
 		fmt.Println("No changes not staged for commit.")
 	}
 
+	if len(renames) > 0 {
+		fmt.Println("Renamed files (not staged):")
+		for _, newPath := range sortedRenameTargets(renames) {
+			fmt.Printf("  renamed: %s -> %s\n", renames[newPath], newPath)
+		}
+		fmt.Println("   Use 'dgit mv' or stage both paths to record the rename")
+		fmt.Println()
+	}
+
 	if len(result.UntrackedFiles) > 0 {
 		fmt.Println("Untracked files:")
 		for _, fileStatus := range result.UntrackedFiles {
@@ -115,6 +131,33 @@ func runStatus(cmd *cobra.Command, args []string) {
 	}
 }
 
+// printBranchStatus shows which branch HEAD is on, or the commit it is detached at
+func printBranchStatus(dgitDir string, head *log.Commit) {
+	branch, err := refs.NewRefManager(dgitDir).CurrentBranch()
+	if err != nil {
+		printWarning(fmt.Sprintf("Failed to read current branch: %v", err))
+		return
+	}
+
+	switch {
+	case branch != "":
+		fmt.Printf("On branch %s\n", branch)
+	case head != nil:
+		fmt.Printf("HEAD detached at %s (v%d)\n", head.Hash[:8], head.Version)
+	default:
+		fmt.Println("HEAD detached")
+	}
+	if bisect.NewBisectManager(dgitDir).InProgress() {
+		fmt.Println("You are currently bisecting; use 'dgit bisect reset' to return to where you started.")
+	}
+}
+
+// getWorkingTreeChanges compares the working tree under the repository root against a commit
+func getWorkingTreeChanges(dgitDir string, commit *log.Commit) (*status.FileStatusResult, error) {
+	currentDirFiles := scanCurrentDirectory(filepath.Dir(dgitDir))
+	return status.NewStatusManager(dgitDir).CompareWithCommit(commit, currentDirFiles)
+}
+
 // scanCurrentDirectory scans for design files and returns their hashes
 func scanCurrentDirectory(currentWorkDir string) map[string]string {
 	currentDirFiles := make(map[string]string)
@@ -148,11 +191,11 @@ func scanCurrentDirectory(currentWorkDir string) map[string]string {
 	return currentDirFiles
 }
 
-// filterStagedFiles removes files that are already staged
+// filterStagedFiles removes files that are already staged, including staged removals
 func filterStagedFiles(files []status.FileStatus, stagingArea *staging.StagingArea) []status.FileStatus {
 	var filtered []status.FileStatus
 	for _, file := range files {
-		if !stagingArea.HasFile(file.Path) {
+		if !stagingArea.HasFile(filepath.Join(stagingArea.WorkTreeRoot(), file.Path)) && !stagingArea.IsRemoved(file.Path) {
 			filtered = append(filtered, file)
 		}
 	}
@@ -194,6 +237,29 @@ func getMetadataChangeSummary(filePath string, lastCommit *log.Commit, currentWo
 		changes = append(changes, fmt.Sprintf("ColorMode: %s→%s", oldColorMode, currentFileInfo.ColorMode))
 	}
 
+	// Commits made before these counts were recorded do not have them
+	counts := []struct {
+		key   string
+		label string
+		value int
+	}{
+		{"components", "Components", currentFileInfo.Components},
+		{"instances", "Instances", currentFileInfo.Instances},
+		{"text_layers", "Texts", currentFileInfo.TextLayers},
+		{"assets", "Assets", currentFileInfo.Assets},
+		{"meshes", "Meshes", currentFileInfo.Meshes},
+		{"vertices", "Vertices", currentFileInfo.Vertices},
+		{"polygons", "Polygons", currentFileInfo.Polygons},
+		{"materials", "Materials", currentFileInfo.Materials},
+		{"textures", "Textures", currentFileInfo.Textures},
+		{"animations", "Animations", currentFileInfo.Animations},
+	}
+	for _, count := range counts {
+		if old, ok := oldMetaRaw[count.key].(float64); ok && old != float64(count.value) {
+			changes = append(changes, fmt.Sprintf("%s: %.0f→%d", count.label, old, count.value))
+		}
+	}
+
 	if len(changes) > 0 {
 		return " (" + strings.Join(changes, ", ") + ")"
 	}
@@ -220,9 +286,120 @@ func getStatusFileType(filePath string) string {
 }
 
 // printStagingStatus displays files staged for commit
-func printStatusStagingInfo(stagingArea *staging.StagingArea) {
+func printStatusStagingInfo(stagingArea *staging.StagingArea, renames map[string]string) {
+	renamedFrom := make(map[string]bool)
 	for _, file := range stagingArea.GetStagedFiles() {
 		fileType := getStatusFileType(file.Path)
+		if oldPath, ok := renames[filepath.ToSlash(file.Path)]; ok {
+			renamedFrom[oldPath] = true
+			fmt.Printf("  [%s] renamed: %s -> %s\n", fileType, oldPath, file.Path)
+			continue
+		}
 		fmt.Printf("  [%s] new file: %s\n", fileType, file.Path)
 	}
+	for _, path := range stagingArea.GetRemovedFiles() {
+		if !renamedFrom[path] {
+			fmt.Printf("  [%s] deleted: %s\n", getStatusFileType(path), path)
+		}
+	}
+}
+
+// stagedRenames pairs staged removals with staged files, using 'dgit mv' records first
+func stagedRenames(logManager *log.LogManager, stagingArea *staging.StagingArea, head *log.Commit) map[string]string {
+	renames := make(map[string]string)
+	removed := stagingArea.GetRemovedFiles()
+	if len(removed) == 0 || head == nil {
+		return renames
+	}
+	tracked := head.TrackedFiles()
+
+	explicit := make(map[string]bool)
+	var added []log.RenameCandidate
+	for _, file := range stagingArea.GetStagedFiles() {
+		path := filepath.ToSlash(file.Path)
+		if file.RenamedFrom != "" && stagingArea.IsRemoved(file.RenamedFrom) {
+			renames[path] = file.RenamedFrom
+			explicit[file.RenamedFrom] = true
+			continue
+		}
+		if _, ok := tracked[path]; !ok {
+			added = append(added, workTreeCandidate(path, file.AbsolutePath, ""))
+		}
+	}
+
+	var deleted []log.RenameCandidate
+	for _, path := range removed {
+		if entry, ok := tracked[path]; ok && !explicit[path] {
+			deleted = append(deleted, logManager.TreeCandidate(path, entry))
+		}
+	}
+	if len(deleted) > 0 && len(added) > 0 {
+		for newPath, oldPath := range log.DetectRenames(deleted, added) {
+			renames[newPath] = oldPath
+		}
+	}
+	return renames
+}
+
+// detectWorkingTreeRenames pairs deleted tracked files with untracked files and drops them from both lists
+func detectWorkingTreeRenames(logManager *log.LogManager, head *log.Commit, result *status.FileStatusResult, currentDirFiles map[string]string, workTreeRoot string) map[string]string {
+	if head == nil || len(result.DeletedFiles) == 0 || len(result.UntrackedFiles) == 0 {
+		return nil
+	}
+	tracked := head.TrackedFiles()
+
+	var deleted, added []log.RenameCandidate
+	for _, file := range result.DeletedFiles {
+		deleted = append(deleted, logManager.TreeCandidate(file.Path, tracked[file.Path]))
+	}
+	for _, file := range result.UntrackedFiles {
+		added = append(added, workTreeCandidate(file.Path, filepath.Join(workTreeRoot, file.Path), currentDirFiles[file.Path]))
+	}
+
+	renames := log.DetectRenames(deleted, added)
+	if len(renames) == 0 {
+		return nil
+	}
+	renamedFrom := make(map[string]bool)
+	for _, oldPath := range renames {
+		renamedFrom[oldPath] = true
+	}
+
+	var remainingDeleted, remainingUntracked []status.FileStatus
+	for _, file := range result.DeletedFiles {
+		if !renamedFrom[file.Path] {
+			remainingDeleted = append(remainingDeleted, file)
+		}
+	}
+	for _, file := range result.UntrackedFiles {
+		if _, ok := renames[file.Path]; !ok {
+			remainingUntracked = append(remainingUntracked, file)
+		}
+	}
+	result.DeletedFiles = remainingDeleted
+	result.UntrackedFiles = remainingUntracked
+	return renames
+}
+
+// workTreeCandidate scans a working tree file for rename detection
+func workTreeCandidate(path, absPath, hash string) log.RenameCandidate {
+	if hash == "" {
+		hash, _ = status.CalculateFileHash(absPath)
+	}
+	candidate := log.RenameCandidate{Path: path, Hash: hash}
+	if info, err := scanner.NewFileScanner().ScanFile(absPath); err == nil {
+		candidate.Dimensions = info.Dimensions
+		candidate.Layers = info.LayerNames
+	}
+	return candidate
+}
+
+// sortedRenameTargets returns the new paths of a rename map in order
+func sortedRenameTargets(renames map[string]string) []string {
+	paths := make([]string, 0, len(renames))
+	for path := range renames {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
 }
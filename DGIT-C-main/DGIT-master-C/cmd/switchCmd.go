@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"dgit/internal/log"
+	"dgit/internal/refs"
+	"dgit/internal/restore"
+	"dgit/internal/staging"
+
+	"github.com/spf13/cobra"
+)
+
+// SwitchCmd switches the working tree to another branch
+var SwitchCmd = &cobra.Command{
+	Use:   "switch <branch>",
+	Short: "Switch to another branch",
+	Long: `Switch HEAD to another branch and update the working tree to match it.
+
+Files that differ between the branches are rewritten and files that only
+exist on the current branch are removed. The switch is refused when there
+are staged changes, modified tracked files, or untracked files that would
+be overwritten, unless --force is given.
+
+Examples:
+  dgit switch feature/logo        # Switch to an existing branch
+  dgit switch -c feature/logo     # Create a branch at HEAD and switch to it
+  dgit switch -c hotfix v2        # Create a branch at version 2 and switch to it
+  dgit switch --force main        # Switch, discarding local changes`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  runSwitch,
+}
+
+func init() {
+	SwitchCmd.Flags().BoolP("create", "c", false, "Create the branch before switching to it")
+	SwitchCmd.Flags().BoolP("force", "f", false, "Discard staged and local changes when switching")
+}
+
+// runSwitch moves HEAD to a branch and checks out its files
+func runSwitch(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	refManager := refs.NewRefManager(dgitDir)
+	logManager := log.NewLogManager(dgitDir)
+
+	create, _ := cmd.Flags().GetBool("create")
+	force, _ := cmd.Flags().GetBool("force")
+	name := args[0]
+
+	if len(args) > 1 && !create {
+		printError("a start point can only be given together with --create")
+		os.Exit(1)
+	}
+
+	currentBranch, err := refManager.CurrentBranch()
+	if err != nil {
+		printError(fmt.Sprintf("reading HEAD: %v", err))
+		os.Exit(1)
+	}
+
+	// Resolve the commit the branch points (or will point) to
+	var target *log.Commit
+	if create {
+		if err := refs.ValidateBranchName(name); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if refManager.BranchExists(name) {
+			printError(fmt.Sprintf("branch '%s' already exists", name))
+			os.Exit(1)
+		}
+		startPoint := ""
+		if len(args) > 1 {
+			startPoint = args[1]
+		}
+		target, err = resolveStartPoint(logManager, startPoint)
+	} else {
+		if !refManager.BranchExists(name) {
+			exitWithError(fmt.Sprintf("branch '%s' not found", name),
+				fmt.Sprintf("Use 'dgit switch -c %s' to create it", name))
+		}
+		if name == currentBranch {
+			fmt.Printf("Already on '%s'\n", name)
+			return
+		}
+		var tip string
+		tip, err = refManager.ReadBranch(name)
+		if err == nil {
+			target, err = logManager.GetCommitByHash(tip)
+		}
+	}
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	head, err := logManager.GetHeadCommit()
+	if err != nil {
+		printError(fmt.Sprintf("resolving HEAD: %v", err))
+		os.Exit(1)
+	}
+
+	// Only a change of commit touches the working tree
+	if head == nil || head.Hash != target.Hash {
+		stagingArea := staging.NewStagingArea(dgitDir)
+		if err := stagingArea.LoadStaging(); err != nil {
+			printError(fmt.Sprintf("loading staging area: %v", err))
+			os.Exit(1)
+		}
+
+		if !force {
+			checkSwitchSafety(dgitDir, stagingArea, head, target)
+		} else {
+			if err := stagingArea.ClearStaging(); err != nil {
+				printError(fmt.Sprintf("clearing staging area: %v", err))
+				os.Exit(1)
+			}
+		}
+
+		result, err := restore.NewRestoreManager(dgitDir).CheckoutCommit(head, target, force)
+		if err != nil {
+			for file, fileErr := range result.ErrorFiles {
+				printWarning(fmt.Sprintf("%s: %v", file, fileErr))
+			}
+			printError(fmt.Sprintf("checking out %s: %v", name, err))
+			os.Exit(1)
+		}
+		if len(result.RestoredFiles) > 0 || len(result.RemovedFiles) > 0 {
+			fmt.Printf("Updated %d file(s), removed %d file(s)\n", len(result.RestoredFiles), len(result.RemovedFiles))
+		}
+	}
+
+	if create {
+		if err := refManager.CreateBranch(name, target.Hash); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		recordBranchCreation(refManager, name, target, args[1:])
+	}
+	if err := refManager.SetHeadToBranch(name); err != nil {
+		printError(fmt.Sprintf("updating HEAD: %v", err))
+		os.Exit(1)
+	}
+	from := currentBranch
+	if from == "" && head != nil {
+		from = head.Hash[:8]
+	}
+	oldHash := ""
+	if head != nil {
+		oldHash = head.Hash
+	}
+	if err := refManager.RecordHeadMove(oldHash, target.Hash, "switch", fmt.Sprintf("moving from %s to %s", from, name)); err != nil {
+		printWarning(fmt.Sprintf("Failed to update reflog: %v", err))
+	}
+
+	if create {
+		printSuccess(fmt.Sprintf("Switched to a new branch '%s' at %s (v%d)", name, target.Hash[:8], target.Version))
+	} else {
+		printSuccess(fmt.Sprintf("Switched to branch '%s' at %s (v%d)", name, target.Hash[:8], target.Version))
+	}
+}
+
+// checkSwitchSafety exits when switching would lose staged work, local modifications or untracked files
+func checkSwitchSafety(dgitDir string, stagingArea *staging.StagingArea, head, target *log.Commit) {
+	if !stagingArea.IsEmpty() {
+		exitWithError("you have staged changes that would be lost by switching branches",
+			"Commit them first, or use --force to discard them")
+	}
+
+	changes, err := getWorkingTreeChanges(dgitDir, head)
+	if err != nil {
+		printError(fmt.Sprintf("checking working tree: %v", err))
+		os.Exit(1)
+	}
+
+	if len(changes.ModifiedFiles) > 0 || len(changes.DeletedFiles) > 0 {
+		printError("your local changes would be overwritten by switching branches:")
+		for _, file := range changes.ModifiedFiles {
+			fmt.Fprintf(os.Stderr, "  modified: %s\n", file.Path)
+		}
+		for _, file := range changes.DeletedFiles {
+			fmt.Fprintf(os.Stderr, "  deleted:  %s\n", file.Path)
+		}
+		exitWithError("", "")
+	}
+
+	targetFiles := target.TrackedFiles()
+	var overwritten []string
+	for _, file := range changes.UntrackedFiles {
+		if _, ok := targetFiles[file.Path]; ok {
+			overwritten = append(overwritten, file.Path)
+		}
+	}
+	if len(overwritten) > 0 {
+		printError("untracked files would be overwritten by switching branches:")
+		for _, path := range overwritten {
+			fmt.Fprintf(os.Stderr, "  %s\n", path)
+		}
+		printSuggestion("Move or add them first, or use --force to overwrite them")
+		os.Exit(1)
+	}
+}
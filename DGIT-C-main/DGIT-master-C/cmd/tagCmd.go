@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	initializer "dgit/internal/init"
+	"dgit/internal/log"
+	"dgit/internal/refs"
+
+	"github.com/spf13/cobra"
+)
+
+// TagCmd creates, lists and deletes annotated tags
+var TagCmd = &cobra.Command{
+	Use:   "tag [name] [version_hash_or_branch]",
+	Short: "Create, list, or delete tags",
+	Long: `Give a commit a permanent, human-friendly name such as a client delivery.
+
+Tags are stored under .dgit/refs/tags with an optional annotation message
+and tagger. Tag names can be used anywhere a version or hash is accepted
+(restore, show, log).
+
+Examples:
+  dgit tag                                    # List tags
+  dgit tag -l "client-*"                      # List tags matching a pattern
+  dgit tag client-friday                      # Tag HEAD
+  dgit tag client-friday v7 -m "Sent to ACME" # Tag version 7 with a message
+  dgit tag -d client-friday                   # Delete a tag`,
+	Args: cobra.MaximumNArgs(2),
+	Run:  runTag,
+}
+
+func init() {
+	TagCmd.Flags().BoolP("list", "l", false, "List tags, optionally filtered by a pattern")
+	TagCmd.Flags().StringP("message", "m", "", "Annotation message for the tag")
+	TagCmd.Flags().String("tagger", "", "Tagger name (defaults to the repository author)")
+	TagCmd.Flags().BoolP("delete", "d", false, "Delete a tag")
+	TagCmd.Flags().BoolP("force", "f", false, "Replace an existing tag")
+}
+
+// runTag dispatches between listing, creating and deleting tags
+func runTag(cmd *cobra.Command, args []string) {
+	dgitDir := checkDgitRepository()
+	refManager := refs.NewRefManager(dgitDir)
+	logManager := log.NewLogManager(dgitDir)
+
+	listFlag, _ := cmd.Flags().GetBool("list")
+	deleteFlag, _ := cmd.Flags().GetBool("delete")
+
+	switch {
+	case deleteFlag:
+		if len(args) != 1 {
+			printError("tag deletion requires exactly one tag name")
+			os.Exit(1)
+		}
+		deleteTag(refManager, args[0])
+	case listFlag || len(args) == 0:
+		pattern := ""
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+		listTags(refManager, logManager, pattern)
+	default:
+		createTag(cmd, dgitDir, refManager, logManager, args)
+	}
+}
+
+// listTags prints tags with the commit they point to
+func listTags(refManager *refs.RefManager, logManager *log.LogManager, pattern string) {
+	tags, err := refManager.ListTags()
+	if err != nil {
+		printError(fmt.Sprintf("listing tags: %v", err))
+		os.Exit(1)
+	}
+
+	nameWidth := 0
+	var matched []*refs.Tag
+	for _, tag := range tags {
+		if pattern != "" {
+			if ok, _ := filepath.Match(pattern, tag.Name); !ok {
+				continue
+			}
+		}
+		matched = append(matched, tag)
+		if len(tag.Name) > nameWidth {
+			nameWidth = len(tag.Name)
+		}
+	}
+
+	for _, tag := range matched {
+		target := tag.Target
+		if commit, err := logManager.GetCommitByHash(tag.Target); err == nil {
+			target = fmt.Sprintf("%s (v%d)", commit.Hash[:8], commit.Version)
+		}
+
+		line := fmt.Sprintf("%-*s  %s", nameWidth, tag.Name, target)
+		if tag.Message != "" {
+			line += "  " + strings.SplitN(tag.Message, "\n", 2)[0]
+		}
+		fmt.Println(line)
+	}
+}
+
+// createTag tags HEAD or the given revision
+func createTag(cmd *cobra.Command, dgitDir string, refManager *refs.RefManager, logManager *log.LogManager, args []string) {
+	name := args[0]
+	message, _ := cmd.Flags().GetString("message")
+	tagger, _ := cmd.Flags().GetString("tagger")
+	force, _ := cmd.Flags().GetBool("force")
+
+	startPoint := ""
+	if len(args) > 1 {
+		startPoint = args[1]
+	}
+	target, err := resolveStartPoint(logManager, startPoint)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if tagger == "" {
+		tagger = getConfiguredAuthor(dgitDir)
+	}
+
+	tag := &refs.Tag{
+		Name:      name,
+		Target:    target.Hash,
+		Message:   message,
+		Tagger:    tagger,
+		Timestamp: time.Now(),
+	}
+	if err := refManager.CreateTag(tag, force); err != nil {
+		if refManager.TagExists(name) && !force {
+			exitWithError(err.Error(), "Use --force to move the tag")
+		}
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	printSuccess(fmt.Sprintf("Tagged %s (v%d) as '%s'", target.Hash[:8], target.Version, name))
+}
+
+// deleteTag removes a tag
+func deleteTag(refManager *refs.RefManager, name string) {
+	tag, err := refManager.ReadTag(name)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := refManager.DeleteTag(name); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	printSuccess(fmt.Sprintf("Deleted tag '%s' (was %s)", name, tag.Target[:min(8, len(tag.Target))]))
+}
+
+// getConfiguredAuthor returns the author configured for the repository
+func getConfiguredAuthor(dgitDir string) string {
+	if config, err := initializer.GetConfig(dgitDir); err == nil && config.Author != "" {
+		return config.Author
+	}
+	return "DGit User"
+}
@@ -6,6 +6,7 @@ require (
 	github.com/fatih/color v1.18.0
 	github.com/klauspost/compress v1.17.4
 	github.com/kr/binarydist v0.1.0
+	github.com/mattn/go-isatty v0.0.20
 	github.com/pierrec/lz4/v4 v4.1.21
 	github.com/spf13/cobra v1.8.0
 )
@@ -14,7 +15,6 @@ require (
 	github.com/dsnet/compress v0.0.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 )
@@ -0,0 +1,211 @@
+package bisect
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"dgit/internal/log"
+	"dgit/internal/restore"
+)
+
+// BisectState is the bisection in progress, stored in .dgit/BISECT_STATE
+type BisectState struct {
+	OriginalHead   string    `json:"original_head"`
+	OriginalBranch string    `json:"original_branch,omitempty"` // Empty when HEAD was detached
+	Bad            string    `json:"bad,omitempty"`
+	Good           []string  `json:"good,omitempty"`
+	Skipped        []string  `json:"skipped,omitempty"`
+	Current        string    `json:"current,omitempty"`  // Commit restored for testing
+	WorkDir        string    `json:"work_dir,omitempty"` // Directory holding the version under test; empty for the working tree
+	FirstBad       string    `json:"first_bad,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+}
+
+// Step is the outcome of narrowing down the candidates
+type Step struct {
+	Next      *log.Commit   // Commit to test next; nil when bisection is finished
+	FirstBad  *log.Commit   // The first bad commit, once found
+	Skipped   []*log.Commit // When only skipped commits remain: the commits that may be the first bad one
+	Remaining int           // Commits left to test after Next
+	Steps     int           // Rough number of test steps left
+}
+
+// BisectManager finds the commit that introduced a problem by binary search over history
+type BisectManager struct {
+	DgitDir        string
+	StateFile      string
+	logManager     *log.LogManager
+	restoreManager *restore.RestoreManager
+}
+
+// NewBisectManager creates a new bisect manager
+func NewBisectManager(dgitDir string) *BisectManager {
+	return &BisectManager{
+		DgitDir:        dgitDir,
+		StateFile:      filepath.Join(dgitDir, "BISECT_STATE"),
+		logManager:     log.NewLogManager(dgitDir),
+		restoreManager: restore.NewRestoreManager(dgitDir),
+	}
+}
+
+// InProgress reports whether a bisection has been started
+func (bm *BisectManager) InProgress() bool {
+	_, err := os.Stat(bm.StateFile)
+	return err == nil
+}
+
+// LoadState reads the bisection in progress
+func (bm *BisectManager) LoadState() (*BisectState, error) {
+	data, err := os.ReadFile(bm.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("not bisecting")
+		}
+		return nil, fmt.Errorf("failed to read bisect state: %w", err)
+	}
+
+	var state BisectState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse bisect state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveState records the bisection in progress
+func (bm *BisectManager) SaveState(state *BisectState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bisect state: %w", err)
+	}
+	return os.WriteFile(bm.StateFile, data, 0644)
+}
+
+// ClearState ends the bisection
+func (bm *BisectManager) ClearState() error {
+	if err := os.Remove(bm.StateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear bisect state: %w", err)
+	}
+	return nil
+}
+
+// NextStep picks the commit that best halves the remaining candidates
+// Candidates are the commits reachable from the bad commit but not from any good commit
+func (bm *BisectManager) NextStep(state *BisectState) (*Step, error) {
+	if state.Bad == "" || len(state.Good) == 0 {
+		return nil, fmt.Errorf("bisection needs a bad and at least one good commit")
+	}
+
+	allCommits, err := bm.logManager.GetAllCommits()
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]*log.Commit, len(allCommits))
+	for _, c := range allCommits {
+		byHash[c.Hash] = c
+	}
+
+	excluded := make(map[string]bool)
+	for _, good := range state.Good {
+		for hash := range reachable(byHash, good, nil) {
+			excluded[hash] = true
+		}
+	}
+	if excluded[state.Bad] {
+		return nil, fmt.Errorf("the bad commit %s is an ancestor of a good commit; were good and bad swapped?", state.Bad[:8])
+	}
+
+	candidates := make(map[string]bool)
+	for hash := range reachable(byHash, state.Bad, excluded) {
+		candidates[hash] = true
+	}
+	if len(candidates) == 1 {
+		return &Step{FirstBad: byHash[state.Bad]}, nil
+	}
+
+	skipped := make(map[string]bool)
+	for _, hash := range state.Skipped {
+		skipped[hash] = true
+	}
+
+	// The best commit to test splits the candidates into two halves of equal size
+	var best *log.Commit
+	bestScore := -1
+	testable := 0
+	for hash := range candidates {
+		if hash == state.Bad || skipped[hash] {
+			continue
+		}
+		testable++
+		below := len(reachable(byHash, hash, excluded))
+		score := below
+		if rest := len(candidates) - below; rest < score {
+			score = rest
+		}
+		c := byHash[hash]
+		if score > bestScore || (score == bestScore && c.Timestamp.Before(best.Timestamp)) ||
+			(score == bestScore && c.Timestamp.Equal(best.Timestamp) && c.Hash < best.Hash) {
+			best, bestScore = c, score
+		}
+	}
+
+	if best == nil {
+		// Only skipped commits are left, so the first bad commit cannot be narrowed down further
+		step := &Step{}
+		for hash := range candidates {
+			step.Skipped = append(step.Skipped, byHash[hash])
+		}
+		sort.Slice(step.Skipped, func(i, j int) bool { return step.Skipped[i].Version < step.Skipped[j].Version })
+		return step, nil
+	}
+	return &Step{
+		Next:      best,
+		Remaining: testable - 1,
+		Steps:     bits.Len(uint(testable - 1)),
+	}, nil
+}
+
+// Extract writes every tracked file of a commit into dir, replacing its previous contents
+func (bm *BisectManager) Extract(commit *log.Commit, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", dir, err)
+	}
+	for path := range commit.TrackedFiles() {
+		data, err := bm.restoreManager.ReadCommitFile(commit, path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// reachable returns the commits reachable from hash, following merge parents and stopping at stop
+func reachable(byHash map[string]*log.Commit, hash string, stop map[string]bool) map[string]bool {
+	seen := make(map[string]bool)
+	queue := []string{hash}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == "" || seen[current] || stop[current] {
+			continue
+		}
+		c, ok := byHash[current]
+		if !ok {
+			continue
+		}
+		seen[current] = true
+		queue = append(queue, c.Parents...)
+	}
+	return seen
+}
@@ -0,0 +1,156 @@
+package bisect
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dgit/internal/log"
+)
+
+// newTestBisect records commits c01..cNN, each the parent of the next, plus any extra commits
+func newTestBisect(t *testing.T, n int, extra ...*log.Commit) *BisectManager {
+	dgitDir := filepath.Join(t.TempDir(), ".dgit")
+	if err := os.MkdirAll(filepath.Join(dgitDir, "commits"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	commits := extra
+	for i := 1; i <= n; i++ {
+		c := &log.Commit{Hash: hash(i), Message: hash(i), Version: i, Timestamp: start.Add(time.Duration(i) * time.Hour)}
+		if i > 1 {
+			c.ParentHash, c.Parents = hash(i-1), []string{hash(i - 1)}
+		}
+		commits = append(commits, c)
+	}
+	for _, c := range commits {
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dgitDir, "commits", c.Hash+".json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewBisectManager(dgitDir)
+}
+
+// hash names the i-th commit of the test history
+func hash(i int) string {
+	return fmt.Sprintf("c%02d0000000000", i)
+}
+
+func TestNextStepFindsTheFirstBadCommit(t *testing.T) {
+	const n = 16
+	bm := newTestBisect(t, n)
+	for firstBad := 2; firstBad <= n; firstBad++ {
+		state := &BisectState{Bad: hash(n), Good: []string{hash(1)}}
+		tested := 0
+		for {
+			step, err := bm.NextStep(state)
+			if err != nil {
+				t.Fatalf("first bad v%d: %v", firstBad, err)
+			}
+			if step.FirstBad != nil {
+				if step.FirstBad.Hash != hash(firstBad) {
+					t.Errorf("first bad v%d: found v%d", firstBad, step.FirstBad.Version)
+				}
+				break
+			}
+			tested++
+			if tested > 4 {
+				t.Fatalf("first bad v%d: more than 4 steps for %d commits", firstBad, n)
+			}
+			if step.Next.Version >= firstBad {
+				state.Bad = step.Next.Hash
+			} else {
+				state.Good = append(state.Good, step.Next.Hash)
+			}
+		}
+	}
+}
+
+func TestNextStep(t *testing.T) {
+	merge := &log.Commit{
+		Hash: "m0000000000000", Version: 9, Timestamp: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		ParentHash: hash(8), Parents: []string{hash(8), "b0000000000000"},
+	}
+	branch := &log.Commit{
+		Hash: "b0000000000000", Version: 3, Timestamp: time.Date(2025, 3, 1, 11, 30, 0, 0, time.UTC),
+		ParentHash: hash(2), Parents: []string{hash(2)},
+	}
+	bm := newTestBisect(t, 8, merge, branch)
+
+	cases := []struct {
+		name      string
+		state     BisectState
+		next      string
+		firstBad  string
+		skipped   []string
+		remaining int
+		err       string
+	}{
+		{name: "middle of the line", state: BisectState{Bad: hash(8), Good: []string{hash(1)}}, next: hash(4), remaining: 5},
+		{name: "adjacent", state: BisectState{Bad: hash(5), Good: []string{hash(4)}}, firstBad: hash(5)},
+		{name: "skipped commit", state: BisectState{Bad: hash(8), Good: []string{hash(1)}, Skipped: []string{hash(4)}}, next: hash(5), remaining: 4},
+		{name: "only skipped commits left", state: BisectState{Bad: hash(5), Good: []string{hash(2)}, Skipped: []string{hash(3), hash(4)}},
+			skipped: []string{hash(3), hash(4), hash(5)}},
+		{name: "merged branch is a candidate", state: BisectState{Bad: merge.Hash, Good: []string{hash(6)}}, next: hash(8), remaining: 2},
+		{name: "good on both sides of the merge", state: BisectState{Bad: merge.Hash, Good: []string{hash(8), branch.Hash}}, firstBad: merge.Hash},
+		{name: "swapped", state: BisectState{Bad: hash(2), Good: []string{hash(5)}}, err: "the bad commit c0200000 is an ancestor of a good commit"},
+		{name: "no good commit", state: BisectState{Bad: hash(5)}, err: "bisection needs a bad and at least one good commit"},
+	}
+	for _, c := range cases {
+		step, err := bm.NextStep(&c.state)
+		if c.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+				t.Errorf("%s: error %v, want %s", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		var next, firstBad string
+		if step.Next != nil {
+			next = step.Next.Hash
+		}
+		if step.FirstBad != nil {
+			firstBad = step.FirstBad.Hash
+		}
+		var skipped []string
+		for _, commit := range step.Skipped {
+			skipped = append(skipped, commit.Hash)
+		}
+		if next != c.next || firstBad != c.firstBad || !reflect.DeepEqual(skipped, c.skipped) || step.Remaining != c.remaining {
+			t.Errorf("%s: next %q, first bad %q, skipped %v, %d remaining; want %q, %q, %v, %d",
+				c.name, next, firstBad, skipped, step.Remaining, c.next, c.firstBad, c.skipped, c.remaining)
+		}
+	}
+}
+
+func TestBisectState(t *testing.T) {
+	bm := newTestBisect(t, 1)
+	if bm.InProgress() {
+		t.Fatal("bisecting in a new repository")
+	}
+	if _, err := bm.LoadState(); err == nil {
+		t.Error("expected an error loading a missing bisect state")
+	}
+	state := &BisectState{OriginalHead: hash(1), OriginalBranch: "main", Bad: hash(1), Good: []string{"x"}, StartedAt: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)}
+	if err := bm.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := bm.LoadState(); err != nil || !reflect.DeepEqual(loaded, state) {
+		t.Errorf("loaded %+v, %v; want %+v", loaded, err, state)
+	}
+	if err := bm.ClearState(); err != nil || bm.InProgress() {
+		t.Errorf("clear: %v, in progress %v", err, bm.InProgress())
+	}
+}
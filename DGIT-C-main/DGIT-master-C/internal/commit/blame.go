@@ -0,0 +1,150 @@
+package commit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"dgit/internal/log"
+	"dgit/internal/restore"
+	"dgit/internal/scanner/photoshop"
+)
+
+// LayerBlame records the last commit that changed a layer's content or properties
+type LayerBlame struct {
+	Index      int       `json:"index"`              // Position in the PSD, 0 is the bottom layer
+	LayerID    int       `json:"layer_id,omitempty"` // Persistent layer ID when the file stores one
+	Name       string    `json:"name"`
+	Commit     string    `json:"commit"`
+	Version    int       `json:"version"`
+	Author     string    `json:"author"`
+	Date       time.Time `json:"date"`
+	Path       string    `json:"path"`                 // File path at that commit, which differs after renames
+	Change     string    `json:"change"`               // "added" or "modified"
+	Properties []string  `json:"properties,omitempty"` // What changed: pixels, name, opacity, visibility, blend_mode, position
+}
+
+// BlameLayers attributes each layer of a PSD to the commit that last changed it
+// history is the file's history from LogManager.FileHistory (newest first); the result
+// describes the layers of its newest revision
+func (cm *CommitManager) BlameLayers(history []*log.FileRevision) ([]LayerBlame, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("file has no history")
+	}
+	if history[0].Change == "deleted" {
+		return nil, fmt.Errorf("file was deleted in %s (v%d)", history[0].Commit.Hash[:8], history[0].Commit.Version)
+	}
+
+	tempDir, err := os.MkdirTemp("", "dgit-blame-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	restoreManager := restore.NewRestoreManager(cm.DgitDir)
+
+	var prevLayers []DetailedLayer
+	var blame []LayerBlame
+	for i := len(history) - 1; i >= 0; i-- {
+		revision := history[i]
+		if revision.Change == "deleted" {
+			// A file added again later starts a fresh history
+			prevLayers, blame = nil, nil
+			continue
+		}
+
+		layers, err := cm.readRevisionLayers(restoreManager, revision, tempDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s at v%d: %w", revision.Path, revision.Commit.Version, err)
+		}
+		blame = cm.advanceBlame(prevLayers, layers, blame, revision)
+		prevLayers = layers
+	}
+
+	// Report in layer panel order: top layer first
+	sort.SliceStable(blame, func(i, j int) bool { return blame[i].Index > blame[j].Index })
+	return blame, nil
+}
+
+// advanceBlame carries blame from one version of a file to the next using compareLayerVersions
+func (cm *CommitManager) advanceBlame(oldLayers, newLayers []DetailedLayer, oldBlame []LayerBlame, revision *log.FileRevision) []LayerBlame {
+	attribute := func(layer DetailedLayer, change string, properties []string) LayerBlame {
+		c := revision.Commit
+		return LayerBlame{
+			Index:      layer.ID,
+			LayerID:    layer.LayerID,
+			Name:       layer.Name,
+			Commit:     c.Hash,
+			Version:    c.Version,
+			Author:     c.Author,
+			Date:       c.Timestamp,
+			Path:       revision.Path,
+			Change:     change,
+			Properties: properties,
+		}
+	}
+
+	blame := make([]LayerBlame, len(newLayers))
+	if oldLayers == nil {
+		for i, layer := range newLayers {
+			blame[i] = attribute(layer, "added", nil)
+		}
+		return blame
+	}
+
+	analysis := cm.compareLayerVersions(oldLayers, newLayers)
+	added := make(map[int]bool)
+	for _, change := range analysis.AddedLayers {
+		added[change.LayerID] = true
+	}
+	changed := make(map[int]LayerChange)
+	for _, change := range analysis.ChangedLayers {
+		changed[change.LayerID] = change
+	}
+
+	byID := photoshop.HasLayerIDs(oldLayers, newLayers)
+	newKeys := photoshop.LayerKeys(newLayers, byID)
+	oldIndex := make(map[string]int)
+	for i, key := range photoshop.LayerKeys(oldLayers, byID) {
+		oldIndex[key] = i
+	}
+
+	for i, layer := range newLayers {
+		if change, ok := changed[layer.ID]; ok {
+			var properties []string
+			if change.OldHash != change.NewHash {
+				properties = append(properties, "pixels")
+			}
+			for property := range change.PropertyChanges {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+			blame[i] = attribute(layer, "modified", properties)
+			continue
+		}
+		j, existed := oldIndex[newKeys[i]]
+		if added[layer.ID] || !existed || j >= len(oldBlame) {
+			blame[i] = attribute(layer, "added", nil)
+			continue
+		}
+		// Unchanged layers keep their attribution but follow reordering
+		blame[i] = oldBlame[j]
+		blame[i].Index = layer.ID
+	}
+	return blame
+}
+
+// readRevisionLayers extracts a file version to a temp file and parses its layers
+func (cm *CommitManager) readRevisionLayers(restoreManager *restore.RestoreManager, revision *log.FileRevision, tempDir string) ([]DetailedLayer, error) {
+	data, err := restoreManager.ReadCommitFile(revision.Commit, revision.Path)
+	if err != nil {
+		return nil, err
+	}
+	tempPath := filepath.Join(tempDir, revision.Commit.Hash+filepath.Ext(revision.Path))
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempPath)
+	return cm.extractPSDLayerInfo(tempPath)
+}
@@ -0,0 +1,136 @@
+package commit
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"dgit/internal/log"
+)
+
+// duplicateLayers are two layers named "Shadow" without persistent IDs, bottom to top
+func duplicateLayers(lowerHash, upperHash string) []DetailedLayer {
+	return []DetailedLayer{
+		{ID: 0, Name: "Background", ContentHash: "bg", Visible: true},
+		{ID: 1, Name: "Shadow", ContentHash: lowerHash, Visible: true},
+		{ID: 2, Name: "Shadow", ContentHash: upperHash, Visible: true},
+	}
+}
+
+func TestCompareLayersKeepsDuplicateNames(t *testing.T) {
+	analysis := CompareLayers(duplicateLayers("a", "b"), duplicateLayers("a", "c"))
+	if len(analysis.ChangedLayers) != 1 || analysis.ChangedLayers[0].LayerID != 2 {
+		t.Errorf("changed layers = %+v", analysis.ChangedLayers)
+	}
+	if len(analysis.AddedLayers)+len(analysis.DeletedLayers)+len(analysis.MovedLayers) != 0 {
+		t.Errorf("added %+v, deleted %+v, moved %+v", analysis.AddedLayers, analysis.DeletedLayers, analysis.MovedLayers)
+	}
+
+	// A third layer of the same name is an addition, not a change of the existing ones
+	grown := append(duplicateLayers("a", "b"), DetailedLayer{ID: 3, Name: "Shadow", ContentHash: "d"})
+	analysis = CompareLayers(duplicateLayers("a", "b"), grown)
+	if len(analysis.AddedLayers) != 1 || analysis.AddedLayers[0].LayerID != 3 || len(analysis.ChangedLayers) != 0 {
+		t.Errorf("added %+v, changed %+v", analysis.AddedLayers, analysis.ChangedLayers)
+	}
+}
+
+func TestCompareLayersByID(t *testing.T) {
+	oldLayers := []DetailedLayer{{ID: 0, LayerID: 5, Name: "Logo", ContentHash: "a"}, {ID: 1, LayerID: 6, Name: "Text", ContentHash: "b"}}
+	newLayers := []DetailedLayer{{ID: 0, LayerID: 6, Name: "Text", ContentHash: "b"}, {ID: 1, LayerID: 5, Name: "Brand", ContentHash: "a"}}
+	analysis := CompareLayers(oldLayers, newLayers)
+	if len(analysis.ChangedLayers) != 1 || analysis.ChangedLayers[0].LayerName != "Brand" {
+		t.Errorf("changed layers = %+v", analysis.ChangedLayers)
+	}
+	if len(analysis.MovedLayers) != 1 || len(analysis.AddedLayers) != 0 {
+		t.Errorf("moved %+v, added %+v", analysis.MovedLayers, analysis.AddedLayers)
+	}
+
+	// Repeated IDs cannot identify layers, so names are used instead
+	repeated := []DetailedLayer{{LayerID: 5, Name: "Logo", ContentHash: "a"}, {LayerID: 5, Name: "Text", ContentHash: "b"}}
+	renamed := []DetailedLayer{{LayerID: 5, Name: "Logo", ContentHash: "a"}, {LayerID: 5, Name: "Caption", ContentHash: "b"}}
+	analysis = CompareLayers(repeated, renamed)
+	if len(analysis.AddedLayers) != 1 || len(analysis.DeletedLayers) != 1 || len(analysis.ChangedLayers) != 0 {
+		t.Errorf("added %+v, deleted %+v, changed %+v", analysis.AddedLayers, analysis.DeletedLayers, analysis.ChangedLayers)
+	}
+}
+
+func TestAdvanceBlameKeepsDuplicateNames(t *testing.T) {
+	cm := &CommitManager{}
+	first := &log.FileRevision{Commit: &log.Commit{Hash: "1111111111", Version: 1}, Path: "a.psd"}
+	second := &log.FileRevision{Commit: &log.Commit{Hash: "2222222222", Version: 2}, Path: "a.psd"}
+
+	blame := cm.advanceBlame(nil, duplicateLayers("a", "b"), nil, first)
+	blame = cm.advanceBlame(duplicateLayers("a", "b"), duplicateLayers("a", "c"), blame, second)
+
+	var commits []string
+	for _, layer := range blame {
+		commits = append(commits, layer.Commit)
+	}
+	// Only the upper Shadow layer was edited in the second commit
+	if want := []string{"1111111111", "1111111111", "2222222222"}; !reflect.DeepEqual(commits, want) {
+		t.Errorf("commits = %v, want %v", commits, want)
+	}
+	if blame[2].Change != "modified" || !reflect.DeepEqual(blame[2].Properties, []string{"pixels"}) {
+		t.Errorf("upper Shadow blame = %+v", blame[2])
+	}
+}
+
+func TestAdvanceBlame(t *testing.T) {
+	base := []DetailedLayer{
+		{ID: 0, Name: "Background", ContentHash: "bg", Opacity: 255, Visible: true},
+		{ID: 1, Name: "Logo", ContentHash: "logo", Opacity: 255, Visible: true},
+		{ID: 2, Name: "Text", ContentHash: "text", Opacity: 255, Visible: true},
+	}
+	edit := func(edits func(layers []DetailedLayer) []DetailedLayer) []DetailedLayer {
+		return edits(append([]DetailedLayer{}, base...))
+	}
+	cases := []struct {
+		name   string
+		layers []DetailedLayer
+		want   []string // Name, version, change and properties of each layer, bottom to top
+	}{
+		{"unchanged", base, []string{"Background v1 added []", "Logo v1 added []", "Text v1 added []"}},
+		{"pixels", edit(func(l []DetailedLayer) []DetailedLayer {
+			l[1].ContentHash = "logo2"
+			return l
+		}), []string{"Background v1 added []", "Logo v2 modified [pixels]", "Text v1 added []"}},
+		{"opacity and visibility", edit(func(l []DetailedLayer) []DetailedLayer {
+			l[2].Opacity, l[2].Visible = 128, false
+			return l
+		}), []string{"Background v1 added []", "Logo v1 added []", "Text v2 modified [opacity visibility]"}},
+		{"position and blend mode", edit(func(l []DetailedLayer) []DetailedLayer {
+			l[1].Position, l[1].BlendMode = [4]int32{0, 10, 20, 30}, "mul "
+			return l
+		}), []string{"Background v1 added []", "Logo v2 modified [blend_mode position]", "Text v1 added []"}},
+		{"reordered", edit(func(l []DetailedLayer) []DetailedLayer {
+			l[1], l[2] = l[2], l[1]
+			l[1].ID, l[2].ID = 1, 2
+			return l
+		}), []string{"Background v1 added []", "Text v1 added []", "Logo v1 added []"}},
+		{"new layer", edit(func(l []DetailedLayer) []DetailedLayer {
+			return append(l, DetailedLayer{ID: 3, Name: "Badge", ContentHash: "badge", Opacity: 255, Visible: true})
+		}), []string{"Background v1 added []", "Logo v1 added []", "Text v1 added []", "Badge v2 added []"}},
+		{"deleted layer", edit(func(l []DetailedLayer) []DetailedLayer {
+			l = append(l[:1], l[2:]...)
+			l[1].ID = 1
+			return l
+		}), []string{"Background v1 added []", "Text v1 added []"}},
+	}
+	cm := &CommitManager{}
+	first := &log.FileRevision{Commit: &log.Commit{Hash: "1111111111", Version: 1}, Path: "hero.psd"}
+	second := &log.FileRevision{Commit: &log.Commit{Hash: "2222222222", Version: 2}, Path: "hero.psd"}
+	for _, c := range cases {
+		blame := cm.advanceBlame(nil, base, nil, first)
+		blame = cm.advanceBlame(base, c.layers, blame, second)
+		var got []string
+		for i, layer := range blame {
+			if layer.Index != c.layers[i].ID {
+				t.Errorf("%s: layer %d has index %d", c.name, i, layer.Index)
+			}
+			got = append(got, fmt.Sprintf("%s v%d %s %v", layer.Name, layer.Version, layer.Change, layer.Properties))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: blame = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -15,8 +15,13 @@ import (
 	"strings"
 	"time"
 
+	"dgit/internal/log"
+	"dgit/internal/refs"
+	"dgit/internal/restore"
 	"dgit/internal/scanner"
+	"dgit/internal/scanner/illustrator"
 	"dgit/internal/staging"
+	"dgit/internal/thumbnail"
 
 	// Compression Libraries
 	"github.com/klauspost/compress/zstd"
@@ -35,6 +40,9 @@ const (
 // DetailedLayer represents detailed layer information from photoshop package
 type DetailedLayer = photoshop.DetailedLayer
 
+// TreeEntry records where a tracked file's content is stored
+type TreeEntry = log.TreeEntry
+
 // CompressionResult contains detailed compression operation metrics
 type CompressionResult struct {
 	Strategy         string    `json:"strategy"` // "lz4", "zip", "bsdiff", "xdelta3", "psd_smart"
@@ -43,6 +51,7 @@ type CompressionResult struct {
 	CompressedSize   int64     `json:"compressed_size"`
 	CompressionRatio float64   `json:"compression_ratio"`
 	BaseVersion      int       `json:"base_version,omitempty"`
+	BaseHash         string    `json:"base_hash,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 
 	// Performance Metrics
@@ -61,6 +70,11 @@ type Commit struct {
 	Version         int                    `json:"version"`
 	Metadata        map[string]interface{} `json:"metadata"`
 	ParentHash      string                 `json:"parent_hash,omitempty"`
+	Branch          string                 `json:"branch,omitempty"`
+	Parents         []string               `json:"parents,omitempty"`
+	MergeParents    []string               `json:"merge_parents,omitempty"`
+	Renames         map[string]string      `json:"renames,omitempty"`
+	Tree            map[string]TreeEntry   `json:"tree,omitempty"`
 	SnapshotZip     string                 `json:"snapshot_zip,omitempty"`
 	CompressionInfo *CompressionResult     `json:"compression_info,omitempty"`
 }
@@ -85,6 +99,8 @@ type CommitManager struct {
 	// Compression configuration
 	lz4CompressionLevel int
 	enableBackgroundOpt bool
+
+	refManager *refs.RefManager
 }
 
 // NewCommitManager creates a new commit manager with simplified structure
@@ -117,6 +133,7 @@ func NewCommitManager(dgitDir string) *CommitManager {
 		CompressionThreshold: 0.3,
 		lz4CompressionLevel:  1,
 		enableBackgroundOpt:  true,
+		refManager:           refs.NewRefManager(dgitDir),
 	}
 
 	cm.loadConfig()
@@ -124,32 +141,209 @@ func NewCommitManager(dgitDir string) *CommitManager {
 }
 
 // CreateCommit creates a new commit with staged files
-func (cm *CommitManager) CreateCommit(message string, stagedFiles []*staging.StagedFile) (*Commit, error) {
-	startTime := time.Now()
-
+// removed lists repository-relative paths that are no longer tracked after the commit
+func (cm *CommitManager) CreateCommit(message string, stagedFiles []*staging.StagedFile, removed []string) (*Commit, error) {
 	// Validate input
-	if len(stagedFiles) == 0 {
+	if len(stagedFiles) == 0 && len(removed) == 0 {
 		return nil, fmt.Errorf("no files staged for commit")
 	}
+	parent, err := cm.getHeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	c, err := cm.createCommit(message, stagedFiles, removed, parent, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	operation := "commit"
+	if parent == nil {
+		operation = "commit (initial)"
+	}
+	cm.recordCommit(parent, c, operation)
+	return c, nil
+}
+
+// CreateMergeCommit records the merge of mergeParent into HEAD
+// mergedTree is the merge result; staged files (merged or hand-resolved content) are stored in the new snapshot
+func (cm *CommitManager) CreateMergeCommit(message string, stagedFiles []*staging.StagedFile, mergedTree map[string]TreeEntry, mergeParent string) (*Commit, error) {
+	if mergeParent == "" {
+		return nil, fmt.Errorf("merge commit requires a second parent")
+	}
+	parent, err := cm.getHeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	c, err := cm.createCommit(message, stagedFiles, nil, parent, mergedTree, []string{mergeParent})
+	if err != nil {
+		return nil, err
+	}
+	cm.recordCommit(parent, c, "commit (merge)")
+	return c, nil
+}
+
+// CreateCommitWithTree records a commit whose tree is given explicitly, such as the result of a revert
+// Only the staged files are stored; other entries keep referring to existing snapshots
+func (cm *CommitManager) CreateCommitWithTree(message string, stagedFiles []*staging.StagedFile, tree map[string]TreeEntry) (*Commit, error) {
+	if tree == nil {
+		tree = map[string]TreeEntry{}
+	}
+	parent, err := cm.getHeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	c, err := cm.createCommit(message, stagedFiles, nil, parent, tree, nil)
+	if err != nil {
+		return nil, err
+	}
+	cm.recordCommit(parent, c, "commit")
+	return c, nil
+}
+
+// AmendCommit replaces HEAD with a new commit holding its changes plus the staged files
+// Files stored by HEAD are compressed again together with the staged files, and the
+// branch moves to the new commit. An empty message keeps HEAD's message.
+func (cm *CommitManager) AmendCommit(message string, stagedFiles []*staging.StagedFile, removed []string) (*Commit, error) {
+	head, err := cm.getHeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if head == nil {
+		return nil, fmt.Errorf("no commit to amend")
+	}
+	if message == "" {
+		message = head.Message
+	}
+
+	var parent *log.Commit
+	if head.ParentHash != "" {
+		parent, err = log.NewLogManager(cm.DgitDir).GetCommitByHash(head.ParentHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent of %s: %w", head.Hash, err)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "dgit-amend-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Carry over the files HEAD stored that are not being replaced
+	files := append([]*staging.StagedFile{}, stagedFiles...)
+	staged := make(map[string]bool)
+	for _, f := range stagedFiles {
+		staged[f.Path] = true
+	}
+	for _, path := range removed {
+		staged[path] = true
+	}
+	for path, entry := range head.TrackedFiles() {
+		if entry.Commit != head.Hash || staged[path] {
+			continue
+		}
+		carried, err := cm.stageCommittedFile(head, path, tempDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to carry over %s: %w", path, err)
+		}
+		carried.RenamedFrom = head.Renames[path]
+		files = append(files, carried)
+	}
+	if len(files) == 0 && len(removed) == 0 && len(head.Renames) == 0 {
+		return nil, fmt.Errorf("nothing to amend")
+	}
+
+	amended, err := cm.createCommit(message, files, removed, parent, head.TrackedFiles(), head.MergeParents)
+	if err != nil {
+		return nil, err
+	}
+	if err := cm.refManager.RecordRefUpdate(head.Hash, amended.Hash, "amend", amended.Message); err != nil {
+		fmt.Printf("Warning: failed to record reflog: %v\n", err)
+	}
+	return amended, nil
+}
+
+// stageCommittedFile prepares a file stored by a commit for storing again
+// The working tree copy is used when it still matches; otherwise the stored content is extracted
+func (cm *CommitManager) stageCommittedFile(c *log.Commit, path, tempDir string) (*staging.StagedFile, error) {
+	absPath := filepath.Join(filepath.Dir(cm.DgitDir), path)
+	entry := c.TrackedFiles()[path]
+	if hash, _, err := hashFileContent(absPath); err != nil || entry.Hash == "" || hash != entry.Hash {
+		data, err := restore.NewRestoreManager(cm.DgitDir).ReadCommitFile(c, path)
+		if err != nil {
+			return nil, err
+		}
+		absPath = filepath.Join(tempDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(absPath, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return &staging.StagedFile{
+		Path:         path,
+		AbsolutePath: absPath,
+		FileType:     strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."),
+		Size:         info.Size(),
+		ModTime:      info.ModTime(),
+		AddedAt:      time.Now(),
+	}, nil
+}
+
+// createCommit stores the staged files and records a commit on top of parent
+// baseTree replaces the parent's tree as the starting point when given
+func (cm *CommitManager) createCommit(message string, stagedFiles []*staging.StagedFile, removed []string, parent *log.Commit, baseTree map[string]TreeEntry, mergeParents []string) (*Commit, error) {
+	startTime := time.Now()
 
-	// Generate version and commit metadata
-	currentVersion := cm.GetCurrentVersion()
-	newVersion := currentVersion + 1
+	// Versions count along the current line of history, so each branch numbers its own commits
+	newVersion := 1
+	parentHash := ""
+	if parent != nil {
+		newVersion = parent.Version + 1
+		parentHash = parent.Hash
+	}
 
-	hash := cm.generateCommitHash(message, stagedFiles, newVersion)
+	hash := cm.generateCommitHash(message, stagedFiles, newVersion, parentHash+strings.Join(mergeParents, ""))
 	author := cm.getAuthor()
+	branch, err := cm.refManager.CurrentBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current branch: %w", err)
+	}
 
 	// Create commit structure
 	commit := &Commit{
-		Hash:       hash,
-		Message:    message,
-		Timestamp:  time.Now(),
-		Author:     author,
-		FilesCount: len(stagedFiles),
-		Version:    newVersion,
-		Metadata:   make(map[string]interface{}),
-		ParentHash: cm.getCurrentCommitHash(),
+		Hash:         hash,
+		Message:      message,
+		Timestamp:    time.Now(),
+		Author:       author,
+		FilesCount:   len(stagedFiles),
+		Version:      newVersion,
+		Metadata:     make(map[string]interface{}),
+		ParentHash:   parentHash,
+		Branch:       branch,
+		MergeParents: mergeParents,
+	}
+	if parentHash != "" {
+		commit.Parents = append([]string{parentHash}, mergeParents...)
+	}
+
+	// Record the full set of tracked files so any commit can be checked out on its own
+	if baseTree == nil && parent != nil {
+		baseTree = parent.TrackedFiles()
+	}
+	tree, err := cm.buildTree(baseTree, stagedFiles, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build commit tree: %w", err)
+	}
+	for _, path := range removed {
+		delete(tree, path)
 	}
+	commit.Tree = tree
 
 	// Extract design file metadata for commit tracking
 	meta, err := cm.scanFilesMetadata(stagedFiles)
@@ -158,17 +352,31 @@ func (cm *CommitManager) CreateCommit(message string, stagedFiles []*staging.Sta
 	}
 	commit.Metadata = meta
 
-	// Create snapshot with compression
-	compressionResult, err := cm.createSnapshot(stagedFiles, newVersion, currentVersion, startTime)
-	if err != nil {
-		return nil, fmt.Errorf("snapshot creation failed: %w", err)
+	// Renames are only tracked along the first parent; merges bring in the other side's paths as they are
+	if parent != nil && len(mergeParents) == 0 {
+		commit.Renames = cm.detectRenames(parent.TrackedFiles(), commit, stagedFiles)
 	}
+	if parent != nil {
+		cm.recordDocumentChanges(commit, parent, stagedFiles)
+	}
+
+	// A merge that only combines already stored content has nothing to snapshot
+	var compressionResult *CompressionResult
+	if len(stagedFiles) > 0 {
+		compressionResult, err = cm.createSnapshot(stagedFiles, commit, parent, startTime)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot creation failed: %w", err)
+		}
 
-	commit.CompressionInfo = compressionResult
-	if compressionResult.Strategy == "zip" {
-		commit.SnapshotZip = compressionResult.OutputFile
+		commit.CompressionInfo = compressionResult
+		if compressionResult.Strategy == "zip" {
+			commit.SnapshotZip = compressionResult.OutputFile
+		}
 	}
 
+	// Thumbnails are cached by content hash, so they are stored before the commit becomes visible
+	cm.storeThumbnails(commit.Tree, stagedFiles)
+
 	// Save commit metadata and update repository state
 	if err := cm.saveCommitMetadata(commit); err != nil {
 		return nil, fmt.Errorf("save metadata failed: %w", err)
@@ -177,6 +385,10 @@ func (cm *CommitManager) CreateCommit(message string, stagedFiles []*staging.Sta
 		return nil, fmt.Errorf("update HEAD failed: %w", err)
 	}
 
+	if compressionResult == nil {
+		return commit, nil
+	}
+
 	// Calculate final performance metrics
 	totalTime := time.Since(startTime)
 	compressionResult.SpeedImprovement = 45000.0 / compressionResult.CompressionTime
@@ -186,38 +398,39 @@ func (cm *CommitManager) CreateCommit(message string, stagedFiles []*staging.Sta
 
 	// Schedule background optimization for better compression ratios (non-blocking)
 	if cm.enableBackgroundOpt && compressionResult.Strategy == "lz4" {
-		go cm.scheduleBackgroundOptimization(newVersion, compressionResult)
+		go cm.scheduleBackgroundOptimization(compressionResult)
 	}
 
 	return commit, nil
 }
 
 // createSnapshot chooses optimal compression strategy based on file characteristics
-func (cm *CommitManager) createSnapshot(files []*staging.StagedFile, version, prevVersion int, startTime time.Time) (*CompressionResult, error) {
+// Deltas are always taken against the parent commit so they stay valid on every branch
+func (cm *CommitManager) createSnapshot(files []*staging.StagedFile, commit *Commit, parent *log.Commit, startTime time.Time) (*CompressionResult, error) {
 	// Strategy 1: LZ4 compression for appropriate files
-	if cm.shouldUseLZ4(files, version) {
-		return cm.compressWithLZ4(files, version, startTime)
+	if cm.shouldUseLZ4(files, parent) {
+		return cm.compressWithLZ4(files, commit.Hash, startTime)
 	}
 
 	// Strategy 2: Smart Delta for compatible files
-	if version > 1 && !cm.shouldCreateNewSnapshot(prevVersion) {
-		deltaResult, err := cm.createDelta(files, version, prevVersion, startTime)
+	if !cm.shouldCreateNewSnapshot(parent) {
+		deltaResult, err := cm.createDelta(files, commit, parent, startTime)
 		if err == nil && deltaResult.CompressionRatio <= 0.7 { // 70% threshold for efficiency
 			return deltaResult, nil
 		}
 		// Clean up failed or inefficient delta and fallback to LZ4
 		if err == nil {
-			os.Remove(filepath.Join(cm.DeltaDir, deltaResult.OutputFile))
+			os.Remove(filepath.Join(cm.CacheDir, deltaResult.OutputFile))
 		}
 	}
 
 	// Strategy 3: LZ4 Fallback
-	return cm.compressWithLZ4(files, version, startTime)
+	return cm.compressWithLZ4(files, commit.Hash, startTime)
 }
 
 // shouldUseLZ4 determines when to use LZ4 compression vs smart delta compression
-func (cm *CommitManager) shouldUseLZ4(files []*staging.StagedFile, version int) bool {
-	if version == 1 {
+func (cm *CommitManager) shouldUseLZ4(files []*staging.StagedFile, parent *log.Commit) bool {
+	if parent == nil {
 		return true
 	}
 
@@ -241,15 +454,15 @@ func (cm *CommitManager) shouldUseLZ4(files []*staging.StagedFile, version int)
 }
 
 // createDelta creates smart delta compression for design files
-func (cm *CommitManager) createDelta(files []*staging.StagedFile, version, baseVersion int, startTime time.Time) (*CompressionResult, error) {
+func (cm *CommitManager) createDelta(files []*staging.StagedFile, commit *Commit, parent *log.Commit, startTime time.Time) (*CompressionResult, error) {
 	// Select optimal delta algorithm based on file types
 	algorithm := cm.selectDeltaAlgorithm(files)
 
 	switch algorithm {
 	case "psd_smart":
-		return cm.createPSDSmartDelta(files, version, baseVersion)
+		return cm.createPSDSmartDelta(files, commit, parent)
 	case "bsdiff":
-		return cm.createBsdiffDelta(files, version, baseVersion)
+		return cm.createBsdiffDelta(files, commit, parent)
 	default:
 		return nil, fmt.Errorf("no suitable delta algorithm")
 	}
@@ -257,11 +470,9 @@ func (cm *CommitManager) createDelta(files []*staging.StagedFile, version, baseV
 
 // selectDeltaAlgorithm chooses optimal delta compression method
 func (cm *CommitManager) selectDeltaAlgorithm(files []*staging.StagedFile) string {
-	// Check for PSD files (use intelligent PSD-specific delta)
-	for _, f := range files {
-		if strings.ToLower(filepath.Ext(f.Path)) == ".psd" {
-			return "psd_smart"
-		}
+	// Smart delta stores a single PSD, so it only applies when nothing else is staged
+	if len(files) == 1 && strings.ToLower(filepath.Ext(files[0].Path)) == ".psd" {
+		return "psd_smart"
 	}
 
 	// For other design files, use optimized bsdiff
@@ -269,11 +480,11 @@ func (cm *CommitManager) selectDeltaAlgorithm(files []*staging.StagedFile) strin
 }
 
 // compressWithLZ4 creates LZ4 compressed files with structured headers
-func (cm *CommitManager) compressWithLZ4(files []*staging.StagedFile, version int, startTime time.Time) (*CompressionResult, error) {
+func (cm *CommitManager) compressWithLZ4(files []*staging.StagedFile, commitHash string, startTime time.Time) (*CompressionResult, error) {
 	compressionStartTime := time.Now()
 
 	// Store in versions directory for immediate access
-	versionPath := filepath.Join(cm.VersionsDir, fmt.Sprintf("v%d.lz4", version))
+	versionPath := filepath.Join(cm.VersionsDir, commitHash+".lz4")
 
 	// Create LZ4 compressed file
 	outFile, err := os.Create(versionPath)
@@ -378,25 +589,25 @@ func (cm *CommitManager) compressWithLZ4(files []*staging.StagedFile, version in
 // Background optimization system for improved compression ratios
 
 // createBsdiffDelta creates binary diff delta compression
-func (cm *CommitManager) createBsdiffDelta(files []*staging.StagedFile, version, baseVersion int) (*CompressionResult, error) {
+func (cm *CommitManager) createBsdiffDelta(files []*staging.StagedFile, commit *Commit, parent *log.Commit) (*CompressionResult, error) {
 	compressionStart := time.Now()
 
 	// Create temporary current version file in cache
-	tempCurrent := filepath.Join(cm.CacheDir, fmt.Sprintf("temp_v%d.lz4", version))
+	tempCurrent := filepath.Join(cm.CacheDir, fmt.Sprintf("temp_%s.lz4", commit.Hash))
 	defer os.Remove(tempCurrent)
 
 	if err := cm.createTempLZ4File(files, tempCurrent); err != nil {
 		return nil, err
 	}
 
-	// Find base version file in versions directory
-	basePath := cm.findVersionInStorage(baseVersion)
+	// Deltas can only be applied on top of a full snapshot of the parent
+	basePath := cm.findSnapshotInStorage(parent)
 	if basePath == "" {
-		return nil, fmt.Errorf("base v%d not found", baseVersion)
+		return nil, fmt.Errorf("no full snapshot for base v%d (%s)", parent.Version, parent.Hash)
 	}
 
 	// Create delta file in cache
-	deltaPath := filepath.Join(cm.CacheDir, fmt.Sprintf("v%d_from_v%d.bsdiff", version, baseVersion))
+	deltaPath := filepath.Join(cm.CacheDir, fmt.Sprintf("%s_from_%s.bsdiff", commit.Hash, parent.Hash))
 
 	// Open files for delta compression
 	baseFile, err := cm.openStoredFile(basePath)
@@ -423,28 +634,28 @@ func (cm *CommitManager) createBsdiffDelta(files []*staging.StagedFile, version,
 	}
 
 	compressionTime := float64(time.Since(compressionStart).Nanoseconds()) / 1000000.0
-	return cm.calculateCompressionResult("bsdiff", deltaPath, files, baseVersion, compressionTime)
+	return cm.calculateCompressionResult("bsdiff", deltaPath, files, parent, compressionTime)
 }
 
 // Background optimization system for improved compression ratios
 
 // scheduleBackgroundOptimization queues background optimization tasks
-func (cm *CommitManager) scheduleBackgroundOptimization(version int, result *CompressionResult) {
+func (cm *CommitManager) scheduleBackgroundOptimization(result *CompressionResult) {
 	// Wait briefly to ensure user operations complete
 	time.Sleep(3 * time.Second)
 
 	// Move from versions to cache for background optimization
-	cm.optimizeToCache(version, result)
+	cm.optimizeToCache(result)
 }
 
 // optimizeToCache converts LZ4 versions to optimized cache
-func (cm *CommitManager) optimizeToCache(version int, result *CompressionResult) {
+func (cm *CommitManager) optimizeToCache(result *CompressionResult) {
 	if result.Strategy != "lz4" {
 		return
 	}
 
 	versionPath := filepath.Join(cm.VersionsDir, result.OutputFile)
-	cachePath := filepath.Join(cm.CacheDir, fmt.Sprintf("v%d_optimized.zstd", version))
+	cachePath := filepath.Join(cm.CacheDir, log.OptimizedCacheName(result.OutputFile))
 
 	// Open LZ4 source file
 	versionFile, err := os.Open(versionPath)
@@ -474,7 +685,7 @@ func (cm *CommitManager) optimizeToCache(version int, result *CompressionResult)
 }
 
 // createPSDSmartDelta creates PSD delta compression with layer-level change detection
-func (cm *CommitManager) createPSDSmartDelta(files []*staging.StagedFile, version, baseVersion int) (*CompressionResult, error) {
+func (cm *CommitManager) createPSDSmartDelta(files []*staging.StagedFile, commit *Commit, parent *log.Commit) (*CompressionResult, error) {
 	compressionStart := time.Now()
 
 	// Find PSD file in staged files
@@ -490,31 +701,31 @@ func (cm *CommitManager) createPSDSmartDelta(files []*staging.StagedFile, versio
 		return nil, fmt.Errorf("no PSD file found")
 	}
 
-	fmt.Printf("Analyzing PSD layers for smart delta (v%d vs v%d)...\n", version, baseVersion)
+	fmt.Printf("Analyzing PSD layers for smart delta (v%d vs v%d)...\n", commit.Version, parent.Version)
 
 	// Extract detailed layer information from current PSD
 	currentLayers, err := cm.extractPSDLayerInfo(psdFile.AbsolutePath)
 	if err != nil {
 		fmt.Printf("Warning: Failed to extract current layer info: %v\n", err)
-		return cm.fallbackToBinaryDelta(files, version, baseVersion)
+		return cm.fallbackToBinaryDelta(files, commit, parent)
 	}
 
 	// Extract layer information from previous version
-	previousLayers, err := cm.extractPreviousVersionLayers(baseVersion, psdFile.Path)
+	previousLayers, err := cm.extractPreviousVersionLayers(parent, psdFile.Path)
 	if err != nil {
 		fmt.Printf("Warning: Failed to extract previous layer info: %v\n", err)
-		return cm.fallbackToBinaryDelta(files, version, baseVersion)
+		return cm.fallbackToBinaryDelta(files, commit, parent)
 	}
 
 	// Compare layers and detect changes
 	changeAnalysis := cm.compareLayerVersions(previousLayers, currentLayers)
 
 	// Display change summary to user
-	cm.displayLayerChanges(changeAnalysis, baseVersion, version)
+	cm.displayLayerChanges(changeAnalysis, parent.Version, commit.Version)
 
 	// Create smart delta with layer change information
-	deltaPath := filepath.Join(cm.CacheDir, fmt.Sprintf("v%d_from_v%d.psd_smart", version, baseVersion))
-	deltaSize, err := cm.createSmartDeltaFile(deltaPath, psdFile, changeAnalysis, baseVersion, version)
+	deltaPath := filepath.Join(cm.CacheDir, fmt.Sprintf("%s_from_%s.psd_smart", commit.Hash, parent.Hash))
+	deltaSize, err := cm.createSmartDeltaFile(deltaPath, psdFile, changeAnalysis, parent, commit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create smart delta file: %w", err)
 	}
@@ -529,7 +740,8 @@ func (cm *CommitManager) createPSDSmartDelta(files []*staging.StagedFile, versio
 		CompressionRatio: float64(deltaSize) / float64(psdFile.Size),
 		CompressionTime:  compressionTime,
 		CacheLevel:       "cache",
-		BaseVersion:      baseVersion,
+		BaseVersion:      parent.Version,
+		BaseHash:         parent.Hash,
 		CreatedAt:        time.Now(),
 	}, nil
 }
@@ -550,6 +762,7 @@ type ChangeAnalysis struct {
 	ChangedLayers  []LayerChange `json:"changed_layers"`
 	AddedLayers    []LayerChange `json:"added_layers"`
 	DeletedLayers  []LayerChange `json:"deleted_layers"`
+	MovedLayers    []LayerChange `json:"moved_layers,omitempty"` // Layers whose stacking order changed
 	UnchangedCount int           `json:"unchanged_count"`
 	ChangesSummary string        `json:"changes_summary"`
 }
@@ -564,9 +777,20 @@ func (cm *CommitManager) extractPSDLayerInfo(psdPath string) ([]DetailedLayer, e
 }
 
 // extractPreviousVersionLayers extracts layer info from previous version
-func (cm *CommitManager) extractPreviousVersionLayers(baseVersion int, filePath string) ([]DetailedLayer, error) {
+func (cm *CommitManager) extractPreviousVersionLayers(base *log.Commit, filePath string) ([]DetailedLayer, error) {
+	// The file may have been stored by an older commit if the base did not touch it
+	storing := base
+	if entry, ok := base.TrackedFiles()[filePath]; ok && entry.Commit != "" && entry.Commit != base.Hash {
+		older, err := log.NewLogManager(cm.DgitDir).GetCommitByHash(entry.Commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load commit %s: %w", entry.Commit, err)
+		}
+		storing = older
+	}
+	baseVersion := storing.Version
+
 	// Find the previous version file in storage hierarchy
-	basePath := cm.findVersionInStorage(baseVersion)
+	basePath := cm.findStoredSnapshot(storing)
 	if basePath == "" {
 		return nil, fmt.Errorf("previous version v%d not found in storage", baseVersion)
 	}
@@ -577,7 +801,7 @@ func (cm *CommitManager) extractPreviousVersionLayers(baseVersion int, filePath
 	tempDir := filepath.Join(cm.CacheDir, "temp")
 	os.MkdirAll(tempDir, 0755)
 
-	tempPSDPath := filepath.Join(tempDir, fmt.Sprintf("temp_v%d.psd", baseVersion))
+	tempPSDPath := filepath.Join(tempDir, fmt.Sprintf("temp_%s.psd", storing.Hash))
 	defer os.Remove(tempPSDPath)
 
 	// Extract/decompress the cached file to get the original PSD
@@ -611,7 +835,7 @@ func (cm *CommitManager) displayCompressionStats(result *CompressionResult, tota
 		fmt.Printf("Cache: %s | File: %s\n", result.CacheLevel, result.OutputFile)
 	case "psd_smart":
 		fmt.Printf("PSD Smart Delta: %.1f%% space saved in %.1fms\n", compressionPercent, result.CompressionTime)
-		fmt.Printf("Base: v%d | Changes detected and optimized\n", result.BaseVersion)
+		fmt.Printf("Base: v%d (%s) | Changes detected and optimized\n", result.BaseVersion, result.BaseHash)
 	case "bsdiff":
 		fmt.Printf("Binary Delta: %.1f%% saved in %.1fms\n", compressionPercent, result.CompressionTime)
 		fmt.Printf("Base: v%d | Delta file: %s\n", result.BaseVersion, result.OutputFile)
@@ -650,32 +874,51 @@ func (cm *CommitManager) loadConfig() {
 	}
 }
 
-// findVersionInStorage searches for version file in simplified storage hierarchy
-func (cm *CommitManager) findVersionInStorage(version int) string {
-	// Check versions directory first
-	versionPath := filepath.Join(cm.VersionsDir, fmt.Sprintf("v%d.lz4", version))
-	if cm.fileExists(versionPath) {
-		return versionPath
+// findSnapshotInStorage returns the full snapshot of a commit usable as a delta base
+func (cm *CommitManager) findSnapshotInStorage(c *log.Commit) string {
+	if c == nil {
+		return ""
 	}
-
-	// Check cache directory
-	cachePath := filepath.Join(cm.CacheDir, fmt.Sprintf("v%d.lz4", version))
-	if cm.fileExists(cachePath) {
-		return cachePath
+	info := c.CompressionInfo
+	if info == nil {
+		// Legacy ZIP snapshots
+		if c.SnapshotZip != "" && cm.fileExists(filepath.Join(cm.ObjectsDir, c.SnapshotZip)) {
+			return filepath.Join(cm.ObjectsDir, c.SnapshotZip)
+		}
+		return ""
 	}
 
-	// Check optimized cache
-	optimizedPath := filepath.Join(cm.CacheDir, fmt.Sprintf("v%d_optimized.zstd", version))
-	if cm.fileExists(optimizedPath) {
-		return optimizedPath
+	switch info.Strategy {
+	case "lz4":
+		// Check versions directory first, then cache and optimized cache
+		candidates := []string{
+			filepath.Join(cm.VersionsDir, info.OutputFile),
+			filepath.Join(cm.CacheDir, info.OutputFile),
+			filepath.Join(cm.CacheDir, log.OptimizedCacheName(info.OutputFile)),
+		}
+		for _, path := range candidates {
+			if cm.fileExists(path) {
+				return path
+			}
+		}
+	case "zip":
+		if path := filepath.Join(cm.ObjectsDir, info.OutputFile); cm.fileExists(path) {
+			return path
+		}
 	}
+	return ""
+}
 
-	// Check legacy objects
-	legacyPath := filepath.Join(cm.ObjectsDir, fmt.Sprintf("v%d.zip", version))
-	if cm.fileExists(legacyPath) {
-		return legacyPath
+// findStoredSnapshot returns the stored file holding a commit's content, including smart deltas
+func (cm *CommitManager) findStoredSnapshot(c *log.Commit) string {
+	if path := cm.findSnapshotInStorage(c); path != "" {
+		return path
+	}
+	if c.CompressionInfo != nil && c.CompressionInfo.Strategy == "psd_smart" {
+		if path := filepath.Join(cm.CacheDir, c.CompressionInfo.OutputFile); cm.fileExists(path) {
+			return path
+		}
 	}
-
 	return ""
 }
 
@@ -767,7 +1010,7 @@ func (cm *CommitManager) createTempLZ4File(files []*staging.StagedFile, outputPa
 }
 
 // calculateCompressionResult computesdetailed compression statistics
-func (cm *CommitManager) calculateCompressionResult(strategy, outputFile string, files []*staging.StagedFile, baseVersion int, compressionTimeMs float64) (*CompressionResult, error) {
+func (cm *CommitManager) calculateCompressionResult(strategy, outputFile string, files []*staging.StagedFile, base *log.Commit, compressionTimeMs float64) (*CompressionResult, error) {
 	var originalSize int64
 	for _, f := range files {
 		originalSize += f.Size
@@ -788,24 +1031,34 @@ func (cm *CommitManager) calculateCompressionResult(strategy, outputFile string,
 		CompressionRatio: float64(compressedSize) / float64(originalSize),
 		CompressionTime:  compressionTimeMs,
 		CacheLevel:       "cache",
-		BaseVersion:      baseVersion,
+		BaseVersion:      base.Version,
+		BaseHash:         base.Hash,
 		CreatedAt:        time.Now(),
 	}, nil
 }
 
 // shouldCreateNewSnapshot enforces delta chain length limit for optimal performance
-func (cm *CommitManager) shouldCreateNewSnapshot(ver int) bool {
-	return cm.getDeltaChainLength(ver) >= cm.MaxDeltaChainLength
+func (cm *CommitManager) shouldCreateNewSnapshot(parent *log.Commit) bool {
+	return cm.getDeltaChainLength(parent) >= cm.MaxDeltaChainLength
 }
 
-// getDeltaChainLength counts delta chain length back to last ZIP snapshot
-func (cm *CommitManager) getDeltaChainLength(ver int) int {
+// getDeltaChainLength counts delta commits back along the parents to the last full snapshot
+func (cm *CommitManager) getDeltaChainLength(c *log.Commit) int {
+	logManager := log.NewLogManager(cm.DgitDir)
 	count := 0
-	for v := ver; v > 0; v-- {
-		if cm.fileExists(filepath.Join(cm.ObjectsDir, fmt.Sprintf("v%d.zip", v))) {
+	for c != nil && count < cm.MaxDeltaChainLength {
+		if cm.findSnapshotInStorage(c) != "" {
 			break
 		}
 		count++
+		if c.ParentHash == "" {
+			break
+		}
+		parent, err := logManager.GetCommitByHash(c.ParentHash)
+		if err != nil {
+			break
+		}
+		c = parent
 	}
 	return count
 }
@@ -816,30 +1069,114 @@ func (cm *CommitManager) fileExists(path string) bool {
 	return err == nil
 }
 
-// GetCurrentVersion returns the current version by scanning JSON metadata files
+// GetCurrentVersion returns the version of the commit HEAD points to
 func (cm *CommitManager) GetCurrentVersion() int {
-	entries, err := os.ReadDir(cm.CommitsDir)
-	if err != nil {
+	head, err := cm.getHeadCommit()
+	if err != nil || head == nil {
 		return 0
 	}
-	maxVersion := 0
-	for _, e := range entries {
-		if strings.HasPrefix(e.Name(), "v") && strings.HasSuffix(e.Name(), ".json") {
-			n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(e.Name(), "v"), ".json"))
-			if n > maxVersion {
-				maxVersion = n
+	return head.Version
+}
+
+// getHeadCommit loads the commit HEAD points to, or nil when the branch has no commits yet
+func (cm *CommitManager) getHeadCommit() (*log.Commit, error) {
+	return log.NewLogManager(cm.DgitDir).GetHeadCommit()
+}
+
+// buildTree carries the base tree forward and records the staged files as stored by this commit
+func (cm *CommitManager) buildTree(base map[string]TreeEntry, files []*staging.StagedFile, commitHash string) (map[string]TreeEntry, error) {
+	tree := make(map[string]TreeEntry)
+	for path, entry := range base {
+		tree[path] = entry
+	}
+
+	for _, f := range files {
+		contentHash, size, err := hashFileContent(f.AbsolutePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", f.Path, err)
+		}
+		tree[f.Path] = TreeEntry{Hash: contentHash, Size: size, Commit: commitHash}
+	}
+	return tree, nil
+}
+
+// storeThumbnails saves the embedded previews of the committed PSD and AI files for history galleries
+// Files without one are skipped; 'dgit show --thumbnail' renders them from the composite on demand
+func (cm *CommitManager) storeThumbnails(tree map[string]TreeEntry, files []*staging.StagedFile) {
+	thumbnailManager := thumbnail.NewThumbnailManager(cm.DgitDir)
+	for _, f := range files {
+		if entry, ok := tree[f.Path]; ok && thumbnail.Supported(f.Path) {
+			thumbnailManager.StoreEmbedded(entry.Hash, f.AbsolutePath)
+		}
+	}
+}
+
+// detectRenames pairs files that left the parent's tree with files new in the commit's tree
+// Paths staged by 'dgit mv' are taken as given; the rest are matched by content and layer metadata
+func (cm *CommitManager) detectRenames(parentTree map[string]TreeEntry, c *Commit, stagedFiles []*staging.StagedFile) map[string]string {
+	logManager := log.NewLogManager(cm.DgitDir)
+	renames := make(map[string]string)
+	explicitOld := make(map[string]bool)
+	for _, f := range stagedFiles {
+		if _, tracked := parentTree[f.RenamedFrom]; f.RenamedFrom != "" && tracked {
+			if _, stillTracked := c.Tree[f.RenamedFrom]; !stillTracked {
+				renames[f.Path] = f.RenamedFrom
+				explicitOld[f.RenamedFrom] = true
 			}
 		}
 	}
-	return maxVersion
+
+	var removedFiles, addedFiles []log.RenameCandidate
+	for path, entry := range parentTree {
+		if _, ok := c.Tree[path]; !ok && !explicitOld[path] {
+			removedFiles = append(removedFiles, logManager.TreeCandidate(path, entry))
+		}
+	}
+	for path, entry := range c.Tree {
+		if _, ok := parentTree[path]; ok || renames[path] != "" {
+			continue
+		}
+		if entry.Commit == c.Hash {
+			addedFiles = append(addedFiles, log.CandidateFromMetadata(path, entry.Hash, c.Metadata[path]))
+		} else {
+			addedFiles = append(addedFiles, logManager.TreeCandidate(path, entry))
+		}
+	}
+	if len(removedFiles) > 0 && len(addedFiles) > 0 {
+		for newPath, oldPath := range log.DetectRenames(removedFiles, addedFiles) {
+			renames[newPath] = oldPath
+		}
+	}
+
+	if len(renames) == 0 {
+		return nil
+	}
+	return renames
+}
+
+// hashFileContent returns the SHA256 content hash and size of a file
+func hashFileContent(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), size, nil
 }
 
 // generateCommitHash produces a secure 12-character SHA256-based hash
-func (cm *CommitManager) generateCommitHash(msg string, files []*staging.StagedFile, ver int) string {
+func (cm *CommitManager) generateCommitHash(msg string, files []*staging.StagedFile, ver int, parentHash string) string {
 	h := sha256.New()
 	h.Write([]byte(msg))
 	h.Write([]byte(strconv.Itoa(ver)))
-	h.Write([]byte(time.Now().Format(time.RFC3339)))
+	h.Write([]byte(parentHash))
+	h.Write([]byte(time.Now().Format(time.RFC3339Nano)))
 	for _, f := range files {
 		h.Write([]byte(f.AbsolutePath))
 		h.Write([]byte(strconv.FormatInt(f.Size, 10)))
@@ -861,14 +1198,6 @@ func (cm *CommitManager) getAuthor() string {
 	return "DGit User"
 }
 
-// getCurrentCommitHash reads the current HEAD commit hash
-func (cm *CommitManager) getCurrentCommitHash() string {
-	if d, err := os.ReadFile(cm.HeadFile); err == nil {
-		return strings.TrimSpace(string(d))
-	}
-	return ""
-}
-
 // scanFilesMetadata extractsdetailed metadata from design files
 func (cm *CommitManager) scanFilesMetadata(files []*staging.StagedFile) (map[string]interface{}, error) {
 	md := make(map[string]interface{})
@@ -886,7 +1215,7 @@ func (cm *CommitManager) scanFilesMetadata(files []*staging.StagedFile) (map[str
 			continue
 		}
 		// Storedetailed design file metadata
-		md[f.Path] = map[string]interface{}{
+		fileMeta := map[string]interface{}{
 			"type":          info.Type,
 			"dimensions":    info.Dimensions,
 			"color_mode":    info.ColorMode,
@@ -898,13 +1227,87 @@ func (cm *CommitManager) scanFilesMetadata(files []*staging.StagedFile) (map[str
 			"size":          f.Size,
 			"last_modified": f.ModTime,
 		}
+		for key, count := range info.Counts() {
+			fileMeta[key] = count
+		}
+		if info.Type == "ai" {
+			if doc, err := illustrator.ReadDocument(f.AbsolutePath); err == nil {
+				fileMeta["document"] = doc
+			}
+		}
+		md[f.Path] = fileMeta
 	}
 	return md, nil
 }
 
-// saveCommitMetadata writes commit metadata to JSON file
+// recordDocumentChanges stores how each committed AI file differs from its version in the parent
+func (cm *CommitManager) recordDocumentChanges(c *Commit, parent *log.Commit, files []*staging.StagedFile) {
+	parentTree := parent.TrackedFiles()
+	for _, f := range files {
+		fileMeta, ok := c.Metadata[f.Path].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		newDoc, ok := fileMeta["document"].(*illustrator.Document)
+		if !ok {
+			continue
+		}
+		oldPath := f.Path
+		if renamed, ok := c.Renames[f.Path]; ok {
+			oldPath = renamed
+		}
+		if _, tracked := parentTree[oldPath]; !tracked {
+			continue
+		}
+		oldDoc, err := cm.committedDocument(parent, oldPath)
+		if err != nil {
+			continue
+		}
+		if changes := illustrator.CompareDocuments(oldDoc, newDoc); changes.HasChanges() {
+			fileMeta["document_changes"] = changes
+		}
+	}
+}
+
+// committedDocument returns the contents of an AI file as committed, from stored metadata when available
+func (cm *CommitManager) committedDocument(c *log.Commit, path string) (*illustrator.Document, error) {
+	storing := c
+	if entry := c.TrackedFiles()[path]; entry.Commit != "" && entry.Commit != c.Hash {
+		if stored, err := log.NewLogManager(cm.DgitDir).GetCommitByHash(entry.Commit); err == nil {
+			storing = stored
+		}
+	}
+	if fileMeta, ok := storing.Metadata[path].(map[string]interface{}); ok && fileMeta["document"] != nil {
+		data, err := json.Marshal(fileMeta["document"])
+		if err == nil {
+			var doc illustrator.Document
+			if err := json.Unmarshal(data, &doc); err == nil {
+				return &doc, nil
+			}
+		}
+	}
+
+	// Commits made before documents were recorded: read the file itself
+	data, err := restore.NewRestoreManager(cm.DgitDir).ReadCommitFile(c, path)
+	if err != nil {
+		return nil, err
+	}
+	tempFile, err := os.CreateTemp("", "dgit-doc-*.ai")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.Write(data)
+	tempFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	return illustrator.ReadDocument(tempFile.Name())
+}
+
+// saveCommitMetadata writes commit metadata to a JSON file named by the commit hash
 func (cm *CommitManager) saveCommitMetadata(c *Commit) error {
-	path := filepath.Join(cm.CommitsDir, fmt.Sprintf("v%d.json", c.Version))
+	path := filepath.Join(cm.CommitsDir, c.Hash+".json")
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal commit: %w", err)
@@ -912,9 +1315,20 @@ func (cm *CommitManager) saveCommitMetadata(c *Commit) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-// updateHead writes the new commit hash to HEAD file
+// recordCommit adds a new commit to the reflog of HEAD and the current branch
+func (cm *CommitManager) recordCommit(parent *log.Commit, c *Commit, operation string) {
+	oldHash := ""
+	if parent != nil {
+		oldHash = parent.Hash
+	}
+	if err := cm.refManager.RecordRefUpdate(oldHash, c.Hash, operation, c.Message); err != nil {
+		fmt.Printf("Warning: failed to record reflog: %v\n", err)
+	}
+}
+
+// updateHead moves the current branch (or detached HEAD) to the new commit
 func (cm *CommitManager) updateHead(hash string) error {
-	return os.WriteFile(cm.HeadFile, []byte(hash), 0644)
+	return cm.refManager.UpdateHead(hash)
 }
 
 // Layer analysis functions for PSD smart delta
@@ -929,6 +1343,8 @@ func (cm *CommitManager) extractCachedFileToPSD(cachedPath, outputPath, original
 		return cm.extractZstdToPSD(cachedPath, outputPath, originalFilePath)
 	case strings.HasSuffix(cachedPath, ".zip"):
 		return cm.extractZipToPSD(cachedPath, outputPath, originalFilePath)
+	case strings.HasSuffix(cachedPath, ".psd_smart"):
+		return cm.extractSmartDeltaToPSD(cachedPath, outputPath)
 	default:
 		return fmt.Errorf("unsupported cache file format: %s", cachedPath)
 	}
@@ -982,6 +1398,23 @@ func (cm *CommitManager) extractZipToPSD(zipPath, outputPath, originalFilePath s
 	return fmt.Errorf("target file not found in ZIP archive: %s", targetFileName)
 }
 
+// extractSmartDeltaToPSD extracts the PSD embedded in a smart delta file
+func (cm *CommitManager) extractSmartDeltaToPSD(deltaPath, outputPath string) error {
+	data, err := os.ReadFile(deltaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read smart delta: %w", err)
+	}
+
+	marker := []byte("\nBINARY_DATA:\n")
+	idx := bytes.Index(data, marker)
+	if idx == -1 {
+		return fmt.Errorf("invalid smart delta format: %s", deltaPath)
+	}
+
+	lz4Reader := lz4.NewReader(bytes.NewReader(data[idx+len(marker):]))
+	return cm.extractStreamToPSD(lz4Reader, outputPath, "")
+}
+
 // extractZipEntryToPSD extracts a specific ZIP entry to PSD file
 func (cm *CommitManager) extractZipEntryToPSD(zipEntry *zip.File, outputPath string) error {
 	reader, err := zipEntry.Open()
@@ -1117,6 +1550,12 @@ func (cm *CommitManager) extractStructuredStreamToPSD(data []byte, outputPath, o
 
 // compareLayerVersions compares two sets of layers and identifies changes
 func (cm *CommitManager) compareLayerVersions(oldLayers, newLayers []DetailedLayer) *ChangeAnalysis {
+	return CompareLayers(oldLayers, newLayers)
+}
+
+// CompareLayers compares two versions of a PSD's layers
+// Layers are matched by persistent layer ID when both versions have one, otherwise by name and occurrence
+func CompareLayers(oldLayers, newLayers []DetailedLayer) *ChangeAnalysis {
 	analysis := &ChangeAnalysis{
 		TotalLayers:   len(newLayers),
 		ChangedLayers: []LayerChange{},
@@ -1125,19 +1564,22 @@ func (cm *CommitManager) compareLayerVersions(oldLayers, newLayers []DetailedLay
 	}
 
 	// Create hash maps for efficient lookup
+	// Layers are matched by persistent layer ID when both versions store one, so renames are tracked
+	byID := photoshop.HasLayerIDs(oldLayers, newLayers)
+	oldKeys, newKeys := photoshop.LayerKeys(oldLayers, byID), photoshop.LayerKeys(newLayers, byID)
 	oldLayerMap := make(map[string]DetailedLayer)
 	newLayerMap := make(map[string]DetailedLayer)
 
-	for _, layer := range oldLayers {
-		oldLayerMap[layer.Name] = layer
+	for i, layer := range oldLayers {
+		oldLayerMap[oldKeys[i]] = layer
 	}
-	for _, layer := range newLayers {
-		newLayerMap[layer.Name] = layer
+	for i, layer := range newLayers {
+		newLayerMap[newKeys[i]] = layer
 	}
 
 	// Find added layers
-	for _, newLayer := range newLayers {
-		if _, exists := oldLayerMap[newLayer.Name]; !exists {
+	for i, newLayer := range newLayers {
+		if _, exists := oldLayerMap[newKeys[i]]; !exists {
 			analysis.AddedLayers = append(analysis.AddedLayers, LayerChange{
 				LayerID:    newLayer.ID,
 				LayerName:  newLayer.Name,
@@ -1148,8 +1590,8 @@ func (cm *CommitManager) compareLayerVersions(oldLayers, newLayers []DetailedLay
 	}
 
 	// Find deleted layers
-	for _, oldLayer := range oldLayers {
-		if _, exists := newLayerMap[oldLayer.Name]; !exists {
+	for i, oldLayer := range oldLayers {
+		if _, exists := newLayerMap[oldKeys[i]]; !exists {
 			analysis.DeletedLayers = append(analysis.DeletedLayers, LayerChange{
 				LayerID:    oldLayer.ID,
 				LayerName:  oldLayer.Name,
@@ -1160,11 +1602,11 @@ func (cm *CommitManager) compareLayerVersions(oldLayers, newLayers []DetailedLay
 	}
 
 	// Find modified layers
-	for _, newLayer := range newLayers {
-		if oldLayer, exists := oldLayerMap[newLayer.Name]; exists {
-			if oldLayer.ContentHash != newLayer.ContentHash {
-				// Layer content changed - detect what specifically changed
-				propertyChanges := cm.detectPropertyChanges(oldLayer, newLayer)
+	for i, newLayer := range newLayers {
+		if oldLayer, exists := oldLayerMap[newKeys[i]]; exists {
+			// Layer content or properties changed - detect what specifically changed
+			propertyChanges := detectPropertyChanges(oldLayer, newLayer)
+			if oldLayer.ContentHash != newLayer.ContentHash || len(propertyChanges) > 0 {
 
 				analysis.ChangedLayers = append(analysis.ChangedLayers, LayerChange{
 					LayerID:         newLayer.ID,
@@ -1178,19 +1620,101 @@ func (cm *CommitManager) compareLayerVersions(oldLayers, newLayers []DetailedLay
 		}
 	}
 
+	analysis.MovedLayers = detectMovedLayers(oldLayers, newLayers, oldKeys, newKeys)
+
 	// Calculate unchanged layers
 	analysis.UnchangedCount = len(newLayers) - len(analysis.ChangedLayers) - len(analysis.AddedLayers)
 
 	// Generate summary
-	analysis.ChangesSummary = cm.generateChangesSummary(analysis)
+	analysis.ChangesSummary = generateChangesSummary(analysis)
 
 	return analysis
 }
 
+// detectMovedLayers finds layers whose position in the stack changed
+// Layers present in both versions keep their order along the longest common subsequence;
+// the rest were moved above or below other layers
+func detectMovedLayers(oldLayers, newLayers []DetailedLayer, oldKeys, newKeys []string) []LayerChange {
+	inNew := make(map[string]bool)
+	for _, key := range newKeys {
+		inNew[key] = true
+	}
+	oldIndex := make(map[string]int)
+	var oldOrder []string
+	for i, layer := range oldLayers {
+		if inNew[oldKeys[i]] {
+			oldIndex[oldKeys[i]] = layer.ID
+			oldOrder = append(oldOrder, oldKeys[i])
+		}
+	}
+	var common []DetailedLayer
+	var commonKeys []string
+	for i, layer := range newLayers {
+		if _, ok := oldIndex[newKeys[i]]; ok {
+			common = append(common, layer)
+			commonKeys = append(commonKeys, newKeys[i])
+		}
+	}
+
+	// Longest common subsequence of the shared layers in old and new order
+	n, m := len(oldOrder), len(common)
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldOrder[i] == commonKeys[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	inPlace := make(map[string]bool)
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case oldOrder[i] == commonKeys[j]:
+			inPlace[oldOrder[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	var moved []LayerChange
+	for j, layer := range common {
+		key := commonKeys[j]
+		if inPlace[key] {
+			continue
+		}
+		moved = append(moved, LayerChange{
+			LayerID:    layer.ID,
+			LayerName:  layer.Name,
+			ChangeType: "moved",
+			PropertyChanges: map[string]interface{}{
+				"order": map[string]interface{}{"old": oldIndex[key], "new": layer.ID},
+			},
+		})
+	}
+	return moved
+}
+
 // detectPropertyChanges identifies specific property changes between layer versions
-func (cm *CommitManager) detectPropertyChanges(oldLayer, newLayer DetailedLayer) map[string]interface{} {
+func detectPropertyChanges(oldLayer, newLayer DetailedLayer) map[string]interface{} {
 	changes := make(map[string]interface{})
 
+	// Check name changes (only possible when layers are matched by ID)
+	if oldLayer.Name != newLayer.Name {
+		changes["name"] = map[string]interface{}{
+			"old": oldLayer.Name,
+			"new": newLayer.Name,
+		}
+	}
+
 	// Check opacity changes
 	if oldLayer.Opacity != newLayer.Opacity {
 		changes["opacity"] = map[string]interface{}{
@@ -1227,8 +1751,8 @@ func (cm *CommitManager) detectPropertyChanges(oldLayer, newLayer DetailedLayer)
 }
 
 // generateChangesSummary creates human-readable summary of changes
-func (cm *CommitManager) generateChangesSummary(analysis *ChangeAnalysis) string {
-	totalChanges := len(analysis.ChangedLayers) + len(analysis.AddedLayers) + len(analysis.DeletedLayers)
+func generateChangesSummary(analysis *ChangeAnalysis) string {
+	totalChanges := len(analysis.ChangedLayers) + len(analysis.AddedLayers) + len(analysis.DeletedLayers) + len(analysis.MovedLayers)
 
 	if totalChanges == 0 {
 		return "No layer changes detected"
@@ -1245,6 +1769,9 @@ func (cm *CommitManager) generateChangesSummary(analysis *ChangeAnalysis) string
 	if len(analysis.ChangedLayers) > 0 {
 		summary += fmt.Sprintf(", %d modified", len(analysis.ChangedLayers))
 	}
+	if len(analysis.MovedLayers) > 0 {
+		summary += fmt.Sprintf(", %d moved", len(analysis.MovedLayers))
+	}
 
 	return summary
 }
@@ -1286,6 +1813,14 @@ func (cm *CommitManager) displayLayerChanges(analysis *ChangeAnalysis, baseVersi
 		}
 	}
 
+	// Show moved layers
+	if len(analysis.MovedLayers) > 0 {
+		fmt.Printf("\n↕️  Moved layers:\n")
+		for _, change := range analysis.MovedLayers {
+			fmt.Printf("  ↕ %s\n", change.LayerName)
+		}
+	}
+
 	if analysis.UnchangedCount > 0 {
 		fmt.Printf("\n🔹 %d layer(s) unchanged\n", analysis.UnchangedCount)
 	}
@@ -1294,7 +1829,7 @@ func (cm *CommitManager) displayLayerChanges(analysis *ChangeAnalysis, baseVersi
 }
 
 // createSmartDeltaFile creates the actual delta file withdetailed metadata
-func (cm *CommitManager) createSmartDeltaFile(deltaPath string, psdFile *staging.StagedFile, analysis *ChangeAnalysis, baseVersion, version int) (int64, error) {
+func (cm *CommitManager) createSmartDeltaFile(deltaPath string, psdFile *staging.StagedFile, analysis *ChangeAnalysis, base *log.Commit, commit *Commit) (int64, error) {
 	outFile, err := os.Create(deltaPath)
 	if err != nil {
 		return 0, err
@@ -1304,8 +1839,10 @@ func (cm *CommitManager) createSmartDeltaFile(deltaPath string, psdFile *staging
 	// Createdetailed delta metadata
 	deltaMetadata := map[string]interface{}{
 		"type":           "psd_smart_delta",
-		"from_version":   baseVersion,
-		"to_version":     version,
+		"from_version":   base.Version,
+		"from_hash":      base.Hash,
+		"to_version":     commit.Version,
+		"to_hash":        commit.Hash,
 		"file_path":      psdFile.Path,
 		"original_size":  psdFile.Size,
 		"timestamp":      time.Now(),
@@ -1346,7 +1883,7 @@ func (cm *CommitManager) createSmartDeltaFile(deltaPath string, psdFile *staging
 }
 
 // fallbackToBinaryDelta falls back to regular binary delta if smart analysis fails
-func (cm *CommitManager) fallbackToBinaryDelta(files []*staging.StagedFile, version, baseVersion int) (*CompressionResult, error) {
+func (cm *CommitManager) fallbackToBinaryDelta(files []*staging.StagedFile, commit *Commit, parent *log.Commit) (*CompressionResult, error) {
 	fmt.Printf("Falling back to binary delta compression...\n")
-	return cm.createBsdiffDelta(files, version, baseVersion)
+	return cm.createBsdiffDelta(files, commit, parent)
 }
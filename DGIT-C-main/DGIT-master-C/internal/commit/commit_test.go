@@ -0,0 +1,211 @@
+package commit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"dgit/internal/log"
+	"dgit/internal/refs"
+	"dgit/internal/restore"
+	"dgit/internal/staging"
+)
+
+// testRepo is a work tree with an empty .dgit directory on the main branch
+type testRepo struct {
+	t       *testing.T
+	workDir string
+	dgitDir string
+	cm      *CommitManager
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	workDir := t.TempDir()
+	dgitDir := filepath.Join(workDir, ".dgit")
+	if err := os.MkdirAll(dgitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := refs.NewRefManager(dgitDir).SetHeadToBranch(refs.DefaultBranch); err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCommitManager(dgitDir)
+	cm.enableBackgroundOpt = false
+	return &testRepo{t: t, workDir: workDir, dgitDir: dgitDir, cm: cm}
+}
+
+// padded repeats a line so that files are large enough to compress
+func padded(line string) string {
+	return strings.Repeat(line+"\n", 64)
+}
+
+// stage writes files to the work tree and returns them as staged files
+// Each file holds its content padded to a compressible size.
+func (r *testRepo) stage(files map[string]string) []*staging.StagedFile {
+	r.t.Helper()
+	var staged []*staging.StagedFile
+	for path, line := range files {
+		absPath := filepath.Join(r.workDir, path)
+		content := padded(line)
+		if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+		staged = append(staged, &staging.StagedFile{
+			Path: path, AbsolutePath: absPath, FileType: filepath.Ext(path)[1:], Size: int64(len(content)),
+		})
+	}
+	sort.Slice(staged, func(i, j int) bool { return staged[i].Path < staged[j].Path })
+	return staged
+}
+
+// contents reads every file of a commit back from storage, undoing the padding of stage
+func (r *testRepo) contents(c *Commit) map[string]string {
+	r.t.Helper()
+	stored, err := log.NewLogManager(r.dgitDir).GetCommitByHash(c.Hash)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for path := range stored.TrackedFiles() {
+		data, err := restore.NewRestoreManager(r.dgitDir).ReadCommitFile(stored, path)
+		if err != nil {
+			r.t.Fatalf("reading %s from %s: %v", path, c.Hash, err)
+		}
+		files[path] = strings.SplitN(string(data), "\n", 2)[0]
+		if string(data) != padded(files[path]) {
+			r.t.Errorf("%s in %s: stored content is not a padded line", path, c.Hash)
+		}
+	}
+	return files
+}
+
+func TestAmendCommit(t *testing.T) {
+	cases := []struct {
+		name    string
+		message string
+		staged  map[string]string
+		removed []string
+		want    map[string]string
+		wantMsg string
+	}{
+		{
+			name:    "message only",
+			message: "Logo and notes, final",
+			want:    map[string]string{"logo.svg": "<svg>v2</svg>", "notes.txt": "round 2", "brief.txt": "brief"},
+			wantMsg: "Logo and notes, final",
+		},
+		{
+			name:    "staged file replaces the amended content",
+			staged:  map[string]string{"logo.svg": "<svg>v3</svg>"},
+			want:    map[string]string{"logo.svg": "<svg>v3</svg>", "notes.txt": "round 2", "brief.txt": "brief"},
+			wantMsg: "Logo and notes",
+		},
+		{
+			name:    "new file and removal",
+			staged:  map[string]string{"icon.svg": "<svg>icon</svg>"},
+			removed: []string{"brief.txt"},
+			want:    map[string]string{"logo.svg": "<svg>v2</svg>", "notes.txt": "round 2", "icon.svg": "<svg>icon</svg>"},
+			wantMsg: "Logo and notes",
+		},
+	}
+	for _, c := range cases {
+		r := newTestRepo(t)
+		first, err := r.cm.CreateCommit("Initial", r.stage(map[string]string{"logo.svg": "<svg>v1</svg>", "brief.txt": "brief"}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := r.cm.CreateCommit("Logo and notes", r.stage(map[string]string{"logo.svg": "<svg>v2</svg>", "notes.txt": "round 2"}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		amended, err := r.cm.AmendCommit(c.message, r.stage(c.staged), c.removed)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if amended.Hash == head.Hash || amended.ParentHash != first.Hash || amended.Version != 2 || amended.Message != c.wantMsg {
+			t.Errorf("%s: amended %s (v%d) on %s, %q", c.name, amended.Hash, amended.Version, amended.ParentHash, amended.Message)
+		}
+		if got := r.contents(amended); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: files = %v, want %v", c.name, got, c.want)
+		}
+
+		rm := refs.NewRefManager(r.dgitDir)
+		if hash, _ := rm.ResolveHead(); hash != amended.Hash {
+			t.Errorf("%s: HEAD = %s, want %s", c.name, hash, amended.Hash)
+		}
+		entries, err := rm.ReadReflog("HEAD")
+		if err != nil || len(entries) == 0 {
+			t.Fatalf("%s: reflog %v, %v", c.name, entries, err)
+		}
+		last := entries[len(entries)-1]
+		if last.OldHash != head.Hash || last.NewHash != amended.Hash || last.Message != c.wantMsg {
+			t.Errorf("%s: reflog entry = %+v", c.name, last)
+		}
+	}
+}
+
+func TestAmendCommitWithoutCommits(t *testing.T) {
+	if _, err := newTestRepo(t).cm.AmendCommit("Nothing", nil, nil); err == nil {
+		t.Error("expected an error amending without commits")
+	}
+}
+
+func TestCreateCommitRecordsRenames(t *testing.T) {
+	cases := []struct {
+		name        string
+		staged      map[string]string
+		renamedFrom map[string]string // Staged path to the path given to 'dgit mv'
+		removed     []string
+		want        map[string]string
+	}{
+		{
+			name:        "moved with dgit mv and edited",
+			staged:      map[string]string{"final.svg": "<svg>v2</svg>"},
+			renamedFrom: map[string]string{"final.svg": "logo.svg"},
+			removed:     []string{"logo.svg"},
+			want:        map[string]string{"final.svg": "logo.svg"},
+		},
+		{
+			name:    "moved without dgit mv",
+			staged:  map[string]string{"brand/logo.svg": "<svg>v1</svg>"},
+			removed: []string{"logo.svg"},
+			want:    map[string]string{"brand/logo.svg": "logo.svg"},
+		},
+		{
+			name:        "mv recorded before the old path came back",
+			staged:      map[string]string{"copy.svg": "<svg>v1</svg>"},
+			renamedFrom: map[string]string{"copy.svg": "logo.svg"},
+		},
+		{
+			name:    "unrelated files",
+			staged:  map[string]string{"icon.svg": "<svg>icon</svg>"},
+			removed: []string{"logo.svg"},
+		},
+	}
+	for _, c := range cases {
+		r := newTestRepo(t)
+		if _, err := r.cm.CreateCommit("Initial", r.stage(map[string]string{"logo.svg": "<svg>v1</svg>", "notes.txt": "brief"}), nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(r.workDir, "brand"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		staged := r.stage(c.staged)
+		for _, file := range staged {
+			file.RenamedFrom = c.renamedFrom[file.Path]
+		}
+
+		created, err := r.cm.CreateCommit("Rename", staged, c.removed)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(created.Renames, c.want) {
+			t.Errorf("%s: renames = %v, want %v", c.name, created.Renames, c.want)
+		}
+	}
+}
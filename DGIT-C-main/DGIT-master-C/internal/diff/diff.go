@@ -0,0 +1,521 @@
+package diff
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dgit/internal/commit"
+	"dgit/internal/log"
+	"dgit/internal/restore"
+	"dgit/internal/scanner"
+	"dgit/internal/scanner/figma"
+	"dgit/internal/scanner/illustrator"
+	"dgit/internal/scanner/photoshop"
+	"dgit/internal/staging"
+	"dgit/internal/status"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// File states reported by a diff
+const (
+	StatusAdded    = "added"
+	StatusDeleted  = "deleted"
+	StatusModified = "modified"
+	StatusRenamed  = "renamed"
+)
+
+// SideFile is a file as seen on one side of a comparison
+type SideFile struct {
+	Hash string // SHA-256 of the content; empty when unknown (commits made before trees)
+	Size int64
+}
+
+// Side is one version of the repository: a commit, the staging area or the working tree
+type Side struct {
+	Label string
+	Files map[string]SideFile // Repository-relative path -> file
+	// Renames recorded by dgit mv in this version, keyed by new path with the old path as value
+	Renames map[string]string
+	read    func(path string) ([]byte, error)
+	local   func(path string) string // File on disk holding the content; nil when it has to be extracted
+}
+
+// MetadataChange is a design property that differs between two versions of a file
+type MetadataChange struct {
+	Field string `json:"field"` // dimensions, color_mode, layers, artboards
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// FileDiff describes how one file differs between two sides
+type FileDiff struct {
+	Path            string                       `json:"path"`
+	OldPath         string                       `json:"old_path,omitempty"` // Previous path of a renamed file
+	Status          string                       `json:"status"`
+	OldSize         int64                        `json:"old_size"`
+	NewSize         int64                        `json:"new_size"`
+	MetadataChanges []MetadataChange             `json:"metadata_changes,omitempty"`
+	Layers          *commit.ChangeAnalysis       `json:"layers,omitempty"`   // Layer changes of PSD files
+	Document        *illustrator.DocumentChanges `json:"document,omitempty"` // Content changes of AI files
+	Nodes           *figma.NodeChanges           `json:"nodes,omitempty"`    // Node changes of Figma files
+	Visual          *VisualDiff                  `json:"visual,omitempty"`   // Pixel comparison, when requested
+	Error           string                       `json:"error,omitempty"`    // Why the file could not be analyzed
+}
+
+// Result is the outcome of comparing two sides
+type Result struct {
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Files []FileDiff `json:"files"`
+}
+
+// DiffManager compares commits, the staging area and the working tree
+type DiffManager struct {
+	DgitDir        string
+	logManager     *log.LogManager
+	restoreManager *restore.RestoreManager
+}
+
+// NewDiffManager creates a new diff manager
+func NewDiffManager(dgitDir string) *DiffManager {
+	return &DiffManager{
+		DgitDir:        dgitDir,
+		logManager:     log.NewLogManager(dgitDir),
+		restoreManager: restore.NewRestoreManager(dgitDir),
+	}
+}
+
+// EmptySide stands for the repository before its first commit
+func EmptySide() *Side {
+	return &Side{Label: "empty tree", Files: make(map[string]SideFile)}
+}
+
+// CommitSide returns the files tracked in a commit
+func (dm *DiffManager) CommitSide(c *log.Commit) *Side {
+	side := &Side{
+		Label:   fmt.Sprintf("v%d (%s)", c.Version, c.Hash[:8]),
+		Files:   make(map[string]SideFile),
+		Renames: c.Renames,
+		read: func(path string) ([]byte, error) {
+			return dm.restoreManager.ReadCommitFile(c, path)
+		},
+	}
+	for path, entry := range c.TrackedFiles() {
+		side.Files[path] = SideFile{Hash: entry.Hash, Size: entry.Size}
+	}
+	return side
+}
+
+// StagingSide returns what the next commit would contain: head with the staged changes applied
+// head may be nil before the first commit
+func (dm *DiffManager) StagingSide(head *log.Commit) (*Side, error) {
+	stagingArea := staging.NewStagingArea(dm.DgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		return nil, fmt.Errorf("failed to load staging area: %w", err)
+	}
+
+	side := &Side{Label: "index", Files: make(map[string]SideFile), Renames: make(map[string]string)}
+	if head != nil {
+		for path, entry := range head.TrackedFiles() {
+			side.Files[path] = SideFile{Hash: entry.Hash, Size: entry.Size}
+		}
+	}
+	for _, path := range stagingArea.GetRemovedFiles() {
+		delete(side.Files, path)
+	}
+
+	staged := make(map[string]*staging.StagedFile)
+	for _, file := range stagingArea.GetStagedFiles() {
+		path := filepath.ToSlash(file.Path)
+		data, err := readStagedFile(stagingArea, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read staged file %s: %w", path, err)
+		}
+		staged[path] = file
+		side.Files[path] = SideFile{Hash: contentHash(data), Size: int64(len(data))}
+		if file.RenamedFrom != "" {
+			side.Renames[path] = file.RenamedFrom
+		}
+	}
+
+	side.read = func(path string) ([]byte, error) {
+		if file, ok := staged[path]; ok {
+			return readStagedFile(stagingArea, file)
+		}
+		if head == nil {
+			return nil, fmt.Errorf("file '%s' is not staged", path)
+		}
+		return dm.restoreManager.ReadCommitFile(head, path)
+	}
+	return side, nil
+}
+
+// WorkTreeSide returns the working tree copies of the files tracked by index
+// Files missing from disk count as deleted; untracked files are not part of the working tree side
+func (dm *DiffManager) WorkTreeSide(index *Side) *Side {
+	root := filepath.Dir(dm.DgitDir)
+	local := func(path string) string {
+		return filepath.Join(root, filepath.FromSlash(path))
+	}
+
+	side := &Side{
+		Label: "working tree",
+		Files: make(map[string]SideFile),
+		read: func(path string) ([]byte, error) {
+			return os.ReadFile(local(path))
+		},
+		local: local,
+	}
+	for path := range index.Files {
+		info, err := os.Stat(local(path))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		hash, err := status.CalculateFileHash(local(path))
+		if err != nil {
+			continue
+		}
+		side.Files[path] = SideFile{Hash: hash, Size: info.Size()}
+	}
+	return side
+}
+
+// Compare lists the files that differ between two sides, limited to paths when any are given
+// With detailed set, modified design files are scanned for metadata and layer changes
+func (dm *DiffManager) Compare(from, to *Side, paths []string, detailed bool) (*Result, error) {
+	tempDir, err := os.MkdirTemp("", "dgit-diff-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	result := &Result{From: from.Label, To: to.Label, Files: []FileDiff{}}
+	var removed, added []string
+	for path, oldFile := range from.Files {
+		if !matchesPaths(path, paths) {
+			continue
+		}
+		newFile, ok := to.Files[path]
+		if !ok {
+			removed = append(removed, path)
+			continue
+		}
+		same, err := sameContent(from, to, path, path, oldFile, newFile)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			result.Files = append(result.Files, FileDiff{Path: path, Status: StatusModified, OldSize: oldFile.Size, NewSize: newFile.Size})
+		}
+	}
+	for path := range to.Files {
+		if _, ok := from.Files[path]; !ok && matchesPaths(path, paths) {
+			added = append(added, path)
+		}
+	}
+
+	renames := recordedRenames(to, removed, added)
+	for newPath, oldPath := range dm.detectRenames(from, to, unpaired(removed, renames, true), unpaired(added, renames, false), tempDir) {
+		renames[newPath] = oldPath
+	}
+	renamedFrom := make(map[string]bool)
+	for newPath, oldPath := range renames {
+		renamedFrom[oldPath] = true
+		result.Files = append(result.Files, FileDiff{Path: newPath, OldPath: oldPath, Status: StatusRenamed,
+			OldSize: from.Files[oldPath].Size, NewSize: to.Files[newPath].Size})
+	}
+	for _, path := range removed {
+		if !renamedFrom[path] {
+			result.Files = append(result.Files, FileDiff{Path: path, Status: StatusDeleted, OldSize: from.Files[path].Size})
+		}
+	}
+	for _, path := range added {
+		if _, ok := renames[path]; !ok {
+			result.Files = append(result.Files, FileDiff{Path: path, Status: StatusAdded, NewSize: to.Files[path].Size})
+		}
+	}
+	sort.Slice(result.Files, func(i, j int) bool { return result.Files[i].Path < result.Files[j].Path })
+
+	if detailed {
+		for i := range result.Files {
+			file := &result.Files[i]
+			if file.Status == StatusModified || file.Status == StatusRenamed {
+				dm.describeChanges(from, to, file, tempDir)
+			}
+		}
+	}
+	return result, nil
+}
+
+// recordedRenames returns the renames that to records for files removed and added between the sides
+// Explicit moves are kept even when the content changed too much for detectRenames to pair them.
+func recordedRenames(to *Side, removed, added []string) map[string]string {
+	isRemoved := make(map[string]bool)
+	for _, path := range removed {
+		isRemoved[path] = true
+	}
+	renames := make(map[string]string)
+	for _, newPath := range added {
+		if oldPath, ok := to.Renames[newPath]; ok && isRemoved[oldPath] {
+			renames[newPath] = oldPath
+		}
+	}
+	return renames
+}
+
+// unpaired returns the removed (old) or added paths that are not part of a rename yet
+func unpaired(paths []string, renames map[string]string, old bool) []string {
+	paired := make(map[string]bool)
+	for newPath, oldPath := range renames {
+		if old {
+			paired[oldPath] = true
+		} else {
+			paired[newPath] = true
+		}
+	}
+	var rest []string
+	for _, path := range paths {
+		if !paired[path] {
+			rest = append(rest, path)
+		}
+	}
+	return rest
+}
+
+// detectRenames pairs removed and added files by content and design similarity
+func (dm *DiffManager) detectRenames(from, to *Side, removed, added []string, tempDir string) map[string]string {
+	if len(removed) == 0 || len(added) == 0 {
+		return nil
+	}
+	candidate := func(side *Side, path string) log.RenameCandidate {
+		c := log.RenameCandidate{Path: path, Hash: side.Files[path].Hash}
+		if local, err := materialize(side, path, tempDir); err == nil {
+			if c.Hash == "" {
+				c.Hash, _ = status.CalculateFileHash(local)
+			}
+			if info, err := scanner.NewFileScanner().ScanFile(local); err == nil {
+				c.Dimensions = info.Dimensions
+				c.Layers = info.LayerNames
+			}
+		}
+		return c
+	}
+
+	var oldCandidates, newCandidates []log.RenameCandidate
+	for _, path := range removed {
+		oldCandidates = append(oldCandidates, candidate(from, path))
+	}
+	for _, path := range added {
+		newCandidates = append(newCandidates, candidate(to, path))
+	}
+	return log.DetectRenames(oldCandidates, newCandidates)
+}
+
+// describeChanges fills in the metadata and layer changes of a modified or renamed file
+func (dm *DiffManager) describeChanges(from, to *Side, file *FileDiff, tempDir string) {
+	oldPath := file.Path
+	if file.OldPath != "" {
+		oldPath = file.OldPath
+	}
+	if !scanner.IsDesignFile(file.Path) || !scanner.IsDesignFile(oldPath) {
+		return
+	}
+	same, err := sameContent(from, to, oldPath, file.Path, from.Files[oldPath], to.Files[file.Path])
+	if err != nil {
+		file.Error = err.Error()
+		return
+	}
+	if same {
+		return // A pure rename
+	}
+
+	oldLocal, err := materialize(from, oldPath, tempDir)
+	if err != nil {
+		file.Error = err.Error()
+		return
+	}
+	newLocal, err := materialize(to, file.Path, tempDir)
+	if err != nil {
+		file.Error = err.Error()
+		return
+	}
+
+	fileScanner := scanner.NewFileScanner()
+	oldInfo, oldErr := fileScanner.ScanFile(oldLocal)
+	newInfo, newErr := fileScanner.ScanFile(newLocal)
+	if oldErr == nil && newErr == nil {
+		file.MetadataChanges = compareMetadata(oldInfo, newInfo)
+	}
+
+	if isPSD(oldPath) && isPSD(file.Path) {
+		oldPSD, err := photoshop.GetDetailedPSDInfo(oldLocal)
+		if err != nil {
+			file.Error = fmt.Sprintf("failed to parse %s in %s: %v", oldPath, from.Label, err)
+			return
+		}
+		newPSD, err := photoshop.GetDetailedPSDInfo(newLocal)
+		if err != nil {
+			file.Error = fmt.Sprintf("failed to parse %s in %s: %v", file.Path, to.Label, err)
+			return
+		}
+		if len(oldPSD.Layers) > 0 || len(newPSD.Layers) > 0 {
+			file.Layers = commit.CompareLayers(oldPSD.Layers, newPSD.Layers)
+		}
+	}
+
+	if isAI(oldPath) && isAI(file.Path) {
+		oldDoc, err := illustrator.ReadDocument(oldLocal)
+		if err != nil {
+			file.Error = fmt.Sprintf("failed to parse %s in %s: %v", oldPath, from.Label, err)
+			return
+		}
+		newDoc, err := illustrator.ReadDocument(newLocal)
+		if err != nil {
+			file.Error = fmt.Sprintf("failed to parse %s in %s: %v", file.Path, to.Label, err)
+			return
+		}
+		if changes := illustrator.CompareDocuments(oldDoc, newDoc); changes.HasChanges() {
+			file.Document = changes
+		}
+	}
+
+	if isFigma(oldPath) && isFigma(file.Path) {
+		oldFig, err := figma.GetFigmaInfo(oldLocal)
+		if err != nil {
+			file.Error = fmt.Sprintf("failed to parse %s in %s: %v", oldPath, from.Label, err)
+			return
+		}
+		newFig, err := figma.GetFigmaInfo(newLocal)
+		if err != nil {
+			file.Error = fmt.Sprintf("failed to parse %s in %s: %v", file.Path, to.Label, err)
+			return
+		}
+		if changes := figma.CompareNodes(oldFig, newFig); changes.HasChanges() {
+			file.Nodes = changes
+		}
+	}
+}
+
+// compareMetadata lists the design properties that differ between two scans
+func compareMetadata(oldInfo, newInfo *scanner.DesignFile) []MetadataChange {
+	fields := []MetadataChange{
+		{Field: "dimensions", Old: oldInfo.Dimensions, New: newInfo.Dimensions},
+		{Field: "color_mode", Old: oldInfo.ColorMode, New: newInfo.ColorMode},
+		{Field: "layers", Old: strconv.Itoa(oldInfo.Layers), New: strconv.Itoa(newInfo.Layers)},
+		{Field: "artboards", Old: strconv.Itoa(oldInfo.Artboards), New: strconv.Itoa(newInfo.Artboards)},
+		{Field: "components", Old: strconv.Itoa(oldInfo.Components), New: strconv.Itoa(newInfo.Components)},
+		{Field: "instances", Old: strconv.Itoa(oldInfo.Instances), New: strconv.Itoa(newInfo.Instances)},
+		{Field: "text_layers", Old: strconv.Itoa(oldInfo.TextLayers), New: strconv.Itoa(newInfo.TextLayers)},
+		{Field: "assets", Old: strconv.Itoa(oldInfo.Assets), New: strconv.Itoa(newInfo.Assets)},
+		{Field: "meshes", Old: strconv.Itoa(oldInfo.Meshes), New: strconv.Itoa(newInfo.Meshes)},
+		{Field: "vertices", Old: strconv.Itoa(oldInfo.Vertices), New: strconv.Itoa(newInfo.Vertices)},
+		{Field: "polygons", Old: strconv.Itoa(oldInfo.Polygons), New: strconv.Itoa(newInfo.Polygons)},
+		{Field: "materials", Old: strconv.Itoa(oldInfo.Materials), New: strconv.Itoa(newInfo.Materials)},
+		{Field: "textures", Old: strconv.Itoa(oldInfo.Textures), New: strconv.Itoa(newInfo.Textures)},
+		{Field: "animations", Old: strconv.Itoa(oldInfo.Animations), New: strconv.Itoa(newInfo.Animations)},
+	}
+	var changes []MetadataChange
+	for _, field := range fields {
+		if field.Old != field.New {
+			changes = append(changes, field)
+		}
+	}
+	return changes
+}
+
+// sameContent reports whether two files have identical content, reading them when a hash is unknown
+func sameContent(from, to *Side, oldPath, newPath string, oldFile, newFile SideFile) (bool, error) {
+	if oldFile.Hash != "" && newFile.Hash != "" {
+		return oldFile.Hash == newFile.Hash, nil
+	}
+	oldData, err := from.read(oldPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s from %s: %w", oldPath, from.Label, err)
+	}
+	newData, err := to.read(newPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s from %s: %w", newPath, to.Label, err)
+	}
+	return bytes.Equal(oldData, newData), nil
+}
+
+// materialize returns a file on disk holding a side's version of path
+// Versions that only exist in storage are extracted into tempDir, keeping the file extension for the scanners
+func materialize(side *Side, path, tempDir string) (string, error) {
+	if side.local != nil {
+		return side.local(path), nil
+	}
+	data, err := side.read(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from %s: %w", path, side.Label, err)
+	}
+	tempFile, err := os.CreateTemp(tempDir, "*-"+filepath.Base(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tempFile.Close()
+	if _, err := tempFile.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return tempFile.Name(), nil
+}
+
+// readStagedFile reads the content recorded for a staged file
+// Small files are kept LZ4-compressed in the versions directory; others are cached as a link or copy
+func readStagedFile(stagingArea *staging.StagingArea, file *staging.StagedFile) ([]byte, error) {
+	cachePath := stagingArea.CacheFilePath(file)
+	if file.PreCompressed {
+		in, err := os.Open(cachePath)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+		return io.ReadAll(lz4.NewReader(in))
+	}
+	if data, err := os.ReadFile(cachePath); err == nil {
+		return data, nil
+	}
+	return os.ReadFile(file.AbsolutePath)
+}
+
+// matchesPaths reports whether path is one of paths or inside one of them; no paths matches everything
+func matchesPaths(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.TrimSuffix(p, "/")
+		if p == "." || p == "" || path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isPSD reports whether a path names a Photoshop document
+func isPSD(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".psd")
+}
+
+// isAI reports whether a path names an Illustrator document
+func isAI(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ai")
+}
+
+// isFigma reports whether a path names a local Figma file
+func isFigma(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".fig")
+}
+
+// contentHash returns the SHA-256 of data as stored in commit trees
+func contentHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
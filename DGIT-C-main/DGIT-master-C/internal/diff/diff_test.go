@@ -0,0 +1,192 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"dgit/internal/commit"
+	"dgit/internal/scanner/scantest"
+)
+
+// memorySide is a side whose files are held in memory
+func memorySide(label string, files map[string]string, renames map[string]string) *Side {
+	side := &Side{Label: label, Files: make(map[string]SideFile), Renames: renames}
+	for path, content := range files {
+		side.Files[path] = SideFile{Hash: contentHash([]byte(content)), Size: int64(len(content))}
+	}
+	side.read = func(path string) ([]byte, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("%s not found", path)
+		}
+		return []byte(content), nil
+	}
+	return side
+}
+
+// statuses summarizes a result as "status path" or "status old -> new" lines
+func statuses(result *Result) []string {
+	var lines []string
+	for _, file := range result.Files {
+		if file.OldPath != "" {
+			lines = append(lines, fmt.Sprintf("%s %s -> %s", file.Status, file.OldPath, file.Path))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %s", file.Status, file.Path))
+		}
+	}
+	return lines
+}
+
+func TestCompare(t *testing.T) {
+	from := map[string]string{"logo.txt": "logo v1", "notes.txt": "notes", "old.txt": "unchanged text"}
+	cases := []struct {
+		name    string
+		to      map[string]string
+		renames map[string]string
+		paths   []string
+		want    []string
+	}{
+		{
+			name: "modified, added and deleted",
+			to:   map[string]string{"logo.txt": "logo v2", "notes.txt": "notes", "new.txt": "brand new"},
+			want: []string{"modified logo.txt", "added new.txt", "deleted old.txt"},
+		},
+		{
+			name: "rename detected by content",
+			to:   map[string]string{"logo.txt": "logo v1", "notes.txt": "notes", "kept.txt": "unchanged text"},
+			want: []string{"renamed old.txt -> kept.txt"},
+		},
+		{
+			name:    "recorded rename with changed content",
+			to:      map[string]string{"logo.txt": "logo v1", "notes.txt": "notes", "moved.txt": "rewritten entirely"},
+			renames: map[string]string{"moved.txt": "old.txt"},
+			want:    []string{"renamed old.txt -> moved.txt"},
+		},
+		{
+			name: "changed content without a recorded rename",
+			to:   map[string]string{"logo.txt": "logo v1", "notes.txt": "notes", "moved.txt": "rewritten entirely"},
+			want: []string{"added moved.txt", "deleted old.txt"},
+		},
+		{
+			name:    "recorded rename of a file that still exists",
+			to:      map[string]string{"logo.txt": "logo v1", "notes.txt": "notes", "old.txt": "unchanged text", "copy.txt": "other"},
+			renames: map[string]string{"copy.txt": "old.txt"},
+			want:    []string{"added copy.txt"},
+		},
+		{
+			name:  "limited to paths",
+			to:    map[string]string{"logo.txt": "logo v2", "notes.txt": "notes v2", "old.txt": "unchanged text"},
+			paths: []string{"notes.txt"},
+			want:  []string{"modified notes.txt"},
+		},
+	}
+	dm := &DiffManager{}
+	for _, c := range cases {
+		result, err := dm.Compare(memorySide("from", from, nil), memorySide("to", c.to, c.renames), c.paths, false)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := statuses(result); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// designChanges summarizes the metadata and layer changes of a file diff
+func designChanges(file FileDiff) []string {
+	var lines []string
+	for _, change := range file.MetadataChanges {
+		lines = append(lines, fmt.Sprintf("%s %s -> %s", change.Field, change.Old, change.New))
+	}
+	if file.Layers == nil {
+		return lines
+	}
+	describe := func(kind string, changes []commit.LayerChange) {
+		for _, change := range changes {
+			var properties []string
+			for property := range change.PropertyChanges {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+			line := kind + " " + change.LayerName
+			if len(properties) > 0 {
+				line += " (" + strings.Join(properties, ", ") + ")"
+			}
+			lines = append(lines, line)
+		}
+	}
+	describe("added", file.Layers.AddedLayers)
+	describe("deleted", file.Layers.DeletedLayers)
+	describe("modified", file.Layers.ChangedLayers)
+	describe("moved", file.Layers.MovedLayers)
+	return lines
+}
+
+func TestCompareDescribesDesignChanges(t *testing.T) {
+	background := scantest.PSDLayer{Name: "Background", ID: 2, Pixel: 10}
+	logo := scantest.PSDLayer{Name: "Logo", ID: 3, Pixel: 20}
+	title := scantest.PSDLayer{Name: "Title", ID: 4, Pixel: 30}
+	from := map[string]string{
+		"poster.psd": string(scantest.LayeredPSD([]scantest.PSDLayer{background, logo, title})),
+		"notes.txt":  "notes",
+	}
+
+	faded := logo
+	faded.Opacity, faded.Blend, faded.Hidden = 128, "mul ", true
+	repainted := logo
+	repainted.Pixel = 99
+	renamed := logo
+	renamed.Name = "Brand mark"
+	shadow := scantest.PSDLayer{Name: "Shadow", ID: 5, Pixel: 40}
+
+	cases := []struct {
+		name   string
+		layers []scantest.PSDLayer
+		path   string // Path of the poster in the new version, a recorded rename when not poster.psd
+		want   []string
+	}{
+		{"layer properties", []scantest.PSDLayer{background, faded, title}, "poster.psd",
+			[]string{"modified Logo (blend_mode, opacity, visibility)"}},
+		{"layer pixels", []scantest.PSDLayer{background, repainted, title}, "poster.psd",
+			[]string{"modified Logo"}},
+		{"layer renamed", []scantest.PSDLayer{background, renamed, title}, "poster.psd",
+			[]string{"modified Brand mark (name)"}},
+		{"layer deleted", []scantest.PSDLayer{background, title}, "poster.psd",
+			[]string{"layers 3 -> 2", "deleted Logo"}},
+		{"layer added", []scantest.PSDLayer{background, logo, shadow, title}, "poster.psd",
+			[]string{"layers 3 -> 4", "added Shadow"}},
+		{"layers reordered", []scantest.PSDLayer{background, title, logo}, "poster.psd",
+			[]string{"moved Logo (order)"}},
+		{"renamed file", []scantest.PSDLayer{background, faded, title}, "final/poster.psd",
+			[]string{"modified Logo (blend_mode, opacity, visibility)"}},
+	}
+	dm := &DiffManager{}
+	for _, c := range cases {
+		to := map[string]string{c.path: string(scantest.LayeredPSD(c.layers)), "notes.txt": "notes v2"}
+		var renames map[string]string
+		if c.path != "poster.psd" {
+			renames = map[string]string{c.path: "poster.psd"}
+		}
+		result, err := dm.Compare(memorySide("from", from, nil), memorySide("to", to, renames), nil, true)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var poster *FileDiff
+		for i, file := range result.Files {
+			if file.Path == c.path {
+				poster = &result.Files[i]
+			} else if file.MetadataChanges != nil || file.Layers != nil {
+				t.Errorf("%s: %s has design changes", c.name, file.Path)
+			}
+		}
+		if poster == nil || poster.Error != "" {
+			t.Fatalf("%s: %s not described: %+v", c.name, c.path, result.Files)
+		}
+		if got := designChanges(*poster); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
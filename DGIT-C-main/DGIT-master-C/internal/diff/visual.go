@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dgit/internal/scanner/photoshop"
+)
+
+// sideBySideGap is the space between the two versions in the side-by-side image
+const sideBySideGap = 16
+
+// Bounds is a rectangle of the canvas in pixels
+type Bounds struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// VisualDiff is the pixel comparison of the composite images of two versions of a PSD
+type VisualDiff struct {
+	OldImage       string  `json:"old_image"`    // PNG of the old composite
+	NewImage       string  `json:"new_image"`    // PNG of the new composite
+	SideBySide     string  `json:"side_by_side"` // PNG with both versions next to each other
+	Heatmap        string  `json:"heatmap"`      // PNG of the new version with changed pixels highlighted
+	ChangedPixels  int     `json:"changed_pixels"`
+	ChangedPercent float64 `json:"changed_percent"`
+	Bounds         *Bounds `json:"bounds,omitempty"` // Smallest rectangle holding every changed pixel; nil when nothing changed
+}
+
+// VisualCompare renders both versions of a modified or renamed PSD and writes the comparison PNGs to outDir
+// Canvases of different sizes are compared over their union; pixels outside either canvas count as changed
+func (dm *DiffManager) VisualCompare(from, to *Side, file *FileDiff, outDir string) (*VisualDiff, error) {
+	oldPath := file.Path
+	if file.OldPath != "" {
+		oldPath = file.OldPath
+	}
+	if !isPSD(oldPath) || !isPSD(file.Path) {
+		return nil, fmt.Errorf("visual diffs are only available for PSD files")
+	}
+
+	oldData, err := from.read(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %w", oldPath, from.Label, err)
+	}
+	newData, err := to.read(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %w", file.Path, to.Label, err)
+	}
+	oldImage, err := photoshop.DecodeComposite(oldData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s in %s: %w", oldPath, from.Label, err)
+	}
+	newImage, err := photoshop.DecodeComposite(newData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s in %s: %w", file.Path, to.Label, err)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	base := filepath.Join(outDir, strings.TrimSuffix(strings.ReplaceAll(file.Path, "/", "_"), filepath.Ext(file.Path)))
+	visual := &VisualDiff{
+		OldImage:   base + ".old.png",
+		NewImage:   base + ".new.png",
+		SideBySide: base + ".side-by-side.png",
+		Heatmap:    base + ".heatmap.png",
+	}
+
+	heatmap, changed, bounds := pixelHeatmap(oldImage, newImage)
+	area := heatmap.Bounds().Dx() * heatmap.Bounds().Dy()
+	visual.ChangedPixels = changed
+	visual.ChangedPercent = float64(changed) * 100 / float64(area)
+	if changed > 0 {
+		visual.Bounds = &Bounds{X: bounds.Min.X, Y: bounds.Min.Y, Width: bounds.Dx(), Height: bounds.Dy()}
+	}
+
+	for path, img := range map[string]image.Image{
+		visual.OldImage:   oldImage,
+		visual.NewImage:   newImage,
+		visual.SideBySide: sideBySide(oldImage, newImage),
+		visual.Heatmap:    heatmap,
+	} {
+		if err := writePNG(path, img); err != nil {
+			return nil, err
+		}
+	}
+	return visual, nil
+}
+
+// pixelHeatmap marks the pixels that differ between two images
+// Unchanged pixels show the new image faded to gray; changed pixels are red, brighter for larger differences.
+// It returns the heatmap, the number of changed pixels and their bounding box.
+func pixelHeatmap(oldImage, newImage *image.RGBA) (*image.RGBA, int, image.Rectangle) {
+	canvas := oldImage.Bounds().Union(newImage.Bounds())
+	heatmap := image.NewRGBA(canvas)
+	changed := 0
+	var bounds image.Rectangle
+
+	for y := canvas.Min.Y; y < canvas.Max.Y; y++ {
+		for x := canvas.Min.X; x < canvas.Max.X; x++ {
+			p := image.Pt(x, y)
+			inOld, inNew := p.In(oldImage.Bounds()), p.In(newImage.Bounds())
+
+			delta := 255
+			if inOld && inNew {
+				delta = maxChannelDelta(oldImage.RGBAAt(x, y), newImage.RGBAAt(x, y))
+			}
+			if delta == 0 {
+				c := newImage.RGBAAt(x, y)
+				gray := uint8((299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000)
+				faded := 128 + gray/2
+				heatmap.SetRGBA(x, y, color.RGBA{faded, faded, faded, 255})
+				continue
+			}
+
+			changed++
+			bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			heatmap.SetRGBA(x, y, color.RGBA{uint8(128 + delta/2), 0, 0, 255})
+		}
+	}
+	return heatmap, changed, bounds
+}
+
+// maxChannelDelta returns the largest difference between the color channels of two pixels
+func maxChannelDelta(a, b color.RGBA) int {
+	delta := 0
+	for _, d := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B)} {
+		if d < 0 {
+			d = -d
+		}
+		delta = max(delta, d)
+	}
+	return delta
+}
+
+// sideBySide places two images next to each other on a white background
+func sideBySide(left, right image.Image) *image.RGBA {
+	lb, rb := left.Bounds(), right.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, lb.Dx()+sideBySideGap+rb.Dx(), max(lb.Dy(), rb.Dy())))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, image.Rect(0, 0, lb.Dx(), lb.Dy()), left, lb.Min, draw.Src)
+	draw.Draw(out, image.Rect(lb.Dx()+sideBySideGap, 0, out.Bounds().Dx(), rb.Dy()), right, rb.Min, draw.Src)
+	return out
+}
+
+// writePNG encodes an image to a PNG file
+func writePNG(path string, img image.Image) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := png.Encode(out, img); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return out.Close()
+}
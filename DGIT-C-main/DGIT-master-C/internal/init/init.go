@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"dgit/internal/refs"
 )
 
 // DGitDir defines the standard DGit repository directory name
@@ -136,9 +138,10 @@ func (ri *RepositoryInitializer) createStructure(dgitPath string) error {
 		"staging",
 
 		// System Directories
-		"temp",  // Temporary workspace
-		"refs",  // Reference information
-		"hooks", // Hook scripts
+		"temp",       // Temporary workspace
+		"refs",       // Reference information
+		"refs/heads", // Branch references
+		"hooks",      // Hook scripts
 
 		// Performance Monitoring
 		"logs",
@@ -306,10 +309,9 @@ func (ri *RepositoryInitializer) createPerformanceMonitoring(dgitPath string) er
 	return nil
 }
 
-// createInitialHead creates the initial HEAD file
+// createInitialHead creates the initial HEAD file pointing at the default branch
 func (ri *RepositoryInitializer) createInitialHead(dgitPath string) error {
-	headPath := filepath.Join(dgitPath, "HEAD")
-	if err := os.WriteFile(headPath, []byte(""), 0644); err != nil {
+	if err := refs.NewRefManager(dgitPath).SetHeadToBranch(refs.DefaultBranch); err != nil {
 		return fmt.Errorf("failed to create HEAD file: %w", err)
 	}
 	return nil
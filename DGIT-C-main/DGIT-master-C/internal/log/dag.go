@@ -0,0 +1,265 @@
+package log
+
+import (
+	"sort"
+	"strings"
+)
+
+// GraphRow is one line of an ASCII history graph
+type GraphRow struct {
+	Graph   string  // Lane drawing for this line, e.g. "| *" or "|/"
+	Commit  *Commit // Commit shown on this line; nil for connector lines
+	Padding string  // Lanes running alongside the further lines of a commit
+}
+
+// normalizeParents fills Parents for commits stored before it existed, and keeps the legacy fields in sync
+func (c *Commit) normalizeParents() {
+	if len(c.Parents) == 0 {
+		if c.ParentHash != "" {
+			c.Parents = append([]string{c.ParentHash}, c.MergeParents...)
+		}
+		return
+	}
+	c.ParentHash = c.Parents[0]
+	c.MergeParents = nil
+	if len(c.Parents) > 1 {
+		c.MergeParents = append([]string{}, c.Parents[1:]...)
+	}
+}
+
+// GetTopoHistory returns the commits reachable from the given tips, children before parents
+// Commits reachable from any hash in exclude are left out, as in a from..to range
+func (lm *LogManager) GetTopoHistory(tips []string, exclude []string) ([]*Commit, error) {
+	allCommits, err := lm.loadAllCommits()
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]*Commit, len(allCommits))
+	for _, commit := range allCommits {
+		byHash[commit.Hash] = commit
+	}
+
+	excluded := reachableFrom(byHash, exclude, nil)
+	selected := reachableFrom(byHash, tips, excluded)
+	commits := make([]*Commit, 0, len(selected))
+	for hash := range selected {
+		commits = append(commits, byHash[hash])
+	}
+	return TopoSort(commits, tips), nil
+}
+
+// TopoSort orders commits so that every commit comes before its parents
+// The tips given are emitted first, in order; remaining heads follow newest first. The walk is
+// depth-first, so a merged branch is listed as one block before the line it was merged into.
+// Timestamps only break ties between unrelated heads and never override ancestry.
+func TopoSort(commits []*Commit, tips []string) []*Commit {
+	byHash := make(map[string]*Commit, len(commits))
+	for _, commit := range commits {
+		byHash[commit.Hash] = commit
+	}
+	children := make(map[string]int, len(commits))
+	for _, commit := range commits {
+		for _, parent := range commit.Parents {
+			if _, ok := byHash[parent]; ok {
+				children[parent]++
+			}
+		}
+	}
+
+	var heads []*Commit
+	queued := make(map[string]bool)
+	for _, tip := range tips {
+		if commit, ok := byHash[tip]; ok && children[tip] == 0 && !queued[tip] {
+			heads = append(heads, commit)
+			queued[tip] = true
+		}
+	}
+	var others []*Commit
+	for _, commit := range commits {
+		if children[commit.Hash] == 0 && !queued[commit.Hash] {
+			others = append(others, commit)
+		}
+	}
+	sort.SliceStable(others, func(i, j int) bool {
+		if !others[i].Timestamp.Equal(others[j].Timestamp) {
+			return others[i].Timestamp.After(others[j].Timestamp)
+		}
+		return others[i].Hash < others[j].Hash
+	})
+	heads = append(heads, others...)
+
+	// A stack of ready commits; the first head is on top
+	stack := make([]*Commit, 0, len(heads))
+	for i := len(heads) - 1; i >= 0; i-- {
+		stack = append(stack, heads[i])
+	}
+
+	sorted := make([]*Commit, 0, len(commits))
+	emitted := make(map[string]bool, len(commits))
+	for len(stack) > 0 {
+		commit := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if emitted[commit.Hash] {
+			continue
+		}
+		emitted[commit.Hash] = true
+		sorted = append(sorted, commit)
+
+		// The first parent is pushed first so merged-in parents are walked before it
+		for _, parent := range commit.Parents {
+			if _, ok := byHash[parent]; !ok {
+				continue
+			}
+			children[parent]--
+			if children[parent] == 0 {
+				stack = append(stack, byHash[parent])
+			}
+		}
+	}
+
+	// Only a corrupt history with a cycle leaves commits behind; list them rather than drop them
+	for _, commit := range commits {
+		if !emitted[commit.Hash] {
+			sorted = append(sorted, commit)
+		}
+	}
+	return sorted
+}
+
+// BuildGraph lays out topologically sorted commits as ASCII lanes, like 'git log --graph'
+func BuildGraph(commits []*Commit) []GraphRow {
+	inGraph := make(map[string]bool, len(commits))
+	for _, commit := range commits {
+		inGraph[commit.Hash] = true
+	}
+
+	var lanes []string
+	var rows []GraphRow
+	for _, commit := range commits {
+		idx := laneIndex(lanes, commit.Hash, -1)
+		if idx < 0 {
+			lanes = append(lanes, commit.Hash)
+			idx = len(lanes) - 1
+		}
+		var parents []string
+		for _, parent := range commit.Parents {
+			if inGraph[parent] {
+				parents = append(parents, parent)
+			}
+		}
+
+		padding := drawLanes(len(lanes), -1)
+		if len(parents) == 0 {
+			// Nothing continues below a root commit
+			padding = strings.TrimRight(padding[:2*idx]+" "+padding[2*idx+1:], " ")
+		}
+		rows = append(rows, GraphRow{Graph: drawLanes(len(lanes), idx), Commit: commit, Padding: padding})
+
+		// Merged-in parents open new lanes to the right of the commit
+		insertAt := idx + 1
+		for i := 1; i < len(parents); i++ {
+			if laneIndex(lanes, parents[i], -1) >= 0 {
+				continue
+			}
+			rows = append(rows, GraphRow{Graph: drawBranchOut(len(lanes), idx)})
+			lanes = append(lanes[:insertAt], append([]string{parents[i]}, lanes[insertAt:]...)...)
+			insertAt++
+		}
+
+		if len(parents) == 0 {
+			// A root commit ends its lane
+			lanes = append(lanes[:idx], lanes[idx+1:]...)
+			if idx < len(lanes) {
+				rows = append(rows, GraphRow{Graph: drawCollapse(len(lanes)+1, idx, -1)})
+			}
+		} else {
+			// The first parent continues the lane; lanes meeting at the same parent join the leftmost one
+			lanes[idx] = parents[0]
+			if other := laneIndex(lanes, parents[0], idx); other >= 0 {
+				removed, target := idx, other
+				if other > idx {
+					removed, target = other, idx
+				}
+				lanes = append(lanes[:removed], lanes[removed+1:]...)
+				rows = append(rows, GraphRow{Graph: drawCollapse(len(lanes)+1, removed, target)})
+			}
+		}
+	}
+	return rows
+}
+
+// laneIndex finds the lane expecting a commit, ignoring the lane at skip
+func laneIndex(lanes []string, hash string, skip int) int {
+	for i, lane := range lanes {
+		if lane == hash && i != skip {
+			return i
+		}
+	}
+	return -1
+}
+
+// drawLanes draws n lanes with the commit marker in lane idx
+func drawLanes(n, idx int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = "|"
+		if i == idx {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// drawBranchOut draws a new lane forking to the right of lane idx; lanes further right shift over
+func drawBranchOut(n, idx int) string {
+	line := []byte(strings.Repeat(" ", 2*n+2))
+	for i := 0; i <= idx; i++ {
+		line[2*i] = '|'
+	}
+	line[2*idx+1] = '\\'
+	for j := idx + 1; j < n; j++ {
+		line[2*j+1] = '\\'
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// drawCollapse draws lane removed joining lane target (or just ending when target is -1);
+// lanes to the right of it shift left
+func drawCollapse(n, removed, target int) string {
+	line := []byte(strings.Repeat(" ", 2*n))
+	for i := 0; i < removed; i++ {
+		line[2*i] = '|'
+	}
+	if target >= 0 {
+		line[2*removed-1] = '/'
+		for k := 2*target + 1; k < 2*removed-1; k++ {
+			if line[k] == ' ' {
+				line[k] = '_'
+			}
+		}
+	}
+	for j := removed + 1; j < n; j++ {
+		line[2*j-1] = '/'
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// reachableFrom returns the commits reachable from any of the given hashes, stopping at stop
+func reachableFrom(byHash map[string]*Commit, hashes []string, stop map[string]bool) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string{}, hashes...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == "" || seen[current] || stop[current] {
+			continue
+		}
+		commit, ok := byHash[current]
+		if !ok {
+			continue
+		}
+		seen[current] = true
+		queue = append(queue, commit.Parents...)
+	}
+	return seen
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+)
+
+// hashes lists the hashes of commits in order
+func hashes(commits []*Commit) []string {
+	list := make([]string, len(commits))
+	for i, commit := range commits {
+		list[i] = commit.Hash
+	}
+	return list
+}
+
+func TestGetTopoHistory(t *testing.T) {
+	r := mergedHistory(t)
+	cases := []struct {
+		name    string
+		tips    []string
+		exclude []string
+		want    []string
+	}{
+		{"whole history", []string{"aaaa00000005"}, nil,
+			[]string{"aaaa00000005", "cccc00000004", "bbbb00000003", "aaaa00000003", "aaaa00000002", "aaaa00000001"}},
+		{"merged branch tip", []string{"bbbb00000003"}, nil,
+			[]string{"bbbb00000003", "aaaa00000002", "aaaa00000001"}},
+		{"tip inside another tip's history", []string{"bbbb00000003", "aaaa00000005"}, nil,
+			[]string{"aaaa00000005", "cccc00000004", "bbbb00000003", "aaaa00000003", "aaaa00000002", "aaaa00000001"}},
+		{"range", []string{"aaaa00000005"}, []string{"bbbb00000003"},
+			[]string{"aaaa00000005", "cccc00000004", "aaaa00000003"}},
+		{"empty range", []string{"aaaa00000002"}, []string{"aaaa00000005"}, []string{}},
+		{"unknown tip", []string{"ffff00000001"}, nil, []string{}},
+	}
+	for _, c := range cases {
+		commits, err := r.lm.GetTopoHistory(c.tips, c.exclude)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := hashes(commits); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestTopoSort(t *testing.T) {
+	r := diverged(t)
+	var commits []*Commit
+	for _, hash := range []string{"aaaa00000001", "aaaa00000002", "bbbb00000002", "cccc00000002", "cccc00000003"} {
+		commits = append(commits, r.commits[hash])
+	}
+	cases := []struct {
+		name string
+		tips []string
+		want []string
+	}{
+		{"newest head first", nil,
+			[]string{"cccc00000003", "cccc00000002", "bbbb00000002", "aaaa00000002", "aaaa00000001"}},
+		{"tip first", []string{"bbbb00000002"},
+			[]string{"bbbb00000002", "cccc00000003", "cccc00000002", "aaaa00000002", "aaaa00000001"}},
+		{"tips in order", []string{"aaaa00000002", "bbbb00000002"},
+			[]string{"aaaa00000002", "bbbb00000002", "cccc00000003", "cccc00000002", "aaaa00000001"}},
+		{"tip with children is not a head", []string{"cccc00000002"},
+			[]string{"cccc00000003", "cccc00000002", "bbbb00000002", "aaaa00000002", "aaaa00000001"}},
+	}
+	for _, c := range cases {
+		if got := hashes(TopoSort(commits, c.tips)); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// A corrupt history with a cycle keeps all its commits
+	loop := []*Commit{
+		{Hash: "x", Parents: []string{"y"}},
+		{Hash: "y", Parents: []string{"x"}},
+	}
+	if got := hashes(TopoSort(loop, nil)); !reflect.DeepEqual(got, []string{"x", "y"}) {
+		t.Errorf("cycle: got %v", got)
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	node := func(hash string, parents ...string) *Commit {
+		return &Commit{Hash: hash, Message: hash, Parents: parents}
+	}
+	cases := []struct {
+		name    string
+		commits []*Commit
+		lines   []string // Graph and message of each row
+		padding []string // Padding of each commit row
+	}{
+		{
+			"linear",
+			[]*Commit{node("a3", "a2"), node("a2", "a1"), node("a1")},
+			[]string{"* a3", "* a2", "* a1"},
+			[]string{"|", "|", ""},
+		},
+		{
+			"merge",
+			[]*Commit{node("a5", "c4"), node("c4", "a3", "b3"), node("b3", "a2"), node("a3", "a2"), node("a2", "a1"), node("a1")},
+			[]string{"* a5", "* c4", "|\\", "| * b3", "* | a3", "|/", "* a2", "* a1"},
+			[]string{"|", "|", "| |", "| |", "|", ""},
+		},
+		{
+			"branches without merges",
+			[]*Commit{node("c3", "c2"), node("c2", "a1"), node("b2", "a1"), node("a2", "a1"), node("a1")},
+			[]string{"* c3", "* c2", "| * b2", "|/", "| * a2", "|/", "* a1"},
+			[]string{"|", "|", "| |", "| |", ""},
+		},
+		{
+			"octopus merge",
+			[]*Commit{node("m", "a", "b", "c"), node("c"), node("b"), node("a")},
+			[]string{"* m", "|\\", "|\\ \\", "| | * c", "| * b", "* a"},
+			[]string{"|", "| |", "|", ""},
+		},
+		{
+			"root beside a running lane",
+			[]*Commit{node("a2", "a1"), node("b2", "b1"), node("a1"), node("b1")},
+			[]string{"* a2", "| * b2", "* | a1", " /", "* b1"},
+			[]string{"|", "| |", "  |", ""},
+		},
+		{
+			"parent outside the graph",
+			[]*Commit{node("a3", "a2"), node("a2", "a1")},
+			[]string{"* a3", "* a2"},
+			[]string{"|", ""},
+		},
+	}
+	for _, c := range cases {
+		var lines, padding []string
+		for _, row := range BuildGraph(c.commits) {
+			if row.Commit == nil {
+				lines = append(lines, row.Graph)
+				continue
+			}
+			lines = append(lines, row.Graph+" "+row.Commit.Message)
+			padding = append(padding, row.Padding)
+		}
+		if !reflect.DeepEqual(lines, c.lines) {
+			t.Errorf("%s: got lines %q, want %q", c.name, lines, c.lines)
+		}
+		if !reflect.DeepEqual(padding, c.padding) {
+			t.Errorf("%s: got padding %q, want %q", c.name, padding, c.padding)
+		}
+	}
+}
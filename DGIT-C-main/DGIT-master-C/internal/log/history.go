@@ -0,0 +1,94 @@
+package log
+
+// FileRevision is a commit that touched a file, as seen by per-file history
+type FileRevision struct {
+	Commit   *Commit
+	Path     string                 // Path of the file at this commit
+	OldPath  string                 // Previous path when the commit renamed the file
+	Change   string                 // "added", "modified", "renamed" or "deleted"
+	Metadata map[string]interface{} // Scanned metadata at this commit; nil when deleted or unknown
+}
+
+// FileHistory returns the commits that touched a file, newest first, following renames
+// commits is a newest-first line of history such as the result of GetHistoryFrom
+func (lm *LogManager) FileHistory(commits []*Commit, path string) []*FileRevision {
+	byHash := make(map[string]*Commit, len(commits))
+	for _, c := range commits {
+		byHash[c.Hash] = c
+	}
+	parentOf := func(c *Commit) *Commit {
+		if c.ParentHash == "" {
+			return nil
+		}
+		if parent, ok := byHash[c.ParentHash]; ok {
+			return parent
+		}
+		parent, err := lm.GetCommitByHash(c.ParentHash)
+		if err != nil {
+			return nil
+		}
+		return parent
+	}
+
+	var revisions []*FileRevision
+	name := path
+	for _, c := range commits {
+		tree := c.TrackedFiles()
+		parentTree := map[string]TreeEntry{}
+		if parent := parentOf(c); parent != nil {
+			parentTree = parent.TrackedFiles()
+		}
+
+		entry, tracked := tree[name]
+		if !tracked {
+			if _, wasTracked := parentTree[name]; wasTracked {
+				revisions = append(revisions, &FileRevision{Commit: c, Path: name, Change: "deleted"})
+			}
+			continue
+		}
+
+		revision := &FileRevision{Commit: c, Path: name}
+		oldPath := lm.CommitRenames(c)[name]
+		previous, existed := parentTree[name]
+		switch {
+		case oldPath != "":
+			revision.OldPath = oldPath
+			revision.Change = "renamed"
+		case !existed:
+			revision.Change = "added"
+		case entryChanged(previous, entry, c.Hash):
+			revision.Change = "modified"
+		default:
+			continue
+		}
+		revision.Metadata = lm.FileMetadata(name, entry)
+		revisions = append(revisions, revision)
+
+		if oldPath != "" {
+			name = oldPath
+		}
+	}
+	return revisions
+}
+
+// FileMetadata returns the scanned metadata of a tracked file from the commit storing its content
+func (lm *LogManager) FileMetadata(path string, entry TreeEntry) map[string]interface{} {
+	if entry.Commit == "" {
+		return nil
+	}
+	stored, err := lm.GetCommitByHash(entry.Commit)
+	if err != nil {
+		return nil
+	}
+	meta, _ := stored.Metadata[path].(map[string]interface{})
+	return meta
+}
+
+// entryChanged reports whether a file differs from its parent version
+// Legacy commits have no content hashes, so storing the file again counts as a change
+func entryChanged(previous, current TreeEntry, commitHash string) bool {
+	if previous.Hash != "" && current.Hash != "" {
+		return previous.Hash != current.Hash
+	}
+	return current.Commit == commitHash
+}
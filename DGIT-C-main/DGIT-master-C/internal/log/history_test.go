@@ -0,0 +1,74 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFileHistory(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("aaaa00000001", "Initial", nil, map[string]string{"logo.ai": "logo1", "hero.psd": "hero1"}, nil)
+	r.commit("aaaa00000002", "Logo v2", []string{"aaaa00000001"}, map[string]string{"logo.ai": "logo2", "hero.psd": "hero1"}, nil)
+	r.commit("aaaa00000003", "Move logo", []string{"aaaa00000002"}, map[string]string{"brand/logo.ai": "logo2", "hero.psd": "hero1"}, map[string]string{"brand/logo.ai": "logo.ai"})
+	r.commit("aaaa00000004", "Hero v2", []string{"aaaa00000003"}, map[string]string{"brand/logo.ai": "logo2", "hero.psd": "hero2"}, nil)
+	r.commit("aaaa00000005", "Logo v3", []string{"aaaa00000004"}, map[string]string{"brand/logo.ai": "logo3", "hero.psd": "hero2"}, nil)
+	r.commit("aaaa00000006", "Drop hero", []string{"aaaa00000005"}, map[string]string{"brand/logo.ai": "logo3"}, nil)
+	r.commit("aaaa00000007", "Hero again", []string{"aaaa00000006"}, map[string]string{"brand/logo.ai": "logo3", "hero.psd": "hero3"}, nil)
+	r.branch("main", "aaaa00000007")
+	history, err := r.lm.GetCommitHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path string
+		want []string // Version, change, path and old path of each revision
+	}{
+		{"brand/logo.ai", []string{
+			"5 modified brand/logo.ai ",
+			"3 renamed brand/logo.ai logo.ai",
+			"2 modified logo.ai ",
+			"1 added logo.ai ",
+		}},
+		{"hero.psd", []string{
+			"7 added hero.psd ",
+			"6 deleted hero.psd ",
+			"4 modified hero.psd ",
+			"1 added hero.psd ",
+		}},
+		{"logo.ai", []string{
+			"3 deleted logo.ai ",
+			"2 modified logo.ai ",
+			"1 added logo.ai ",
+		}},
+		{"missing.ai", []string{}},
+	}
+	for _, c := range cases {
+		got := []string{}
+		for _, revision := range r.lm.FileHistory(history, c.path) {
+			got = append(got, fmt.Sprintf("%d %s %s %s", revision.Commit.Version, revision.Change, revision.Path, revision.OldPath))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: history = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestEntryChanged(t *testing.T) {
+	cases := []struct {
+		name              string
+		previous, current TreeEntry
+		changed           bool
+	}{
+		{"same hash", TreeEntry{Hash: "a", Commit: "c1"}, TreeEntry{Hash: "a", Commit: "c2"}, false},
+		{"different hash", TreeEntry{Hash: "a", Commit: "c1"}, TreeEntry{Hash: "b", Commit: "c1"}, true},
+		{"legacy entry stored again", TreeEntry{Commit: "c1"}, TreeEntry{Commit: "c2"}, true},
+		{"legacy entry carried over", TreeEntry{Commit: "c1"}, TreeEntry{Commit: "c1"}, false},
+	}
+	for _, c := range cases {
+		if got := entryChanged(c.previous, c.current, "c2"); got != c.changed {
+			t.Errorf("%s: changed = %v, want %v", c.name, got, c.changed)
+		}
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
+
+	"dgit/internal/refs"
 )
 
 // CompressionResult contains comprehensive compression operation results
@@ -20,6 +21,7 @@ type CompressionResult struct {
 	CompressedSize   int64     `json:"compressed_size"`
 	CompressionRatio float64   `json:"compression_ratio"`
 	BaseVersion      int       `json:"base_version,omitempty"`
+	BaseHash         string    `json:"base_hash,omitempty"` // Commit the delta was computed against
 	CreatedAt        time.Time `json:"created_at"`
 
 	// Performance Metrics - Core data for speed improvement tracking
@@ -39,12 +41,58 @@ type Commit struct {
 	Version    int                    `json:"version"`
 	Metadata   map[string]interface{} `json:"metadata"`
 	ParentHash string                 `json:"parent_hash,omitempty"`
+	Branch     string                 `json:"branch,omitempty"` // Branch the commit was created on
+
+	// Parent commits in order; the first parent is the line of history the commit was made on
+	// ParentHash and MergeParents mirror Parents[0] and Parents[1:] for older readers
+	Parents []string `json:"parents,omitempty"`
+
+	// Additional parents of a merge commit; ParentHash stays the first parent
+	MergeParents []string `json:"merge_parents,omitempty"`
+
+	// Files renamed by this commit, keyed by new path with the old path as value
+	Renames map[string]string `json:"renames,omitempty"`
+
+	// Full set of tracked files at this commit, including files carried over from parents
+	Tree map[string]TreeEntry `json:"tree,omitempty"`
 
 	// Enhanced compression information for performance analysis
 	SnapshotZip     string             `json:"snapshot_zip,omitempty"`     // Legacy field for backward compatibility
 	CompressionInfo *CompressionResult `json:"compression_info,omitempty"` // Compression metrics and data
 }
 
+// TreeEntry records a tracked file and the commit whose snapshot stores its content
+type TreeEntry struct {
+	Hash   string `json:"hash"`   // SHA256 of file content
+	Size   int64  `json:"size"`   // File size in bytes
+	Commit string `json:"commit"` // Hash of the commit holding the content in its snapshot
+}
+
+// TrackedFiles returns the files tracked at this commit
+// Commits created before trees were recorded only know the files stored in their own snapshot
+func (c *Commit) TrackedFiles() map[string]TreeEntry {
+	if len(c.Tree) > 0 {
+		return c.Tree
+	}
+
+	files := make(map[string]TreeEntry)
+	for path, meta := range c.Metadata {
+		entry := TreeEntry{Commit: c.Hash}
+		if info, ok := meta.(map[string]interface{}); ok {
+			if size, ok := info["size"].(float64); ok {
+				entry.Size = int64(size)
+			}
+		}
+		files[path] = entry
+	}
+	return files
+}
+
+// OptimizedCacheName returns the background-optimized cache file name for an LZ4 snapshot
+func OptimizedCacheName(outputFile string) string {
+	return strings.TrimSuffix(outputFile, ".lz4") + "_optimized.zstd"
+}
+
 // LogManager handles commit history operations with simplified storage system
 // Updated to work with simplified 2-tier storage system for optimal performance
 type LogManager struct {
@@ -68,88 +116,233 @@ func NewLogManager(dgitDir string) *LogManager {
 	}
 }
 
-// GetCommitHistory returns complete commit history sorted by timestamp (newest first)
-// Efficiently loads all commits with compression information
+// GetCommitHistory returns the history reachable from HEAD (newest first)
+// Follows parent links so only commits on the current branch are listed
 func (lm *LogManager) GetCommitHistory() ([]*Commit, error) {
-	entries, err := os.ReadDir(lm.CommitsDir)
+	headHash, err := refs.NewRefManager(lm.DgitDir).ResolveHead()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read commits directory: %w", err)
+		return nil, err
 	}
+	return lm.GetHistoryFrom(headHash)
+}
 
-	var commits []*Commit
-	// Process all commit metadata files
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "v") && strings.HasSuffix(entry.Name(), ".json") {
-			commitPath := filepath.Join(lm.CommitsDir, entry.Name())
-			commit, err := lm.loadCommit(commitPath)
-			if err != nil {
-				// Skip failed commits but continue processing others
-				continue
-			}
-			commits = append(commits, commit)
+// GetHistoryFrom returns the first-parent history of the given commit (newest first)
+// Commits brought in by merges are left out; use GetTopoHistory for the whole DAG
+func (lm *LogManager) GetHistoryFrom(hash string) ([]*Commit, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	allCommits, err := lm.loadAllCommits()
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]*Commit, len(allCommits))
+	for _, commit := range allCommits {
+		byHash[commit.Hash] = commit
+	}
+
+	var history []*Commit
+	visited := make(map[string]bool)
+	for hash != "" && !visited[hash] {
+		commit, exists := byHash[hash]
+		if !exists {
+			break
 		}
+		visited[hash] = true
+		history = append(history, commit)
+		hash = commit.ParentHash
 	}
 
-	// Sort commits by timestamp (newest first) for intuitive display
-	sort.Slice(commits, func(i, j int) bool {
-		return commits[i].Timestamp.After(commits[j].Timestamp)
-	})
+	return history, nil
+}
 
-	return commits, nil
+// GetAncestors returns the hashes of every commit reachable from the given commit, including itself
+// Unlike GetHistoryFrom it follows merge parents as well
+func (lm *LogManager) GetAncestors(hash string) (map[string]bool, error) {
+	ancestors := make(map[string]bool)
+	if hash == "" {
+		return ancestors, nil
+	}
+
+	allCommits, err := lm.loadAllCommits()
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]*Commit, len(allCommits))
+	for _, commit := range allCommits {
+		byHash[commit.Hash] = commit
+	}
+
+	queue := []string{hash}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == "" || ancestors[current] {
+			continue
+		}
+		commit, exists := byHash[current]
+		if !exists {
+			continue
+		}
+		ancestors[current] = true
+		queue = append(queue, commit.Parents...)
+	}
+	return ancestors, nil
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant
+func (lm *LogManager) IsAncestor(ancestor, descendant string) (bool, error) {
+	ancestors, err := lm.GetAncestors(descendant)
+	if err != nil {
+		return false, err
+	}
+	return ancestors[ancestor], nil
+}
+
+// FindMergeBase returns the nearest common ancestor of two commits, or nil when the histories are unrelated
+func (lm *LogManager) FindMergeBase(a, b string) (*Commit, error) {
+	ancestorsOfA, err := lm.GetAncestors(a)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk b's ancestry breadth-first so the closest shared commit is found first
+	visited := make(map[string]bool)
+	queue := []string{b}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == "" || visited[current] {
+			continue
+		}
+		visited[current] = true
+		if ancestorsOfA[current] {
+			return lm.GetCommitByHash(current)
+		}
+		commit, err := lm.GetCommitByHash(current)
+		if err != nil {
+			continue
+		}
+		queue = append(queue, commit.Parents...)
+	}
+	return nil, nil
+}
+
+// GetAllCommits returns every commit in the repository across all branches
+// Sorted topologically: every commit comes before its parents
+func (lm *LogManager) GetAllCommits() ([]*Commit, error) {
+	commits, err := lm.loadAllCommits()
+	if err != nil {
+		return nil, err
+	}
+	return TopoSort(commits, nil), nil
+}
+
+// GetHeadCommit returns the commit HEAD points to, or nil when there are no commits yet
+func (lm *LogManager) GetHeadCommit() (*Commit, error) {
+	headHash, err := refs.NewRefManager(lm.DgitDir).ResolveHead()
+	if err != nil {
+		return nil, err
+	}
+	if headHash == "" {
+		return nil, nil
+	}
+	return lm.GetCommitByHash(headHash)
 }
 
 // GetCommit returns a specific commit by version number
-// Efficiently loads individual commit with all metadata
+// Versions are numbered along each line of history, so a version resolves on the current
+// branch's first-parent line. Other branches are only used when exactly one commit outside it
+// has the version; otherwise the error lists the candidate hashes.
 func (lm *LogManager) GetCommit(version int) (*Commit, error) {
-	commitPath := filepath.Join(lm.CommitsDir, fmt.Sprintf("v%d.json", version))
-	return lm.loadCommit(commitPath)
+	history, err := lm.GetCommitHistory()
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range history {
+		if commit.Version == version {
+			return commit, nil
+		}
+	}
+
+	allCommits, err := lm.loadAllCommits()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Commit
+	for _, commit := range allCommits {
+		if commit.Version == version {
+			matches = append(matches, commit)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("version v%d not found", version)
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Slice(matches, func(i, j int) bool { return matches[i].Timestamp.Before(matches[j].Timestamp) })
+		candidates := make([]string, len(matches))
+		for i, commit := range matches {
+			candidates[i] = fmt.Sprintf("%s (%s)", commit.Hash, strings.SplitN(commit.Message, "\n", 2)[0])
+		}
+		return nil, fmt.Errorf("version v%d is not on the current branch and is ambiguous; use one of these commit hashes:\n  %s", version, strings.Join(candidates, "\n  "))
+	}
 }
 
 // GetCommitByHash retrieves a commit by its full or short hash
 // Supports partial hash matching for user convenience
 func (lm *LogManager) GetCommitByHash(hash string) (*Commit, error) {
-	entries, err := os.ReadDir(lm.CommitsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read commits directory: %w", err)
+	if hash == "" {
+		return nil, fmt.Errorf("empty commit hash")
 	}
 
-	// Search through all commit files for hash match
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "v") && strings.HasSuffix(entry.Name(), ".json") {
-			commitPath := filepath.Join(lm.CommitsDir, entry.Name())
-			commit, err := lm.loadCommit(commitPath)
-			if err != nil {
-				continue
-			}
-			// Support both full and partial hash matching
-			if strings.HasPrefix(commit.Hash, hash) {
-				return commit, nil
-			}
-		}
+	// Commits created with branch support are stored under their full hash
+	if commit, err := lm.loadCommit(filepath.Join(lm.CommitsDir, hash+".json")); err == nil && commit.Hash == hash {
+		return commit, nil
 	}
-	return nil, fmt.Errorf("commit with hash '%s' not found", hash)
-}
 
-// GetCurrentVersion returns the current version number by scanning metadata files
-// Efficiently determines the latest version for next commit numbering
-func (lm *LogManager) GetCurrentVersion() int {
-	entries, err := os.ReadDir(lm.CommitsDir)
+	allCommits, err := lm.loadAllCommits()
 	if err != nil {
-		return 0
+		return nil, err
 	}
 
-	maxVersion := 0
-	// Find the highest version number in commit metadata files
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "v") && strings.HasSuffix(entry.Name(), ".json") {
-			versionStr := strings.TrimPrefix(strings.TrimSuffix(entry.Name(), ".json"), "v")
-			if version, err := strconv.Atoi(versionStr); err == nil && version > maxVersion {
-				maxVersion = version
-			}
+	// Support both full and partial hash matching; a prefix must identify a single commit
+	hash = strings.ToLower(hash)
+	var matches []*Commit
+	for _, commit := range allCommits {
+		if commit.Hash == hash {
+			return commit, nil
+		}
+		if strings.HasPrefix(commit.Hash, hash) {
+			matches = append(matches, commit)
 		}
 	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("commit with hash '%s' not found", hash)
+	case 1:
+		return matches[0], nil
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Hash < matches[j].Hash })
+	candidates := make([]string, len(matches))
+	for i, commit := range matches {
+		candidates[i] = fmt.Sprintf("%s (v%d %s)", commit.Hash, commit.Version, strings.SplitN(commit.Message, "\n", 2)[0])
+	}
+	return nil, fmt.Errorf("short hash '%s' is ambiguous; candidates:\n  %s", hash, strings.Join(candidates, "\n  "))
+}
 
-	return maxVersion
+// GetCurrentVersion returns the version number of the HEAD commit
+// Returns 0 when the current branch has no commits yet
+func (lm *LogManager) GetCurrentVersion() int {
+	head, err := lm.GetHeadCommit()
+	if err != nil || head == nil {
+		return 0
+	}
+	return head.Version
 }
 
 // GenerateCommitSummary generates human-readable summary with metrics
@@ -206,7 +399,7 @@ func (lm *LogManager) GenerateCommitSummary(commit *Commit) string {
 // GetCompressionStatistics returns compression analytics
 // Provides detailed performance metrics across all commits for optimization insights
 func (lm *LogManager) GetCompressionStatistics() (*CompressionStatistics, error) {
-	commits, err := lm.GetCommitHistory()
+	commits, err := lm.GetAllCommits()
 	if err != nil {
 		return nil, err
 	}
@@ -363,7 +556,7 @@ func (lm *LogManager) GetCommitEfficiency(commit *Commit) string {
 // FindCommitsByStorageType finds commits using specific storage strategies
 // Enhanced for compression system with strategy filtering
 func (lm *LogManager) FindCommitsByStorageType(storageType string) ([]*Commit, error) {
-	allCommits, err := lm.GetCommitHistory()
+	allCommits, err := lm.GetAllCommits()
 	if err != nil {
 		return nil, err
 	}
@@ -490,7 +683,7 @@ type SizeBreakdown struct {
 // GetCacheUtilization returns cache utilization statistics
 // Provides insights into simplified storage system performance and efficiency
 func (lm *LogManager) GetCacheUtilization() (*CacheUtilization, error) {
-	commits, err := lm.GetCommitHistory()
+	commits, err := lm.GetAllCommits()
 	if err != nil {
 		return nil, err
 	}
@@ -538,6 +731,80 @@ func (lm *LogManager) loadCommit(path string) (*Commit, error) {
 	if err := json.Unmarshal(data, &commit); err != nil {
 		return nil, err
 	}
+	commit.normalizeParents()
 
 	return &commit, nil
 }
+
+// SetCommitMessage rewrites the message of a stored commit, keeping its hash and storage untouched
+func (lm *LogManager) SetCommitMessage(hash, message string) error {
+	path, err := lm.commitFilePath(hash)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+	// Edit the raw document so fields unknown to this package are preserved
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse commit %s: %w", hash, err)
+	}
+	raw["message"] = message
+
+	data, err = json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal commit %s: %w", hash, err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// commitFilePath returns the metadata file of a commit by its full hash
+func (lm *LogManager) commitFilePath(hash string) (string, error) {
+	path := filepath.Join(lm.CommitsDir, hash+".json")
+	if commit, err := lm.loadCommit(path); err == nil && commit.Hash == hash {
+		return path, nil
+	}
+
+	// Legacy commits are stored as vN.json
+	entries, err := os.ReadDir(lm.CommitsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commits directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(lm.CommitsDir, entry.Name())
+		if commit, err := lm.loadCommit(path); err == nil && commit.Hash == hash {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("commit with hash '%s' not found", hash)
+}
+
+// loadAllCommits loads every commit metadata file in the commits directory
+// Handles both legacy "vN.json" files and hash-named files written since branch support
+func (lm *LogManager) loadAllCommits() ([]*Commit, error) {
+	entries, err := os.ReadDir(lm.CommitsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits directory: %w", err)
+	}
+
+	var commits []*Commit
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || entry.Name() == "index.json" {
+			continue
+		}
+		commit, err := lm.loadCommit(filepath.Join(lm.CommitsDir, entry.Name()))
+		if err != nil || commit.Hash == "" {
+			// Skip failed commits but continue processing others
+			continue
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
@@ -0,0 +1,200 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dgit/internal/refs"
+)
+
+// testRepo writes commit records and references without any stored file contents
+type testRepo struct {
+	t       *testing.T
+	dgitDir string
+	lm      *LogManager
+	refs    *refs.RefManager
+	commits map[string]*Commit
+	clock   time.Time
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	dgitDir := filepath.Join(t.TempDir(), ".dgit")
+	if err := os.MkdirAll(filepath.Join(dgitDir, "commits"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := &testRepo{
+		t:       t,
+		dgitDir: dgitDir,
+		lm:      NewLogManager(dgitDir),
+		refs:    refs.NewRefManager(dgitDir),
+		commits: make(map[string]*Commit),
+		clock:   time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	if err := r.refs.SetHeadToBranch(refs.DefaultBranch); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// commit records a commit an hour after the previous one, numbered after its first parent
+// tree lists the tracked files as path and content pairs; renames maps new paths to old ones
+func (r *testRepo) commit(hash, message string, parents []string, tree map[string]string, renames map[string]string) *Commit {
+	r.t.Helper()
+	r.clock = r.clock.Add(time.Hour)
+	c := &Commit{Hash: hash, Message: message, Timestamp: r.clock, Author: "Designer", Version: 1, Renames: renames}
+	if len(parents) > 0 {
+		c.Version = r.commits[parents[0]].Version + 1
+		c.ParentHash = parents[0]
+		c.Parents = parents
+		c.MergeParents = parents[1:]
+	}
+	c.Tree = make(map[string]TreeEntry)
+	for path, content := range tree {
+		c.Tree[path] = TreeEntry{Hash: content, Size: int64(len(content)), Commit: hash}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dgitDir, "commits", hash+".json"), data, 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.commits[hash] = c
+	return c
+}
+
+// branch points a branch at a commit
+func (r *testRepo) branch(name, hash string) {
+	r.t.Helper()
+	if err := r.refs.WriteBranch(name, hash); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// diverged is main with two commits and two branches that both made their own v2 from v1
+func diverged(t *testing.T) *testRepo {
+	r := newTestRepo(t)
+	r.commit("aaaa00000001", "Initial layout", nil, nil, nil)
+	r.commit("aaaa00000002", "Main v2", []string{"aaaa00000001"}, nil, nil)
+	r.commit("bbbb00000002", "Logo v2", []string{"aaaa00000001"}, nil, nil)
+	r.commit("cccc00000002", "Colors v2", []string{"aaaa00000001"}, nil, nil)
+	r.commit("cccc00000003", "Colors v3", []string{"cccc00000002"}, nil, nil)
+	r.branch("main", "aaaa00000002")
+	r.branch("logo", "bbbb00000002")
+	r.branch("colors", "cccc00000003")
+	return r
+}
+
+func TestGetCommitResolvesVersionsOnTheCurrentBranch(t *testing.T) {
+	r := diverged(t)
+	cases := []struct {
+		branch  string
+		version int
+		want    string
+	}{
+		{"main", 2, "aaaa00000002"},
+		{"logo", 2, "bbbb00000002"},
+		{"colors", 2, "cccc00000002"},
+		{"main", 1, "aaaa00000001"},
+		{"main", 3, "cccc00000003"}, // Only one commit has v3
+	}
+	for _, c := range cases {
+		r.refs.SetHeadToBranch(c.branch)
+		commit, err := r.lm.GetCommit(c.version)
+		if err != nil || commit.Hash != c.want {
+			t.Errorf("v%d on %s: got %v, %v; want %s", c.version, c.branch, commit, err, c.want)
+		}
+	}
+}
+
+func TestGetCommitListsCandidatesOfAmbiguousVersions(t *testing.T) {
+	r := diverged(t)
+	r.refs.DetachHead("aaaa00000001")
+	_, err := r.lm.GetCommit(2)
+	if err == nil {
+		t.Fatal("expected an error for a version on several branches")
+	}
+	for _, hash := range []string{"aaaa00000002", "bbbb00000002", "cccc00000002"} {
+		if !strings.Contains(err.Error(), hash) {
+			t.Errorf("error does not list %s: %v", hash, err)
+		}
+	}
+	if _, err := r.lm.GetCommit(9); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing version: %v", err)
+	}
+}
+
+func TestFindMergeBase(t *testing.T) {
+	r := diverged(t)
+	r.commit("dddd00000004", "Merge main into colors", []string{"cccc00000003", "aaaa00000002"}, nil, nil)
+	r.commit("eeee00000001", "Unrelated import", nil, nil, nil)
+	cases := []struct {
+		a, b string
+		want string // Empty for unrelated histories
+	}{
+		{"aaaa00000002", "bbbb00000002", "aaaa00000001"},
+		{"bbbb00000002", "cccc00000003", "aaaa00000001"},
+		{"cccc00000003", "cccc00000002", "cccc00000002"},
+		{"cccc00000002", "cccc00000003", "cccc00000002"},
+		{"dddd00000004", "aaaa00000002", "aaaa00000002"},
+		{"bbbb00000002", "dddd00000004", "aaaa00000001"},
+		{"eeee00000001", "aaaa00000002", ""},
+	}
+	for _, c := range cases {
+		base, err := r.lm.FindMergeBase(c.a, c.b)
+		if err != nil {
+			t.Errorf("%s and %s: %v", c.a, c.b, err)
+			continue
+		}
+		got := ""
+		if base != nil {
+			got = base.Hash
+		}
+		if got != c.want {
+			t.Errorf("%s and %s: base = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestSetCommitMessage(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("aaaa00000001", "Initial layout", nil, nil, nil)
+	// Commits from before branch support are stored as vN.json, with fields this package does not know
+	legacy := `{"hash": "ffff00000001", "message": "Legacy", "version": 1, "compression_info": {"strategy": "zip"}}`
+	if err := os.WriteFile(filepath.Join(r.dgitDir, "commits", "v1.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		hash, message string
+		wantErr       bool
+	}{
+		{"aaaa00000001", "Initial layout for ACME", false},
+		{"ffff00000001", "Legacy import", false},
+		{"aaaa0000", "Prefixes are not enough", true},
+		{"9999", "Missing", true},
+	}
+	for _, c := range cases {
+		err := r.lm.SetCommitMessage(c.hash, c.message)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: %v", c.hash, err)
+			continue
+		}
+		if c.wantErr {
+			continue
+		}
+		commit, err := r.lm.GetCommitByHash(c.hash)
+		if err != nil || commit.Message != c.message {
+			t.Errorf("%s: got %v, %v; want message %q", c.hash, commit, err, c.message)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dgitDir, "commits", "v1.json"))
+	if err != nil || !strings.Contains(string(data), `"strategy": "zip"`) {
+		t.Errorf("unknown fields were not preserved: %s, %v", data, err)
+	}
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RenameSimilarityThreshold is the minimum layer similarity for pairing files whose content differs
+const RenameSimilarityThreshold = 0.7
+
+// RenameCandidate describes a deleted or added file considered for rename detection
+type RenameCandidate struct {
+	Path       string
+	Hash       string   // SHA256 of file content, empty when unknown
+	Dimensions string   // Canvas size such as "1920x1080", empty when unknown
+	Layers     []string // Layer names, empty for files without layers
+}
+
+// DetectRenames pairs deleted files with added files and returns a map of new path to old path
+// Identical content always counts as a rename. Otherwise files with the same extension and
+// canvas size are paired when their layer names are similar enough.
+func DetectRenames(removed, added []RenameCandidate) map[string]string {
+	renames := make(map[string]string)
+	usedOld := make(map[string]bool)
+
+	// Sort copies so pairing does not depend on the caller's ordering
+	removed = append([]RenameCandidate{}, removed...)
+	added = append([]RenameCandidate{}, added...)
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Path < removed[j].Path })
+	sort.Slice(added, func(i, j int) bool { return added[i].Path < added[j].Path })
+
+	// Exact content matches first
+	byHash := make(map[string][]string)
+	for _, old := range removed {
+		if old.Hash != "" {
+			byHash[old.Hash] = append(byHash[old.Hash], old.Path)
+		}
+	}
+	for _, candidate := range added {
+		for _, oldPath := range byHash[candidate.Hash] {
+			if candidate.Hash != "" && !usedOld[oldPath] {
+				renames[candidate.Path] = oldPath
+				usedOld[oldPath] = true
+				break
+			}
+		}
+	}
+
+	// Then the most similar remaining pairs by layer names
+	type scoredPair struct {
+		oldPath, newPath string
+		score            float64
+	}
+	var pairs []scoredPair
+	for _, candidate := range added {
+		if _, done := renames[candidate.Path]; done {
+			continue
+		}
+		for _, old := range removed {
+			if usedOld[old.Path] {
+				continue
+			}
+			if score := renameSimilarity(old, candidate); score >= RenameSimilarityThreshold {
+				pairs = append(pairs, scoredPair{old.Path, candidate.Path, score})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].score != pairs[j].score {
+			return pairs[i].score > pairs[j].score
+		}
+		return pairs[i].newPath < pairs[j].newPath
+	})
+	for _, pair := range pairs {
+		if usedOld[pair.oldPath] {
+			continue
+		}
+		if _, done := renames[pair.newPath]; done {
+			continue
+		}
+		renames[pair.newPath] = pair.oldPath
+		usedOld[pair.oldPath] = true
+	}
+
+	return renames
+}
+
+// renameSimilarity scores how likely two files are versions of the same document
+func renameSimilarity(a, b RenameCandidate) float64 {
+	if !strings.EqualFold(filepath.Ext(a.Path), filepath.Ext(b.Path)) {
+		return 0
+	}
+	if a.Dimensions != "" && b.Dimensions != "" && a.Dimensions != b.Dimensions {
+		return 0
+	}
+	if len(a.Layers) == 0 || len(b.Layers) == 0 {
+		return 0
+	}
+
+	// Jaccard index over layer names
+	names := make(map[string]int)
+	for _, name := range a.Layers {
+		names[name] |= 1
+	}
+	for _, name := range b.Layers {
+		names[name] |= 2
+	}
+	shared := 0
+	for _, sides := range names {
+		if sides == 3 {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(names))
+}
+
+// CandidateFromMetadata builds a rename candidate from scanned commit metadata
+func CandidateFromMetadata(path, hash string, meta interface{}) RenameCandidate {
+	candidate := RenameCandidate{Path: path, Hash: hash}
+	info, ok := meta.(map[string]interface{})
+	if !ok {
+		return candidate
+	}
+	if dimensions, ok := info["dimensions"].(string); ok {
+		candidate.Dimensions = dimensions
+	}
+	switch names := info["layer_names"].(type) {
+	case []string:
+		candidate.Layers = names
+	case []interface{}:
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				candidate.Layers = append(candidate.Layers, s)
+			}
+		}
+	}
+	return candidate
+}
+
+// TreeCandidate builds a rename candidate for a tracked file using the metadata of the commit storing it
+func (lm *LogManager) TreeCandidate(path string, entry TreeEntry) RenameCandidate {
+	if entry.Commit != "" {
+		if stored, err := lm.GetCommitByHash(entry.Commit); err == nil {
+			return CandidateFromMetadata(path, entry.Hash, stored.Metadata[path])
+		}
+	}
+	return RenameCandidate{Path: path, Hash: entry.Hash}
+}
+
+// CommitRenames returns the renames made by a commit
+// Renames recorded at commit time are used when present; older commits are compared with their parent
+func (lm *LogManager) CommitRenames(c *Commit) map[string]string {
+	if len(c.Renames) > 0 {
+		return c.Renames
+	}
+	if c.ParentHash == "" || len(c.Tree) == 0 {
+		return nil
+	}
+	parent, err := lm.GetCommitByHash(c.ParentHash)
+	if err != nil || len(parent.Tree) == 0 {
+		return nil
+	}
+
+	var removed, added []RenameCandidate
+	for path, entry := range parent.Tree {
+		if _, ok := c.Tree[path]; !ok {
+			removed = append(removed, lm.TreeCandidate(path, entry))
+		}
+	}
+	for path, entry := range c.Tree {
+		if _, ok := parent.Tree[path]; !ok {
+			added = append(added, lm.TreeCandidate(path, entry))
+		}
+	}
+	if len(removed) == 0 || len(added) == 0 {
+		return nil
+	}
+	return DetectRenames(removed, added)
+}
@@ -0,0 +1,112 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectRenames(t *testing.T) {
+	cases := []struct {
+		name           string
+		removed, added []RenameCandidate
+		want           map[string]string
+	}{
+		{
+			name:    "identical content",
+			removed: []RenameCandidate{{Path: "logo.ai", Hash: "h1"}},
+			added:   []RenameCandidate{{Path: "brand/logo.ai", Hash: "h1"}},
+			want:    map[string]string{"brand/logo.ai": "logo.ai"},
+		},
+		{
+			name:    "identical copies pair in path order",
+			removed: []RenameCandidate{{Path: "b.psd", Hash: "h1"}, {Path: "a.psd", Hash: "h1"}},
+			added:   []RenameCandidate{{Path: "y.psd", Hash: "h1"}, {Path: "x.psd", Hash: "h1"}},
+			want:    map[string]string{"x.psd": "a.psd", "y.psd": "b.psd"},
+		},
+		{
+			name:    "similar layers",
+			removed: []RenameCandidate{{Path: "hero.psd", Hash: "h1", Dimensions: "1920x1080", Layers: []string{"Sky", "Logo", "Text"}}},
+			added:   []RenameCandidate{{Path: "hero-v2.psd", Hash: "h2", Dimensions: "1920x1080", Layers: []string{"Sky", "Logo", "Text"}}},
+			want:    map[string]string{"hero-v2.psd": "hero.psd"},
+		},
+		{
+			name: "most similar pair wins",
+			removed: []RenameCandidate{
+				{Path: "a.psd", Hash: "h1", Layers: []string{"Sky", "Logo", "Text", "Badge"}},
+				{Path: "b.psd", Hash: "h2", Layers: []string{"Sky", "Logo", "Text", "Shadow"}},
+			},
+			added: []RenameCandidate{{Path: "c.psd", Hash: "h3", Layers: []string{"Sky", "Logo", "Text", "Shadow"}}},
+			want:  map[string]string{"c.psd": "b.psd"},
+		},
+		{
+			name:    "too few shared layers",
+			removed: []RenameCandidate{{Path: "hero.psd", Hash: "h1", Layers: []string{"Sky", "Logo", "Text"}}},
+			added:   []RenameCandidate{{Path: "banner.psd", Hash: "h2", Layers: []string{"Sky", "Logo", "Price", "Badge"}}},
+			want:    map[string]string{},
+		},
+		{
+			name:    "different canvas size",
+			removed: []RenameCandidate{{Path: "hero.psd", Hash: "h1", Dimensions: "1920x1080", Layers: []string{"Sky"}}},
+			added:   []RenameCandidate{{Path: "icon.psd", Hash: "h2", Dimensions: "64x64", Layers: []string{"Sky"}}},
+			want:    map[string]string{},
+		},
+		{
+			name:    "different extension",
+			removed: []RenameCandidate{{Path: "hero.psd", Hash: "h1", Layers: []string{"Sky"}}},
+			added:   []RenameCandidate{{Path: "hero.ai", Hash: "h2", Layers: []string{"Sky"}}},
+			want:    map[string]string{},
+		},
+		{
+			name:    "unknown content",
+			removed: []RenameCandidate{{Path: "a.blend"}},
+			added:   []RenameCandidate{{Path: "b.blend"}},
+			want:    map[string]string{},
+		},
+	}
+	for _, c := range cases {
+		if got := DetectRenames(c.removed, c.added); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: renames = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCandidateFromMetadata(t *testing.T) {
+	cases := []struct {
+		meta interface{}
+		want RenameCandidate
+	}{
+		{nil, RenameCandidate{Path: "a.psd", Hash: "h"}},
+		{map[string]interface{}{"dimensions": "10x20", "layer_names": []string{"Sky"}}, RenameCandidate{Path: "a.psd", Hash: "h", Dimensions: "10x20", Layers: []string{"Sky"}}},
+		// Metadata read back from JSON holds interface slices
+		{map[string]interface{}{"layer_names": []interface{}{"Sky", 3, "Logo"}}, RenameCandidate{Path: "a.psd", Hash: "h", Layers: []string{"Sky", "Logo"}}},
+	}
+	for _, c := range cases {
+		if got := CandidateFromMetadata("a.psd", "h", c.meta); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: got %+v, want %+v", c.meta, got, c.want)
+		}
+	}
+}
+
+func TestCommitRenames(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("aaaa00000001", "Initial", nil, map[string]string{"logo.ai": "logo", "hero.psd": "hero"}, nil)
+	recorded := r.commit("aaaa00000002", "Move the hero", []string{"aaaa00000001"}, map[string]string{"logo.ai": "logo", "final.psd": "hero2"}, map[string]string{"final.psd": "hero.psd"})
+	detected := r.commit("aaaa00000003", "Move the logo", []string{"aaaa00000002"}, map[string]string{"brand/logo.ai": "logo", "final.psd": "hero2"}, nil)
+	unchanged := r.commit("aaaa00000004", "Edit the logo", []string{"aaaa00000003"}, map[string]string{"brand/logo.ai": "logo2", "final.psd": "hero2"}, nil)
+	root := r.commit("bbbb00000001", "Import", nil, map[string]string{"logo.ai": "logo"}, nil)
+
+	cases := []struct {
+		commit *Commit
+		want   map[string]string
+	}{
+		{recorded, map[string]string{"final.psd": "hero.psd"}},
+		{detected, map[string]string{"brand/logo.ai": "logo.ai"}},
+		{unchanged, nil},
+		{root, nil},
+	}
+	for _, c := range cases {
+		if got := r.lm.CommitRenames(c.commit); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: renames = %v, want %v", c.commit.Message, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dgit/internal/refs"
+)
+
+// RevisionRange is a parsed "from..to" expression; an omitted side stands for HEAD
+type RevisionRange struct {
+	From *Commit
+	To   *Commit
+}
+
+// dateLayouts are the absolute formats accepted inside @{...}
+var dateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// ResolveRevision resolves a revision expression to a commit
+//
+// Supported forms:
+//
+//	HEAD, @              the current commit
+//	<branch>, <tag>      the commit a branch or tag points to
+//	<hash prefix>        a commit hash of at least 4 characters
+//	v<N>, <N>            a version number on the current line of history
+//	<rev>~<N>            the N-th first-parent ancestor (~ alone means ~1)
+//	<rev>^, <rev>^<N>    the N-th parent; ^2 is the merged branch of a merge commit
+//	<rev>@{<date>}       the commit <rev> was at on a date, e.g. @{2026-10-01 14:00} or @{yesterday}
+//	<ref>@{<N>}          where HEAD or a branch was N reflog entries ago; @{N} alone uses the current branch
+func (lm *LogManager) ResolveRevision(expr string) (*Commit, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty revision")
+	}
+
+	// The base ends where the first ancestry operator starts
+	base, suffix := expr, ""
+	if i := strings.IndexAny(expr, "~^"); i >= 0 {
+		base, suffix = expr[:i], expr[i:]
+	}
+
+	commit, err := lm.resolveBase(base)
+	if err != nil {
+		return nil, err
+	}
+	return lm.applyAncestry(commit, suffix, expr)
+}
+
+// ResolveRange parses "from..to"; an omitted side defaults to HEAD
+// A single revision yields a range with only To set
+func (lm *LogManager) ResolveRange(expr string) (*RevisionRange, error) {
+	fromRef, toRef, isRange := strings.Cut(expr, "..")
+	if !isRange {
+		to, err := lm.ResolveRevision(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &RevisionRange{To: to}, nil
+	}
+	if strings.HasPrefix(toRef, ".") {
+		return nil, fmt.Errorf("invalid range '%s': symmetric differences (a...b) are not supported", expr)
+	}
+	if fromRef == "" {
+		fromRef = "HEAD"
+	}
+	if toRef == "" {
+		toRef = "HEAD"
+	}
+
+	from, err := lm.ResolveRevision(fromRef)
+	if err != nil {
+		return nil, err
+	}
+	to, err := lm.ResolveRevision(toRef)
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionRange{From: from, To: to}, nil
+}
+
+// RangeCommits lists the first-parent history of To that is not reachable from From (newest first)
+func (lm *LogManager) RangeCommits(r *RevisionRange) ([]*Commit, error) {
+	history, err := lm.GetHistoryFrom(r.To.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if r.From == nil {
+		return history, nil
+	}
+
+	excluded, err := lm.GetAncestors(r.From.Hash)
+	if err != nil {
+		return nil, err
+	}
+	var selected []*Commit
+	for _, commit := range history {
+		if !excluded[commit.Hash] {
+			selected = append(selected, commit)
+		}
+	}
+	return selected, nil
+}
+
+// resolveBase resolves a revision without ancestry operators
+func (lm *LogManager) resolveBase(base string) (*Commit, error) {
+	name, selector := base, ""
+	if i := strings.Index(base, "@{"); i >= 0 {
+		if !strings.HasSuffix(base, "}") {
+			return nil, fmt.Errorf("invalid revision '%s': missing '}'", base)
+		}
+		name, selector = base[:i], base[i+2:len(base)-1]
+	}
+
+	// A number selects a reflog entry rather than a date, and works for refs that no longer resolve
+	if n, err := strconv.Atoi(selector); err == nil {
+		if n < 0 {
+			return nil, fmt.Errorf("invalid revision '%s': reflog entries are numbered from 0", base)
+		}
+		return lm.reflogCommit(name, n, base)
+	}
+
+	commit, err := lm.resolveName(name)
+	if err != nil {
+		return nil, err
+	}
+	if selector == "" {
+		return commit, nil
+	}
+	if commit == nil {
+		return nil, fmt.Errorf("no commits yet")
+	}
+
+	at, err := parseRevisionDate(selector, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid revision '%s': %w", base, err)
+	}
+	return lm.commitAtDate(commit, at, base)
+}
+
+// reflogCommit returns the commit a reference pointed to n reflog entries ago; 0 is the latest entry
+func (lm *LogManager) reflogCommit(name string, n int, expr string) (*Commit, error) {
+	refManager := refs.NewRefManager(lm.DgitDir)
+	ref, err := refManager.ReflogRef(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := refManager.ReadReflog(ref)
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(entries) {
+		return nil, fmt.Errorf("revision '%s': the reflog of %s only has %d entries", expr, ref, len(entries))
+	}
+	hash := entries[len(entries)-1-n].NewHash
+	if hash == "" {
+		return nil, fmt.Errorf("revision '%s': %s pointed to no commit at that entry", expr, ref)
+	}
+	return lm.GetCommitByHash(hash)
+}
+
+// resolveName resolves HEAD, branches, tags, hash prefixes and version numbers
+func (lm *LogManager) resolveName(name string) (*Commit, error) {
+	if name == "" || name == "HEAD" || name == "@" {
+		head, err := lm.GetHeadCommit()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+		}
+		if head == nil {
+			return nil, fmt.Errorf("HEAD does not point to a commit yet")
+		}
+		return head, nil
+	}
+
+	refManager := refs.NewRefManager(lm.DgitDir)
+	if refManager.BranchExists(name) {
+		tip, err := refManager.ReadBranch(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read branch '%s': %w", name, err)
+		}
+		return lm.GetCommitByHash(tip)
+	}
+	if refManager.TagExists(name) {
+		tag, err := refManager.ReadTag(name)
+		if err != nil {
+			return nil, err
+		}
+		return lm.GetCommitByHash(tag.Target)
+	}
+
+	if isHashPrefix(name) {
+		commit, err := lm.GetCommitByHash(name)
+		if err == nil {
+			return commit, nil
+		}
+		// An ambiguous prefix is reported rather than falling back to a version number
+		if strings.Contains(err.Error(), "ambiguous") {
+			return nil, err
+		}
+	}
+
+	if version, err := strconv.Atoi(strings.TrimPrefix(name, "v")); err == nil {
+		commit, err := lm.GetCommit(version)
+		if err != nil {
+			return nil, err
+		}
+		return commit, nil
+	}
+
+	return nil, fmt.Errorf("unknown revision '%s': not a branch, tag, commit hash or version", name)
+}
+
+// applyAncestry walks ~N and ^N operators from a commit
+func (lm *LogManager) applyAncestry(commit *Commit, suffix, expr string) (*Commit, error) {
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		digits := 0
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+		n := 1
+		if digits > 0 {
+			n, _ = strconv.Atoi(suffix[:digits])
+			suffix = suffix[digits:]
+		}
+		if suffix != "" && suffix[0] != '~' && suffix[0] != '^' {
+			return nil, fmt.Errorf("invalid revision '%s'", expr)
+		}
+
+		var err error
+		if op == '~' {
+			for i := 0; i < n; i++ {
+				if commit, err = lm.nthParent(commit, 1, expr); err != nil {
+					return nil, err
+				}
+			}
+		} else if n > 0 {
+			if commit, err = lm.nthParent(commit, n, expr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return commit, nil
+}
+
+// nthParent returns the first parent (n=1) or a merge parent (n>=2) of a commit
+func (lm *LogManager) nthParent(commit *Commit, n int, expr string) (*Commit, error) {
+	parent := ""
+	if n <= len(commit.Parents) {
+		parent = commit.Parents[n-1]
+	}
+	if parent == "" {
+		if n == 1 {
+			return nil, fmt.Errorf("revision '%s' goes past the first commit (v%d %s has no parent)", expr, commit.Version, commit.Hash[:8])
+		}
+		return nil, fmt.Errorf("revision '%s': commit %s has no parent %d", expr, commit.Hash[:8], n)
+	}
+	return lm.GetCommitByHash(parent)
+}
+
+// commitAtDate returns the newest first-parent ancestor of tip created at or before the given time
+func (lm *LogManager) commitAtDate(tip *Commit, at time.Time, expr string) (*Commit, error) {
+	history, err := lm.GetHistoryFrom(tip.Hash)
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range history {
+		if !commit.Timestamp.After(at) {
+			return commit, nil
+		}
+	}
+	return nil, fmt.Errorf("revision '%s': no commit at or before %s", expr, at.Format("2006-01-02 15:04"))
+}
+
+// parseRevisionDate understands absolute dates and a few relative forms
+// Relative forms: now, today, yesterday, "<N> <unit>s ago" with minute, hour, day, week, month or year
+func parseRevisionDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "now":
+		return now, nil
+	case "today":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			// A bare date means the end of that day
+			if layout == "2006-01-02" {
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+
+	fields := strings.Fields(strings.ReplaceAll(strings.ToLower(value), ".", " "))
+	if len(fields) == 3 && fields[2] == "ago" {
+		n, err := strconv.Atoi(fields[0])
+		if err == nil && n >= 0 {
+			switch strings.TrimSuffix(fields[1], "s") {
+			case "minute":
+				return now.Add(-time.Duration(n) * time.Minute), nil
+			case "hour":
+				return now.Add(-time.Duration(n) * time.Hour), nil
+			case "day":
+				return now.AddDate(0, 0, -n), nil
+			case "week":
+				return now.AddDate(0, 0, -7*n), nil
+			case "month":
+				return now.AddDate(0, -n, 0), nil
+			case "year":
+				return now.AddDate(-n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date '%s'", value)
+}
+
+// isHashPrefix reports whether a name could be an abbreviated commit hash
+func isHashPrefix(name string) bool {
+	if len(name) < 4 || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
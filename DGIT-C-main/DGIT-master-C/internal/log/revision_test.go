@@ -0,0 +1,184 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dgit/internal/refs"
+)
+
+// mergedHistory is main with a merge of the logo branch, a tag and a reflog for main
+//
+//	a1 - a2 - a3 - c4 - a5   main
+//	       \       /
+//	        b3 ---         logo
+func mergedHistory(t *testing.T) *testRepo {
+	r := newTestRepo(t)
+	r.commit("aaaa00000001", "Initial layout", nil, nil, nil)                                  // 10:00
+	r.commit("aaaa00000002", "Hero banner", []string{"aaaa00000001"}, nil, nil)                // 11:00
+	r.commit("aaaa00000003", "Colors", []string{"aaaa00000002"}, nil, nil)                     // 12:00
+	r.commit("bbbb00000003", "New logo", []string{"aaaa00000002"}, nil, nil)                   // 13:00
+	r.commit("cccc00000004", "Merge logo", []string{"aaaa00000003", "bbbb00000003"}, nil, nil) // 14:00
+	r.commit("aaaa00000005", "Final touches", []string{"cccc00000004"}, nil, nil)              // 15:00
+	r.branch("main", "aaaa00000005")
+	r.branch("logo", "bbbb00000003")
+	if err := r.refs.CreateTag(&refs.Tag{Name: "client-review", Target: "aaaa00000002"}, false); err != nil {
+		t.Fatal(err)
+	}
+	previous := ""
+	for _, hash := range []string{"aaaa00000001", "aaaa00000002", "aaaa00000003", "cccc00000004", "aaaa00000005"} {
+		if err := r.refs.RecordRefUpdate(previous, hash, "commit", r.commits[hash].Message); err != nil {
+			t.Fatal(err)
+		}
+		previous = hash
+	}
+	return r
+}
+
+func TestResolveRevision(t *testing.T) {
+	r := mergedHistory(t)
+	cases := []struct {
+		expr string
+		want string // Hash, or the start of the error message
+	}{
+		{"HEAD", "aaaa00000005"},
+		{"@", "aaaa00000005"},
+		{"main", "aaaa00000005"},
+		{"logo", "bbbb00000003"},
+		{"client-review", "aaaa00000002"},
+
+		// Ancestry
+		{"HEAD~", "cccc00000004"},
+		{"HEAD~1", "cccc00000004"},
+		{"HEAD~2", "aaaa00000003"},
+		{"HEAD~~", "aaaa00000003"},
+		{"HEAD^", "cccc00000004"},
+		{"HEAD^^", "aaaa00000003"},
+		{"HEAD^0", "aaaa00000005"},
+		{"HEAD~1^2", "bbbb00000003"},
+		{"cccc^2~1", "aaaa00000002"},
+		{"main~4", "aaaa00000001"},
+		{"logo~1", "aaaa00000002"},
+		{"client-review~1", "aaaa00000001"},
+		{"main~5", "revision 'main~5' goes past the first commit"},
+		{"aaaa00000003^2", "revision 'aaaa00000003^2': commit aaaa0000 has no parent 2"},
+		{"HEAD~x", "invalid revision 'HEAD~x'"},
+
+		// Hashes and versions
+		{"aaaa00000003", "aaaa00000003"},
+		{"bbbb", "bbbb00000003"},
+		{"CCCC", "cccc00000004"},
+		{"aaaa", "short hash 'aaaa' is ambiguous"},
+		{"v3", "aaaa00000003"},
+		{"4", "cccc00000004"},
+		{"v9", "version v9 not found"},
+
+		// Dates
+		{"HEAD@{2025-03-01T12:30:00Z}", "aaaa00000003"},
+		{"main@{2025-03-01T14:00:00Z}", "cccc00000004"},
+		{"@{2025-03-01T13:59:00Z}", "aaaa00000003"},
+		{"logo@{2025-03-01T12:00:00Z}", "aaaa00000002"},
+		{"HEAD@{2025-03-01T09:00:00Z}", "revision 'HEAD@{2025-03-01T09:00:00Z}': no commit at or before"},
+		{"HEAD@{soon}", "invalid revision 'HEAD@{soon}': unrecognized date 'soon'"},
+
+		// Reflog entries
+		{"@{0}", "aaaa00000005"},
+		{"@{1}", "cccc00000004"},
+		{"HEAD@{4}", "aaaa00000001"},
+		{"main@{2}", "aaaa00000003"},
+		{"HEAD@{1}~1", "aaaa00000003"},
+		{"HEAD@{5}", "revision 'HEAD@{5}': the reflog of HEAD only has 5 entries"},
+		{"HEAD@{-1}", "invalid revision 'HEAD@{-1}': reflog entries are numbered from 0"},
+		{"logo@{0}", "revision 'logo@{0}': the reflog of refs/heads/logo only has 0 entries"},
+		{"HEAD@{1", "invalid revision 'HEAD@{1': missing '}'"},
+
+		{"", "empty revision"},
+		{"nope", "unknown revision 'nope'"},
+	}
+	for _, c := range cases {
+		commit, err := r.lm.ResolveRevision(c.expr)
+		switch {
+		case err != nil && !strings.HasPrefix(err.Error(), c.want):
+			t.Errorf("%q: %v; want %s", c.expr, err, c.want)
+		case err == nil && commit.Hash != c.want:
+			t.Errorf("%q: got %s, want %s", c.expr, commit.Hash, c.want)
+		}
+	}
+}
+
+func TestResolveRangeCommits(t *testing.T) {
+	r := mergedHistory(t)
+	cases := []struct {
+		expr string
+		want []string // Hashes newest first, or nil for an error
+	}{
+		{"logo..main", []string{"aaaa00000005", "cccc00000004", "aaaa00000003"}},
+		{"main..logo", []string{}},
+		{"client-review..", []string{"aaaa00000005", "cccc00000004", "aaaa00000003"}},
+		{"..logo", []string{}},
+		{"HEAD~2..HEAD", []string{"aaaa00000005", "cccc00000004"}},
+		{"logo", []string{"bbbb00000003", "aaaa00000002", "aaaa00000001"}},
+		{"logo...main", nil},
+		{"logo..nope", nil},
+	}
+	for _, c := range cases {
+		rng, err := r.lm.ResolveRange(c.expr)
+		if err != nil {
+			if c.want != nil {
+				t.Errorf("%q: %v", c.expr, err)
+			}
+			continue
+		}
+		commits, err := r.lm.RangeCommits(rng)
+		if err != nil {
+			t.Errorf("%q: %v", c.expr, err)
+			continue
+		}
+		hashes := []string{}
+		for _, commit := range commits {
+			hashes = append(hashes, commit.Hash)
+		}
+		if c.want == nil || !reflect.DeepEqual(hashes, c.want) {
+			t.Errorf("%q: got %v, want %v", c.expr, hashes, c.want)
+		}
+	}
+}
+
+func TestParseRevisionDate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Time
+	}{
+		{"now", now},
+		{"today", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"Yesterday", time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)},
+		{"2026-10-01", time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+		{"2026-10-01 14:00", time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC)},
+		{"2026-10-01T14:00:30", time.Date(2026, 10, 1, 14, 0, 30, 0, time.UTC)},
+		{"2026-10-01T14:00:00+02:00", time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)},
+		{"5 minutes ago", now.Add(-5 * time.Minute)},
+		{"1 hour ago", now.Add(-time.Hour)},
+		{"2.days.ago", time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)},
+		{"3 weeks ago", time.Date(2026, 9, 27, 15, 30, 0, 0, time.UTC)},
+		{"1 month ago", time.Date(2026, 9, 18, 15, 30, 0, 0, time.UTC)},
+		{"2 years ago", time.Date(2024, 10, 18, 15, 30, 0, 0, time.UTC)},
+		{"2 fortnights ago", time.Time{}},
+		{"-1 days ago", time.Time{}},
+		{"next week", time.Time{}},
+	}
+	for _, c := range cases {
+		got, err := parseRevisionDate(c.value, now)
+		if c.want.IsZero() {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.value, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(c.want) {
+			t.Errorf("%q: got %v, %v; want %v", c.value, got, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dgit/internal/log"
+	"dgit/internal/restore"
+)
+
+// Resolution values recorded for conflicted files
+const (
+	ResolutionOurs   = "ours"
+	ResolutionTheirs = "theirs"
+)
+
+// FileConflict describes a file changed on both sides of a merge
+type FileConflict struct {
+	Path       string `json:"path"`
+	Reason     string `json:"reason"`
+	Resolution string `json:"resolution,omitempty"` // "ours" or "theirs" once resolved
+}
+
+// LayerMerge describes a PSD whose sides changed different layers and were combined layer by layer
+type LayerMerge struct {
+	Path          string   `json:"path"`
+	OursChanged   []string `json:"ours_changed"`
+	TheirsChanged []string `json:"theirs_changed"`
+	Content       []byte   `json:"-"`
+}
+
+// MergeResult is the outcome of a three-way merge of commit trees
+type MergeResult struct {
+	Tree        map[string]log.TreeEntry // Merged tree; conflicted files keep our entry until resolved
+	FromTheirs  []string                 // Files taken unchanged from their side
+	Removed     []string                 // Files removed on their side and unchanged on ours
+	LayerMerged []*LayerMerge
+	Conflicts   []*FileConflict
+}
+
+// MergeState records an unfinished merge between 'dgit merge' and 'dgit merge --continue'
+type MergeState struct {
+	Ours        string                   `json:"ours"`
+	Theirs      string                   `json:"theirs"`
+	TheirsName  string                   `json:"theirs_name"`
+	Base        string                   `json:"base,omitempty"`
+	Message     string                   `json:"message"`
+	Tree        map[string]log.TreeEntry `json:"tree"`
+	LayerMerged []string                 `json:"layer_merged,omitempty"` // Files whose merged content lives in the working tree
+	Conflicts   []*FileConflict          `json:"conflicts,omitempty"`
+	StartedAt   time.Time                `json:"started_at"`
+}
+
+// Unresolved returns the conflicted files that have no resolution yet
+func (s *MergeState) Unresolved() []string {
+	var paths []string
+	for _, conflict := range s.Conflicts {
+		if conflict.Resolution == "" {
+			paths = append(paths, conflict.Path)
+		}
+	}
+	return paths
+}
+
+// Conflict returns the conflict recorded for a file, or nil
+func (s *MergeState) Conflict(path string) *FileConflict {
+	for _, conflict := range s.Conflicts {
+		if conflict.Path == path {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// MergeManager performs three-way merges and tracks merges in progress
+type MergeManager struct {
+	DgitDir   string
+	StateFile string
+
+	restoreManager *restore.RestoreManager
+}
+
+// NewMergeManager creates a new merge manager
+func NewMergeManager(dgitDir string) *MergeManager {
+	return &MergeManager{
+		DgitDir:        dgitDir,
+		StateFile:      filepath.Join(dgitDir, "MERGE_STATE"),
+		restoreManager: restore.NewRestoreManager(dgitDir),
+	}
+}
+
+// MergeTrees combines the changes made on both sides since their merge base
+// Files changed on only one side are taken automatically; files changed on both
+// sides are conflicts unless they are PSDs whose sides touched different layers.
+// A nil base means the histories are unrelated and every file counts as added.
+func (mm *MergeManager) MergeTrees(base, ours, theirs *log.Commit) (*MergeResult, error) {
+	baseTree := map[string]log.TreeEntry{}
+	if base != nil {
+		baseTree = mm.hashedTree(base)
+	}
+	oursTree := mm.hashedTree(ours)
+	theirsTree := mm.hashedTree(theirs)
+
+	result := &MergeResult{Tree: make(map[string]log.TreeEntry)}
+	for path, entry := range oursTree {
+		result.Tree[path] = entry
+	}
+
+	for _, path := range unionPaths(baseTree, oursTree, theirsTree) {
+		baseEntry, inBase := baseTree[path]
+		oursEntry, inOurs := oursTree[path]
+		theirsEntry, inTheirs := theirsTree[path]
+
+		oursChanged := !sameContent(baseEntry, inBase, oursEntry, inOurs)
+		theirsChanged := !sameContent(baseEntry, inBase, theirsEntry, inTheirs)
+
+		switch {
+		case !theirsChanged || sameContent(oursEntry, inOurs, theirsEntry, inTheirs):
+			// Nothing to take, or both sides made the same change
+		case !oursChanged:
+			if inTheirs {
+				result.Tree[path] = theirsEntry
+				result.FromTheirs = append(result.FromTheirs, path)
+			} else {
+				delete(result.Tree, path)
+				result.Removed = append(result.Removed, path)
+			}
+		case !inOurs || !inTheirs:
+			reason := "removed on their side, modified on ours"
+			if !inOurs {
+				reason = "removed on our side, modified on theirs"
+			}
+			result.Conflicts = append(result.Conflicts, &FileConflict{Path: path, Reason: reason})
+		case strings.ToLower(filepath.Ext(path)) == ".psd" && inBase:
+			layerMerge, err := mm.mergePSD(path, base, ours, theirs)
+			if err != nil {
+				result.Conflicts = append(result.Conflicts, &FileConflict{Path: path, Reason: err.Error()})
+				continue
+			}
+			result.LayerMerged = append(result.LayerMerged, layerMerge)
+		default:
+			reason := "modified on both sides"
+			if !inBase {
+				reason = "added on both sides with different content"
+			}
+			result.Conflicts = append(result.Conflicts, &FileConflict{Path: path, Reason: reason})
+		}
+	}
+
+	return result, nil
+}
+
+// hashedTree returns a commit's tree with content hashes filled in for legacy entries
+func (mm *MergeManager) hashedTree(commit *log.Commit) map[string]log.TreeEntry {
+	tree := make(map[string]log.TreeEntry)
+	for path, entry := range commit.TrackedFiles() {
+		if entry.Hash == "" {
+			if data, err := mm.restoreManager.ReadCommitFile(commit, path); err == nil {
+				entry.Hash = contentHash(data)
+			}
+		}
+		tree[path] = entry
+	}
+	return tree
+}
+
+// ReadFile returns the content of a file as recorded in a commit
+func (mm *MergeManager) ReadFile(commit *log.Commit, path string) ([]byte, error) {
+	return mm.restoreManager.ReadCommitFile(commit, path)
+}
+
+// InProgress reports whether a merge is waiting for conflict resolution
+func (mm *MergeManager) InProgress() bool {
+	_, err := os.Stat(mm.StateFile)
+	return err == nil
+}
+
+// LoadState reads the state of the merge in progress
+func (mm *MergeManager) LoadState() (*MergeState, error) {
+	data, err := os.ReadFile(mm.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no merge in progress")
+		}
+		return nil, fmt.Errorf("failed to read merge state: %w", err)
+	}
+
+	var state MergeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse merge state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveState records the merge in progress
+func (mm *MergeManager) SaveState(state *MergeState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal merge state: %w", err)
+	}
+	return os.WriteFile(mm.StateFile, data, 0644)
+}
+
+// ClearState ends the merge in progress
+func (mm *MergeManager) ClearState() error {
+	if err := os.Remove(mm.StateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear merge state: %w", err)
+	}
+	return nil
+}
+
+// sameContent compares two optional tree entries by content
+func sameContent(a log.TreeEntry, aExists bool, b log.TreeEntry, bExists bool) bool {
+	if !aExists || !bExists {
+		return aExists == bExists
+	}
+	if a.Hash != "" && b.Hash != "" {
+		return a.Hash == b.Hash
+	}
+	return a.Commit == b.Commit && a.Size == b.Size
+}
+
+// unionPaths returns every path present in any of the trees, sorted
+func unionPaths(trees ...map[string]log.TreeEntry) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, tree := range trees {
+		for path := range tree {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,234 @@
+package merge
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"dgit/internal/log"
+)
+
+// treeCommit returns a commit whose tree maps paths to content hashes
+func treeCommit(hash string, files map[string]string) *log.Commit {
+	tree := make(map[string]log.TreeEntry)
+	for path, content := range files {
+		tree[path] = log.TreeEntry{Hash: content, Size: int64(len(content)), Commit: hash}
+	}
+	return &log.Commit{Hash: hash, Tree: tree}
+}
+
+func TestMergeTrees(t *testing.T) {
+	base := map[string]string{"logo.ai": "logo1", "colors.ase": "colors1", "cover.sketch": "cover1", "notes.txt": "notes1"}
+	cases := []struct {
+		name       string
+		base       map[string]string // nil for unrelated histories
+		ours       map[string]string
+		theirs     map[string]string
+		tree       map[string]string
+		fromTheirs []string
+		removed    []string
+		conflicts  map[string]string // Path to reason, or "" for any reason
+	}{
+		{
+			name:       "changes on one side each",
+			base:       base,
+			ours:       map[string]string{"logo.ai": "logo2", "colors.ase": "colors1", "cover.sketch": "cover1", "notes.txt": "notes1"},
+			theirs:     map[string]string{"logo.ai": "logo1", "colors.ase": "colors2", "cover.sketch": "cover1", "notes.txt": "notes1", "icon.fig": "icon1"},
+			tree:       map[string]string{"logo.ai": "logo2", "colors.ase": "colors2", "cover.sketch": "cover1", "notes.txt": "notes1", "icon.fig": "icon1"},
+			fromTheirs: []string{"colors.ase", "icon.fig"},
+		},
+		{
+			name:    "removal on their side",
+			base:    base,
+			ours:    map[string]string{"logo.ai": "logo2", "colors.ase": "colors1", "cover.sketch": "cover1", "notes.txt": "notes1"},
+			theirs:  map[string]string{"logo.ai": "logo1", "colors.ase": "colors1", "cover.sketch": "cover1"},
+			tree:    map[string]string{"logo.ai": "logo2", "colors.ase": "colors1", "cover.sketch": "cover1"},
+			removed: []string{"notes.txt"},
+		},
+		{
+			name:   "the same change on both sides",
+			base:   base,
+			ours:   map[string]string{"logo.ai": "logo2", "colors.ase": "colors1", "cover.sketch": "cover1"},
+			theirs: map[string]string{"logo.ai": "logo2", "colors.ase": "colors1", "cover.sketch": "cover1"},
+			tree:   map[string]string{"logo.ai": "logo2", "colors.ase": "colors1", "cover.sketch": "cover1"},
+		},
+		{
+			name:   "conflicts keep our entry",
+			base:   base,
+			ours:   map[string]string{"logo.ai": "logo2", "colors.ase": "colors2", "notes.txt": "notes1"},
+			theirs: map[string]string{"logo.ai": "logo3", "cover.sketch": "cover2", "notes.txt": "notes1"},
+			tree:   map[string]string{"logo.ai": "logo2", "colors.ase": "colors2", "notes.txt": "notes1"},
+			conflicts: map[string]string{
+				"logo.ai":      "modified on both sides",
+				"colors.ase":   "removed on their side, modified on ours",
+				"cover.sketch": "removed on our side, modified on theirs",
+			},
+		},
+		{
+			name:       "unrelated histories",
+			ours:       map[string]string{"logo.ai": "logo1", "mine.txt": "mine"},
+			theirs:     map[string]string{"logo.ai": "logo9", "theirs.txt": "theirs"},
+			tree:       map[string]string{"logo.ai": "logo1", "mine.txt": "mine", "theirs.txt": "theirs"},
+			fromTheirs: []string{"theirs.txt"},
+			conflicts:  map[string]string{"logo.ai": "added on both sides with different content"},
+		},
+		{
+			name:      "PSDs without readable layers conflict",
+			base:      map[string]string{"hero.psd": "hero1"},
+			ours:      map[string]string{"hero.psd": "hero2"},
+			theirs:    map[string]string{"hero.psd": "hero3"},
+			tree:      map[string]string{"hero.psd": "hero2"},
+			conflicts: map[string]string{"hero.psd": ""},
+		},
+	}
+	for _, c := range cases {
+		mm := NewMergeManager(filepath.Join(t.TempDir(), ".dgit"))
+		var baseCommit *log.Commit
+		if c.base != nil {
+			baseCommit = treeCommit("base", c.base)
+		}
+		result, err := mm.MergeTrees(baseCommit, treeCommit("ours", c.ours), treeCommit("theirs", c.theirs))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		tree := make(map[string]string)
+		for path, entry := range result.Tree {
+			tree[path] = entry.Hash
+		}
+		if !reflect.DeepEqual(tree, c.tree) {
+			t.Errorf("%s: tree = %v, want %v", c.name, tree, c.tree)
+		}
+		if !reflect.DeepEqual(result.FromTheirs, c.fromTheirs) || !reflect.DeepEqual(result.Removed, c.removed) {
+			t.Errorf("%s: from theirs %v, removed %v; want %v, %v", c.name, result.FromTheirs, result.Removed, c.fromTheirs, c.removed)
+		}
+		conflicts := make(map[string]string)
+		for _, conflict := range result.Conflicts {
+			conflicts[conflict.Path] = conflict.Reason
+			if c.conflicts[conflict.Path] == "" {
+				conflicts[conflict.Path] = ""
+			}
+		}
+		if len(conflicts) > 0 || len(c.conflicts) > 0 {
+			if !reflect.DeepEqual(conflicts, c.conflicts) {
+				t.Errorf("%s: conflicts = %v, want %v", c.name, conflicts, c.conflicts)
+			}
+		}
+	}
+}
+
+func TestMergeState(t *testing.T) {
+	mm := NewMergeManager(t.TempDir())
+	if mm.InProgress() {
+		t.Fatal("merge in progress in a new repository")
+	}
+	if _, err := mm.LoadState(); err == nil {
+		t.Error("expected an error loading a missing merge state")
+	}
+
+	state := &MergeState{
+		Ours:   "c2",
+		Theirs: "b2",
+		Conflicts: []*FileConflict{
+			{Path: "logo.ai", Reason: "modified on both sides"},
+			{Path: "cover.sketch", Reason: "modified on both sides", Resolution: ResolutionTheirs},
+			{Path: "hero.psd", Reason: "modified on both sides"},
+		},
+	}
+	if err := mm.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := mm.LoadState()
+	if err != nil || !mm.InProgress() {
+		t.Fatalf("loaded %v, %v", loaded, err)
+	}
+	if want := []string{"logo.ai", "hero.psd"}; !reflect.DeepEqual(loaded.Unresolved(), want) {
+		t.Errorf("unresolved = %v, want %v", loaded.Unresolved(), want)
+	}
+	if conflict := loaded.Conflict("cover.sketch"); conflict == nil || conflict.Resolution != ResolutionTheirs {
+		t.Errorf("cover.sketch conflict = %+v", conflict)
+	}
+	if loaded.Conflict("notes.txt") != nil {
+		t.Error("conflict found for a clean file")
+	}
+
+	if err := mm.ClearState(); err != nil || mm.InProgress() {
+		t.Errorf("clear: %v, in progress %v", err, mm.InProgress())
+	}
+	if err := mm.ClearState(); err != nil {
+		t.Errorf("clearing twice: %v", err)
+	}
+}
+
+// TestMergeTreesRevertsCommits covers 'dgit revert', which merges from the reverted
+// commit towards its parent on top of HEAD
+func TestMergeTreesRevertsCommits(t *testing.T) {
+	parent := map[string]string{"logo.ai": "logo1", "notes.txt": "notes1"}
+	cases := []struct {
+		name       string
+		target     map[string]string
+		head       map[string]string
+		tree       map[string]string
+		fromTheirs []string
+		removed    []string
+		conflicts  []string
+	}{
+		{
+			name:       "modified file returns to its previous content",
+			target:     map[string]string{"logo.ai": "logo2", "notes.txt": "notes1"},
+			head:       map[string]string{"logo.ai": "logo2", "notes.txt": "notes2"},
+			tree:       map[string]string{"logo.ai": "logo1", "notes.txt": "notes2"},
+			fromTheirs: []string{"logo.ai"},
+		},
+		{
+			name:    "added file is removed",
+			target:  map[string]string{"logo.ai": "logo1", "notes.txt": "notes1", "icon.fig": "icon1"},
+			head:    map[string]string{"logo.ai": "logo1", "notes.txt": "notes1", "icon.fig": "icon1"},
+			tree:    map[string]string{"logo.ai": "logo1", "notes.txt": "notes1"},
+			removed: []string{"icon.fig"},
+		},
+		{
+			name:       "removed file comes back",
+			target:     map[string]string{"logo.ai": "logo1"},
+			head:       map[string]string{"logo.ai": "logo2"},
+			tree:       map[string]string{"logo.ai": "logo2", "notes.txt": "notes1"},
+			fromTheirs: []string{"notes.txt"},
+		},
+		{
+			name:      "file changed again since the commit",
+			target:    map[string]string{"logo.ai": "logo2", "notes.txt": "notes1"},
+			head:      map[string]string{"logo.ai": "logo3", "notes.txt": "notes1"},
+			tree:      map[string]string{"logo.ai": "logo3", "notes.txt": "notes1"},
+			conflicts: []string{"logo.ai"},
+		},
+		{
+			name:   "changes no longer present",
+			target: map[string]string{"logo.ai": "logo2", "notes.txt": "notes1"},
+			head:   map[string]string{"logo.ai": "logo1", "notes.txt": "notes1"},
+			tree:   map[string]string{"logo.ai": "logo1", "notes.txt": "notes1"},
+		},
+	}
+	for _, c := range cases {
+		mm := NewMergeManager(filepath.Join(t.TempDir(), ".dgit"))
+		result, err := mm.MergeTrees(treeCommit("target", c.target), treeCommit("head", c.head), treeCommit("parent", parent))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		tree := make(map[string]string)
+		for path, entry := range result.Tree {
+			tree[path] = entry.Hash
+		}
+		var conflicts []string
+		for _, conflict := range result.Conflicts {
+			conflicts = append(conflicts, conflict.Path)
+		}
+		if !reflect.DeepEqual(tree, c.tree) {
+			t.Errorf("%s: tree = %v, want %v", c.name, tree, c.tree)
+		}
+		if !reflect.DeepEqual(result.FromTheirs, c.fromTheirs) || !reflect.DeepEqual(result.Removed, c.removed) || !reflect.DeepEqual(conflicts, c.conflicts) {
+			t.Errorf("%s: restored %v, removed %v, conflicts %v; want %v, %v, %v",
+				c.name, result.FromTheirs, result.Removed, conflicts, c.fromTheirs, c.removed, c.conflicts)
+		}
+	}
+}
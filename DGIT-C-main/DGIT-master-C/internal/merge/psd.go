@@ -0,0 +1,194 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dgit/internal/log"
+	"dgit/internal/scanner/photoshop"
+)
+
+// psdVersion holds one side of a PSD merge
+type psdVersion struct {
+	info     *photoshop.DetailedPSDInfo
+	sections *photoshop.LayerSections
+}
+
+// mergePSD combines a PSD changed on both sides when each side changed different layers
+// Layers are matched by layer ID, or by name when a version has none (see photoshop.LayerKeys). The merged document keeps our header, resources and
+// composite preview; Photoshop rebuilds the composite from the layers when it is saved.
+func (mm *MergeManager) mergePSD(path string, base, ours, theirs *log.Commit) (*LayerMerge, error) {
+	tempDir, err := os.MkdirTemp("", "dgit-merge-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var versions [3]*psdVersion
+	for i, commit := range []*log.Commit{base, ours, theirs} {
+		version, err := mm.loadPSDVersion(tempDir, commit, path, i)
+		if err != nil {
+			return nil, fmt.Errorf("modified on both sides (layer merge unavailable: %v)", err)
+		}
+		versions[i] = version
+	}
+	baseVersion, oursVersion, theirsVersion := versions[0], versions[1], versions[2]
+
+	// Layers can only be combined when the canvas is the same on every side
+	baseCanvas := baseVersion.info.CanvasInfo
+	for _, version := range versions[1:] {
+		canvas := version.info.CanvasInfo
+		if canvas.Width != baseCanvas.Width || canvas.Height != baseCanvas.Height ||
+			canvas.ColorMode != baseCanvas.ColorMode || canvas.BitDepth != baseCanvas.BitDepth {
+			return nil, fmt.Errorf("modified on both sides (canvas changed: %dx%d -> %dx%d)",
+				baseCanvas.Width, baseCanvas.Height, canvas.Width, canvas.Height)
+		}
+	}
+
+	byID := photoshop.HasLayerIDs(baseVersion.sections.Layers, oursVersion.sections.Layers, theirsVersion.sections.Layers)
+	baseKeys := photoshop.LayerKeys(baseVersion.sections.Layers, byID)
+	oursKeys := photoshop.LayerKeys(oursVersion.sections.Layers, byID)
+	theirsKeys := photoshop.LayerKeys(theirsVersion.sections.Layers, byID)
+	baseLayers := indexLayers(baseVersion.sections.Layers, baseKeys)
+	oursLayers := indexLayers(oursVersion.sections.Layers, oursKeys)
+	theirsLayers := indexLayers(theirsVersion.sections.Layers, theirsKeys)
+
+	merge := &LayerMerge{Path: path}
+	var conflicting []string
+	merged := make(map[string]*photoshop.LayerBlock)
+	for _, key := range keyUnion(baseKeys, oursKeys, theirsKeys) {
+		baseLayer, oursLayer, theirsLayer := baseLayers[key], oursLayers[key], theirsLayers[key]
+		oursChanged := layerHash(oursLayer) != layerHash(baseLayer)
+		theirsChanged := layerHash(theirsLayer) != layerHash(baseLayer)
+		name := layerName(oursLayer, theirsLayer, baseLayer)
+
+		if oursChanged {
+			merge.OursChanged = append(merge.OursChanged, name)
+		}
+		if theirsChanged {
+			merge.TheirsChanged = append(merge.TheirsChanged, name)
+		}
+
+		switch {
+		case oursChanged && theirsChanged && layerHash(oursLayer) != layerHash(theirsLayer):
+			conflicting = append(conflicting, name)
+		case theirsChanged:
+			merged[key] = theirsLayer
+		default:
+			merged[key] = oursLayer
+		}
+	}
+
+	if len(conflicting) > 0 {
+		return nil, fmt.Errorf("layers changed on both sides: %s", strings.Join(conflicting, ", "))
+	}
+
+	// Keep our stacking order and slot their new layers in above the layer they followed
+	order := append([]string{}, oursKeys...)
+	for i, key := range theirsKeys {
+		if oursLayers[key] != nil || baseLayers[key] != nil {
+			continue
+		}
+		position := 0
+		for j := i - 1; j >= 0; j-- {
+			if index := indexOf(order, theirsKeys[j]); index >= 0 {
+				position = index + 1
+				break
+			}
+		}
+		order = append(order[:position], append([]string{key}, order[position:]...)...)
+	}
+
+	result := *oursVersion.sections
+	result.Layers = nil
+	for _, key := range order {
+		if layer := merged[key]; layer != nil {
+			result.Layers = append(result.Layers, *layer)
+		}
+	}
+	merge.Content = result.Bytes()
+	return merge, nil
+}
+
+// loadPSDVersion extracts a PSD from a commit and parses its layers
+func (mm *MergeManager) loadPSDVersion(tempDir string, commit *log.Commit, path string, index int) (*psdVersion, error) {
+	data, err := mm.restoreManager.ReadCommitFile(commit, path)
+	if err != nil {
+		return nil, err
+	}
+
+	tempPath := filepath.Join(tempDir, fmt.Sprintf("%d.psd", index))
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write temp PSD: %w", err)
+	}
+	info, err := photoshop.GetDetailedPSDInfo(tempPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := photoshop.ReadLayerSections(data)
+	if err != nil {
+		return nil, err
+	}
+	return &psdVersion{info: info, sections: sections}, nil
+}
+
+// indexLayers maps layer keys to layers
+func indexLayers(layers []photoshop.LayerBlock, keys []string) map[string]*photoshop.LayerBlock {
+	index := make(map[string]*photoshop.LayerBlock, len(layers))
+	for i := range layers {
+		index[keys[i]] = &layers[i]
+	}
+	return index
+}
+
+// keyUnion returns every layer key of base, ours and theirs, in first-seen order
+func keyUnion(keyLists ...[]string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, list := range keyLists {
+		for _, key := range list {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// layerName returns the name of the first present layer, for reporting
+func layerName(layers ...*photoshop.LayerBlock) string {
+	for _, layer := range layers {
+		if layer != nil {
+			return layer.Name
+		}
+	}
+	return ""
+}
+
+// layerHash returns a layer's content hash, or "" for a missing layer
+func layerHash(layer *photoshop.LayerBlock) string {
+	if layer == nil {
+		return ""
+	}
+	return layer.ContentHash()
+}
+
+// indexOf returns the position of name in names, or -1
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// contentHash returns the SHA256 hash used for tree entries
+func contentHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
@@ -0,0 +1,35 @@
+package merge
+
+import (
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/photoshop"
+)
+
+// nestedGroups lists, bottom to top, two groups with the same name and layer names,
+// the second holding a nested group
+var nestedGroups = []photoshop.LayerBlock{
+	{Name: "Background"},
+	{Name: "</Layer group>", Section: photoshop.SectionEnd},
+	{Name: "Shadow"},
+	{Name: "Card", Section: photoshop.SectionOpen},
+	{Name: "</Layer group>", Section: photoshop.SectionEnd},
+	{Name: "</Layer group>", Section: photoshop.SectionEnd},
+	{Name: "Shadow"},
+	{Name: "Icon", Section: photoshop.SectionClosed},
+	{Name: "Card", Section: photoshop.SectionOpen},
+}
+
+func TestIndexLayersKeepsDuplicateNames(t *testing.T) {
+	index := indexLayers(nestedGroups, photoshop.LayerKeys(nestedGroups, false))
+	if len(index) != len(nestedGroups) {
+		t.Errorf("%d of %d layers indexed", len(index), len(nestedGroups))
+	}
+	if index["name:Shadow#1"] != &nestedGroups[6] {
+		t.Error("second Shadow layer is not indexed by its occurrence")
+	}
+	if got := keyUnion([]string{"a", "b"}, []string{"b", "c"}); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("union = %v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package refs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReflogEntry records a single change of a reference
+type ReflogEntry struct {
+	OldHash   string    `json:"old"`
+	NewHash   string    `json:"new"`
+	Operation string    `json:"operation"`         // "commit", "amend", "reword", ...
+	Message   string    `json:"message,omitempty"` // Operation details, e.g. the original commit message
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecordRefUpdate appends an entry to the reflog of HEAD and of the current branch
+func (rm *RefManager) RecordRefUpdate(oldHash, newHash, operation, message string) error {
+	entry := newReflogEntry(oldHash, newHash, operation, message)
+	if err := rm.AppendReflog("HEAD", entry); err != nil {
+		return err
+	}
+	branch, err := rm.CurrentBranch()
+	if err != nil || branch == "" {
+		return err
+	}
+	return rm.AppendReflog(BranchRef(branch), entry)
+}
+
+// RecordHeadMove appends an entry to the reflog of HEAD only, for checkouts that leave branches untouched
+func (rm *RefManager) RecordHeadMove(oldHash, newHash, operation, message string) error {
+	return rm.AppendReflog("HEAD", newReflogEntry(oldHash, newHash, operation, message))
+}
+
+// RecordBranchUpdate appends an entry to the reflog of a branch that HEAD does not point to
+func (rm *RefManager) RecordBranchUpdate(branch, oldHash, newHash, operation, message string) error {
+	return rm.AppendReflog(BranchRef(branch), newReflogEntry(oldHash, newHash, operation, message))
+}
+
+// BranchRef returns the full reference name of a branch, as used for its reflog
+func BranchRef(branch string) string {
+	return "refs/heads/" + branch
+}
+
+// ReflogRef maps a name given on the command line to the reference whose log it means
+// "HEAD" (or "@") is HEAD's own log; an empty name is the current branch, or HEAD when detached
+func (rm *RefManager) ReflogRef(name string) (string, error) {
+	switch name {
+	case "HEAD", "@":
+		return "HEAD", nil
+	case "":
+		branch, err := rm.CurrentBranch()
+		if err != nil {
+			return "", err
+		}
+		if branch == "" {
+			return "HEAD", nil
+		}
+		return BranchRef(branch), nil
+	}
+	if strings.HasPrefix(name, "refs/heads/") {
+		name = strings.TrimPrefix(name, "refs/heads/")
+	}
+	if !rm.BranchExists(name) {
+		if _, err := os.Stat(rm.reflogPath(BranchRef(name))); err != nil {
+			return "", fmt.Errorf("no reflog for '%s': not HEAD or a branch", name)
+		}
+	}
+	return BranchRef(name), nil
+}
+
+// AppendReflog appends an entry to the log of a reference such as "HEAD" or "refs/heads/main"
+func (rm *RefManager) AppendReflog(ref string, entry ReflogEntry) error {
+	path := rm.reflogPath(ref)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reflog directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reflog entry: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open reflog for %s: %w", ref, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write reflog for %s: %w", ref, err)
+	}
+	return nil
+}
+
+// ReadReflog returns the entries of a reference log, oldest first
+func (rm *RefManager) ReadReflog(ref string) ([]ReflogEntry, error) {
+	file, err := os.Open(rm.reflogPath(ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open reflog for %s: %w", ref, err)
+	}
+	defer file.Close()
+
+	var entries []ReflogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry ReflogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reflog for %s: %w", ref, err)
+	}
+	return entries, nil
+}
+
+// DeleteReflog removes the log of a reference, such as a deleted branch
+func (rm *RefManager) DeleteReflog(ref string) error {
+	path := rm.reflogPath(ref)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete reflog for %s: %w", ref, err)
+	}
+	for dir := filepath.Dir(path); dir != rm.LogsDir && strings.HasPrefix(dir, rm.LogsDir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// newReflogEntry creates a reflog entry stamped with the current time
+func newReflogEntry(oldHash, newHash, operation, message string) ReflogEntry {
+	return ReflogEntry{
+		OldHash:   oldHash,
+		NewHash:   newHash,
+		Operation: operation,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+// reflogPath returns the file holding a reference's log
+func (rm *RefManager) reflogPath(ref string) string {
+	return filepath.Join(rm.LogsDir, filepath.FromSlash(ref))
+}
@@ -0,0 +1,109 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// reflogHashes lists the new hashes of a reference log, oldest first
+func reflogHashes(t *testing.T, rm *RefManager, ref string) []string {
+	t.Helper()
+	entries, err := rm.ReadReflog(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := []string{}
+	for _, entry := range entries {
+		hashes = append(hashes, entry.NewHash)
+	}
+	return hashes
+}
+
+func TestRecordRefUpdates(t *testing.T) {
+	rm := newTestRefs(t)
+	rm.SetHeadToBranch(DefaultBranch)
+	rm.WriteBranch("logo", "l1")
+
+	steps := []func() error{
+		func() error { return rm.RecordRefUpdate("", "c1", "commit (initial)", "Initial") },
+		func() error { return rm.RecordRefUpdate("c1", "c2", "commit", "Hero") },
+		func() error { return rm.RecordBranchUpdate("logo", "l1", "l2", "branch", "Moved by merge") },
+		func() error { rm.DetachHead("c1"); return rm.RecordHeadMove("c2", "c1", "checkout", "moving to v1") },
+		func() error { return rm.RecordRefUpdate("c1", "d1", "commit", "Detached work") },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	cases := map[string][]string{
+		"HEAD":             {"c1", "c2", "c1", "d1"},
+		"refs/heads/main":  {"c1", "c2"},
+		"refs/heads/logo":  {"l2"},
+		"refs/heads/other": {},
+	}
+	for ref, want := range cases {
+		if got := reflogHashes(t, rm, ref); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v, want %v", ref, got, want)
+		}
+	}
+
+	entries, _ := rm.ReadReflog("HEAD")
+	if entry := entries[1]; entry.OldHash != "c1" || entry.Operation != "commit" || entry.Message != "Hero" || entry.Timestamp.IsZero() {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestReflogRef(t *testing.T) {
+	rm := newTestRefs(t)
+	rm.SetHeadToBranch("feature/logo")
+	rm.WriteBranch("feature/logo", "c1")
+	rm.AppendReflog(BranchRef("deleted"), ReflogEntry{NewHash: "c0"})
+
+	cases := []struct {
+		name     string
+		detached bool
+		want     string // Empty for an error
+	}{
+		{"HEAD", false, "HEAD"},
+		{"@", false, "HEAD"},
+		{"", false, "refs/heads/feature/logo"},
+		{"", true, "HEAD"},
+		{"feature/logo", false, "refs/heads/feature/logo"},
+		{"refs/heads/feature/logo", false, "refs/heads/feature/logo"},
+		{"deleted", false, "refs/heads/deleted"}, // The log outlives the branch file
+		{"missing", false, ""},
+	}
+	for _, c := range cases {
+		if c.detached {
+			rm.DetachHead("c1")
+		} else {
+			rm.SetHeadToBranch("feature/logo")
+		}
+		got, err := rm.ReflogRef(c.name)
+		if (err != nil) != (c.want == "") || got != c.want {
+			t.Errorf("%q (detached %v): got %q, %v; want %q", c.name, c.detached, got, err, c.want)
+		}
+	}
+}
+
+func TestDeletingABranchDeletesItsReflog(t *testing.T) {
+	rm := newTestRefs(t)
+	rm.SetHeadToBranch(DefaultBranch)
+	rm.WriteBranch("feature/logo", "c1")
+	if err := rm.RecordBranchUpdate("feature/logo", "", "c1", "branch", "Created from main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.DeleteBranch("feature/logo"); err != nil {
+		t.Fatal(err)
+	}
+	if got := reflogHashes(t, rm, BranchRef("feature/logo")); len(got) != 0 {
+		t.Errorf("reflog kept: %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(rm.LogsDir, "refs", "heads", "feature")); !os.IsNotExist(err) {
+		t.Errorf("empty reflog directory left behind: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package refs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultBranch is the branch created by 'dgit init' and used when migrating legacy repositories
+const DefaultBranch = "main"
+
+// symbolicRefPrefix marks HEAD contents that point at a branch instead of a commit
+const symbolicRefPrefix = "ref: "
+
+// Branch describes a single branch reference
+type Branch struct {
+	Name    string `json:"name"`
+	Hash    string `json:"hash"`
+	Current bool   `json:"current"`
+}
+
+// RefManager handles HEAD, branch and tag references stored under .dgit/refs
+type RefManager struct {
+	DgitDir  string
+	HeadFile string
+	RefsDir  string
+	HeadsDir string // Branch references (.dgit/refs/heads/)
+	TagsDir  string // Tag references (.dgit/refs/tags/)
+	LogsDir  string // Reference logs (.dgit/logs/)
+}
+
+// NewRefManager creates a new reference manager for the repository
+func NewRefManager(dgitDir string) *RefManager {
+	refsDir := filepath.Join(dgitDir, "refs")
+	headsDir := filepath.Join(refsDir, "heads")
+	os.MkdirAll(headsDir, 0755)
+
+	return &RefManager{
+		DgitDir:  dgitDir,
+		HeadFile: filepath.Join(dgitDir, "HEAD"),
+		RefsDir:  refsDir,
+		HeadsDir: headsDir,
+		TagsDir:  filepath.Join(refsDir, "tags"),
+		LogsDir:  filepath.Join(dgitDir, "logs"),
+	}
+}
+
+// CurrentBranch returns the branch HEAD points to, or an empty string when HEAD is detached
+func (rm *RefManager) CurrentBranch() (string, error) {
+	head, err := rm.readHead()
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(head, symbolicRefPrefix) {
+		return strings.TrimPrefix(strings.TrimPrefix(head, symbolicRefPrefix), "refs/heads/"), nil
+	}
+	return "", nil
+}
+
+// IsDetached reports whether HEAD points directly at a commit instead of a branch
+func (rm *RefManager) IsDetached() bool {
+	branch, err := rm.CurrentBranch()
+	return err == nil && branch == ""
+}
+
+// ResolveHead returns the commit hash HEAD currently points to
+// An empty hash means the current branch has no commits yet
+func (rm *RefManager) ResolveHead() (string, error) {
+	head, err := rm.readHead()
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(head, symbolicRefPrefix) {
+		branch := strings.TrimPrefix(strings.TrimPrefix(head, symbolicRefPrefix), "refs/heads/")
+		hash, err := rm.ReadBranch(branch)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return hash, nil
+	}
+	return head, nil
+}
+
+// UpdateHead moves the current branch to the given commit
+// When HEAD is detached the commit hash is written to HEAD directly
+func (rm *RefManager) UpdateHead(hash string) error {
+	branch, err := rm.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return rm.DetachHead(hash)
+	}
+	return rm.WriteBranch(branch, hash)
+}
+
+// SetHeadToBranch points HEAD at the named branch
+func (rm *RefManager) SetHeadToBranch(name string) error {
+	if err := ValidateBranchName(name); err != nil {
+		return err
+	}
+	return os.WriteFile(rm.HeadFile, []byte(symbolicRefPrefix+"refs/heads/"+name), 0644)
+}
+
+// DetachHead points HEAD directly at a commit hash
+func (rm *RefManager) DetachHead(hash string) error {
+	return os.WriteFile(rm.HeadFile, []byte(hash), 0644)
+}
+
+// ReadBranch returns the commit hash stored in a branch reference
+func (rm *RefManager) ReadBranch(name string) (string, error) {
+	data, err := os.ReadFile(rm.branchPath(name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// BranchExists checks if a branch reference exists
+func (rm *RefManager) BranchExists(name string) bool {
+	if ValidateBranchName(name) != nil {
+		return false
+	}
+	info, err := os.Stat(rm.branchPath(name))
+	return err == nil && !info.IsDir()
+}
+
+// WriteBranch creates or moves a branch reference to the given commit
+func (rm *RefManager) WriteBranch(name, hash string) error {
+	if err := ValidateBranchName(name); err != nil {
+		return err
+	}
+	path := rm.branchPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create branch directory: %w", err)
+	}
+	return os.WriteFile(path, []byte(hash), 0644)
+}
+
+// CreateBranch creates a new branch pointing at the given commit
+func (rm *RefManager) CreateBranch(name, hash string) error {
+	if err := ValidateBranchName(name); err != nil {
+		return err
+	}
+	if rm.BranchExists(name) {
+		return fmt.Errorf("branch '%s' already exists", name)
+	}
+	if hash == "" {
+		return fmt.Errorf("cannot create branch '%s': no commits yet", name)
+	}
+	return rm.WriteBranch(name, hash)
+}
+
+// DeleteBranch removes a branch reference
+// The branch HEAD currently points to cannot be deleted
+func (rm *RefManager) DeleteBranch(name string) error {
+	if !rm.BranchExists(name) {
+		return fmt.Errorf("branch '%s' not found", name)
+	}
+	current, err := rm.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if current == name {
+		return fmt.Errorf("cannot delete branch '%s' checked out at HEAD", name)
+	}
+
+	path := rm.branchPath(name)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete branch '%s': %w", name, err)
+	}
+
+	// Clean up empty parent directories left by names like "feature/logo"
+	for dir := filepath.Dir(path); dir != rm.HeadsDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return rm.DeleteReflog(BranchRef(name))
+}
+
+// ListBranches returns all branches sorted by name
+func (rm *RefManager) ListBranches() ([]Branch, error) {
+	current, err := rm.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []Branch
+	err = filepath.Walk(rm.HeadsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, relErr := filepath.Rel(rm.HeadsDir, path)
+		if relErr != nil {
+			return nil
+		}
+		name := filepath.ToSlash(relPath)
+		hash, readErr := rm.ReadBranch(name)
+		if readErr != nil {
+			return nil
+		}
+		branches = append(branches, Branch{
+			Name:    name,
+			Hash:    hash,
+			Current: name == current,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read branches: %w", err)
+	}
+
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Name < branches[j].Name
+	})
+	return branches, nil
+}
+
+// ValidateBranchName checks that a branch name is safe to use as a reference path
+func ValidateBranchName(name string) error {
+	if name == "" {
+		return fmt.Errorf("branch name cannot be empty")
+	}
+	if err := validateRefName(name); err != nil {
+		return fmt.Errorf("invalid branch name: %s", name)
+	}
+	return nil
+}
+
+// validateRefName rejects names that cannot be stored as files or clash with revision syntax
+func validateRefName(name string) error {
+	if name == "" || name == "HEAD" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid reference name")
+	}
+	if strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("invalid reference name")
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, "//") || strings.Contains(name, "@{") {
+		return fmt.Errorf("invalid reference name")
+	}
+	for _, r := range name {
+		if r <= ' ' || strings.ContainsRune("~^:?*[\\", r) {
+			return fmt.Errorf("invalid reference name")
+		}
+	}
+	return nil
+}
+
+// readHead reads HEAD, migrating legacy repositories that stored a bare commit hash
+func (rm *RefManager) readHead() (string, error) {
+	data, err := os.ReadFile(rm.HeadFile)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	head := strings.TrimSpace(string(data))
+
+	if strings.HasPrefix(head, symbolicRefPrefix) {
+		return head, nil
+	}
+
+	// Repositories created before branch support keep the latest commit hash in HEAD.
+	// Adopt it as the default branch so existing history stays on a named line.
+	branches, _ := os.ReadDir(rm.HeadsDir)
+	if len(branches) > 0 && head != "" {
+		return head, nil // Genuinely detached HEAD
+	}
+	if head != "" {
+		if err := rm.WriteBranch(DefaultBranch, head); err != nil {
+			return "", fmt.Errorf("failed to migrate HEAD: %w", err)
+		}
+	}
+	if err := rm.SetHeadToBranch(DefaultBranch); err != nil {
+		return "", fmt.Errorf("failed to migrate HEAD: %w", err)
+	}
+	return symbolicRefPrefix + "refs/heads/" + DefaultBranch, nil
+}
+
+// branchPath returns the file path of a branch reference
+func (rm *RefManager) branchPath(name string) string {
+	return filepath.Join(rm.HeadsDir, filepath.FromSlash(name))
+}
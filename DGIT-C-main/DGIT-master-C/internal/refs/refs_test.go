@@ -0,0 +1,150 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestRefs(t *testing.T) *RefManager {
+	return NewRefManager(filepath.Join(t.TempDir(), ".dgit"))
+}
+
+func TestValidateBranchName(t *testing.T) {
+	cases := map[string]bool{
+		"main":           true,
+		"feature/logo":   true,
+		"client-v2.1":    true,
+		"":               false,
+		"HEAD":           false,
+		"-force":         false,
+		"/root":          false,
+		"trailing/":      false,
+		"ends.":          false,
+		"a..b":           false,
+		"a//b":           false,
+		"main@{1}":       false,
+		"with space":     false,
+		"main~1":         false,
+		"main^":          false,
+		"a:b":            false,
+		"glob*":          false,
+		"back\\slash":    false,
+		"question?":      false,
+		"bracket[0]":     false,
+		"tab\tseparated": false,
+	}
+	for name, valid := range cases {
+		if err := ValidateBranchName(name); (err == nil) != valid {
+			t.Errorf("%q: got %v, want valid=%v", name, err, valid)
+		}
+	}
+}
+
+func TestBranchLifecycle(t *testing.T) {
+	rm := newTestRefs(t)
+	if err := rm.SetHeadToBranch(DefaultBranch); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.CreateBranch("feature/logo", ""); err == nil {
+		t.Error("expected an error for a branch without commits")
+	}
+	if err := rm.UpdateHead("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.CreateBranch("feature/logo", "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.CreateBranch("feature/logo", "c1"); err == nil {
+		t.Error("expected an error for an existing branch")
+	}
+	if err := rm.CreateBranch("colors", "c1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rm.SetHeadToBranch("feature/logo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.UpdateHead("c2"); err != nil {
+		t.Fatal(err)
+	}
+	branches, err := rm.ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Branch{{"colors", "c1", false}, {"feature/logo", "c2", true}, {"main", "c1", false}}
+	if !reflect.DeepEqual(branches, want) {
+		t.Errorf("branches = %+v, want %+v", branches, want)
+	}
+
+	if err := rm.DeleteBranch("feature/logo"); err == nil {
+		t.Error("expected an error deleting the checked out branch")
+	}
+	if err := rm.SetHeadToBranch(DefaultBranch); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.DeleteBranch("feature/logo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rm.HeadsDir, "feature")); !os.IsNotExist(err) {
+		t.Errorf("empty branch directory left behind: %v", err)
+	}
+	if err := rm.DeleteBranch("feature/logo"); err == nil {
+		t.Error("expected an error deleting a missing branch")
+	}
+}
+
+func TestHeadStates(t *testing.T) {
+	cases := []struct {
+		name     string
+		head     string   // Raw HEAD contents, if any
+		branches []string // Branches pointing at c1 before HEAD is read
+		branch   string
+		hash     string
+	}{
+		{"new repository", "", nil, DefaultBranch, ""},
+		{"unborn branch", "ref: refs/heads/draft", nil, "draft", ""},
+		{"branch", "ref: refs/heads/main", []string{"main"}, "main", "c1"},
+		{"legacy hash is adopted as main", "c9", nil, DefaultBranch, "c9"},
+		{"detached", "c9", []string{"main"}, "", "c9"},
+	}
+	for _, c := range cases {
+		rm := newTestRefs(t)
+		if c.head != "" {
+			if err := os.WriteFile(rm.HeadFile, []byte(c.head+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, name := range c.branches {
+			if err := rm.WriteBranch(name, "c1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		branch, err := rm.CurrentBranch()
+		if err != nil || branch != c.branch {
+			t.Errorf("%s: branch = %q, %v; want %q", c.name, branch, err, c.branch)
+		}
+		if hash, err := rm.ResolveHead(); err != nil || hash != c.hash {
+			t.Errorf("%s: HEAD = %q, %v; want %q", c.name, hash, err, c.hash)
+		}
+		if rm.IsDetached() != (c.branch == "") {
+			t.Errorf("%s: detached = %v", c.name, rm.IsDetached())
+		}
+	}
+}
+
+func TestUpdateHeadOnDetachedHead(t *testing.T) {
+	rm := newTestRefs(t)
+	rm.WriteBranch(DefaultBranch, "c1")
+	rm.DetachHead("c1")
+	if err := rm.UpdateHead("c2"); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := rm.ReadBranch(DefaultBranch); hash != "c1" {
+		t.Errorf("main moved to %s", hash)
+	}
+	if hash, _ := rm.ResolveHead(); hash != "c2" {
+		t.Errorf("HEAD = %s, want c2", hash)
+	}
+}
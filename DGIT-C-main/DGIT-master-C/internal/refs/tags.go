@@ -0,0 +1,144 @@
+package refs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag is an annotated, immutable name for a commit such as a client delivery
+type Tag struct {
+	Name      string    `json:"name"`
+	Target    string    `json:"target"` // Hash of the tagged commit
+	Message   string    `json:"message,omitempty"`
+	Tagger    string    `json:"tagger,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CreateTag stores a new tag under .dgit/refs/tags
+// An existing tag is only replaced when force is set
+func (rm *RefManager) CreateTag(tag *Tag, force bool) error {
+	if err := ValidateTagName(tag.Name); err != nil {
+		return err
+	}
+	if tag.Target == "" {
+		return fmt.Errorf("cannot create tag '%s': no target commit", tag.Name)
+	}
+	if rm.TagExists(tag.Name) && !force {
+		return fmt.Errorf("tag '%s' already exists", tag.Name)
+	}
+
+	data, err := json.MarshalIndent(tag, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tag: %w", err)
+	}
+
+	path := rm.tagPath(tag.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create tag directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadTag loads a tag by name
+func (rm *RefManager) ReadTag(name string) (*Tag, error) {
+	if err := ValidateTagName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(rm.tagPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("tag '%s' not found", name)
+		}
+		return nil, fmt.Errorf("failed to read tag '%s': %w", name, err)
+	}
+
+	var tag Tag
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return nil, fmt.Errorf("failed to parse tag '%s': %w", name, err)
+	}
+	return &tag, nil
+}
+
+// TagExists checks if a tag exists
+func (rm *RefManager) TagExists(name string) bool {
+	if ValidateTagName(name) != nil {
+		return false
+	}
+	info, err := os.Stat(rm.tagPath(name))
+	return err == nil && !info.IsDir()
+}
+
+// DeleteTag removes a tag
+func (rm *RefManager) DeleteTag(name string) error {
+	if !rm.TagExists(name) {
+		return fmt.Errorf("tag '%s' not found", name)
+	}
+
+	path := rm.tagPath(name)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete tag '%s': %w", name, err)
+	}
+	for dir := filepath.Dir(path); dir != rm.TagsDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// ListTags returns all tags sorted by name
+func (rm *RefManager) ListTags() ([]*Tag, error) {
+	var tags []*Tag
+	err := filepath.Walk(rm.TagsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, relErr := filepath.Rel(rm.TagsDir, path)
+		if relErr != nil {
+			return nil
+		}
+		tag, readErr := rm.ReadTag(filepath.ToSlash(relPath))
+		if readErr != nil {
+			return nil
+		}
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// ValidateTagName checks that a tag name is safe to use as a reference path
+func ValidateTagName(name string) error {
+	if err := validateRefName(name); err != nil {
+		return fmt.Errorf("invalid tag name: %s", name)
+	}
+	// Names like "3" or "v3" would shadow version numbers in revision arguments
+	if _, err := strconv.Atoi(strings.TrimPrefix(name, "v")); err == nil {
+		return fmt.Errorf("invalid tag name: %s (looks like a version number)", name)
+	}
+	return nil
+}
+
+// tagPath returns the file path of a tag reference
+func (rm *RefManager) tagPath(name string) string {
+	return filepath.Join(rm.TagsDir, filepath.FromSlash(name))
+}
@@ -0,0 +1,91 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateTagName(t *testing.T) {
+	cases := map[string]bool{
+		"client-review":    true,
+		"delivery/2025-q1": true,
+		"v1.0":             true,
+		"version2":         true,
+		"3":                false, // Shadows version numbers
+		"v3":               false,
+		"":                 false,
+		"HEAD":             false,
+		"review~1":         false,
+		"a..b":             false,
+	}
+	for name, valid := range cases {
+		if err := ValidateTagName(name); (err == nil) != valid {
+			t.Errorf("%q: got %v, want valid=%v", name, err, valid)
+		}
+	}
+}
+
+func TestTagLifecycle(t *testing.T) {
+	rm := newTestRefs(t)
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	review := &Tag{Name: "client/review", Target: "c1", Message: "Sent to the client", Tagger: "Designer", Timestamp: at}
+
+	if err := rm.CreateTag(&Tag{Name: "empty"}, false); err == nil {
+		t.Error("expected an error for a tag without a target")
+	}
+	if err := rm.CreateTag(review, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.CreateTag(&Tag{Name: "approved", Target: "c2", Timestamp: at}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rm.ReadTag("client/review"); err != nil || !reflect.DeepEqual(got, review) {
+		t.Errorf("read %+v, %v; want %+v", got, err, review)
+	}
+
+	moved := &Tag{Name: "client/review", Target: "c3", Timestamp: at}
+	if err := rm.CreateTag(moved, false); err == nil {
+		t.Error("expected an error replacing a tag without force")
+	}
+	if got, _ := rm.ReadTag("client/review"); got.Target != "c1" {
+		t.Errorf("tag moved to %s without force", got.Target)
+	}
+	if err := rm.CreateTag(moved, true); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := rm.ListTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targets []string
+	for _, tag := range tags {
+		targets = append(targets, tag.Name+"="+tag.Target)
+	}
+	if want := []string{"approved=c2", "client/review=c3"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("tags = %v, want %v", targets, want)
+	}
+
+	if err := rm.DeleteTag("client/review"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rm.TagsDir, "client")); !os.IsNotExist(err) {
+		t.Errorf("empty tag directory left behind: %v", err)
+	}
+	if _, err := rm.ReadTag("client/review"); err == nil {
+		t.Error("expected an error reading a deleted tag")
+	}
+	if err := rm.DeleteTag("client/review"); err == nil {
+		t.Error("expected an error deleting a missing tag")
+	}
+}
+
+func TestListTagsWithoutTags(t *testing.T) {
+	tags, err := newTestRefs(t).ListTags()
+	if err != nil || len(tags) != 0 {
+		t.Errorf("got %v, %v", tags, err)
+	}
+}
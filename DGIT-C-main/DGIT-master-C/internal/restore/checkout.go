@@ -0,0 +1,440 @@
+package restore
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dgit/internal/log"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/kr/binarydist"
+	"github.com/pierrec/lz4/v4"
+)
+
+// ============================================================================
+// TREE-BASED RESTORATION
+// ============================================================================
+
+// ReadCommitFile returns the content of a tracked file as recorded in the given commit
+// The content is read from the snapshot of whichever commit last stored the file
+func (rm *RestoreManager) ReadCommitFile(commit *log.Commit, filePath string) ([]byte, error) {
+	entry, ok := commit.TrackedFiles()[filePath]
+	if !ok {
+		return nil, fmt.Errorf("file '%s' is not tracked in v%d", filePath, commit.Version)
+	}
+
+	storing := commit
+	if entry.Commit != "" && entry.Commit != commit.Hash {
+		var err error
+		storing, err = log.NewLogManager(rm.DgitDir).GetCommitByHash(entry.Commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load commit %s storing '%s': %w", entry.Commit, filePath, err)
+		}
+	}
+
+	files, err := rm.readSnapshotFiles(storing)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[filePath]
+	if !ok {
+		return nil, fmt.Errorf("file '%s' missing from snapshot of v%d (%s)", filePath, storing.Version, storing.Hash)
+	}
+	return data, nil
+}
+
+// CheckoutCommit updates the working tree from one commit to another
+// Files whose content differs are rewritten and files no longer tracked are removed.
+// With force every tracked file is rewritten, discarding local modifications.
+func (rm *RestoreManager) CheckoutCommit(from, to *log.Commit, force bool) (*RestoreResult, error) {
+	result := &RestoreResult{
+		RestoredFiles: []string{},
+		RemovedFiles:  []string{},
+		SkippedFiles:  []string{},
+		ErrorFiles:    make(map[string]error),
+		RestoreMethod: "tree",
+		CacheHitLevel: "versions",
+	}
+
+	var fromFiles, toFiles map[string]log.TreeEntry
+	if from != nil {
+		fromFiles = from.TrackedFiles()
+	}
+	if to != nil {
+		toFiles = to.TrackedFiles()
+		result.SourceVersion = to.Version
+		result.SourceCommitHash = to.Hash
+	}
+
+	root := rm.workTreeRoot()
+	for _, path := range sortedTreePaths(toFiles) {
+		targetPath := filepath.Join(root, path)
+		if old, ok := fromFiles[path]; ok && !force && old.Hash != "" && old.Hash == toFiles[path].Hash && rm.fileExists(targetPath) {
+			result.SkippedFiles = append(result.SkippedFiles, path)
+			continue
+		}
+
+		data, err := rm.ReadCommitFile(to, path)
+		if err != nil {
+			result.ErrorFiles[path] = err
+			continue
+		}
+		if err := rm.createFileFromData(targetPath, data); err != nil {
+			result.ErrorFiles[path] = err
+			continue
+		}
+		result.RestoredFiles = append(result.RestoredFiles, path)
+		result.DataTransferred += int64(len(data))
+	}
+
+	for _, path := range sortedTreePaths(fromFiles) {
+		if _, ok := toFiles[path]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(root, path)); err != nil && !os.IsNotExist(err) {
+			result.ErrorFiles[path] = err
+			continue
+		}
+		result.RemovedFiles = append(result.RemovedFiles, path)
+	}
+
+	result.TotalFilesCount = len(toFiles)
+	if len(result.ErrorFiles) > 0 {
+		return result, fmt.Errorf("failed to check out %d file(s)", len(result.ErrorFiles))
+	}
+	return result, nil
+}
+
+// restoreFromTree restores the requested files (or all tracked files) recorded in a commit's tree
+func (rm *RestoreManager) restoreFromTree(commit *log.Commit, filesToRestore []string) (*RestoreResult, error) {
+	result := &RestoreResult{
+		SourceVersion:    commit.Version,
+		SourceCommitHash: commit.Hash,
+		RestoredFiles:    []string{},
+		SkippedFiles:     []string{},
+		ErrorFiles:       make(map[string]error),
+		RestoreMethod:    "tree",
+		CacheHitLevel:    "versions",
+	}
+
+	// Normalize target file paths for consistent matching
+	normalizedTargets := make([]string, len(filesToRestore))
+	for i, target := range filesToRestore {
+		normalizedTargets[i] = filepath.Clean(strings.ReplaceAll(target, "\\", "/"))
+	}
+
+	root := rm.workTreeRoot()
+	tree := commit.TrackedFiles()
+	for _, path := range sortedTreePaths(tree) {
+		if len(filesToRestore) > 0 && !rm.shouldRestoreFile(path, normalizedTargets) {
+			result.SkippedFiles = append(result.SkippedFiles, path)
+			continue
+		}
+
+		data, err := rm.ReadCommitFile(commit, path)
+		if err != nil {
+			result.ErrorFiles[path] = err
+			continue
+		}
+		if err := rm.createFileFromData(filepath.Join(root, path), data); err != nil {
+			result.ErrorFiles[path] = err
+			continue
+		}
+		result.RestoredFiles = append(result.RestoredFiles, path)
+		result.DataTransferred += int64(len(data))
+	}
+
+	result.TotalFilesCount = len(tree)
+	return result, nil
+}
+
+// readSnapshotFiles returns the files stored by a single commit, keyed by path
+func (rm *RestoreManager) readSnapshotFiles(commit *log.Commit) (map[string][]byte, error) {
+	if files, ok := rm.snapshotCache[commit.Hash]; ok {
+		return files, nil
+	}
+
+	files, err := rm.decodeSnapshot(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot of v%d (%s): %w", commit.Version, commit.Hash, err)
+	}
+	rm.snapshotCache[commit.Hash] = files
+	return files, nil
+}
+
+// decodeSnapshot decodes a commit's stored snapshot according to its compression strategy
+func (rm *RestoreManager) decodeSnapshot(commit *log.Commit) (map[string][]byte, error) {
+	info := commit.CompressionInfo
+	if info == nil {
+		// Legacy ZIP snapshots
+		if commit.SnapshotZip != "" {
+			return rm.readZipFiles(filepath.Join(rm.ObjectsDir, commit.SnapshotZip))
+		}
+		return nil, fmt.Errorf("no stored snapshot")
+	}
+
+	switch info.Strategy {
+	case "lz4":
+		data, err := rm.readFullSnapshot(commit)
+		if err != nil {
+			return nil, err
+		}
+		return parseSnapshotStream(data), nil
+	case "zip":
+		return rm.readZipFiles(filepath.Join(rm.ObjectsDir, info.OutputFile))
+	case "psd_smart", "design_smart_delta":
+		deltaPath := filepath.Join(rm.CacheDir, info.OutputFile)
+		if !rm.fileExists(deltaPath) {
+			deltaPath = filepath.Join(rm.VersionsDir, info.OutputFile)
+		}
+		filePath, data, _, err := rm.parseSmartDeltaFile(deltaPath)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{filePath: data}, nil
+	case "bsdiff":
+		return rm.decodeBsdiffSnapshot(commit)
+	default:
+		return nil, fmt.Errorf("unsupported storage strategy: %s", info.Strategy)
+	}
+}
+
+// decodeBsdiffSnapshot applies a binary delta on top of the full snapshot of its base commit
+// The delta reproduces the LZ4 stream written at commit time, which is then decompressed
+func (rm *RestoreManager) decodeBsdiffSnapshot(commit *log.Commit) (map[string][]byte, error) {
+	info := commit.CompressionInfo
+	logManager := log.NewLogManager(rm.DgitDir)
+
+	var base *log.Commit
+	var err error
+	if info.BaseHash != "" {
+		base, err = logManager.GetCommitByHash(info.BaseHash)
+	} else {
+		base, err = logManager.GetCommit(info.BaseVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delta base: %w", err)
+	}
+
+	baseData, err := rm.readFullSnapshot(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delta base v%d: %w", base.Version, err)
+	}
+
+	deltaPath := filepath.Join(rm.CacheDir, info.OutputFile)
+	if !rm.fileExists(deltaPath) {
+		deltaPath = filepath.Join(rm.DeltaDir, info.OutputFile)
+	}
+	patch, err := os.Open(deltaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open delta file: %w", err)
+	}
+	defer patch.Close()
+
+	var patched bytes.Buffer
+	if err := binarydist.Patch(bytes.NewReader(baseData), &patched, patch); err != nil {
+		return nil, fmt.Errorf("binarydist patch failed: %w", err)
+	}
+
+	stream, err := io.ReadAll(lz4.NewReader(&patched))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress patched snapshot: %w", err)
+	}
+	return parseSnapshotStream(stream), nil
+}
+
+// readFullSnapshot returns the bytes a delta was computed against
+// LZ4 and optimized Zstd snapshots are decompressed; ZIP snapshots are returned as stored
+func (rm *RestoreManager) readFullSnapshot(commit *log.Commit) ([]byte, error) {
+	info := commit.CompressionInfo
+	if info == nil {
+		if commit.SnapshotZip == "" {
+			return nil, fmt.Errorf("no stored snapshot")
+		}
+		return os.ReadFile(filepath.Join(rm.ObjectsDir, commit.SnapshotZip))
+	}
+
+	switch info.Strategy {
+	case "lz4":
+		for _, path := range []string{
+			filepath.Join(rm.VersionsDir, info.OutputFile),
+			filepath.Join(rm.CacheDir, info.OutputFile),
+		} {
+			if file, err := os.Open(path); err == nil {
+				defer file.Close()
+				return io.ReadAll(lz4.NewReader(file))
+			}
+		}
+
+		optimizedPath := filepath.Join(rm.CacheDir, log.OptimizedCacheName(info.OutputFile))
+		file, err := os.Open(optimizedPath)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot file not found: %s", info.OutputFile)
+		}
+		defer file.Close()
+
+		zstdReader, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Zstd reader: %w", err)
+		}
+		defer zstdReader.Close()
+		return io.ReadAll(zstdReader)
+	case "zip":
+		return os.ReadFile(filepath.Join(rm.ObjectsDir, info.OutputFile))
+	default:
+		return nil, fmt.Errorf("v%d is stored as %s, not a full snapshot", commit.Version, info.Strategy)
+	}
+}
+
+// readZipFiles reads every file in a ZIP snapshot
+func (rm *RestoreManager) readZipFiles(zipPath string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file %s: %w", zipPath, err)
+	}
+	defer r.Close()
+
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in zip: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in zip: %w", f.Name, err)
+		}
+		files[strings.ReplaceAll(f.Name, "\\", "/")] = data
+	}
+	return files, nil
+}
+
+// parseSmartDeltaFile reads a PSD smart delta and returns the stored file path, content and metadata
+func (rm *RestoreManager) parseSmartDeltaFile(deltaPath string) (string, []byte, map[string]interface{}, error) {
+	deltaData, err := os.ReadFile(deltaPath)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read delta file: %w", err)
+	}
+
+	// Header: "PSD_SMART_DELTA_V1\nMETADATA_LENGTH:n\n<json>\nBINARY_DATA:\n<lz4>"
+	lines := strings.SplitN(string(deltaData), "\n", 3)
+	if len(lines) < 3 {
+		return "", nil, nil, fmt.Errorf("invalid smart delta format: too few lines")
+	}
+	if lines[0] != "PSD_SMART_DELTA_V1" {
+		return "", nil, nil, fmt.Errorf("invalid smart delta header: %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "METADATA_LENGTH:") {
+		return "", nil, nil, fmt.Errorf("invalid metadata length line: %s", lines[1])
+	}
+
+	metadataLength, err := strconv.Atoi(strings.TrimPrefix(lines[1], "METADATA_LENGTH:"))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to parse metadata length: %w", err)
+	}
+
+	metadataStartPos := len(lines[0]) + 1 + len(lines[1]) + 1
+	if metadataStartPos+metadataLength > len(deltaData) {
+		return "", nil, nil, fmt.Errorf("invalid metadata length: exceeds file size")
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(deltaData[metadataStartPos:metadataStartPos+metadataLength], &metadata); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to parse delta metadata: %w", err)
+	}
+
+	filePath, ok := metadata["file_path"].(string)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("missing file_path in metadata")
+	}
+
+	binaryDataMarker := []byte("\nBINARY_DATA:\n")
+	binaryDataPos := bytes.Index(deltaData[metadataStartPos:], binaryDataMarker)
+	if binaryDataPos == -1 {
+		return "", nil, nil, fmt.Errorf("binary data marker not found")
+	}
+	binaryDataPos += metadataStartPos + len(binaryDataMarker)
+
+	data, err := io.ReadAll(lz4.NewReader(bytes.NewReader(deltaData[binaryDataPos:])))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to decompress LZ4 data: %w", err)
+	}
+
+	return filePath, data, metadata, nil
+}
+
+// resolveCommitReference loads a commit from a version ("v3", "3") or a full or short hash
+func (rm *RestoreManager) resolveCommitReference(commitRef string) (*log.Commit, error) {
+	logManager := log.NewLogManager(rm.DgitDir)
+	if version, err := rm.parseCommitReference(commitRef); err == nil {
+		return logManager.GetCommit(version)
+	}
+	return logManager.GetCommitByHash(commitRef)
+}
+
+// workTreeRoot returns the repository root directory containing .dgit
+func (rm *RestoreManager) workTreeRoot() string {
+	return filepath.Dir(rm.DgitDir)
+}
+
+// parseSnapshotStream splits a structured "FILE:path:size\n[data]" stream into files
+func parseSnapshotStream(data []byte) map[string][]byte {
+	files := make(map[string][]byte)
+	pos := 0
+
+	for pos < len(data) {
+		headerEnd := bytes.IndexByte(data[pos:], '\n')
+		if headerEnd == -1 {
+			break
+		}
+		headerEnd += pos
+
+		headerLine := string(data[pos:headerEnd])
+		sizeSep := strings.LastIndex(headerLine, ":")
+		if !strings.HasPrefix(headerLine, "FILE:") || sizeSep <= len("FILE:") {
+			pos = headerEnd + 1
+			continue
+		}
+
+		// Paths may contain ':' so the size is taken from the last field
+		filePath := headerLine[len("FILE:"):sizeSep]
+		fileSize, err := strconv.Atoi(headerLine[sizeSep+1:])
+		if err != nil || fileSize < 0 {
+			pos = headerEnd + 1
+			continue
+		}
+
+		fileDataStart := headerEnd + 1
+		fileDataEnd := fileDataStart + fileSize
+		if fileDataEnd > len(data) {
+			break
+		}
+
+		files[filePath] = data[fileDataStart:fileDataEnd]
+		pos = fileDataEnd
+	}
+
+	return files
+}
+
+// sortedTreePaths returns the paths of a commit tree in a stable order
+func sortedTreePaths(tree map[string]log.TreeEntry) []string {
+	paths := make([]string, 0, len(tree))
+	for path := range tree {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -29,6 +29,9 @@ type RestoreManager struct {
 	VersionsDir string // Main version storage (.dgit/versions/)
 	CommitsDir  string // Commit metadata (.dgit/commits/)
 	CacheDir    string // Single cache directory (.dgit/cache/)
+
+	// Decoded snapshots keyed by commit hash, reused while restoring many files
+	snapshotCache map[string]map[string][]byte
 }
 
 // NewRestoreManager creates a new restore manager with simplified structure
@@ -42,15 +45,18 @@ func NewRestoreManager(dgitDir string) *RestoreManager {
 		VersionsDir: filepath.Join(dgitDir, "versions"),
 		CommitsDir:  filepath.Join(dgitDir, "commits"),
 		CacheDir:    filepath.Join(dgitDir, "cache"),
+
+		snapshotCache: make(map[string]map[string][]byte),
 	}
 }
 
 // RestoreResult contains restoration operation information
 type RestoreResult struct {
 	RestoredFiles    []string
+	RemovedFiles     []string // Files deleted from the working tree by a checkout
 	SkippedFiles     []string
 	ErrorFiles       map[string]error
-	RestoreMethod    string // "versions", "cache", "smart_delta", "delta_chain", "zip"
+	RestoreMethod    string // "tree", "versions", "cache", "smart_delta", "delta_chain", "zip"
 	RestorationTime  time.Duration
 	TotalFilesCount  int
 	SourceVersion    int
@@ -62,26 +68,30 @@ type RestoreResult struct {
 }
 
 // RestoreFilesFromCommit restores files using optimized strategies
+// targetCommit may carry an already resolved *log.Commit; otherwise the reference is resolved here
 func (rm *RestoreManager) RestoreFilesFromCommit(commitHashOrVersion string, filesToRestore []string, targetCommit interface{}) error {
 	startTime := time.Now()
 
-	// Parse commit reference (supports both hash and version formats)
-	version, err := rm.parseCommitReference(commitHashOrVersion)
-	if err != nil {
-		return err
+	commit, ok := targetCommit.(*log.Commit)
+	if !ok || commit == nil {
+		var err error
+		commit, err = rm.resolveCommitReference(commitHashOrVersion)
+		if err != nil {
+			return fmt.Errorf("failed to load commit data: %w", err)
+		}
 	}
+	version := commit.Version
 
 	fmt.Printf("Analyzing restoration strategy for v%d...\n", version)
 
-	// Load commit data using log manager
-	logManager := log.NewLogManager(rm.DgitDir)
-	commit, err := logManager.GetCommit(version)
-	if err != nil {
-		return fmt.Errorf("failed to load commit data: %w", err)
+	// Commits with a recorded tree can restore every tracked file, including files stored by ancestors
+	var result *RestoreResult
+	var err error
+	if len(commit.Tree) > 0 {
+		result, err = rm.restoreFromTree(commit, filesToRestore)
+	} else {
+		result, err = rm.performFastRestore(commit, filesToRestore)
 	}
-
-	// Choose optimal restoration method based on cache availability
-	result, err := rm.performFastRestore(commit, filesToRestore, version)
 	if err != nil {
 		return err
 	}
@@ -98,7 +108,8 @@ func (rm *RestoreManager) RestoreFilesFromCommit(commitHashOrVersion string, fil
 
 // performFastRestore intelligently chooses the fastest available restoration method
 // Priority: Versions → Cache → Smart Delta → Legacy
-func (rm *RestoreManager) performFastRestore(commit *log.Commit, filesToRestore []string, version int) (*RestoreResult, error) {
+func (rm *RestoreManager) performFastRestore(commit *log.Commit, filesToRestore []string) (*RestoreResult, error) {
+	version := commit.Version
 	result := &RestoreResult{
 		SourceVersion:    commit.Version,
 		SourceCommitHash: commit.Hash,
@@ -170,7 +181,7 @@ func (rm *RestoreManager) tryVersionRestore(commit *log.Commit, filesToRestore [
 	result.CacheHitLevel = "versions"
 
 	// Extract from LZ4 versions directory
-	if err := rm.extractFromLZ4(versionPath, filesToRestore, result); err != nil {
+	if err := rm.extractFromLZ4(commit, versionPath, filesToRestore, result); err != nil {
 		return nil
 	}
 
@@ -179,11 +190,15 @@ func (rm *RestoreManager) tryVersionRestore(commit *log.Commit, filesToRestore [
 
 // tryCacheRestore attempts restoration from cache directory
 func (rm *RestoreManager) tryCacheRestore(commit *log.Commit, filesToRestore []string, result *RestoreResult) *RestoreResult {
+	if commit.CompressionInfo == nil || commit.CompressionInfo.Strategy != "lz4" {
+		return nil
+	}
+
 	// Check for cache version
-	cachePath := filepath.Join(rm.CacheDir, fmt.Sprintf("v%d.lz4", commit.Version))
+	cachePath := filepath.Join(rm.CacheDir, commit.CompressionInfo.OutputFile)
 	if !rm.fileExists(cachePath) {
 		// Check for optimized cache version
-		cachePath = filepath.Join(rm.CacheDir, fmt.Sprintf("v%d_optimized.zstd", commit.Version))
+		cachePath = filepath.Join(rm.CacheDir, log.OptimizedCacheName(commit.CompressionInfo.OutputFile))
 		if !rm.fileExists(cachePath) {
 			return nil
 		}
@@ -196,7 +211,7 @@ func (rm *RestoreManager) tryCacheRestore(commit *log.Commit, filesToRestore []s
 	// Extract from cache with appropriate decompression
 	var err error
 	if strings.HasSuffix(cachePath, ".lz4") {
-		err = rm.extractFromLZ4(cachePath, filesToRestore, result)
+		err = rm.extractFromLZ4(commit, cachePath, filesToRestore, result)
 	} else if strings.HasSuffix(cachePath, ".zstd") {
 		err = rm.extractFromZstd(cachePath, filesToRestore, result)
 	}
@@ -209,22 +224,7 @@ func (rm *RestoreManager) tryCacheRestore(commit *log.Commit, filesToRestore []s
 }
 
 // extractFromLZ4 extracts files from LZ4 storage
-func (rm *RestoreManager) extractFromLZ4(lz4Path string, filesToRestore []string, result *RestoreResult) error {
-	// Extract version number from LZ4 filename
-	fileName := filepath.Base(lz4Path)
-	versionStr := strings.TrimSuffix(strings.TrimPrefix(fileName, "v"), ".lz4")
-	version, err := strconv.Atoi(versionStr)
-	if err != nil {
-		return fmt.Errorf("failed to parse version from filename %s: %w", fileName, err)
-	}
-
-	// Load commit metadata from commits directory
-	logManager := log.NewLogManager(rm.DgitDir)
-	commit, err := logManager.GetCommit(version)
-	if err != nil {
-		return fmt.Errorf("failed to load commit v%d: %w", version, err)
-	}
-
+func (rm *RestoreManager) extractFromLZ4(commit *log.Commit, lz4Path string, filesToRestore []string, result *RestoreResult) error {
 	// Open LZ4 file for decompression
 	file, err := os.Open(lz4Path)
 	if err != nil {
@@ -425,94 +425,18 @@ func (rm *RestoreManager) restoreFromSmartDelta(commit *log.Commit, filesToResto
 
 	fmt.Printf("Restoring from smart delta: %s\n", deltaPath)
 
-	// Read delta file
-	deltaData, err := os.ReadFile(deltaPath)
+	filePath, decompressedData, deltaMetadata, err := rm.parseSmartDeltaFile(deltaPath)
 	if err != nil {
-		return result, fmt.Errorf("failed to read delta file: %w", err)
-	}
-
-	// Parse delta file format
-	content := string(deltaData)
-	lines := strings.Split(content, "\n")
-
-	if len(lines) < 3 {
-		return result, fmt.Errorf("invalid smart delta format: too few lines")
-	}
-
-	// Verify header
-	if lines[0] != "PSD_SMART_DELTA_V1" {
-		return result, fmt.Errorf("invalid smart delta header: %s", lines[0])
-	}
-
-	// Parse metadata length
-	if !strings.HasPrefix(lines[1], "METADATA_LENGTH:") {
-		return result, fmt.Errorf("invalid metadata length line: %s", lines[1])
-	}
-
-	metadataLengthStr := strings.TrimPrefix(lines[1], "METADATA_LENGTH:")
-	metadataLength, err := strconv.Atoi(metadataLengthStr)
-	if err != nil {
-		return result, fmt.Errorf("failed to parse metadata length: %w", err)
-	}
-
-	// Find metadata start position
-	metadataStartPos := len(lines[0]) + 1 + len(lines[1]) + 1
-	if metadataStartPos+metadataLength > len(deltaData) {
-		return result, fmt.Errorf("invalid metadata length: exceeds file size")
-	}
-
-	// Extract metadata JSON
-	metadataBytes := deltaData[metadataStartPos : metadataStartPos+metadataLength]
-
-	// Parse metadata
-	var deltaMetadata map[string]interface{}
-	if err := json.Unmarshal(metadataBytes, &deltaMetadata); err != nil {
-		return result, fmt.Errorf("failed to parse delta metadata: %w", err)
-	}
-
-	// Extract key information from metadata
-	filePath, ok := deltaMetadata["file_path"].(string)
-	if !ok {
-		return result, fmt.Errorf("missing file_path in metadata")
-	}
-
-	baseVersion, ok := deltaMetadata["from_version"].(float64)
-	if !ok {
-		return result, fmt.Errorf("missing from_version in metadata")
+		return result, err
 	}
 
 	// Check if base version exists
-	if int(baseVersion) > 0 {
-		baseVersionPath := filepath.Join(rm.CommitsDir, fmt.Sprintf("v%d.json", int(baseVersion)))
-		if !rm.fileExists(baseVersionPath) {
-			fmt.Printf("Warning: base version v%d metadata not found\n", int(baseVersion))
+	if baseHash, ok := deltaMetadata["from_hash"].(string); ok && baseHash != "" {
+		if _, err := log.NewLogManager(rm.DgitDir).GetCommitByHash(baseHash); err != nil {
+			fmt.Printf("Warning: base commit %s metadata not found\n", baseHash)
 		}
 	}
 
-	// Find binary data position
-	binaryDataMarker := "\nBINARY_DATA:\n"
-	binaryDataPos := bytes.Index(deltaData, []byte(binaryDataMarker))
-	if binaryDataPos == -1 {
-		return result, fmt.Errorf("binary data marker not found")
-	}
-	binaryDataPos += len(binaryDataMarker)
-
-	// Extract LZ4 compressed data
-	compressedData := deltaData[binaryDataPos:]
-
-	// Decompress using LZ4
-	lz4Reader := lz4.NewReader(bytes.NewReader(compressedData))
-	decompressedData, err := io.ReadAll(lz4Reader)
-	if err != nil {
-		return result, fmt.Errorf("failed to decompress LZ4 data: %w", err)
-	}
-
-	// Get current working directory
-	currentWorkDir, err := os.Getwd()
-	if err != nil {
-		return result, fmt.Errorf("failed to get working directory: %w", err)
-	}
-
 	// Check if this file should be restored
 	if len(filesToRestore) > 0 {
 		shouldRestore := false
@@ -529,7 +453,7 @@ func (rm *RestoreManager) restoreFromSmartDelta(commit *log.Commit, filesToResto
 	}
 
 	// For PSD smart delta, the decompressed data is the complete new file
-	targetPath := filepath.Join(currentWorkDir, filePath)
+	targetPath := filepath.Join(rm.workTreeRoot(), filePath)
 
 	// Create directory if needed
 	if err := os.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
@@ -939,7 +863,7 @@ func (rm *RestoreManager) calculateSpeedImprovement(method string, duration time
 		return baselineMs / actualMs
 	case "cache":
 		return baselineMs / actualMs
-	case "smart_delta":
+	case "tree", "smart_delta":
 		return baselineMs / actualMs
 	default:
 		return baselineMs / actualMs
@@ -954,6 +878,9 @@ func (rm *RestoreManager) displayRestoreResults(result *RestoreResult, commitRef
 
 		// Show method-specific information
 		switch result.RestoreMethod {
+		case "tree":
+			fmt.Printf("Tree restoration - %.1fx faster than traditional!\n", result.SpeedImprovement)
+			fmt.Printf("Data transferred: %.2f KB from snapshot storage\n", float64(result.DataTransferred)/1024)
 		case "versions":
 			fmt.Printf("Versions directory restoration - %.1fx faster than traditional!\n", result.SpeedImprovement)
 			fmt.Printf("Data transferred: %.2f KB from versions storage\n", float64(result.DataTransferred)/1024)
@@ -5,8 +5,14 @@
 package scanner
 
 import (
+	"dgit/internal/scanner/blender"
+	"dgit/internal/scanner/fbx"
+	"dgit/internal/scanner/figma"
 	"dgit/internal/scanner/illustrator"
+	"dgit/internal/scanner/obj"
 	"dgit/internal/scanner/photoshop"
+	"dgit/internal/scanner/sketch"
+	"dgit/internal/scanner/xd"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,6 +31,45 @@ type DetailedFileInfo struct {
 	Artboards  int
 	Objects    int
 	LayerNames []string
+
+	// Document structure, for formats with pages, artboards and shared components
+	Pages        []string
+	ArtboardList []ArtboardInfo
+	LayerTree    []LayerNode
+	Components   []string
+	Instances    int
+	Styles       []string
+	Images       []string
+	Texts        []string
+	Assets       []string
+
+	// Scene contents, for 3D formats
+	Scenes     []string
+	Meshes     []MeshInfo
+	Materials  []string
+	Animations []string
+}
+
+// MeshInfo describes one mesh of a 3D file
+type MeshInfo struct {
+	Name     string
+	Vertices int
+	Faces    int
+}
+
+// LayerNode is a layer of a document's layer tree
+type LayerNode struct {
+	Name     string
+	Type     string
+	Children []LayerNode
+}
+
+// ArtboardInfo describes one artboard of a document
+type ArtboardInfo struct {
+	Name   string
+	Page   string
+	Width  float64
+	Height float64
 }
 
 // DetailedScanner performs comprehensive file analysis
@@ -74,6 +119,12 @@ func (ds *DetailedScanner) AnalyzeFile(filePath string) (*DetailedFileInfo, erro
 		return ds.analyzeFigma(filePath, result)
 	case "xd":
 		return ds.analyzeXD(filePath, result)
+	case "blend":
+		return ds.analyzeBlender(filePath, result)
+	case "fbx":
+		return ds.analyzeFBX(filePath, result)
+	case "obj":
+		return ds.analyzeOBJ(filePath, result)
 	default:
 		return result, nil
 	}
@@ -114,30 +165,241 @@ func (ds *DetailedScanner) analyzePSD(filePath string, result *DetailedFileInfo)
 	return result, nil
 }
 
-// analyzeSketch provides basic Sketch file information
+// analyzeSketch performs detailed Sketch file analysis
 func (ds *DetailedScanner) analyzeSketch(filePath string, result *DetailedFileInfo) (*DetailedFileInfo, error) {
-	result.ColorMode = "RGB"
-	result.Version = "Sketch App"
-	result.Layers = 1
-	result.LayerNames = []string{"Sketch Layer"}
+	sketchInfo, err := sketch.GetSketchInfo(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	if len(sketchInfo.Artboards) > 0 {
+		first := sketchInfo.Artboards[0]
+		result.Dimensions = fmt.Sprintf("%.0fx%.0f px", first.Width, first.Height)
+	}
+	result.ColorMode = sketchColorMode(sketchInfo)
+	result.Version = sketchInfo.AppVersion
+	result.Layers = len(sketchInfo.LayerNames)
+	result.Artboards = len(sketchInfo.Artboards)
+	result.Objects = sketchInfo.ObjectCount
+	result.LayerNames = sketchInfo.LayerNames
+
+	result.Pages = sketchInfo.Pages
+	for _, artboard := range sketchInfo.Artboards {
+		result.ArtboardList = append(result.ArtboardList, ArtboardInfo{
+			Name: artboard.Name, Page: artboard.Page, Width: artboard.Width, Height: artboard.Height,
+		})
+	}
+	result.LayerTree = sketchLayerTree(sketchInfo.Layers)
+	result.Components = sketchInfo.Symbols
+	result.Instances = sketchInfo.SymbolInstances
+	result.Styles = sketchInfo.SharedStyles
+	result.Images = sketchInfo.Images
+	result.Texts = sketchInfo.Texts
 	return result, nil
 }
 
-// analyzeFigma provides basic Figma file information
+// sketchLayerTree converts a Sketch layer tree
+func sketchLayerTree(layers []sketch.Layer) []LayerNode {
+	var nodes []LayerNode
+	for _, layer := range layers {
+		nodes = append(nodes, LayerNode{Name: layer.Name, Type: layer.Type, Children: sketchLayerTree(layer.Children)})
+	}
+	return nodes
+}
+
+// analyzeFigma performs detailed Figma file analysis
 func (ds *DetailedScanner) analyzeFigma(filePath string, result *DetailedFileInfo) (*DetailedFileInfo, error) {
+	figmaInfo, err := figma.GetFigmaInfo(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	if len(figmaInfo.Frames) > 0 {
+		first := figmaInfo.Frames[0]
+		result.Dimensions = fmt.Sprintf("%.0fx%.0f px", first.Width, first.Height)
+	}
 	result.ColorMode = "RGB"
-	result.Version = "Figma"
-	result.Layers = 1
-	result.LayerNames = []string{"Figma Frame"}
+	result.Version = fmt.Sprintf("Figma (fig-kiwi v%d)", figmaInfo.Version)
+	result.Layers = len(figmaInfo.LayerNames)
+	result.Artboards = len(figmaInfo.Frames)
+	result.Objects = figmaInfo.ObjectCount
+	result.LayerNames = figmaInfo.LayerNames
+
+	result.Pages = figmaInfo.Pages
+	for _, frame := range figmaInfo.Frames {
+		result.ArtboardList = append(result.ArtboardList, ArtboardInfo{
+			Name: frame.Name, Page: frame.Page, Width: frame.Width, Height: frame.Height,
+		})
+	}
+	result.LayerTree = figmaLayerTree(figmaInfo.Layers)
+	result.Components = figmaInfo.Components
+	result.Instances = figmaInfo.Instances
+	result.Texts = figmaInfo.Texts
+	result.Images = figmaInfo.Images
 	return result, nil
 }
 
-// analyzeXD provides basic Adobe XD file information
+// figmaLayerTree converts a Figma node tree
+func figmaLayerTree(layers []figma.Layer) []LayerNode {
+	var nodes []LayerNode
+	for _, layer := range layers {
+		nodes = append(nodes, LayerNode{Name: layer.Name, Type: layer.Type, Children: figmaLayerTree(layer.Children)})
+	}
+	return nodes
+}
+
+// analyzeXD performs detailed Adobe XD file analysis
 func (ds *DetailedScanner) analyzeXD(filePath string, result *DetailedFileInfo) (*DetailedFileInfo, error) {
+	xdInfo, err := xd.GetXDInfo(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	if len(xdInfo.Artboards) > 0 {
+		first := xdInfo.Artboards[0]
+		result.Dimensions = fmt.Sprintf("%.0fx%.0f px", first.Width, first.Height)
+	}
 	result.ColorMode = "RGB"
-	result.Version = "Adobe XD"
-	result.Layers = 1
-	result.LayerNames = []string{"XD Artboard"}
+	result.Version = xdInfo.Version
+	result.Layers = len(xdInfo.LayerNames)
+	result.Artboards = len(xdInfo.Artboards)
+	result.Objects = xdInfo.ObjectCount
+	result.LayerNames = xdInfo.LayerNames
+
+	for _, artboard := range xdInfo.Artboards {
+		result.ArtboardList = append(result.ArtboardList, ArtboardInfo{
+			Name: artboard.Name, Width: artboard.Width, Height: artboard.Height,
+		})
+	}
+	result.LayerTree = xdLayerTree(xdInfo.Layers)
+	result.Components = xdInfo.Components
+	result.Instances = len(xdInfo.ComponentInstances)
+	result.Texts = xdInfo.Texts
+	result.Assets = xdInfo.Assets
+	return result, nil
+}
+
+// xdLayerTree converts an XD scene graph
+func xdLayerTree(layers []xd.Layer) []LayerNode {
+	var nodes []LayerNode
+	for _, layer := range layers {
+		nodes = append(nodes, LayerNode{Name: layer.Name, Type: layer.Type, Children: xdLayerTree(layer.Children)})
+	}
+	return nodes
+}
+
+// analyzeBlender performs detailed Blender file analysis
+func (ds *DetailedScanner) analyzeBlender(filePath string, result *DetailedFileInfo) (*DetailedFileInfo, error) {
+	blenderInfo, err := blender.GetBlenderInfo(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	if len(blenderInfo.Scenes) > 0 && blenderInfo.Scenes[0].Width > 0 {
+		first := blenderInfo.Scenes[0]
+		result.Dimensions = fmt.Sprintf("%dx%d px", first.Width, first.Height)
+	}
+	result.Version = "Blender " + blenderInfo.Version
+	if blenderInfo.Compression != "" {
+		result.Version += fmt.Sprintf(" (%s compressed)", blenderInfo.Compression)
+	}
+	result.Layers = len(blenderInfo.Collections)
+	result.LayerNames = blenderInfo.Collections
+	result.Artboards = len(blenderInfo.Scenes)
+	result.Objects = len(blenderInfo.Objects)
+	result.LayerTree = blenderLayerTree(blenderInfo.Tree)
+
+	for _, scene := range blenderInfo.Scenes {
+		result.Scenes = append(result.Scenes, scene.Name)
+	}
+	for _, mesh := range blenderInfo.Meshes {
+		result.Meshes = append(result.Meshes, MeshInfo{Name: mesh.Name, Vertices: mesh.Vertices, Faces: mesh.Faces})
+	}
+	result.Materials = blenderInfo.Materials
+	for _, image := range blenderInfo.Images {
+		if image.Packed {
+			result.Images = append(result.Images, image.Name)
+		} else {
+			result.Assets = append(result.Assets, image.Path)
+		}
+	}
+	return result, nil
+}
+
+// blenderLayerTree converts a Blender scene tree
+func blenderLayerTree(layers []blender.Layer) []LayerNode {
+	var nodes []LayerNode
+	for _, layer := range layers {
+		nodes = append(nodes, LayerNode{Name: layer.Name, Type: layer.Type, Children: blenderLayerTree(layer.Children)})
+	}
+	return nodes
+}
+
+// analyzeFBX performs detailed FBX file analysis
+func (ds *DetailedScanner) analyzeFBX(filePath string, result *DetailedFileInfo) (*DetailedFileInfo, error) {
+	fbxInfo, err := fbx.GetFBXInfo(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	result.Version = fbxVersion(fbxInfo)
+	if fbxInfo.Creator != "" {
+		result.Version += ", exported by " + fbxInfo.Creator
+	}
+	result.Layers = len(fbxInfo.Models)
+	result.Objects = len(fbxInfo.Models)
+	for _, model := range fbxInfo.Models {
+		result.LayerNames = append(result.LayerNames, model.Name)
+	}
+	result.LayerTree = fbxLayerTree(fbxInfo.Tree)
+
+	for _, mesh := range fbxInfo.Meshes {
+		result.Meshes = append(result.Meshes, MeshInfo{Name: mesh.Name, Vertices: mesh.Vertices, Faces: mesh.Polygons})
+	}
+	result.Materials = fbxInfo.Materials
+	for _, texture := range fbxInfo.Textures {
+		if texture.File != "" {
+			result.Assets = append(result.Assets, texture.File)
+		} else {
+			result.Assets = append(result.Assets, texture.Name)
+		}
+	}
+	result.Animations = fbxInfo.AnimationStacks
+	return result, nil
+}
+
+// fbxLayerTree converts an FBX model hierarchy
+func fbxLayerTree(layers []fbx.Layer) []LayerNode {
+	var nodes []LayerNode
+	for _, layer := range layers {
+		nodes = append(nodes, LayerNode{Name: layer.Name, Type: layer.Type, Children: fbxLayerTree(layer.Children)})
+	}
+	return nodes
+}
+
+// analyzeOBJ performs detailed Wavefront OBJ file analysis
+func (ds *DetailedScanner) analyzeOBJ(filePath string, result *DetailedFileInfo) (*DetailedFileInfo, error) {
+	objInfo, err := obj.GetOBJInfo(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	result.Version = "Wavefront OBJ"
+	if objInfo.Vertices > 0 {
+		result.Dimensions = objDimensions(objInfo)
+	}
+	result.Layers = len(objInfo.Groups)
+	result.LayerNames = objInfo.Groups
+	result.Objects = len(objInfo.Objects)
+	for _, object := range objInfo.Objects {
+		name := object.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		result.Meshes = append(result.Meshes, MeshInfo{Name: name, Vertices: object.Vertices, Faces: object.Faces})
+	}
+	result.Materials = objInfo.Materials
+	result.Assets = append(append([]string{}, objInfo.Libraries...), objInfo.Textures...)
 	return result, nil
 }
 
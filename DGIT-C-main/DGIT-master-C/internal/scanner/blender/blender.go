@@ -0,0 +1,460 @@
+package blender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// BlenderInfo contains metadata extracted from Blender files
+// A .blend file is a header (pointer size, byte order, version) followed by file blocks:
+// each holds structs of one SDNA type, and the DNA1 block describes every struct's layout.
+// Files saved with compression are wrapped whole in Zstandard (3.0+) or gzip (older).
+type BlenderInfo struct {
+	Version     string   // Blender version that saved the file, e.g. "4.2"
+	PointerSize int      // 4 or 8 bytes
+	BigEndian   bool     // Byte order of the saving machine
+	Compression string   // zstd, gzip or "" for uncompressed files
+	Scenes      []Scene  // Scenes with their render resolution
+	Objects     []Object // Objects of all scenes
+	Meshes      []Mesh   // Mesh data blocks with their geometry counts
+	Materials   []string // Material names
+	Images      []Image  // Image data blocks, packed or linked to external files
+	Collections []string // Collection names, excluding the scenes' master collections
+	Tree        []Layer  // Scenes with their collections and objects
+}
+
+// Scene is a Blender scene
+type Scene struct {
+	Name   string
+	Width  int // Render resolution, before the percentage scale
+	Height int
+}
+
+// Object is a Blender object with its type
+type Object struct {
+	Name string
+	Type string // mesh, camera, light, empty, armature, ...
+}
+
+// Mesh is a mesh data block
+type Mesh struct {
+	Name     string
+	Vertices int
+	Faces    int
+}
+
+// Image is an image data block
+type Image struct {
+	Name   string
+	Path   string // File path, relative paths start with "//"
+	Packed bool   // Whether the image data is stored inside the .blend
+}
+
+// Layer is a scene, collection or object of the scene tree
+type Layer struct {
+	Name     string
+	Type     string
+	Children []Layer
+}
+
+// objectTypes maps Object.type values to names
+var objectTypes = map[int64]string{
+	0:  "empty",
+	1:  "mesh",
+	2:  "curve",
+	3:  "surface",
+	4:  "text",
+	5:  "metaball",
+	10: "light",
+	11: "camera",
+	12: "speaker",
+	13: "light probe",
+	22: "lattice",
+	25: "armature",
+	26: "grease pencil",
+	27: "curves",
+	28: "point cloud",
+	29: "volume",
+	30: "grease pencil",
+}
+
+// fileBlock is a block of a .blend file
+type fileBlock struct {
+	Code string
+	SDNA int
+	Old  uint64 // Address of the data when it was saved, used by pointers
+	Data []byte
+}
+
+// blendFile is a parsed .blend file
+type blendFile struct {
+	order       binary.ByteOrder
+	pointerSize int
+	blocks      []fileBlock
+	byAddress   map[uint64]int
+	dna         *sdna
+}
+
+// GetBlenderInfo extracts scenes, objects, meshes, materials, images and collections from a .blend file
+func GetBlenderInfo(filePath string) (*BlenderInfo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Blender file: %w", err)
+	}
+
+	info := &BlenderInfo{
+		Scenes:      []Scene{},
+		Objects:     []Object{},
+		Meshes:      []Mesh{},
+		Materials:   []string{},
+		Images:      []Image{},
+		Collections: []string{},
+	}
+	if data, info.Compression, err = decompress(data); err != nil {
+		return nil, err
+	}
+
+	file, version, err := parseBlend(data)
+	if err != nil {
+		return nil, err
+	}
+	info.Version = version
+	info.PointerSize = file.pointerSize
+	info.BigEndian = file.order == binary.BigEndian
+	file.collect(info)
+	return info, nil
+}
+
+// decompress unwraps Zstandard and gzip compressed files
+func decompress(data []byte) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		decoder, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open compressed Blender file: %w", err)
+		}
+		defer decoder.Close()
+		out, err := io.ReadAll(decoder)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress Blender file: %w", err)
+		}
+		return out, "zstd", nil
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open compressed Blender file: %w", err)
+		}
+		defer reader.Close()
+		out, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress Blender file: %w", err)
+		}
+		return out, "gzip", nil
+	}
+	return data, "", nil
+}
+
+// parseBlend reads the header, the file blocks and the SDNA catalogue
+// Blender 5.0 introduced a longer header, "BLENDER17-01v0500", with 64-bit block lengths.
+func parseBlend(data []byte) (*blendFile, string, error) {
+	if len(data) < 12 || string(data[:7]) != "BLENDER" {
+		return nil, "", fmt.Errorf("not a Blender file")
+	}
+
+	file := &blendFile{byAddress: make(map[uint64]int)}
+	var headerSize, versionNumber int
+	largeHeads := false
+	switch {
+	case data[7] == '_' || data[7] == '-':
+		file.pointerSize = 4
+		if data[7] == '-' {
+			file.pointerSize = 8
+		}
+		file.order = binary.LittleEndian
+		if data[8] == 'V' {
+			file.order = binary.BigEndian
+		}
+		headerSize = 12
+		versionNumber, _ = strconv.Atoi(string(data[9:12]))
+	case len(data) >= 17 && data[9] == '-' && data[12] == 'v':
+		size, err := strconv.Atoi(string(data[7:9]))
+		if err != nil || size < 17 || size > len(data) {
+			return nil, "", fmt.Errorf("invalid Blender header")
+		}
+		file.pointerSize = 8
+		file.order = binary.LittleEndian
+		headerSize = size
+		versionNumber, _ = strconv.Atoi(string(data[13:17]))
+		largeHeads = true
+	default:
+		return nil, "", fmt.Errorf("unsupported Blender header %q", data[:12])
+	}
+	version := fmt.Sprintf("%d.%d", versionNumber/100, versionNumber%100)
+
+	var dnaData []byte
+	for pos := headerSize; pos < len(data); {
+		block, next, err := file.readBlock(data, pos, largeHeads)
+		if err != nil {
+			return nil, "", err
+		}
+		pos = next
+		if block.Code == "ENDB" {
+			break
+		}
+		if block.Code == "DNA1" {
+			dnaData = block.Data
+		}
+		if block.Old != 0 {
+			file.byAddress[block.Old] = len(file.blocks)
+		}
+		file.blocks = append(file.blocks, block)
+	}
+	if dnaData == nil {
+		return nil, "", fmt.Errorf("Blender file has no SDNA block")
+	}
+	dna, err := parseSDNA(dnaData, file.order, file.pointerSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse SDNA: %w", err)
+	}
+	file.dna = dna
+	return file, version, nil
+}
+
+// readBlock reads the block header at pos and returns the block and the offset of the next one
+func (f *blendFile) readBlock(data []byte, pos int, largeHeads bool) (fileBlock, int, error) {
+	var block fileBlock
+	var length int64
+	headSize := 16 + f.pointerSize
+	if largeHeads {
+		headSize = 32
+	}
+	if pos+headSize > len(data) {
+		return block, 0, fmt.Errorf("truncated block header at offset %d", pos)
+	}
+	head := data[pos : pos+headSize]
+	block.Code = strings.TrimRight(string(head[:4]), "\x00")
+	if largeHeads {
+		block.SDNA = int(int32(f.order.Uint32(head[4:8])))
+		block.Old = f.order.Uint64(head[8:16])
+		length = int64(f.order.Uint64(head[16:24]))
+	} else {
+		length = int64(int32(f.order.Uint32(head[4:8])))
+		block.Old = f.pointer(head, 8)
+		block.SDNA = int(int32(f.order.Uint32(head[8+f.pointerSize:])))
+	}
+	start := pos + headSize
+	if length < 0 || length > int64(len(data)-start) {
+		return block, 0, fmt.Errorf("block %s at offset %d is truncated", block.Code, pos)
+	}
+	block.Data = data[start : start+int(length)]
+	return block, start + int(length), nil
+}
+
+// collect fills info from the ID blocks of the file
+func (f *blendFile) collect(info *BlenderInfo) {
+	// Master collections are embedded in their scene, though some versions write them as GR blocks
+	masters := make(map[uint64]bool)
+	for i := range f.blocks {
+		if f.blocks[i].Code == "SC" {
+			masters[f.pointerField(&f.blocks[i], "master_collection")] = true
+		}
+	}
+
+	for i := range f.blocks {
+		block := &f.blocks[i]
+		switch block.Code {
+		case "SC":
+			scene := Scene{Name: f.idName(block)}
+			scene.Width = int(f.integer(block, "r", "xsch"))
+			scene.Height = int(f.integer(block, "r", "ysch"))
+			info.Scenes = append(info.Scenes, scene)
+			info.Tree = append(info.Tree, f.sceneTree(block, scene.Name))
+		case "OB":
+			info.Objects = append(info.Objects, Object{Name: f.idName(block), Type: f.objectType(block)})
+		case "ME":
+			mesh := Mesh{Name: f.idName(block)}
+			mesh.Vertices = int(f.firstInteger(block, "verts_num", "totvert"))
+			mesh.Faces = int(f.firstInteger(block, "faces_num", "totpoly", "totface"))
+			info.Meshes = append(info.Meshes, mesh)
+		case "MA":
+			info.Materials = append(info.Materials, f.idName(block))
+		case "IM":
+			image := Image{Name: f.idName(block)}
+			image.Path = f.text(block, "filepath")
+			if image.Path == "" {
+				image.Path = f.text(block, "name")
+			}
+			image.Packed = f.pointerField(block, "packedfile") != 0 || f.pointerField(block, "packedfiles", "first") != 0
+			info.Images = append(info.Images, image)
+		case "GR":
+			if masters[block.Old] {
+				continue
+			}
+			info.Collections = append(info.Collections, f.idName(block))
+		}
+	}
+}
+
+// sceneTree builds the collection and object hierarchy of a scene
+// Scenes of 2.80 and later own a master collection; older scenes list their objects as bases.
+func (f *blendFile) sceneTree(scene *fileBlock, name string) Layer {
+	layer := Layer{Name: name, Type: "scene"}
+	if master, ok := f.blockAt(f.pointerField(scene, "master_collection")); ok {
+		layer.Children = f.collectionChildren(master, make(map[uint64]bool))
+		return layer
+	}
+	for _, base := range f.list(f.pointerField(scene, "base", "first")) {
+		if object, ok := f.blockAt(f.pointerField(base, "object")); ok {
+			layer.Children = append(layer.Children, Layer{Name: f.idName(object), Type: f.objectType(object)})
+		}
+	}
+	return layer
+}
+
+// collectionChildren lists the child collections and the objects of a collection
+func (f *blendFile) collectionChildren(collection *fileBlock, visited map[uint64]bool) []Layer {
+	if visited[collection.Old] {
+		return nil
+	}
+	visited[collection.Old] = true
+
+	var children []Layer
+	for _, child := range f.list(f.pointerField(collection, "children", "first")) {
+		if nested, ok := f.blockAt(f.pointerField(child, "collection")); ok {
+			children = append(children, Layer{
+				Name: f.idName(nested), Type: "collection", Children: f.collectionChildren(nested, visited),
+			})
+		}
+	}
+	for _, member := range f.list(f.pointerField(collection, "gobject", "first")) {
+		if object, ok := f.blockAt(f.pointerField(member, "ob")); ok {
+			children = append(children, Layer{Name: f.idName(object), Type: f.objectType(object)})
+		}
+	}
+	return children
+}
+
+// list follows the next pointers of a linked list starting at address
+func (f *blendFile) list(address uint64) []*fileBlock {
+	var elements []*fileBlock
+	seen := make(map[uint64]bool)
+	for address != 0 && !seen[address] && len(elements) < 100000 {
+		seen[address] = true
+		block, ok := f.blockAt(address)
+		if !ok {
+			break
+		}
+		elements = append(elements, block)
+		address = f.pointerField(block, "next")
+	}
+	return elements
+}
+
+// blockAt returns the block saved at an address
+func (f *blendFile) blockAt(address uint64) (*fileBlock, bool) {
+	if address == 0 {
+		return nil, false
+	}
+	i, ok := f.byAddress[address]
+	if !ok {
+		return nil, false
+	}
+	return &f.blocks[i], true
+}
+
+// idName returns the name of an ID block without its two-letter type prefix
+func (f *blendFile) idName(block *fileBlock) string {
+	name := f.text(block, "id", "name")
+	if len(name) > 2 {
+		return name[2:]
+	}
+	return name
+}
+
+// objectType returns the type name of an object block
+func (f *blendFile) objectType(block *fileBlock) string {
+	value := f.integer(block, "type")
+	if name, ok := objectTypes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("type %d", value)
+}
+
+// member locates a member in the first struct of a block
+// Layouts come from the file, so offsets and sizes that overflowed are rejected here.
+func (f *blendFile) member(block *fileBlock, path ...string) (sdnaField, bool) {
+	field, ok := f.dna.field(block.SDNA, path...)
+	if !ok || field.Offset < 0 || field.Size < 0 || field.Size > len(block.Data)-field.Offset {
+		return sdnaField{}, false
+	}
+	return field, true
+}
+
+// text reads a NUL-terminated character array member
+func (f *blendFile) text(block *fileBlock, path ...string) string {
+	field, ok := f.member(block, path...)
+	if !ok || field.Pointer {
+		return ""
+	}
+	value := block.Data[field.Offset : field.Offset+field.Size]
+	if end := bytes.IndexByte(value, 0); end >= 0 {
+		value = value[:end]
+	}
+	return string(value)
+}
+
+// integer reads a signed integer member of any width, or 0 when it is missing
+func (f *blendFile) integer(block *fileBlock, path ...string) int64 {
+	field, ok := f.member(block, path...)
+	if !ok || field.Pointer {
+		return 0
+	}
+	value := block.Data[field.Offset:]
+	switch field.Size {
+	case 1:
+		return int64(int8(value[0]))
+	case 2:
+		return int64(int16(f.order.Uint16(value)))
+	case 4:
+		return int64(int32(f.order.Uint32(value)))
+	case 8:
+		return int64(f.order.Uint64(value))
+	}
+	return 0
+}
+
+// firstInteger reads the first of several alternative members that exists
+// Members are renamed between versions, such as totvert becoming verts_num in 3.4.
+func (f *blendFile) firstInteger(block *fileBlock, names ...string) int64 {
+	for _, name := range names {
+		if _, ok := f.member(block, name); ok {
+			return f.integer(block, name)
+		}
+	}
+	return 0
+}
+
+// pointerField reads a pointer member, or 0 when it is missing
+func (f *blendFile) pointerField(block *fileBlock, path ...string) uint64 {
+	field, ok := f.member(block, path...)
+	if !ok || !field.Pointer {
+		return 0
+	}
+	return f.pointer(block.Data, field.Offset)
+}
+
+// pointer reads an address of the file's pointer size
+func (f *blendFile) pointer(data []byte, offset int) uint64 {
+	if f.pointerSize == 4 {
+		return uint64(f.order.Uint32(data[offset:]))
+	}
+	return f.order.Uint64(data[offset:])
+}
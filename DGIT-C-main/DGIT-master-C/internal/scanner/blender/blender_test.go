@@ -0,0 +1,396 @@
+package blender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// testStructs is a reduced DNA catalogue: a struct type followed by its member declarations
+var testStructs = [][]string{
+	{"ListBase", "void", "*first", "void", "*last"},
+	{"ID", "void", "*next", "void", "*prev", "char", "name[66]"},
+	{"RenderData", "int", "xsch", "int", "ysch"},
+	{"Base", "Base", "*next", "Base", "*prev", "Object", "*object"},
+	{"Scene", "ID", "id", "RenderData", "r", "ListBase", "base", "Collection", "*master_collection"},
+	{"Object", "ID", "id", "short", "type"},
+	{"Mesh", "ID", "id", "int", "totvert", "int", "totpoly"},
+	{"Material", "ID", "id"},
+	{"Image", "ID", "id", "char", "filepath[64]", "PackedFile", "*packedfile"},
+	{"Collection", "ID", "id", "ListBase", "gobject", "ListBase", "children"},
+	{"CollectionObject", "CollectionObject", "*next", "CollectionObject", "*prev", "Object", "*ob"},
+	{"CollectionChild", "CollectionChild", "*next", "CollectionChild", "*prev", "Collection", "*collection"},
+}
+
+// testHeader selects the file header and block head layout
+type testHeader struct {
+	pointerSize int
+	order       binary.ByteOrder
+	large       bool // Blender 5.0 header with 64-bit block lengths
+}
+
+// encodeDNA writes the DNA1 block data for a catalogue, deriving the struct sizes from the members
+func encodeDNA(h testHeader, structs [][]string) []byte {
+	types := []string{"char", "short", "int", "void", "PackedFile"}
+	lengths := map[string]int{"char": 1, "short": 2, "int": 4}
+	typeIndex := func(name string) int {
+		for i, t := range types {
+			if t == name {
+				return i
+			}
+		}
+		types = append(types, name)
+		return len(types) - 1
+	}
+	var names []string
+	nameIndex := func(name string) int {
+		names = append(names, name)
+		return len(names) - 1
+	}
+
+	var strc bytes.Buffer
+	binary.Write(&strc, h.order, uint32(len(structs)))
+	for _, s := range structs {
+		binary.Write(&strc, h.order, uint16(typeIndex(s[0])))
+		binary.Write(&strc, h.order, uint16(len(s)/2))
+		size := 0
+		for i := 1; i+1 < len(s); i += 2 {
+			binary.Write(&strc, h.order, uint16(typeIndex(s[i])))
+			binary.Write(&strc, h.order, uint16(nameIndex(s[i+1])))
+			size += memberLayout(s[i+1], lengths[s[i]], h.pointerSize).Size
+		}
+		lengths[s[0]] = size
+	}
+
+	var b bytes.Buffer
+	section := func(id string, values []string) {
+		b.WriteString(id)
+		binary.Write(&b, h.order, uint32(len(values)))
+		for _, v := range values {
+			b.WriteString(v)
+			b.WriteByte(0)
+		}
+		for b.Len()%4 != 0 {
+			b.WriteByte(0)
+		}
+	}
+	b.WriteString("SDNA")
+	section("NAME", names)
+	section("TYPE", types)
+	b.WriteString("TLEN")
+	for _, t := range types {
+		binary.Write(&b, h.order, uint16(lengths[t]))
+	}
+	for b.Len()%4 != 0 {
+		b.WriteByte(0)
+	}
+	b.WriteString("STRC")
+	b.Write(strc.Bytes())
+	return b.Bytes()
+}
+
+// testBlock is a file block whose struct members are set by path, such as "id.name"
+type testBlock struct {
+	code    string
+	typ     string
+	address uint64
+	values  map[string]interface{}
+}
+
+// buildBlend writes a .blend file with the given blocks, the DNA1 block and ENDB
+func buildBlend(t *testing.T, h testHeader, structs [][]string, blocks []testBlock) []byte {
+	t.Helper()
+	dnaData := encodeDNA(h, structs)
+	dna, err := parseSDNA(dnaData, h.order, h.pointerSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	switch {
+	case h.large:
+		b.WriteString("BLENDER17-01v0500")
+	case h.pointerSize == 4 && h.order == binary.BigEndian:
+		b.WriteString("BLENDER_V279")
+	default:
+		b.WriteString("BLENDER-v402")
+	}
+	writeBlock := func(code string, sdnaIndex int, address uint64, data []byte) {
+		head := make([]byte, 4)
+		copy(head, code)
+		b.Write(head)
+		if h.large {
+			binary.Write(&b, h.order, []uint32{uint32(sdnaIndex)})
+			binary.Write(&b, h.order, []uint64{address, uint64(len(data)), 1})
+		} else {
+			binary.Write(&b, h.order, uint32(len(data)))
+			if h.pointerSize == 4 {
+				binary.Write(&b, h.order, uint32(address))
+			} else {
+				binary.Write(&b, h.order, address)
+			}
+			binary.Write(&b, h.order, []uint32{uint32(sdnaIndex), 1})
+		}
+		b.Write(data)
+	}
+
+	for _, block := range blocks {
+		index := dna.byType[block.typ]
+		data := make([]byte, dna.Structs[index].Size)
+		for path, value := range block.values {
+			field, ok := dna.field(index, strings.Split(path, ".")...)
+			if !ok {
+				t.Fatalf("%s has no member %s", block.typ, path)
+			}
+			out := data[field.Offset : field.Offset+field.Size]
+			switch v := value.(type) {
+			case string:
+				copy(out, v)
+			case int:
+				switch field.Size {
+				case 2:
+					h.order.PutUint16(out, uint16(v))
+				case 4:
+					h.order.PutUint32(out, uint32(v))
+				}
+			case uint64:
+				if h.pointerSize == 4 {
+					h.order.PutUint32(out, uint32(v))
+				} else {
+					h.order.PutUint64(out, v)
+				}
+			}
+		}
+		writeBlock(block.code, index, block.address, data)
+	}
+	writeBlock("DNA1", 0, 0, dnaData)
+	writeBlock("ENDB", 0, 0, nil)
+	return b.Bytes()
+}
+
+// sceneBlocks is a 2.80+ scene with a nested collection, or with a legacy base list when legacy is set
+func sceneBlocks(legacy bool) []testBlock {
+	scene := testBlock{"SC", "Scene", 0x1000, map[string]interface{}{
+		"id.name": "SCScene", "r.xsch": 1920, "r.ysch": 1080, "master_collection": uint64(0x2000),
+	}}
+	if legacy {
+		scene.values["master_collection"] = uint64(0)
+		scene.values["base.first"] = uint64(0xa000)
+	}
+	return []testBlock{
+		scene,
+		{"GR", "Collection", 0x2000, map[string]interface{}{
+			"id.name": "GRScene Collection", "children.first": uint64(0x3000), "gobject.first": uint64(0x4000),
+		}},
+		{"DATA", "CollectionChild", 0x3000, map[string]interface{}{"collection": uint64(0x2100)}},
+		{"GR", "Collection", 0x2100, map[string]interface{}{"id.name": "GRProps", "gobject.first": uint64(0x4100)}},
+		{"DATA", "CollectionObject", 0x4000, map[string]interface{}{"ob": uint64(0x5000), "next": uint64(0x4001)}},
+		{"DATA", "CollectionObject", 0x4001, map[string]interface{}{"ob": uint64(0x5001)}},
+		{"DATA", "CollectionObject", 0x4100, map[string]interface{}{"ob": uint64(0x5002)}},
+		{"DATA", "Base", 0xa000, map[string]interface{}{"object": uint64(0x5000), "next": uint64(0xa001)}},
+		{"DATA", "Base", 0xa001, map[string]interface{}{"object": uint64(0x5001)}},
+		{"OB", "Object", 0x5000, map[string]interface{}{"id.name": "OBCube", "type": 1}},
+		{"OB", "Object", 0x5001, map[string]interface{}{"id.name": "OBCamera", "type": 11}},
+		{"OB", "Object", 0x5002, map[string]interface{}{"id.name": "OBLamp", "type": 10}},
+		{"ME", "Mesh", 0x6000, map[string]interface{}{"id.name": "MECube", "totvert": 8, "totpoly": 6}},
+		{"MA", "Material", 0x7000, map[string]interface{}{"id.name": "MAMaterial"}},
+		{"IM", "Image", 0x8000, map[string]interface{}{"id.name": "IMwood.png", "filepath": "//textures/wood.png"}},
+		{"IM", "Image", 0x8001, map[string]interface{}{"id.name": "IMlogo.png", "filepath": "logo.png", "packedfile": uint64(0x9000)}},
+	}
+}
+
+func TestGetBlenderInfo(t *testing.T) {
+	current := testHeader{pointerSize: 8, order: binary.LittleEndian}
+	info, err := GetBlenderInfo(scantest.WriteFile(t, "scene.blend", buildBlend(t, current, testStructs, sceneBlocks(false))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "4.2" || info.PointerSize != 8 || info.BigEndian || info.Compression != "" {
+		t.Errorf("version %s, %d-byte pointers, big endian %v, compression %q", info.Version, info.PointerSize, info.BigEndian, info.Compression)
+	}
+	if want := []Scene{{Name: "Scene", Width: 1920, Height: 1080}}; !reflect.DeepEqual(info.Scenes, want) {
+		t.Errorf("scenes = %+v", info.Scenes)
+	}
+	wantObjects := []Object{{Name: "Cube", Type: "mesh"}, {Name: "Camera", Type: "camera"}, {Name: "Lamp", Type: "light"}}
+	if !reflect.DeepEqual(info.Objects, wantObjects) {
+		t.Errorf("objects = %+v", info.Objects)
+	}
+	if want := []Mesh{{Name: "Cube", Vertices: 8, Faces: 6}}; !reflect.DeepEqual(info.Meshes, want) {
+		t.Errorf("meshes = %+v", info.Meshes)
+	}
+	if !reflect.DeepEqual(info.Materials, []string{"Material"}) || !reflect.DeepEqual(info.Collections, []string{"Props"}) {
+		t.Errorf("materials %v, collections %v", info.Materials, info.Collections)
+	}
+	wantImages := []Image{{Name: "wood.png", Path: "//textures/wood.png"}, {Name: "logo.png", Path: "logo.png", Packed: true}}
+	if !reflect.DeepEqual(info.Images, wantImages) {
+		t.Errorf("images = %+v", info.Images)
+	}
+	wantTree := []Layer{{Name: "Scene", Type: "scene", Children: []Layer{
+		{Name: "Props", Type: "collection", Children: []Layer{{Name: "Lamp", Type: "light"}}},
+		{Name: "Cube", Type: "mesh"},
+		{Name: "Camera", Type: "camera"},
+	}}}
+	if !reflect.DeepEqual(info.Tree, wantTree) {
+		t.Errorf("tree = %+v", info.Tree)
+	}
+}
+
+func TestGetBlenderInfoHeaderVariants(t *testing.T) {
+	legacy := testHeader{pointerSize: 4, order: binary.BigEndian}
+	info, err := GetBlenderInfo(scantest.WriteFile(t, "scene.blend", buildBlend(t, legacy, testStructs, sceneBlocks(true))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "2.79" || info.PointerSize != 4 || !info.BigEndian {
+		t.Errorf("version %s, %d-byte pointers, big endian %v", info.Version, info.PointerSize, info.BigEndian)
+	}
+	// Without a master collection the tree lists the scene's bases
+	wantTree := []Layer{{Name: "Scene", Type: "scene", Children: []Layer{{Name: "Cube", Type: "mesh"}, {Name: "Camera", Type: "camera"}}}}
+	if !reflect.DeepEqual(info.Tree, wantTree) || len(info.Images) != 2 {
+		t.Errorf("tree %+v, images %+v", info.Tree, info.Images)
+	}
+
+	large := buildBlend(t, testHeader{pointerSize: 8, order: binary.LittleEndian, large: true}, testStructs, sceneBlocks(false))
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write(large)
+	w.Close()
+	info, err = GetBlenderInfo(scantest.WriteFile(t, "scene.blend", compressed.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "5.0" || info.Compression != "gzip" || len(info.Objects) != 3 || len(info.Tree) != 1 {
+		t.Errorf("version %s, compression %q, objects %+v", info.Version, info.Compression, info.Objects)
+	}
+}
+
+func TestGetBlenderInfoRejectsOversizedLengths(t *testing.T) {
+	h := testHeader{pointerSize: 8, order: binary.LittleEndian}
+	valid := buildBlend(t, h, testStructs, sceneBlocks(false))
+
+	negative := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(negative[16:], 0xffffffff)
+	oversized := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(oversized[16:], math.MaxInt32)
+
+	largeHeads := buildBlend(t, testHeader{pointerSize: 8, order: binary.LittleEndian, large: true}, testStructs, sceneBlocks(false))
+	binary.LittleEndian.PutUint64(largeHeads[17+16:], math.MaxUint64)
+
+	dnaCount := bytes.Replace(valid, []byte("SDNANAME"), []byte("SDNANAME\xff\xff\xff\x7f"), 1)
+
+	cases := map[string][]byte{
+		"negative block length":  negative,
+		"oversized block length": oversized,
+		"64-bit block length":    largeHeads,
+		"oversized name count":   dnaCount,
+		"not a Blender file":     []byte("BLENDIR-v402 not really"),
+		"no SDNA":                valid[:12+24+len(valid)/100],
+	}
+	for name, data := range cases {
+		if _, err := GetBlenderInfo(scantest.WriteFile(t, "scene.blend", data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGetBlenderInfoIgnoresOverflowingMembers(t *testing.T) {
+	// Array dimensions whose product overflows must not place members outside the block
+	structs := append([][]string{}, testStructs...)
+	for i, s := range structs {
+		if s[0] == "Object" {
+			structs[i] = []string{"Object", "ID", "id", "char", "pad[4000000000][4000000000]", "short", "type"}
+		}
+	}
+	info, err := GetBlenderInfo(scantest.WriteFile(t, "scene.blend", buildBlend(t, testHeader{pointerSize: 8, order: binary.LittleEndian}, structs, []testBlock{
+		{"OB", "Object", 0x5000, map[string]interface{}{"id.name": "OBCube"}},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Object{{Name: "Cube", Type: "empty"}}; !reflect.DeepEqual(info.Objects, want) {
+		t.Errorf("objects = %+v", info.Objects)
+	}
+}
+
+func TestGetBlenderInfoReadsIDsThroughSDNA(t *testing.T) {
+	// Member offsets come from each file's catalogue, so the same data blocks are read from
+	// differently laid out structs as Blender versions add, pad and rename members
+	cases := []struct {
+		name     string
+		id       []string
+		object   []string
+		mesh     []string
+		vertices string
+		faces    string
+	}{
+		{
+			name:     "2.7x",
+			id:       []string{"ID", "void", "*next", "void", "*prev", "char", "name[66]"},
+			object:   []string{"Object", "ID", "id", "short", "type"},
+			mesh:     []string{"Mesh", "ID", "id", "int", "totvert", "int", "totface"},
+			vertices: "totvert",
+			faces:    "totface",
+		},
+		{
+			name:     "4.x with padding",
+			id:       []string{"ID", "void", "*next", "void", "*prev", "char", "_pad[12]", "char", "name[66]"},
+			object:   []string{"Object", "ID", "id", "void", "*data", "char", "_pad[5]", "short", "type"},
+			mesh:     []string{"Mesh", "ID", "id", "int", "verts_num", "int", "totpoly", "int", "faces_num"},
+			vertices: "verts_num",
+			faces:    "faces_num",
+		},
+		{
+			name:     "5.0 long names",
+			id:       []string{"ID", "void", "*next", "void", "*prev", "char", "name[258]"},
+			object:   []string{"Object", "ID", "id", "short", "type"},
+			mesh:     []string{"Mesh", "ID", "id", "int", "verts_num", "int", "faces_num"},
+			vertices: "verts_num",
+			faces:    "faces_num",
+		},
+	}
+	for _, c := range cases {
+		structs := [][]string{c.id, c.object, c.mesh, {"Material", "ID", "id"}}
+		blocks := []testBlock{
+			{"OB", "Object", 0x100, map[string]interface{}{"id.name": "OBCube", "type": 1}},
+			{"OB", "Object", 0x101, map[string]interface{}{"id.name": "OBSun", "type": 10}},
+			{"OB", "Object", 0x102, map[string]interface{}{"id.name": "OBRig", "type": 25}},
+			{"OB", "Object", 0x103, map[string]interface{}{"id.name": "OBFuture", "type": 99}},
+			{"ME", "Mesh", 0x200, map[string]interface{}{"id.name": "MECube", c.vertices: 8, c.faces: 6}},
+			{"MA", "Material", 0x300, map[string]interface{}{"id.name": "MAMetal"}},
+			{"MA", "Material", 0x301, map[string]interface{}{"id.name": "MAGlass"}},
+		}
+		data := buildBlend(t, testHeader{pointerSize: 8, order: binary.LittleEndian}, structs, blocks)
+		info, err := GetBlenderInfo(scantest.WriteFile(t, "scene.blend", data))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		wantObjects := []Object{
+			{Name: "Cube", Type: "mesh"}, {Name: "Sun", Type: "light"}, {Name: "Rig", Type: "armature"}, {Name: "Future", Type: "type 99"},
+		}
+		if !reflect.DeepEqual(info.Objects, wantObjects) {
+			t.Errorf("%s: objects = %+v", c.name, info.Objects)
+		}
+		if !reflect.DeepEqual(info.Materials, []string{"Metal", "Glass"}) {
+			t.Errorf("%s: materials = %v", c.name, info.Materials)
+		}
+		if want := []Mesh{{Name: "Cube", Vertices: 8, Faces: 6}}; !reflect.DeepEqual(info.Meshes, want) {
+			t.Errorf("%s: meshes = %+v", c.name, info.Meshes)
+		}
+	}
+}
+
+func TestGetBlenderInfoDoesNotPanicOnTruncation(t *testing.T) {
+	h := testHeader{pointerSize: 8, order: binary.LittleEndian}
+	scantest.TruncatedFiles(t, "scene.blend", buildBlend(t, h, testStructs, sceneBlocks(false)), func(path string) {
+		GetBlenderInfo(path)
+	})
+	scantest.Truncations(t, encodeDNA(h, testStructs), func(prefix []byte) {
+		parseSDNA(prefix, binary.LittleEndian, 8)
+	})
+}
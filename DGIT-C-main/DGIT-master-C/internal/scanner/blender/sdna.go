@@ -0,0 +1,222 @@
+package blender
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sdnaField is a member of a DNA struct with its place in the struct's data
+type sdnaField struct {
+	Type    string
+	Name    string // Bare member name, without pointer stars or array dimensions
+	Offset  int
+	Size    int
+	Pointer bool
+}
+
+// sdnaStruct is a DNA struct of the file
+type sdnaStruct struct {
+	Type   string
+	Size   int
+	Fields []sdnaField
+}
+
+// sdna is the structure catalogue stored in the DNA1 block
+// Every file describes the memory layout of the structs it contains, so the data of any
+// Blender version can be read by member name instead of by fixed offsets.
+type sdna struct {
+	Structs []sdnaStruct
+	byType  map[string]int
+}
+
+// parseSDNA decodes the SDNA catalogue of a DNA1 block
+func parseSDNA(data []byte, order binary.ByteOrder, pointerSize int) (*sdna, error) {
+	r := &sdnaReader{data: data, order: order}
+	if err := r.expect("SDNA"); err != nil {
+		return nil, err
+	}
+	if err := r.expect("NAME"); err != nil {
+		return nil, err
+	}
+	names, err := r.strings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read member names: %w", err)
+	}
+	r.align()
+	if err := r.expect("TYPE"); err != nil {
+		return nil, err
+	}
+	types, err := r.strings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read type names: %w", err)
+	}
+	r.align()
+	if err := r.expect("TLEN"); err != nil {
+		return nil, err
+	}
+	lengths := make([]int, len(types))
+	for i := range lengths {
+		length, err := r.uint16()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read type lengths: %w", err)
+		}
+		lengths[i] = int(length)
+	}
+	r.align()
+	if err := r.expect("STRC"); err != nil {
+		return nil, err
+	}
+	count, err := r.uint32()
+	if err != nil || int(count) > len(data) {
+		return nil, fmt.Errorf("invalid struct count")
+	}
+
+	catalogue := &sdna{byType: make(map[string]int, count)}
+	for i := 0; i < int(count); i++ {
+		typeIndex, err1 := r.uint16()
+		fieldCount, err2 := r.uint16()
+		if err1 != nil || err2 != nil || int(typeIndex) >= len(types) {
+			return nil, fmt.Errorf("invalid struct %d", i)
+		}
+		structure := sdnaStruct{Type: types[typeIndex], Size: lengths[typeIndex]}
+		offset := 0
+		for j := 0; j < int(fieldCount); j++ {
+			fieldType, err1 := r.uint16()
+			fieldName, err2 := r.uint16()
+			if err1 != nil || err2 != nil || int(fieldType) >= len(types) || int(fieldName) >= len(names) {
+				return nil, fmt.Errorf("invalid member %d of %s", j, structure.Type)
+			}
+			field := memberLayout(names[fieldName], lengths[fieldType], pointerSize)
+			field.Type = types[fieldType]
+			field.Offset = offset
+			offset += field.Size
+			structure.Fields = append(structure.Fields, field)
+		}
+		catalogue.byType[structure.Type] = len(catalogue.Structs)
+		catalogue.Structs = append(catalogue.Structs, structure)
+	}
+	return catalogue, nil
+}
+
+// memberLayout derives the bare name and size of a member from its declared name
+// Declared names carry the C declarator: "*next", "name[66]", "(*func)()", "mat[4][4]".
+func memberLayout(declared string, typeSize, pointerSize int) sdnaField {
+	field := sdnaField{}
+	name := declared
+	if strings.HasPrefix(name, "*") || strings.HasPrefix(name, "(*") {
+		field.Pointer = true
+	}
+	count := 1
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		for _, dimension := range strings.Split(strings.Trim(name[i:], "[]"), "][") {
+			if n, err := strconv.Atoi(dimension); err == nil && n > 0 {
+				count *= n
+			}
+		}
+		name = name[:i]
+	}
+	field.Name = strings.Trim(name, "*()")
+	if field.Pointer {
+		field.Size = pointerSize * count
+	} else {
+		field.Size = typeSize * count
+	}
+	return field
+}
+
+// field finds a member by path, descending into embedded structs
+func (s *sdna) field(structIndex int, path ...string) (sdnaField, bool) {
+	offset := 0
+	for depth, name := range path {
+		if structIndex < 0 || structIndex >= len(s.Structs) {
+			return sdnaField{}, false
+		}
+		var found *sdnaField
+		for i := range s.Structs[structIndex].Fields {
+			if s.Structs[structIndex].Fields[i].Name == name {
+				found = &s.Structs[structIndex].Fields[i]
+				break
+			}
+		}
+		if found == nil {
+			return sdnaField{}, false
+		}
+		if depth == len(path)-1 {
+			field := *found
+			field.Offset += offset
+			return field, true
+		}
+		if found.Pointer {
+			return sdnaField{}, false
+		}
+		offset += found.Offset
+		next, ok := s.byType[found.Type]
+		if !ok {
+			return sdnaField{}, false
+		}
+		structIndex = next
+	}
+	return sdnaField{}, false
+}
+
+// sdnaReader reads the sections of a DNA1 block
+type sdnaReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+// expect consumes a four-character section identifier
+func (r *sdnaReader) expect(id string) error {
+	if r.pos+4 > len(r.data) || string(r.data[r.pos:r.pos+4]) != id {
+		return fmt.Errorf("SDNA section %s not found", id)
+	}
+	r.pos += 4
+	return nil
+}
+
+// strings reads a count followed by that many NUL-terminated strings
+func (r *sdnaReader) strings() ([]string, error) {
+	count, err := r.uint32()
+	if err != nil || int(count) > len(r.data) {
+		return nil, fmt.Errorf("invalid string count")
+	}
+	values := make([]string, count)
+	for i := range values {
+		end := bytes.IndexByte(r.data[r.pos:], 0)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		values[i] = string(r.data[r.pos : r.pos+end])
+		r.pos += end + 1
+	}
+	return values, nil
+}
+
+// align skips to the next multiple of four
+func (r *sdnaReader) align() {
+	r.pos = (r.pos + 3) &^ 3
+}
+
+// uint16 reads a two-byte integer in file byte order
+func (r *sdnaReader) uint16() (uint16, error) {
+	if r.pos+2 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of SDNA")
+	}
+	value := r.order.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return value, nil
+}
+
+// uint32 reads a four-byte integer in file byte order
+func (r *sdnaReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of SDNA")
+	}
+	value := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return value, nil
+}
@@ -0,0 +1,202 @@
+package fbx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// asciiVersionRe finds the format version in the FBXVersion header property or the leading comment
+var asciiVersionRe = regexp.MustCompile(`(?:FBXVersion:\s*(\d+)|; FBX (\d+)\.(\d+)\.\d+ project file)`)
+
+// Token kinds of the ASCII lexer
+const (
+	tokenEnd = iota
+	tokenKey
+	tokenValue
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenComma
+	tokenNewline
+)
+
+// asciiToken is a token of an ASCII FBX file
+type asciiToken struct {
+	kind int
+	text string
+}
+
+// asciiParser parses the node tree of an ASCII FBX file
+// Nodes are written as "Name: value, value, ... {" with children up to the matching "}";
+// a value list ends at the end of the line unless the line ends with a comma.
+type asciiParser struct {
+	data []byte
+	pos  int
+	peek *asciiToken
+}
+
+// parseASCII decodes the node tree of an ASCII FBX file
+func parseASCII(data []byte) (*node, int, error) {
+	version := 0
+	if match := asciiVersionRe.FindSubmatch(data[:min(len(data), 4096)]); match != nil {
+		if len(match[1]) > 0 {
+			version, _ = strconv.Atoi(string(match[1]))
+		} else {
+			major, _ := strconv.Atoi(string(match[2]))
+			minor, _ := strconv.Atoi(string(match[3]))
+			version = major*1000 + minor*100
+		}
+	}
+
+	p := &asciiParser{data: data}
+	children, err := p.nodes(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &node{Children: children}, version, nil
+}
+
+// nodes parses nodes until the end of the file or a closing brace
+func (p *asciiParser) nodes(depth int) ([]*node, error) {
+	if depth > 64 {
+		return nil, fmt.Errorf("FBX nodes are nested too deeply")
+	}
+	var nodes []*node
+	for {
+		token := p.next()
+		switch token.kind {
+		case tokenEnd, tokenClose:
+			return nodes, nil
+		case tokenNewline, tokenComma:
+			continue
+		case tokenKey:
+			n := &node{Name: token.text}
+			if err := p.properties(n, depth); err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", token.text, p.pos)
+		}
+	}
+}
+
+// properties reads the values of a node and its children, if any
+func (p *asciiParser) properties(n *node, depth int) error {
+	continued := false
+	for {
+		token := p.lookahead()
+		switch token.kind {
+		case tokenValue:
+			p.next()
+			// Integers are kept exact, as object ids use all 64 bits
+			if integer, err := strconv.ParseInt(token.text, 10, 64); err == nil {
+				n.Props = append(n.Props, integer)
+			} else if number, err := strconv.ParseFloat(token.text, 64); err == nil {
+				n.Props = append(n.Props, number)
+			} else {
+				n.Props = append(n.Props, token.text)
+			}
+			continued = false
+		case tokenString:
+			p.next()
+			n.Props = append(n.Props, token.text)
+			continued = false
+		case tokenComma:
+			p.next()
+			continued = true
+		case tokenNewline:
+			p.next()
+			if !continued {
+				return nil
+			}
+		case tokenOpen:
+			p.next()
+			children, err := p.nodes(depth + 1)
+			if err != nil {
+				return err
+			}
+			n.Children = children
+			return nil
+		default:
+			return nil // A key, closing brace or the end of the file ends the value list
+		}
+	}
+}
+
+// lookahead returns the next token without consuming it
+func (p *asciiParser) lookahead() asciiToken {
+	if p.peek == nil {
+		token := p.scan()
+		p.peek = &token
+	}
+	return *p.peek
+}
+
+// next consumes the next token
+func (p *asciiParser) next() asciiToken {
+	token := p.lookahead()
+	p.peek = nil
+	return token
+}
+
+// scan reads a token from the input
+func (p *asciiParser) scan() asciiToken {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == ';':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '\n':
+			p.pos++
+			return asciiToken{kind: tokenNewline, text: "\n"}
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '{':
+			p.pos++
+			return asciiToken{kind: tokenOpen, text: "{"}
+		case c == '}':
+			p.pos++
+			return asciiToken{kind: tokenClose, text: "}"}
+		case c == ',':
+			p.pos++
+			return asciiToken{kind: tokenComma, text: ","}
+		case c == '"':
+			end := p.pos + 1
+			for end < len(p.data) && p.data[end] != '"' {
+				end++
+			}
+			text := string(p.data[p.pos+1 : end])
+			p.pos = min(end+1, len(p.data))
+			return asciiToken{kind: tokenString, text: text}
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && !isDelimiter(p.data[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				p.pos++ // A stray character such as ':'
+				continue
+			}
+			text := string(p.data[start:p.pos])
+			if p.pos < len(p.data) && p.data[p.pos] == ':' {
+				p.pos++
+				return asciiToken{kind: tokenKey, text: text}
+			}
+			return asciiToken{kind: tokenValue, text: text}
+		}
+	}
+	return asciiToken{kind: tokenEnd}
+}
+
+// isDelimiter reports whether c ends a bare word
+func isDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', ',', ':', '{', '}', '"', ';':
+		return true
+	}
+	return false
+}
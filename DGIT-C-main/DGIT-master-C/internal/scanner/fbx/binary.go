@@ -0,0 +1,243 @@
+package fbx
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// binaryMagic starts binary FBX files; the format version follows as a uint32
+var binaryMagic = []byte("Kaydara FBX Binary  \x00\x1a\x00")
+
+// binaryReader reads the node records of a binary FBX file
+// Records are end offset, property count, property list length, name and properties,
+// followed by nested records closed by a null record. From version 7500 the three
+// leading fields are 64-bit.
+type binaryReader struct {
+	data    []byte
+	wide    bool
+	nullLen int
+}
+
+// parseBinary decodes the node tree of a binary FBX file
+func parseBinary(data []byte) (*node, int, error) {
+	if len(data) < len(binaryMagic)+4 {
+		return nil, 0, fmt.Errorf("truncated FBX header")
+	}
+	version := int(binary.LittleEndian.Uint32(data[len(binaryMagic):]))
+	r := &binaryReader{data: data, wide: version >= 7500, nullLen: 13}
+	if r.wide {
+		r.nullLen = 25
+	}
+
+	root := &node{}
+	for pos := len(binaryMagic) + 4; pos < len(data); {
+		child, next, err := r.readNode(pos, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		if child == nil {
+			break // The null record ending the top level; a footer follows
+		}
+		root.Children = append(root.Children, child)
+		pos = next
+	}
+	return root, version, nil
+}
+
+// readNode reads the record at pos, returning nil for a null record
+func (r *binaryReader) readNode(pos, depth int) (*node, int, error) {
+	if depth > 64 {
+		return nil, 0, fmt.Errorf("FBX nodes are nested too deeply")
+	}
+	headLen := 13
+	if r.wide {
+		headLen = 25
+	}
+	if pos+headLen > len(r.data) {
+		return nil, 0, fmt.Errorf("truncated node record at offset %d", pos)
+	}
+	var end64, count64, listLen64 uint64
+	if r.wide {
+		end64 = binary.LittleEndian.Uint64(r.data[pos:])
+		count64 = binary.LittleEndian.Uint64(r.data[pos+8:])
+		listLen64 = binary.LittleEndian.Uint64(r.data[pos+16:])
+	} else {
+		end64 = uint64(binary.LittleEndian.Uint32(r.data[pos:]))
+		count64 = uint64(binary.LittleEndian.Uint32(r.data[pos+4:]))
+		listLen64 = uint64(binary.LittleEndian.Uint32(r.data[pos+8:]))
+	}
+	nameLen := int(r.data[pos+headLen-1])
+	if end64 == 0 {
+		return nil, pos + headLen, nil
+	}
+	// The fields are checked against the file size before they are converted,
+	// so that crafted 64-bit values cannot overflow the offset arithmetic
+	nameStart := pos + headLen
+	propsStart := nameStart + nameLen
+	if end64 > uint64(len(r.data)) || listLen64 > uint64(len(r.data)) || count64 > listLen64 {
+		return nil, 0, fmt.Errorf("invalid node record at offset %d", pos)
+	}
+	end, count, listLen := int(end64), int(count64), int(listLen64)
+	if end < propsStart || listLen > end-propsStart {
+		return nil, 0, fmt.Errorf("invalid node record at offset %d", pos)
+	}
+
+	n := &node{Name: string(r.data[nameStart:propsStart])}
+	p := &propertyReader{data: r.data[propsStart : propsStart+listLen]}
+	for i := 0; i < count; i++ {
+		value, err := p.read()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read property %d of %s: %w", i, n.Name, err)
+		}
+		n.Props = append(n.Props, value)
+	}
+
+	for child := propsStart + listLen; child < end; {
+		if end-child == r.nullLen && isZero(r.data[child:end]) {
+			break
+		}
+		nested, next, err := r.readNode(child, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		if nested == nil {
+			break
+		}
+		n.Children = append(n.Children, nested)
+		child = next
+	}
+	return n, end, nil
+}
+
+// propertyReader reads the typed property values of a node record
+type propertyReader struct {
+	data []byte
+	pos  int
+}
+
+// read decodes one property: scalars become int64, float64, bool or string,
+// arrays become []float64 or []int64, and raw data its length in bytes
+func (p *propertyReader) read() (interface{}, error) {
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("unexpected end of properties")
+	}
+	code := p.data[p.pos]
+	p.pos++
+	switch code {
+	case 'Y':
+		b, err := p.bytes(2)
+		return int64(int16(binary.LittleEndian.Uint16(b))), err
+	case 'C':
+		b, err := p.bytes(1)
+		return err == nil && b[0] != 0, err
+	case 'I':
+		b, err := p.bytes(4)
+		return int64(int32(binary.LittleEndian.Uint32(b))), err
+	case 'F':
+		b, err := p.bytes(4)
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), err
+	case 'D':
+		b, err := p.bytes(8)
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), err
+	case 'L':
+		b, err := p.bytes(8)
+		return int64(binary.LittleEndian.Uint64(b)), err
+	case 'S', 'R':
+		b, err := p.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.bytes(int(binary.LittleEndian.Uint32(b)))
+		if code == 'R' {
+			return len(value), err
+		}
+		return string(value), err
+	case 'f', 'd', 'l', 'i', 'b':
+		return p.array(code)
+	}
+	return nil, fmt.Errorf("unknown property type %q", code)
+}
+
+// array reads an array property, inflating it when it is zlib compressed
+func (p *propertyReader) array(code byte) (interface{}, error) {
+	head, err := p.bytes(12)
+	if err != nil {
+		return nil, err
+	}
+	length := int(binary.LittleEndian.Uint32(head))
+	encoding := binary.LittleEndian.Uint32(head[4:])
+	stored, err := p.bytes(int(binary.LittleEndian.Uint32(head[8:])))
+	if err != nil {
+		return nil, err
+	}
+	size := map[byte]int{'f': 4, 'd': 8, 'l': 8, 'i': 4, 'b': 1}[code]
+	if length < 0 || length > 1<<28 {
+		return nil, fmt.Errorf("array of %d elements is too large", length)
+	}
+
+	raw := stored
+	if encoding == 1 {
+		reader, err := zlib.NewReader(bytes.NewReader(stored))
+		if err != nil {
+			return nil, fmt.Errorf("failed to inflate array: %w", err)
+		}
+		raw, err = io.ReadAll(io.LimitReader(reader, int64(length*size)))
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to inflate array: %w", err)
+		}
+	}
+	if len(raw) < length*size {
+		return nil, fmt.Errorf("array holds %d of %d elements", len(raw)/size, length)
+	}
+
+	switch code {
+	case 'f', 'd':
+		values := make([]float64, length)
+		for i := range values {
+			if code == 'f' {
+				values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:])))
+			} else {
+				values[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:]))
+			}
+		}
+		return values, nil
+	default:
+		values := make([]int64, length)
+		for i := range values {
+			switch code {
+			case 'l':
+				values[i] = int64(binary.LittleEndian.Uint64(raw[i*8:]))
+			case 'i':
+				values[i] = int64(int32(binary.LittleEndian.Uint32(raw[i*4:])))
+			default:
+				values[i] = int64(raw[i])
+			}
+		}
+		return values, nil
+	}
+}
+
+// bytes consumes n bytes
+func (p *propertyReader) bytes(n int) ([]byte, error) {
+	if n < 0 || p.pos+n > len(p.data) {
+		return nil, fmt.Errorf("unexpected end of properties")
+	}
+	b := p.data[p.pos : p.pos+n]
+	p.pos += n
+	return b, nil
+}
+
+// isZero reports whether all bytes are zero
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package fbx
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// FBXInfo contains metadata extracted from binary and ASCII FBX files
+// Both encodings hold the same node tree: the Objects node lists models, geometry,
+// materials, textures and animation stacks, and Connections links them by id.
+type FBXInfo struct {
+	Version         int       // Format version, e.g. 7400 for FBX 7.4
+	Binary          bool      // Binary or ASCII encoding
+	Creator         string    // Application that exported the file
+	Models          []Model   // Scene objects: meshes, cameras, lights, bones, nulls
+	Meshes          []Mesh    // Geometry with vertex and polygon counts
+	Materials       []string  // Material names
+	Textures        []Texture // Textures with the files they reference
+	AnimationStacks []string  // Animation takes
+	Tree            []Layer   // Models arranged by their parent connections
+}
+
+// Model is an object of the FBX scene
+type Model struct {
+	Name string
+	Type string // Mesh, Camera, Light, LimbNode, Null, ...
+}
+
+// Mesh is a geometry of the FBX scene
+type Mesh struct {
+	Name     string
+	Vertices int
+	Polygons int
+}
+
+// Texture is a texture with the image file it references
+type Texture struct {
+	Name string
+	File string
+}
+
+// Layer is a model with its child models
+type Layer struct {
+	Name     string
+	Type     string
+	Children []Layer
+}
+
+// node is a node of the FBX tree with its property values
+type node struct {
+	Name     string
+	Props    []interface{}
+	Children []*node
+}
+
+// GetFBXInfo extracts models, meshes, materials, textures and animation stacks from an FBX file
+func GetFBXInfo(filePath string) (*FBXInfo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FBX file: %w", err)
+	}
+
+	info := &FBXInfo{
+		Models:          []Model{},
+		Meshes:          []Mesh{},
+		Materials:       []string{},
+		Textures:        []Texture{},
+		AnimationStacks: []string{},
+	}
+	var root *node
+	if bytes.HasPrefix(data, binaryMagic) {
+		info.Binary = true
+		root, info.Version, err = parseBinary(data)
+	} else {
+		root, info.Version, err = parseASCII(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FBX file: %w", err)
+	}
+	if root.child("Objects") == nil && root.child("FBXHeaderExtension") == nil {
+		return nil, fmt.Errorf("not an FBX file")
+	}
+
+	if creator := root.child("Creator"); creator != nil {
+		info.Creator = creator.text(0)
+	}
+	info.collect(root)
+	return info, nil
+}
+
+// collect reads the objects of the scene and their connections
+func (info *FBXInfo) collect(root *node) {
+	objects := root.child("Objects")
+	if objects == nil {
+		return
+	}
+
+	models := make(map[int64]*node)
+	var modelOrder []int64
+	for _, object := range objects.Children {
+		switch object.Name {
+		case "Model":
+			model := Model{Name: object.objectName(), Type: object.objectType()}
+			info.Models = append(info.Models, model)
+			if id, ok := object.id(); ok {
+				models[id] = object
+				modelOrder = append(modelOrder, id)
+			}
+			// FBX 6 stores mesh data in the model itself
+			if object.child("Vertices") != nil {
+				info.Meshes = append(info.Meshes, geometry(object))
+			}
+		case "Geometry":
+			if object.objectType() == "Mesh" {
+				info.Meshes = append(info.Meshes, geometry(object))
+			}
+		case "Material":
+			info.Materials = append(info.Materials, object.objectName())
+		case "Texture":
+			texture := Texture{Name: object.objectName()}
+			for _, field := range []string{"RelativeFilename", "FileName"} {
+				if file := object.child(field); file != nil && file.text(0) != "" {
+					texture.File = path.Clean(strings.ReplaceAll(file.text(0), "\\", "/"))
+					break
+				}
+			}
+			info.Textures = append(info.Textures, texture)
+		case "AnimationStack":
+			info.AnimationStacks = append(info.AnimationStacks, object.objectName())
+		}
+	}
+
+	// Object-to-object connections give each model its parent; 0 is the scene root
+	parents := make(map[int64]int64)
+	if connections := root.child("Connections"); connections != nil {
+		for _, connection := range connections.Children {
+			if connection.Name != "C" || connection.text(0) != "OO" || len(connection.Props) < 3 {
+				continue
+			}
+			child, ok1 := integer(connection.Props[1])
+			parent, ok2 := integer(connection.Props[2])
+			if ok1 && ok2 && models[child] != nil && (parent == 0 || models[parent] != nil) {
+				parents[child] = parent
+			}
+		}
+	}
+	children := make(map[int64][]int64)
+	for _, id := range modelOrder {
+		children[parents[id]] = append(children[parents[id]], id)
+	}
+	info.Tree = modelTree(0, children, models, make(map[int64]bool))
+}
+
+// modelTree builds the layer tree below a parent model
+func modelTree(parent int64, children map[int64][]int64, models map[int64]*node, visited map[int64]bool) []Layer {
+	var layers []Layer
+	for _, id := range children[parent] {
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		model := models[id]
+		layers = append(layers, Layer{
+			Name: model.objectName(), Type: model.objectType(), Children: modelTree(id, children, models, visited),
+		})
+	}
+	return layers
+}
+
+// geometry counts the vertices and polygons of a mesh
+// Vertices holds x, y, z triples; PolygonVertexIndex marks the last index of each
+// polygon by storing it as a negative value, -(index+1).
+func geometry(object *node) Mesh {
+	mesh := Mesh{Name: object.objectName()}
+	if vertices := object.child("Vertices"); vertices != nil {
+		mesh.Vertices = vertices.count() / 3
+	}
+	if indices := object.child("PolygonVertexIndex"); indices != nil {
+		for _, value := range indices.values() {
+			if value < 0 {
+				mesh.Polygons++
+			}
+		}
+	}
+	return mesh
+}
+
+// child returns the first child with a name
+func (n *node) child(name string) *node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// values returns the numbers of an array node
+// Binary files store arrays as one property; ASCII 7.x files as the "a" child of
+// a "*count" property; ASCII 6.x files as the node's own property list.
+func (n *node) values() []float64 {
+	props := n.Props
+	if a := n.child("a"); a != nil {
+		props = a.Props
+	}
+	var values []float64
+	for _, prop := range props {
+		switch v := prop.(type) {
+		case []float64:
+			values = append(values, v...)
+		case []int64:
+			for _, i := range v {
+				values = append(values, float64(i))
+			}
+		case float64:
+			values = append(values, v)
+		case int64:
+			values = append(values, float64(v))
+		}
+	}
+	return values
+}
+
+// count returns the number of elements of an array node
+func (n *node) count() int {
+	return len(n.values())
+}
+
+// id returns the object id, the first property of FBX 7 objects
+func (n *node) id() (int64, bool) {
+	if len(n.Props) < 3 {
+		return 0, false
+	}
+	return integer(n.Props[0])
+}
+
+// objectName returns an object's name without its class
+// Binary files write "Name\x00\x01Class", ASCII files "Class::Name".
+func (n *node) objectName() string {
+	name := n.text(0)
+	if len(n.Props) >= 3 {
+		name = n.text(1)
+	}
+	if i := strings.Index(name, "\x00\x01"); i >= 0 {
+		return name[:i]
+	}
+	if i := strings.Index(name, "::"); i >= 0 {
+		return name[i+2:]
+	}
+	return name
+}
+
+// objectType returns an object's subclass, such as Mesh for models and geometry
+func (n *node) objectType() string {
+	return n.text(len(n.Props) - 1)
+}
+
+// text returns a string property, or "" when it is missing or not a string
+func (n *node) text(i int) string {
+	if i < 0 || i >= len(n.Props) {
+		return ""
+	}
+	s, _ := n.Props[i].(string)
+	return s
+}
+
+// integer converts an id property
+func integer(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
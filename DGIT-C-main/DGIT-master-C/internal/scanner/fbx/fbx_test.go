@@ -0,0 +1,271 @@
+package fbx
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// testNode describes a node record for buildBinary
+type testNode struct {
+	name     string
+	props    []interface{}
+	children []testNode
+}
+
+// buildBinary writes a binary FBX file holding the given top-level nodes
+func buildBinary(version int, nodes []testNode) []byte {
+	wide := version >= 7500
+	nullLen := 13
+	if wide {
+		nullLen = 25
+	}
+	var out bytes.Buffer
+	out.Write(binaryMagic)
+	binary.Write(&out, binary.LittleEndian, uint32(version))
+	var write func(n testNode)
+	write = func(n testNode) {
+		var props bytes.Buffer
+		for _, prop := range n.props {
+			switch v := prop.(type) {
+			case string:
+				props.WriteByte('S')
+				binary.Write(&props, binary.LittleEndian, uint32(len(v)))
+				props.WriteString(v)
+			case int64:
+				props.WriteByte('L')
+				binary.Write(&props, binary.LittleEndian, v)
+			case []float64:
+				var raw bytes.Buffer
+				binary.Write(&raw, binary.LittleEndian, v)
+				var packed bytes.Buffer
+				w := zlib.NewWriter(&packed)
+				w.Write(raw.Bytes())
+				w.Close()
+				props.WriteByte('d')
+				binary.Write(&props, binary.LittleEndian, []uint32{uint32(len(v)), 1, uint32(packed.Len())})
+				props.Write(packed.Bytes())
+			case []int32:
+				props.WriteByte('i')
+				binary.Write(&props, binary.LittleEndian, []uint32{uint32(len(v)), 0, uint32(len(v) * 4)})
+				binary.Write(&props, binary.LittleEndian, v)
+			}
+		}
+
+		start := out.Len()
+		head := make([]byte, nullLen)
+		out.Write(head)
+		out.WriteString(n.name)
+		out.Write(props.Bytes())
+		for _, child := range n.children {
+			write(child)
+		}
+		if len(n.children) > 0 {
+			out.Write(make([]byte, nullLen))
+		}
+		record := out.Bytes()[start:]
+		if wide {
+			binary.LittleEndian.PutUint64(record, uint64(out.Len()))
+			binary.LittleEndian.PutUint64(record[8:], uint64(len(n.props)))
+			binary.LittleEndian.PutUint64(record[16:], uint64(props.Len()))
+		} else {
+			binary.LittleEndian.PutUint32(record, uint32(out.Len()))
+			binary.LittleEndian.PutUint32(record[4:], uint32(len(n.props)))
+			binary.LittleEndian.PutUint32(record[8:], uint32(props.Len()))
+		}
+		record[nullLen-1] = byte(len(n.name))
+	}
+	for _, n := range nodes {
+		write(n)
+	}
+	out.Write(make([]byte, nullLen))
+	return out.Bytes()
+}
+
+// sceneNodes is a small scene: a cube mesh under the root, a bone under an armature,
+// a material, a texture and an animation stack
+func sceneNodes() []testNode {
+	return []testNode{
+		{name: "FBXHeaderExtension", children: []testNode{{name: "FBXVersion", props: []interface{}{int64(7400)}}}},
+		{name: "Creator", props: []interface{}{"Blender (stable FBX IO)"}},
+		{name: "Objects", children: []testNode{
+			{name: "Geometry", props: []interface{}{int64(100), "Cube\x00\x01Geometry", "Mesh"}, children: []testNode{
+				{name: "Vertices", props: []interface{}{make([]float64, 24)}},
+				{name: "PolygonVertexIndex", props: []interface{}{[]int32{0, 1, 2, -4, 4, 5, 6, -8}}},
+			}},
+			{name: "Model", props: []interface{}{int64(200), "Cube\x00\x01Model", "Mesh"}},
+			{name: "Model", props: []interface{}{int64(201), "Armature\x00\x01Model", "Null"}},
+			{name: "Model", props: []interface{}{int64(202), "Bone\x00\x01Model", "LimbNode"}},
+			{name: "Material", props: []interface{}{int64(300), "Metal\x00\x01Material", ""}},
+			{name: "Texture", props: []interface{}{int64(400), "Wood\x00\x01Texture", ""}, children: []testNode{
+				{name: "RelativeFilename", props: []interface{}{"textures\\wood.png"}},
+			}},
+			{name: "AnimationStack", props: []interface{}{int64(500), "Take 001\x00\x01AnimStack", ""}},
+		}},
+		{name: "Connections", children: []testNode{
+			{name: "C", props: []interface{}{"OO", int64(200), int64(0)}},
+			{name: "C", props: []interface{}{"OO", int64(201), int64(0)}},
+			{name: "C", props: []interface{}{"OO", int64(202), int64(201)}},
+		}},
+	}
+}
+
+const asciiScene = `; FBX 7.4.0 project file
+FBXHeaderExtension:  {
+	FBXVersion: 7400
+}
+Creator: "FBX SDK/FBX Plugins version 2020.2"
+Objects:  {
+	Geometry: 2035615390896, "Geometry::Quad", "Mesh" {
+		Vertices: *12 {
+			a: 1,1,1,-1,1,1,
+			-1,-1,1,1,-1,1
+		}
+		PolygonVertexIndex: *4 {
+			a: 0,1,2,-4
+		}
+	}
+	Model: 2035612000000, "Model::Quad", "Mesh" {
+		Properties70:  {
+			P: "Lcl Translation", "Lcl Translation", "", "A",0,0,0
+		}
+	}
+	Texture: 4, "Texture::Wood", "" {
+		FileName: "C:\\assets\\wood.png"
+	}
+}
+Connections:  {
+	C: "OO",2035612000000,0
+}
+`
+
+func TestGetFBXInfoBinary(t *testing.T) {
+	for _, version := range []int{7400, 7500} {
+		info, err := GetFBXInfo(scantest.WriteFile(t, "scene.fbx", buildBinary(version, sceneNodes())))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if !info.Binary || info.Version != version || info.Creator != "Blender (stable FBX IO)" {
+			t.Errorf("version %d: got binary=%v version=%d creator=%q", version, info.Binary, info.Version, info.Creator)
+		}
+		if len(info.Meshes) != 1 || info.Meshes[0] != (Mesh{Name: "Cube", Vertices: 8, Polygons: 2}) {
+			t.Errorf("version %d: meshes = %+v", version, info.Meshes)
+		}
+		if len(info.Models) != 3 || len(info.Materials) != 1 || len(info.AnimationStacks) != 1 {
+			t.Errorf("version %d: models=%d materials=%d stacks=%d", version, len(info.Models), len(info.Materials), len(info.AnimationStacks))
+		}
+		if len(info.Textures) != 1 || info.Textures[0].File != "textures/wood.png" {
+			t.Errorf("version %d: textures = %+v", version, info.Textures)
+		}
+		if len(info.Tree) != 2 || info.Tree[1].Name != "Armature" || len(info.Tree[1].Children) != 1 || info.Tree[1].Children[0].Name != "Bone" {
+			t.Errorf("version %d: tree = %+v", version, info.Tree)
+		}
+	}
+}
+
+func TestGetFBXInfoASCII(t *testing.T) {
+	info, err := GetFBXInfo(scantest.WriteFile(t, "scene.fbx", []byte(asciiScene)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Binary || info.Version != 7400 {
+		t.Errorf("got binary=%v version=%d", info.Binary, info.Version)
+	}
+	if len(info.Meshes) != 1 || info.Meshes[0] != (Mesh{Name: "Quad", Vertices: 4, Polygons: 1}) {
+		t.Errorf("meshes = %+v", info.Meshes)
+	}
+	if len(info.Tree) != 1 || info.Tree[0].Name != "Quad" {
+		t.Errorf("tree = %+v", info.Tree)
+	}
+	if len(info.Textures) != 1 || info.Textures[0].File != "C:/assets/wood.png" {
+		t.Errorf("textures = %+v", info.Textures)
+	}
+}
+
+func TestParseBinaryRejectsOversizedLengths(t *testing.T) {
+	data := buildBinary(7500, sceneNodes())
+	record := len(binaryMagic) + 4
+	for _, field := range []int{0, 8, 16} {
+		for _, value := range []uint64{math.MaxInt64, math.MaxUint64, math.MaxInt64 - 8} {
+			crafted := append([]byte(nil), data...)
+			binary.LittleEndian.PutUint64(crafted[record+field:], value)
+			if _, _, err := parseBinary(crafted); err == nil {
+				t.Errorf("field %d = %#x: expected an error", field, value)
+			}
+		}
+	}
+}
+
+func TestGetFBXInfoMeshCounts(t *testing.T) {
+	cases := map[string]struct {
+		objects []testNode
+		want    []Mesh
+	}{
+		"triangles and quads": {
+			objects: []testNode{
+				{name: "Geometry", props: []interface{}{int64(1), "Prism\x00\x01Geometry", "Mesh"}, children: []testNode{
+					{name: "Vertices", props: []interface{}{make([]float64, 18)}},
+					{name: "PolygonVertexIndex", props: []interface{}{[]int32{0, 1, -3, 3, 4, -6, 0, 1, 4, -4, 1, 2, 5, -5, 2, 0, 3, -6}}},
+				}},
+			},
+			want: []Mesh{{Name: "Prism", Vertices: 6, Polygons: 5}},
+		},
+		"several meshes in file order": {
+			objects: []testNode{
+				{name: "Geometry", props: []interface{}{int64(1), "Quad\x00\x01Geometry", "Mesh"}, children: []testNode{
+					{name: "Vertices", props: []interface{}{make([]float64, 12)}},
+					{name: "PolygonVertexIndex", props: []interface{}{[]int32{0, 1, 2, -4}}},
+				}},
+				{name: "Geometry", props: []interface{}{int64(2), "Point\x00\x01Geometry", "Mesh"}, children: []testNode{
+					{name: "Vertices", props: []interface{}{make([]float64, 3)}},
+				}},
+			},
+			want: []Mesh{{Name: "Quad", Vertices: 4, Polygons: 1}, {Name: "Point", Vertices: 1}},
+		},
+		"blend shapes are not meshes": {
+			objects: []testNode{
+				{name: "Geometry", props: []interface{}{int64(1), "Smile\x00\x01Geometry", "Shape"}, children: []testNode{
+					{name: "Vertices", props: []interface{}{make([]float64, 12)}},
+				}},
+			},
+			want: []Mesh{},
+		},
+		"FBX 6 mesh stored in the model": {
+			objects: []testNode{
+				{name: "Model", props: []interface{}{int64(1), "Tri\x00\x01Model", "Mesh"}, children: []testNode{
+					{name: "Vertices", props: []interface{}{make([]float64, 9)}},
+					{name: "PolygonVertexIndex", props: []interface{}{[]int32{0, 1, -3}}},
+				}},
+			},
+			want: []Mesh{{Name: "Tri", Vertices: 3, Polygons: 1}},
+		},
+	}
+	for name, c := range cases {
+		for _, version := range []int{7400, 7500} {
+			nodes := []testNode{
+				{name: "FBXHeaderExtension", children: []testNode{{name: "FBXVersion", props: []interface{}{int64(version)}}}},
+				{name: "Objects", children: c.objects},
+			}
+			info, err := GetFBXInfo(scantest.WriteFile(t, "scene.fbx", buildBinary(version, nodes)))
+			if err != nil {
+				t.Errorf("%s, version %d: %v", name, version, err)
+				continue
+			}
+			if !reflect.DeepEqual(info.Meshes, c.want) {
+				t.Errorf("%s, version %d: meshes = %+v, want %+v", name, version, info.Meshes, c.want)
+			}
+		}
+	}
+}
+
+func TestParseDoesNotPanicOnTruncation(t *testing.T) {
+	for _, version := range []int{7400, 7500} {
+		scantest.Truncations(t, buildBinary(version, sceneNodes()), func(prefix []byte) { parseBinary(prefix) })
+	}
+	scantest.Truncations(t, []byte(asciiScene), func(prefix []byte) { parseASCII(prefix) })
+}
@@ -0,0 +1,89 @@
+package figma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeChanges describes how the nodes of two versions of a Figma file differ
+// Nodes are matched by GUID, so renamed and moved nodes keep their identity.
+type NodeChanges struct {
+	Added       []Node       `json:"added,omitempty"`
+	Removed     []Node       `json:"removed,omitempty"`
+	Renamed     []NodeChange `json:"renamed,omitempty"`
+	Resized     []NodeChange `json:"resized,omitempty"`
+	TextChanged []NodeChange `json:"text_changed,omitempty"`
+	Moved       []NodeChange `json:"moved,omitempty"` // Nodes placed under another parent
+	Summary     string       `json:"summary"`
+}
+
+// NodeChange is a node present in both versions with its old and new state
+type NodeChange struct {
+	Old Node `json:"old"`
+	New Node `json:"new"`
+}
+
+// CompareNodes finds added, removed, renamed, resized, edited and moved nodes
+func CompareNodes(oldInfo, newInfo *FigmaInfo) *NodeChanges {
+	changes := &NodeChanges{}
+	oldNodes := make(map[string]Node, len(oldInfo.Nodes))
+	for _, node := range oldInfo.Nodes {
+		oldNodes[node.ID] = node
+	}
+	newIDs := make(map[string]bool, len(newInfo.Nodes))
+
+	for _, node := range newInfo.Nodes {
+		newIDs[node.ID] = true
+		old, ok := oldNodes[node.ID]
+		if !ok {
+			changes.Added = append(changes.Added, node)
+			continue
+		}
+		change := NodeChange{Old: old, New: node}
+		if old.Name != node.Name {
+			changes.Renamed = append(changes.Renamed, change)
+		}
+		if old.Width != node.Width || old.Height != node.Height {
+			changes.Resized = append(changes.Resized, change)
+		}
+		if old.Text != node.Text {
+			changes.TextChanged = append(changes.TextChanged, change)
+		}
+		if old.Parent != node.Parent {
+			changes.Moved = append(changes.Moved, change)
+		}
+	}
+	for _, node := range oldInfo.Nodes {
+		if !newIDs[node.ID] {
+			changes.Removed = append(changes.Removed, node)
+		}
+	}
+
+	changes.Summary = changes.summarize()
+	return changes
+}
+
+// HasChanges reports whether any difference was found
+func (c *NodeChanges) HasChanges() bool {
+	return len(c.Added)+len(c.Removed)+len(c.Renamed)+len(c.Resized)+len(c.TextChanged)+len(c.Moved) > 0
+}
+
+// summarize describes the changes in one line
+func (c *NodeChanges) summarize() string {
+	var parts []string
+	add := func(count int, label string) {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, label))
+		}
+	}
+	add(len(c.Added), "node(s) added")
+	add(len(c.Removed), "node(s) removed")
+	add(len(c.Renamed), "node(s) renamed")
+	add(len(c.Resized), "node(s) resized")
+	add(len(c.TextChanged), "text(s) edited")
+	add(len(c.Moved), "node(s) moved")
+	if len(parts) == 0 {
+		return "No node changes detected"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,355 @@
+package figma
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// FigmaInfo contains metadata extracted from local Figma files
+// A .fig file is "fig-kiwi", a format version and length-prefixed chunks: the compressed
+// kiwi schema, then the document encoded with it as one message of node changes.
+// Recent exports wrap this canvas.fig in a ZIP archive with meta.json and the images.
+type FigmaInfo struct {
+	Version     int      // fig-kiwi format version
+	Pages       []string // Page names in document order
+	Frames      []Frame  // Top-level frames of all pages
+	Layers      []Layer  // Node tree of each page, top-level frames first
+	LayerNames  []string // Names of all nodes on the pages
+	Components  []string // Names of the components defined in the document
+	Instances   int      // Number of component instances
+	Texts       []string // Content of the text nodes
+	Images      []string // Images stored next to canvas.fig
+	Nodes       []Node   // Every node on the pages, for node-level comparisons
+	ObjectCount int      // Total number of nodes on the pages
+}
+
+// Frame is a top-level frame of a page
+type Frame struct {
+	Name   string
+	Page   string
+	Width  float64
+	Height float64
+}
+
+// Layer is a node of a page with its children
+type Layer struct {
+	Name     string
+	Type     string // Figma node type: FRAME, GROUP, TEXT, SYMBOL (component), INSTANCE, ...
+	Children []Layer
+}
+
+// Node is a node of the document, identified by its GUID across versions
+type Node struct {
+	ID     string  `json:"id"`
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	Parent string  `json:"parent,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	Text   string  `json:"text,omitempty"`
+}
+
+// figHeaders are the signatures of Figma design and FigJam files
+var figHeaders = []string{"fig-kiwi", "fig-jam."}
+
+// zstdMagic starts chunks that newer Figma versions compress with Zstandard
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// maxDecompressedSize bounds canvas.fig and each decompressed chunk, so that a small
+// crafted file cannot exhaust memory while it is scanned
+var maxDecompressedSize = 512 << 20
+
+// errTooLarge reports data that decompresses beyond maxDecompressedSize
+var errTooLarge = errors.New("decompressed data exceeds the size limit")
+
+// GetFigmaInfo extracts pages, frames, components and text nodes from a local Figma file
+func GetFigmaInfo(filePath string) (*FigmaInfo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Figma file: %w", err)
+	}
+
+	var images []string
+	if bytes.HasPrefix(data, []byte("PK")) {
+		if data, images, err = readArchive(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) < 12 || !isFigHeader(data[:8]) {
+		return nil, fmt.Errorf("not a Figma file")
+	}
+	version := int(binary.LittleEndian.Uint32(data[8:12]))
+
+	var chunks [][]byte
+	for pos := 12; pos+4 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[pos : pos+4]))
+		pos += 4
+		if size > len(data)-pos {
+			return nil, fmt.Errorf("chunk %d is truncated", len(chunks))
+		}
+		chunks = append(chunks, data[pos:pos+size])
+		pos += size
+	}
+	if len(chunks) < 2 {
+		return nil, fmt.Errorf("Figma file has no document data")
+	}
+
+	schemaData, err := decompress(chunks[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress schema: %w", err)
+	}
+	messageData, err := decompress(chunks[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress document: %w", err)
+	}
+	schema, err := decodeKiwiSchema(schemaData)
+	if err != nil {
+		return nil, err
+	}
+	message, err := schema.decode(messageData, "Message")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	info := buildInfo(nodeChanges(message))
+	info.Version = version
+	if images != nil {
+		info.Images = images
+	}
+	return info, nil
+}
+
+// readArchive returns canvas.fig and the image names of a zipped Figma export
+func readArchive(data []byte) ([]byte, []string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open Figma archive: %w", err)
+	}
+	var canvas []byte
+	images := []string{}
+	for _, file := range archive.File {
+		switch {
+		case file.Name == "canvas.fig":
+			reader, err := file.Open()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open canvas.fig: %w", err)
+			}
+			canvas, err = readLimited(reader)
+			reader.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read canvas.fig: %w", err)
+			}
+		case strings.HasPrefix(file.Name, "images/") && !strings.HasSuffix(file.Name, "/"):
+			images = append(images, strings.TrimPrefix(file.Name, "images/"))
+		}
+	}
+	if canvas == nil {
+		return nil, nil, fmt.Errorf("canvas.fig not found in Figma archive")
+	}
+	sort.Strings(images)
+	return canvas, images, nil
+}
+
+// isFigHeader reports whether data starts with a Figma signature
+func isFigHeader(header []byte) bool {
+	for _, signature := range figHeaders {
+		if string(header) == signature {
+			return true
+		}
+	}
+	return false
+}
+
+// decompress inflates a chunk compressed with Zstandard, raw deflate or zlib
+func decompress(chunk []byte) ([]byte, error) {
+	if bytes.HasPrefix(chunk, zstdMagic) {
+		decoder, err := zstd.NewReader(bytes.NewReader(chunk), zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxDecompressedSize)))
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		out, err := readLimited(decoder)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, errTooLarge
+		}
+		return out, err
+	}
+	if out, err := readLimited(flate.NewReader(bytes.NewReader(chunk))); err == nil || errors.Is(err, errTooLarge) {
+		return out, err
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(chunk))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readLimited(reader)
+}
+
+// readLimited reads r to the end, failing once it yields more than maxDecompressedSize bytes
+func readLimited(r io.Reader) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(r, int64(maxDecompressedSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDecompressedSize {
+		return nil, errTooLarge
+	}
+	return out, nil
+}
+
+// parsedNode is a node change with the fields needed to place it in the tree
+type parsedNode struct {
+	Node
+	position     string
+	internalOnly bool
+}
+
+// nodeChanges reads the node changes of a document message
+func nodeChanges(message map[string]interface{}) []parsedNode {
+	var nodes []parsedNode
+	for _, raw := range list(message["nodeChanges"]) {
+		change, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		node := parsedNode{Node: Node{
+			ID:   guid(change["guid"]),
+			Type: text(change["type"]),
+			Name: text(change["name"]),
+		}}
+		if parent, ok := change["parentIndex"].(map[string]interface{}); ok {
+			node.Parent = guid(parent["guid"])
+			node.position = text(parent["position"])
+		}
+		if size, ok := change["size"].(map[string]interface{}); ok {
+			node.Width, node.Height = number(size["x"]), number(size["y"])
+		}
+		if textData, ok := change["textData"].(map[string]interface{}); ok {
+			node.Text = text(textData["characters"])
+		}
+		node.internalOnly, _ = change["internalOnly"].(bool)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// buildInfo arranges node changes into pages and collects frames, components and texts
+func buildInfo(nodes []parsedNode) *FigmaInfo {
+	info := &FigmaInfo{
+		Pages:      []string{},
+		Frames:     []Frame{},
+		LayerNames: []string{},
+		Components: []string{},
+		Texts:      []string{},
+		Images:     []string{},
+		Nodes:      []Node{},
+	}
+
+	children := make(map[string][]parsedNode)
+	var document string
+	for _, node := range nodes {
+		if node.Type == "DOCUMENT" {
+			document = node.ID
+			continue
+		}
+		children[node.Parent] = append(children[node.Parent], node)
+	}
+	// Siblings are ordered by fractional index strings
+	for _, siblings := range children {
+		sort.SliceStable(siblings, func(i, j int) bool { return siblings[i].position < siblings[j].position })
+	}
+
+	for _, page := range children[document] {
+		// The internal-only page holds data Figma does not show, such as library components
+		if page.Type != "CANVAS" || page.internalOnly {
+			continue
+		}
+		info.Pages = append(info.Pages, page.Name)
+		info.Nodes = append(info.Nodes, page.Node)
+		for _, child := range children[page.ID] {
+			info.Layers = append(info.Layers, info.walk(child, page.Name, true, children, 0))
+		}
+	}
+	return info
+}
+
+// walk records a node and its descendants, returning its place in the layer tree
+func (info *FigmaInfo) walk(node parsedNode, page string, topLevel bool, children map[string][]parsedNode, depth int) Layer {
+	info.ObjectCount++
+	info.Nodes = append(info.Nodes, node.Node)
+	info.LayerNames = append(info.LayerNames, node.Name)
+	switch node.Type {
+	case "FRAME":
+		if topLevel {
+			info.Frames = append(info.Frames, Frame{Name: node.Name, Page: page, Width: node.Width, Height: node.Height})
+		}
+	case "SYMBOL":
+		info.Components = append(info.Components, node.Name)
+	case "INSTANCE":
+		info.Instances++
+	case "TEXT":
+		if node.Text != "" {
+			info.Texts = append(info.Texts, node.Text)
+		}
+	}
+
+	layer := Layer{Name: node.Name, Type: node.Type}
+	if depth < 256 {
+		for _, child := range children[node.ID] {
+			layer.Children = append(layer.Children, info.walk(child, page, false, children, depth+1))
+		}
+	}
+	return layer
+}
+
+// guid formats a GUID struct as "sessionID:localID"
+func guid(value interface{}) string {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%.0f:%.0f", number(fields["sessionID"]), number(fields["localID"]))
+}
+
+// number converts a decoded numeric value to float64
+func number(value interface{}) float64 {
+	switch v := value.(type) {
+	case float32:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case byte:
+		return float64(v)
+	}
+	return 0
+}
+
+// text returns a decoded string, or "" for other values
+func text(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// list returns a decoded array, or nil for other values
+func list(value interface{}) []interface{} {
+	array, _ := value.([]interface{})
+	return array
+}
@@ -0,0 +1,454 @@
+package figma
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// kiwiWriter writes the kiwi encodings read by kiwiReader
+type kiwiWriter struct {
+	bytes.Buffer
+}
+
+func (w *kiwiWriter) varUint(v uint32) {
+	for v >= 128 {
+		w.WriteByte(byte(v) | 128)
+		v >>= 7
+	}
+	w.WriteByte(byte(v))
+}
+
+func (w *kiwiWriter) varInt(v int32) {
+	w.varUint(uint32(v<<1) ^ uint32(v>>31))
+}
+
+func (w *kiwiWriter) varFloat(f float32) {
+	if f == 0 {
+		w.WriteByte(0)
+		return
+	}
+	binary.Write(w, binary.LittleEndian, bits.RotateLeft32(math.Float32bits(f), 9))
+}
+
+func (w *kiwiWriter) string(s string) {
+	w.WriteString(s)
+	w.WriteByte(0)
+}
+
+// testDefinition is a schema definition; field types are definition names or built-in types
+type testDefinition struct {
+	name   string
+	kind   byte
+	fields [][2]string // Name and type, with a "[]" prefix for arrays
+}
+
+// testSchema covers the parts of Figma's schema that the analyzer reads
+var testSchema = []testDefinition{
+	{"NodeType", kiwiEnum, [][2]string{{"DOCUMENT", ""}, {"CANVAS", ""}, {"FRAME", ""}, {"TEXT", ""}, {"SYMBOL", ""}, {"INSTANCE", ""}}},
+	{"GUID", kiwiStruct, [][2]string{{"sessionID", "uint"}, {"localID", "uint"}}},
+	{"ParentIndex", kiwiStruct, [][2]string{{"guid", "GUID"}, {"position", "string"}}},
+	{"Vector", kiwiStruct, [][2]string{{"x", "float"}, {"y", "float"}}},
+	{"TextData", kiwiMessage, [][2]string{{"characters", "string"}}},
+	{"NodeChange", kiwiMessage, [][2]string{{"guid", "GUID"}, {"type", "NodeType"}, {"name", "string"}, {"parentIndex", "ParentIndex"}, {"size", "Vector"}, {"textData", "TextData"}, {"internalOnly", "bool"}}},
+	{"Message", kiwiMessage, [][2]string{{"nodeChanges", "[]NodeChange"}, {"blobs", "[]byte"}}},
+}
+
+// encodeSchema encodes schema definitions in the binary kiwi schema format
+func encodeSchema(definitions []testDefinition) []byte {
+	index := make(map[string]int)
+	for i, builtin := range kiwiBuiltins {
+		index[builtin] = -1 - i
+	}
+	for i, definition := range definitions {
+		index[definition.name] = i
+	}
+
+	var w kiwiWriter
+	w.varUint(uint32(len(definitions)))
+	for _, definition := range definitions {
+		w.string(definition.name)
+		w.WriteByte(definition.kind)
+		w.varUint(uint32(len(definition.fields)))
+		for i, field := range definition.fields {
+			fieldType, flags := field[1], byte(0)
+			if len(fieldType) > 2 && fieldType[:2] == "[]" {
+				fieldType, flags = fieldType[2:], 1
+			}
+			w.string(field[0])
+			w.varInt(int32(index[fieldType]))
+			w.WriteByte(flags)
+			w.varUint(uint32(i + 1))
+		}
+	}
+	return w.Bytes()
+}
+
+// testNode is a node change of the test document
+type testNode struct {
+	session, local       uint32
+	nodeType             uint32 // Value of the NodeType enum
+	name                 string
+	parent               [2]uint32
+	position             string
+	width, height        float32
+	characters           string
+	internalOnly, noSize bool
+}
+
+// Values of the NodeType enum of testSchema
+const (
+	typeDocument uint32 = iota + 1
+	typeCanvas
+	typeFrame
+	typeText
+	typeSymbol
+	typeInstance
+)
+
+// testNodes is a document with a visible page, frames in fractional index order and an internal page
+var testNodes = []testNode{
+	{session: 0, local: 0, nodeType: typeDocument, name: "Document", noSize: true},
+	{session: 0, local: 1, nodeType: typeCanvas, name: "Page 1", position: "a", noSize: true},
+	{session: 0, local: 2, nodeType: typeCanvas, name: "Internal Only Canvas", position: "b", internalOnly: true, noSize: true},
+	{session: 1, local: 1, nodeType: typeFrame, name: "Home", parent: [2]uint32{0, 1}, position: "b", width: 375, height: 812},
+	{session: 1, local: 5, nodeType: typeFrame, name: "About", parent: [2]uint32{0, 1}, position: "a", width: 1440, height: 900.5},
+	{session: 1, local: 2, nodeType: typeText, name: "Title", parent: [2]uint32{1, 1}, position: "a", width: 100, height: 20, characters: "Hello"},
+	{session: 1, local: 4, nodeType: typeInstance, name: "Button", parent: [2]uint32{1, 1}, position: "b", width: 80, height: 32},
+	{session: 1, local: 3, nodeType: typeSymbol, name: "Button", parent: [2]uint32{0, 1}, position: "c", width: 80, height: 32},
+	{session: 2, local: 1, nodeType: typeSymbol, name: "Library/Icon", parent: [2]uint32{0, 2}, position: "a", width: 16, height: 16},
+}
+
+// encodeMessage encodes node changes as a Message of testSchema
+func encodeMessage(nodes []testNode) []byte {
+	var w kiwiWriter
+	w.varUint(1) // nodeChanges
+	w.varUint(uint32(len(nodes)))
+	for _, node := range nodes {
+		w.varUint(1)
+		w.varUint(node.session)
+		w.varUint(node.local)
+		w.varUint(2)
+		w.varUint(node.nodeType)
+		w.varUint(3)
+		w.string(node.name)
+		if node.nodeType != typeDocument {
+			w.varUint(4)
+			w.varUint(node.parent[0])
+			w.varUint(node.parent[1])
+			w.string(node.position)
+		}
+		if !node.noSize {
+			w.varUint(5)
+			w.varFloat(node.width)
+			w.varFloat(node.height)
+		}
+		if node.characters != "" {
+			w.varUint(6)
+			w.varUint(1)
+			w.string(node.characters)
+			w.varUint(0)
+		}
+		if node.internalOnly {
+			w.varUint(7)
+			w.WriteByte(1)
+		}
+		w.varUint(0)
+	}
+	w.varUint(2) // blobs
+	w.varUint(3)
+	w.Write([]byte{1, 2, 3})
+	w.varUint(0)
+	return w.Bytes()
+}
+
+// buildFig writes a fig-kiwi file with a raw deflate schema chunk and a zlib message chunk
+func buildFig(t *testing.T, schema, message []byte) []byte {
+	t.Helper()
+	var deflated, zlibbed bytes.Buffer
+	fw, _ := flate.NewWriter(&deflated, flate.BestCompression)
+	fw.Write(schema)
+	fw.Close()
+	zw := zlib.NewWriter(&zlibbed)
+	zw.Write(message)
+	zw.Close()
+
+	var b bytes.Buffer
+	b.WriteString("fig-kiwi")
+	binary.Write(&b, binary.LittleEndian, uint32(15))
+	for _, chunk := range [][]byte{deflated.Bytes(), zlibbed.Bytes()} {
+		binary.Write(&b, binary.LittleEndian, uint32(len(chunk)))
+		b.Write(chunk)
+	}
+	return b.Bytes()
+}
+
+func TestGetFigmaInfo(t *testing.T) {
+	info, err := GetFigmaInfo(scantest.WriteFile(t, "design.fig", buildFig(t, encodeSchema(testSchema), encodeMessage(testNodes))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != 15 || !reflect.DeepEqual(info.Pages, []string{"Page 1"}) {
+		t.Errorf("version %d, pages %v", info.Version, info.Pages)
+	}
+	wantFrames := []Frame{{Name: "About", Page: "Page 1", Width: 1440, Height: 900.5}, {Name: "Home", Page: "Page 1", Width: 375, Height: 812}}
+	if !reflect.DeepEqual(info.Frames, wantFrames) {
+		t.Errorf("frames = %+v", info.Frames)
+	}
+	if want := []string{"About", "Home", "Title", "Button", "Button"}; !reflect.DeepEqual(info.LayerNames, want) {
+		t.Errorf("layer names = %v, want %v", info.LayerNames, want)
+	}
+	if !reflect.DeepEqual(info.Components, []string{"Button"}) || info.Instances != 1 {
+		t.Errorf("components %v, %d instances", info.Components, info.Instances)
+	}
+	if !reflect.DeepEqual(info.Texts, []string{"Hello"}) || info.ObjectCount != 5 {
+		t.Errorf("texts %v, %d objects", info.Texts, info.ObjectCount)
+	}
+	if len(info.Layers) != 3 || info.Layers[1].Name != "Home" || len(info.Layers[1].Children) != 2 {
+		t.Errorf("layer tree = %+v", info.Layers)
+	}
+	if want := (Node{ID: "1:2", Type: "TEXT", Name: "Title", Parent: "1:1", Width: 100, Height: 20, Text: "Hello"}); info.Nodes[3] != want {
+		t.Errorf("text node = %+v", info.Nodes[3])
+	}
+}
+
+func TestGetFigmaInfoFromArchive(t *testing.T) {
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	for name, content := range map[string][]byte{
+		"canvas.fig":        buildFig(t, encodeSchema(testSchema), encodeMessage(testNodes)),
+		"meta.json":         []byte(`{"file_name": "design"}`),
+		"images/b2c3":       []byte("png"),
+		"images/a1b2":       []byte("png"),
+		"images/":           nil,
+		"thumbnail.png":     []byte("png"),
+		"images/sub/":       nil,
+		"images/sub/c3d4":   []byte("png"),
+		"other/images/e5f6": []byte("png"),
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(content)
+	}
+	w.Close()
+
+	info, err := GetFigmaInfo(scantest.WriteFile(t, "design.fig", out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1b2", "b2c3", "sub/c3d4"}; !reflect.DeepEqual(info.Images, want) {
+		t.Errorf("images = %v, want %v", info.Images, want)
+	}
+	if len(info.Frames) != 2 {
+		t.Errorf("frames = %+v", info.Frames)
+	}
+}
+
+func TestGetFigmaInfoRejectsInvalidFiles(t *testing.T) {
+	schema, message := encodeSchema(testSchema), encodeMessage(testNodes)
+	valid := buildFig(t, schema, message)
+
+	oversizedChunk := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(oversizedChunk[12:], math.MaxUint32)
+
+	cases := map[string][]byte{
+		"not a Figma file": []byte("PNG\x00 not a figma file at all"),
+		"no document":      valid[:16+int(binary.LittleEndian.Uint32(valid[12:]))],
+		"oversized chunk":  oversizedChunk,
+		"no Message":       buildFig(t, encodeSchema(testSchema[:6]), message),
+		"bad message":      buildFig(t, schema, []byte{9, 9, 9}),
+	}
+	for name, data := range cases {
+		if _, err := GetFigmaInfo(scantest.WriteFile(t, "design.fig", data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDecompressEnforcesSizeLimit(t *testing.T) {
+	defer func(limit int) { maxDecompressedSize = limit }(maxDecompressedSize)
+	maxDecompressedSize = 1 << 20
+
+	compressors := map[string]func([]byte) []byte{
+		"deflate": func(data []byte) []byte {
+			var b bytes.Buffer
+			w, _ := flate.NewWriter(&b, flate.BestCompression)
+			w.Write(data)
+			w.Close()
+			return b.Bytes()
+		},
+		"zlib": func(data []byte) []byte {
+			var b bytes.Buffer
+			w := zlib.NewWriter(&b)
+			w.Write(data)
+			w.Close()
+			return b.Bytes()
+		},
+		"zstd": func(data []byte) []byte {
+			encoder, _ := zstd.NewWriter(nil)
+			defer encoder.Close()
+			return encoder.EncodeAll(data, nil)
+		},
+	}
+	for name, compress := range compressors {
+		if out, err := decompress(compress(make([]byte, 1<<20))); err != nil || len(out) != 1<<20 {
+			t.Errorf("%s: %d bytes at the limit, %v", name, len(out), err)
+		}
+		if _, err := decompress(compress(make([]byte, 1<<20+1))); !errors.Is(err, errTooLarge) {
+			t.Errorf("%s: got %v past the limit", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	f, _ := w.Create("canvas.fig")
+	f.Write(make([]byte, 2<<20))
+	w.Close()
+	if _, _, err := readArchive(out.Bytes()); !errors.Is(err, errTooLarge) {
+		t.Errorf("oversized canvas.fig: got %v", err)
+	}
+}
+
+func TestDecodeKiwiRejectsOversizedLengths(t *testing.T) {
+	var w kiwiWriter
+	w.varUint(math.MaxUint32)
+	if _, err := decodeKiwiSchema(w.Bytes()); err == nil {
+		t.Error("expected an error for an oversized definition count")
+	}
+
+	badType := []testDefinition{{"Message", kiwiMessage, [][2]string{{"x", "missing"}}}}
+	encoded := encodeSchema(badType)
+	encoded[len(encoded)-3] = 0x7e // Field type 63, past the last definition
+	if _, err := decodeKiwiSchema(encoded); err == nil {
+		t.Error("expected an error for a field of an undefined type")
+	}
+
+	schema, err := decodeKiwiSchema(encodeSchema(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Reset()
+	w.varUint(2) // blobs
+	w.varUint(1 << 30)
+	w.Write([]byte{1, 2, 3})
+	if _, err := schema.decode(w.Bytes(), "Message"); err == nil {
+		t.Error("expected an error for an array longer than the data")
+	}
+
+	// A message that contains itself must not recurse without bound
+	nested, err := decodeKiwiSchema(encodeSchema([]testDefinition{{"Message", kiwiMessage, [][2]string{{"child", "Message"}}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nested.decode(bytes.Repeat([]byte{1}, 1000), "Message"); err == nil {
+		t.Error("expected an error for deeply nested data")
+	}
+}
+
+func TestCompareNodes(t *testing.T) {
+	oldInfo := &FigmaInfo{Nodes: []Node{
+		{ID: "1:1", Type: "FRAME", Name: "Home", Width: 375, Height: 812},
+		{ID: "1:2", Type: "TEXT", Name: "Title", Parent: "1:1", Text: "Hello"},
+		{ID: "1:3", Type: "RECTANGLE", Name: "Draft"},
+	}}
+	newInfo := &FigmaInfo{Nodes: []Node{
+		{ID: "1:1", Type: "FRAME", Name: "Landing", Width: 390, Height: 844},
+		{ID: "1:2", Type: "TEXT", Name: "Title", Parent: "1:4", Text: "Welcome"},
+		{ID: "1:4", Type: "GROUP", Name: "Header", Parent: "1:1"},
+	}}
+
+	changes := CompareNodes(oldInfo, newInfo)
+	home := NodeChange{Old: oldInfo.Nodes[0], New: newInfo.Nodes[0]}
+	title := NodeChange{Old: oldInfo.Nodes[1], New: newInfo.Nodes[1]}
+	want := &NodeChanges{
+		Added:       []Node{newInfo.Nodes[2]},
+		Removed:     []Node{oldInfo.Nodes[2]},
+		Renamed:     []NodeChange{home},
+		Resized:     []NodeChange{home},
+		TextChanged: []NodeChange{title},
+		Moved:       []NodeChange{title},
+		Summary:     "1 node(s) added, 1 node(s) removed, 1 node(s) renamed, 1 node(s) resized, 1 text(s) edited, 1 node(s) moved",
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v\nwant %+v", changes, want)
+	}
+	if CompareNodes(oldInfo, oldInfo).HasChanges() {
+		t.Error("a document differs from itself")
+	}
+}
+
+func TestCompareNodesBetweenFigFiles(t *testing.T) {
+	edited := append([]testNode{}, testNodes...)
+	edited[3].name = "Landing"         // Home frame renamed
+	edited[4].width = 1280             // About frame resized
+	edited[5].characters = "Welcome"   // Title text edited
+	edited[6].parent = [2]uint32{1, 5} // Button instance moved into About
+	// The symbol on the internal page, which is not compared, makes way for a new frame
+	edited = append(edited[:8], testNode{
+		session: 3, local: 1, nodeType: typeFrame, name: "Footer", parent: [2]uint32{0, 1}, position: "d", width: 375, height: 80,
+	})
+
+	schema := encodeSchema(testSchema)
+	oldInfo, err := GetFigmaInfo(scantest.WriteFile(t, "old.fig", buildFig(t, schema, encodeMessage(testNodes))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newInfo, err := GetFigmaInfo(scantest.WriteFile(t, "new.fig", buildFig(t, schema, encodeMessage(edited))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := CompareNodes(oldInfo, newInfo)
+	ids := func(list []NodeChange) []string {
+		var out []string
+		for _, change := range list {
+			out = append(out, change.New.ID)
+		}
+		return out
+	}
+	if len(changes.Added) != 1 || changes.Added[0].ID != "3:1" || len(changes.Removed) != 0 {
+		t.Errorf("added %+v, removed %+v", changes.Added, changes.Removed)
+	}
+	checks := map[string][]NodeChange{"1:1": changes.Renamed, "1:5": changes.Resized, "1:2": changes.TextChanged, "1:4": changes.Moved}
+	for id, list := range checks {
+		if got := ids(list); !reflect.DeepEqual(got, []string{id}) {
+			t.Errorf("expected only %s, got %v", id, got)
+		}
+	}
+	if changes.Moved[0].Old.Parent != "1:1" || changes.Moved[0].New.Parent != "1:5" {
+		t.Errorf("moved = %+v", changes.Moved[0])
+	}
+	if changes.TextChanged[0].Old.Text != "Hello" || changes.TextChanged[0].New.Text != "Welcome" {
+		t.Errorf("text change = %+v", changes.TextChanged[0])
+	}
+}
+
+func TestDecodeKiwiDoesNotPanicOnTruncation(t *testing.T) {
+	schemaData, message := encodeSchema(testSchema), encodeMessage(testNodes)
+	scantest.Truncations(t, schemaData, func(prefix []byte) {
+		decodeKiwiSchema(prefix)
+	})
+	schema, err := decodeKiwiSchema(schemaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scantest.Truncations(t, message, func(prefix []byte) {
+		schema.decode(prefix, "Message")
+	})
+	scantest.TruncatedFiles(t, "design.fig", buildFig(t, schemaData, message), func(path string) {
+		GetFigmaInfo(path)
+	})
+}
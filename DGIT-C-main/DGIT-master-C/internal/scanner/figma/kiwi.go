@@ -0,0 +1,298 @@
+package figma
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Kinds of kiwi definitions
+const (
+	kiwiEnum    = 0
+	kiwiStruct  = 1
+	kiwiMessage = 2
+)
+
+// kiwiBuiltins are the built-in field types; a field type of -1-i refers to kiwiBuiltins[i]
+var kiwiBuiltins = []string{"bool", "byte", "int", "uint", "float", "string", "int64", "uint64"}
+
+// kiwiTypeByte is the field type of byte, whose arrays are stored as a length and raw bytes
+const kiwiTypeByte = -2
+
+// kiwiField is a field of a struct or message, or a value of an enum
+type kiwiField struct {
+	Name    string
+	Type    int // Index of a definition, or a negative built-in type
+	IsArray bool
+	Value   uint32 // Field tag in messages, value in enums
+}
+
+// kiwiDefinition is an enum, struct or message of a kiwi schema
+type kiwiDefinition struct {
+	Name    string
+	Kind    byte
+	Fields  []kiwiField
+	byValue map[uint32]int
+}
+
+// kiwiSchema is a decoded kiwi schema
+// Figma stores the schema its data was written with in front of the data itself,
+// so documents can be decoded without knowing the schema in advance.
+type kiwiSchema struct {
+	Definitions []kiwiDefinition
+	byName      map[string]int
+}
+
+// kiwiReader reads the variable-length encodings of the kiwi format
+type kiwiReader struct {
+	data []byte
+	pos  int
+}
+
+// decodeKiwiSchema decodes a binary kiwi schema
+func decodeKiwiSchema(data []byte) (*kiwiSchema, error) {
+	r := &kiwiReader{data: data}
+	count, err := r.varUint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	if int(count) > len(data) {
+		return nil, fmt.Errorf("invalid schema definition count %d", count)
+	}
+
+	schema := &kiwiSchema{byName: make(map[string]int, count)}
+	for i := 0; i < int(count); i++ {
+		definition := kiwiDefinition{byValue: make(map[uint32]int)}
+		if definition.Name, err = r.string(); err != nil {
+			return nil, fmt.Errorf("failed to read definition name: %w", err)
+		}
+		if definition.Kind, err = r.byte(); err != nil {
+			return nil, fmt.Errorf("failed to read kind of %s: %w", definition.Name, err)
+		}
+		fieldCount, err := r.varUint()
+		if err != nil || int(fieldCount) > len(data) {
+			return nil, fmt.Errorf("invalid field count in %s", definition.Name)
+		}
+		for j := 0; j < int(fieldCount); j++ {
+			var field kiwiField
+			name, err1 := r.string()
+			fieldType, err2 := r.varInt()
+			flags, err3 := r.byte()
+			value, err4 := r.varUint()
+			if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+				return nil, fmt.Errorf("failed to read field %d of %s", j, definition.Name)
+			}
+			field.Name, field.Type, field.IsArray, field.Value = name, int(fieldType), flags&1 != 0, value
+			definition.byValue[value] = len(definition.Fields)
+			definition.Fields = append(definition.Fields, field)
+		}
+		schema.byName[definition.Name] = len(schema.Definitions)
+		schema.Definitions = append(schema.Definitions, definition)
+	}
+
+	for _, definition := range schema.Definitions {
+		for _, field := range definition.Fields {
+			if definition.Kind != kiwiEnum && (field.Type >= len(schema.Definitions) || -1-field.Type >= len(kiwiBuiltins)) {
+				return nil, fmt.Errorf("field %s.%s has invalid type %d", definition.Name, field.Name, field.Type)
+			}
+		}
+	}
+	return schema, nil
+}
+
+// decode decodes data encoded with the named root definition
+// Structs and messages become maps keyed by field name, enums their value names, arrays slices;
+// byte arrays are kept as []byte.
+func (s *kiwiSchema) decode(data []byte, root string) (map[string]interface{}, error) {
+	index, ok := s.byName[root]
+	if !ok {
+		return nil, fmt.Errorf("schema has no %s definition", root)
+	}
+	value, err := s.readDefinition(&kiwiReader{data: data}, index, 0)
+	if err != nil {
+		return nil, err
+	}
+	message, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", root)
+	}
+	return message, nil
+}
+
+// readDefinition reads a value of a schema definition
+func (s *kiwiSchema) readDefinition(r *kiwiReader, index, depth int) (interface{}, error) {
+	if depth > 64 {
+		return nil, fmt.Errorf("data is nested too deeply")
+	}
+	definition := &s.Definitions[index]
+	switch definition.Kind {
+	case kiwiEnum:
+		value, err := r.varUint()
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := definition.byValue[value]; ok {
+			return definition.Fields[i].Name, nil
+		}
+		return fmt.Sprintf("%s(%d)", definition.Name, value), nil
+	case kiwiStruct:
+		fields := make(map[string]interface{}, len(definition.Fields))
+		for _, field := range definition.Fields {
+			value, err := s.readField(r, field, depth)
+			if err != nil {
+				return nil, err
+			}
+			fields[field.Name] = value
+		}
+		return fields, nil
+	case kiwiMessage:
+		fields := make(map[string]interface{})
+		for {
+			tag, err := r.varUint()
+			if err != nil {
+				return nil, err
+			}
+			if tag == 0 {
+				return fields, nil
+			}
+			i, ok := definition.byValue[tag]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %d in %s", tag, definition.Name)
+			}
+			value, err := s.readField(r, definition.Fields[i], depth)
+			if err != nil {
+				return nil, err
+			}
+			fields[definition.Fields[i].Name] = value
+		}
+	}
+	return nil, fmt.Errorf("definition %s has unknown kind %d", definition.Name, definition.Kind)
+}
+
+// readField reads a field value, or all elements of an array field
+func (s *kiwiSchema) readField(r *kiwiReader, field kiwiField, depth int) (interface{}, error) {
+	if !field.IsArray {
+		return s.readValue(r, field.Type, depth)
+	}
+	count, err := r.varUint()
+	if err != nil {
+		return nil, err
+	}
+	if int(count) > len(r.data)-r.pos {
+		return nil, fmt.Errorf("array %s is longer than the remaining data", field.Name)
+	}
+	if field.Type == kiwiTypeByte {
+		values := r.data[r.pos : r.pos+int(count)]
+		r.pos += int(count)
+		return values, nil
+	}
+	values := make([]interface{}, count)
+	for i := range values {
+		if values[i], err = s.readValue(r, field.Type, depth); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// readValue reads a single value of a field type
+func (s *kiwiSchema) readValue(r *kiwiReader, fieldType, depth int) (interface{}, error) {
+	switch fieldType {
+	case -1:
+		b, err := r.byte()
+		return b != 0, err
+	case -2:
+		return r.byte()
+	case -3:
+		return r.varInt()
+	case -4:
+		return r.varUint()
+	case -5:
+		return r.varFloat()
+	case -6:
+		return r.string()
+	case -7:
+		v, err := r.varUint64()
+		return int64(v>>1) ^ -int64(v&1), err
+	case -8:
+		return r.varUint64()
+	}
+	return s.readDefinition(r, fieldType, depth+1)
+}
+
+// byte reads one byte
+func (r *kiwiReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// varUint reads an unsigned LEB128 value of up to 32 bits
+func (r *kiwiReader) varUint() (uint32, error) {
+	var value uint32
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&127) << shift
+		if b&128 == 0 {
+			break
+		}
+	}
+	return value, nil
+}
+
+// varInt reads a zigzag-encoded signed value
+func (r *kiwiReader) varInt() (int32, error) {
+	v, err := r.varUint()
+	return int32(v>>1) ^ -int32(v&1), err
+}
+
+// varUint64 reads an unsigned LEB128 value of up to 64 bits
+func (r *kiwiReader) varUint64() (uint64, error) {
+	var value uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b&127) << shift
+		if b&128 == 0 {
+			break
+		}
+	}
+	return value, nil
+}
+
+// varFloat reads a float32 stored with its exponent first, so that 0 takes one byte
+func (r *kiwiReader) varFloat() (float32, error) {
+	first, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if first == 0 {
+		return 0, nil
+	}
+	if r.pos+3 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	bits := binary.LittleEndian.Uint32(append([]byte{first}, r.data[r.pos:r.pos+3]...))
+	r.pos += 3
+	return math.Float32frombits(bits<<23 | bits>>9), nil
+}
+
+// string reads a NUL-terminated UTF-8 string
+func (r *kiwiReader) string() (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s, nil
+}
@@ -0,0 +1,486 @@
+package illustrator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Artboard is a page of an Illustrator document
+type Artboard struct {
+	Index  int        `json:"index"`
+	Bounds [4]float64 `json:"bounds"` // x1, y1, x2, y2 in points
+}
+
+// Swatch is a named colour of the document's swatch library
+type Swatch struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`            // "spot" or "process"
+	Mode  string `json:"mode,omitempty"`  // CMYK, RGB, LAB, GRAY
+	Value string `json:"value,omitempty"` // Component values, e.g. "C=0 M=100 Y=100 K=0"
+}
+
+// EmbeddedImage is a raster image placed in the document
+type EmbeddedImage struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Hash   string `json:"hash"` // Hash of the image data
+}
+
+// Document lists the named contents of an Illustrator file, for comparing versions
+type Document struct {
+	Artboards []Artboard      `json:"artboards"`
+	Layers    []string        `json:"layers"`
+	Fonts     []string        `json:"fonts"`
+	Swatches  []Swatch        `json:"swatches"`
+	Images    []EmbeddedImage `json:"images"`
+}
+
+// ArtboardChange is an artboard whose bounds changed
+type ArtboardChange struct {
+	Index int        `json:"index"`
+	Old   [4]float64 `json:"old"`
+	New   [4]float64 `json:"new"`
+}
+
+// LayerRename is a layer that kept its place but changed its name
+type LayerRename struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// SwatchChange is a swatch whose colour definition changed
+type SwatchChange struct {
+	Name string `json:"name"`
+	Old  Swatch `json:"old"`
+	New  Swatch `json:"new"`
+}
+
+// DocumentChanges describes how two versions of an Illustrator file differ
+type DocumentChanges struct {
+	ArtboardsAdded   []Artboard       `json:"artboards_added,omitempty"`
+	ArtboardsRemoved []Artboard       `json:"artboards_removed,omitempty"`
+	ArtboardsResized []ArtboardChange `json:"artboards_resized,omitempty"`
+	LayersAdded      []string         `json:"layers_added,omitempty"`
+	LayersRemoved    []string         `json:"layers_removed,omitempty"`
+	LayersRenamed    []LayerRename    `json:"layers_renamed,omitempty"`
+	LayersReordered  []string         `json:"layers_reordered,omitempty"`
+	FontsAdded       []string         `json:"fonts_added,omitempty"`
+	FontsRemoved     []string         `json:"fonts_removed,omitempty"`
+	SwatchesAdded    []Swatch         `json:"swatches_added,omitempty"`
+	SwatchesRemoved  []Swatch         `json:"swatches_removed,omitempty"`
+	SwatchesChanged  []SwatchChange   `json:"swatches_changed,omitempty"`
+	ImagesAdded      []EmbeddedImage  `json:"images_added,omitempty"`
+	ImagesRemoved    []EmbeddedImage  `json:"images_removed,omitempty"`
+	Summary          string           `json:"summary"`
+}
+
+var (
+	pdfObjectRe    = regexp.MustCompile(`(?s)\d+\s+\d+\s+obj\b(.*?)\bendobj`)
+	pageTypeRe     = regexp.MustCompile(`/Type\s*/Page\b`)
+	mediaBoxRe     = regexp.MustCompile(`/MediaBox\s*\[\s*([0-9.-]+)\s+([0-9.-]+)\s+([0-9.-]+)\s+([0-9.-]+)\s*\]`)
+	imageSubtypeRe = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	widthRe        = regexp.MustCompile(`/Width\s+(\d+)`)
+	heightRe       = regexp.MustCompile(`/Height\s+(\d+)`)
+	fontNameRe     = regexp.MustCompile(`/(?:BaseFont|FontName)\s*/([^/\s\[\]<>()]+)`)
+	separationRe   = regexp.MustCompile(`/Separation\s*/([^/\s\[\]<>()]+)`)
+	xmpTagRe       = regexp.MustCompile(`<xmpG:(\w+)>([^<]*)</xmpG:\w+>`)
+)
+
+// ReadDocument reads the artboards, layers, fonts, swatches and embedded images of an AI file
+func ReadDocument(filePath string) (*Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AI file: %w", err)
+	}
+	if structure, err := readPDFStructure(data); err == nil {
+		return structure.document(), nil
+	}
+	return parseDocument(string(data)), nil
+}
+
+// parseDocument extracts the document contents from the uncompressed parts of an AI file
+// It is the fallback for legacy files and files whose PDF structure cannot be read.
+func parseDocument(content string) *Document {
+	doc := &Document{
+		Artboards: []Artboard{},
+		Layers:    extractLayerNames(content),
+		Fonts:     []string{},
+		Swatches:  extractSwatches(content),
+		Images:    []EmbeddedImage{},
+	}
+	if doc.Layers == nil {
+		doc.Layers = []string{}
+	}
+
+	for _, match := range pdfObjectRe.FindAllStringSubmatch(content, -1) {
+		body := match[1]
+		dict, stream, _ := strings.Cut(body, "stream")
+		switch {
+		case pageTypeRe.MatchString(dict):
+			if bounds, ok := parseMediaBox(dict); ok {
+				doc.Artboards = append(doc.Artboards, Artboard{Index: len(doc.Artboards) + 1, Bounds: bounds})
+			}
+		case imageSubtypeRe.MatchString(dict):
+			image := EmbeddedImage{}
+			if m := widthRe.FindStringSubmatch(dict); m != nil {
+				image.Width, _ = strconv.Atoi(m[1])
+			}
+			if m := heightRe.FindStringSubmatch(dict); m != nil {
+				image.Height, _ = strconv.Atoi(m[1])
+			}
+			stream, _, _ = strings.Cut(stream, "endstream")
+			stream = strings.TrimPrefix(strings.TrimPrefix(stream, "\r"), "\n")
+			stream = strings.TrimSuffix(strings.TrimSuffix(stream, "\n"), "\r")
+			image.Hash = fmt.Sprintf("%x", sha256.Sum256([]byte(stream)))[:16]
+			doc.Images = append(doc.Images, image)
+		}
+	}
+
+	fonts := make(map[string]bool)
+	for _, match := range fontNameRe.FindAllStringSubmatch(content, -1) {
+		fonts[fontName(match[1])] = true
+	}
+	for font := range fonts {
+		doc.Fonts = append(doc.Fonts, font)
+	}
+	sort.Strings(doc.Fonts)
+	return doc
+}
+
+// parseMediaBox reads the MediaBox of a page dictionary
+func parseMediaBox(dict string) ([4]float64, bool) {
+	var bounds [4]float64
+	m := mediaBoxRe.FindStringSubmatch(dict)
+	if m == nil {
+		return bounds, false
+	}
+	for i := range bounds {
+		value, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return bounds, false
+		}
+		bounds[i] = value
+	}
+	return bounds, true
+}
+
+// extractSwatches reads the swatch groups of the XMP metadata and the spot colours used in the artwork
+func extractSwatches(content string) []Swatch {
+	var spots []string
+	for _, match := range separationRe.FindAllStringSubmatch(content, -1) {
+		spots = append(spots, decodePDFName(match[1]))
+	}
+	return mergeSpotColors(xmpSwatches(content), spots)
+}
+
+// xmpSwatches reads the swatch groups Illustrator records in its XMP metadata
+func xmpSwatches(content string) []Swatch {
+	swatches := []Swatch{}
+	seen := make(map[string]bool)
+
+	parts := strings.Split(content, "<xmpG:swatchName>")
+	for _, part := range parts[1:] {
+		name, rest, ok := strings.Cut(part, "</xmpG:swatchName>")
+		if !ok {
+			continue
+		}
+		rest, _, _ = strings.Cut(rest, "</rdf:li>")
+		swatch := Swatch{Name: unescapeXML(name), Type: "process"}
+		var values []string
+		for _, tag := range xmpTagRe.FindAllStringSubmatch(rest, -1) {
+			switch tag[1] {
+			case "swatchName":
+			case "mode":
+				swatch.Mode = tag[2]
+			case "type":
+				swatch.Type = strings.ToLower(tag[2])
+			case "cyan", "magenta", "yellow", "black", "red", "green", "blue", "gray", "L", "A", "B", "tint":
+				values = append(values, fmt.Sprintf("%s=%s", componentLabel(tag[1]), trimNumber(tag[2])))
+			}
+		}
+		swatch.Value = strings.Join(values, " ")
+		if !seen[swatch.Name] {
+			seen[swatch.Name] = true
+			swatches = append(swatches, swatch)
+		}
+	}
+	return swatches
+}
+
+// mergeSpotColors adds the spot colours used in the artwork that are not in the swatch list
+func mergeSpotColors(swatches []Swatch, spots []string) []Swatch {
+	seen := make(map[string]bool, len(swatches))
+	for _, swatch := range swatches {
+		seen[swatch.Name] = true
+	}
+	for _, name := range spots {
+		if name != "All" && name != "None" && !seen[name] {
+			seen[name] = true
+			swatches = append(swatches, Swatch{Name: name, Type: "spot"})
+		}
+	}
+	return swatches
+}
+
+// CompareDocuments reports the differences between two versions of an Illustrator document
+func CompareDocuments(oldDoc, newDoc *Document) *DocumentChanges {
+	changes := &DocumentChanges{}
+
+	// Artboards are matched by position in the document
+	for i := 0; i < max(len(oldDoc.Artboards), len(newDoc.Artboards)); i++ {
+		switch {
+		case i >= len(oldDoc.Artboards):
+			changes.ArtboardsAdded = append(changes.ArtboardsAdded, newDoc.Artboards[i])
+		case i >= len(newDoc.Artboards):
+			changes.ArtboardsRemoved = append(changes.ArtboardsRemoved, oldDoc.Artboards[i])
+		case oldDoc.Artboards[i].Bounds != newDoc.Artboards[i].Bounds:
+			changes.ArtboardsResized = append(changes.ArtboardsResized, ArtboardChange{
+				Index: i + 1, Old: oldDoc.Artboards[i].Bounds, New: newDoc.Artboards[i].Bounds,
+			})
+		}
+	}
+
+	compareLayers(oldDoc.Layers, newDoc.Layers, changes)
+	changes.FontsAdded, changes.FontsRemoved = diffNames(oldDoc.Fonts, newDoc.Fonts)
+
+	oldSwatches := make(map[string]Swatch)
+	for _, swatch := range oldDoc.Swatches {
+		oldSwatches[swatch.Name] = swatch
+	}
+	newSwatches := make(map[string]bool)
+	for _, swatch := range newDoc.Swatches {
+		newSwatches[swatch.Name] = true
+		old, ok := oldSwatches[swatch.Name]
+		switch {
+		case !ok:
+			changes.SwatchesAdded = append(changes.SwatchesAdded, swatch)
+		case old != swatch:
+			changes.SwatchesChanged = append(changes.SwatchesChanged, SwatchChange{Name: swatch.Name, Old: old, New: swatch})
+		}
+	}
+	for _, swatch := range oldDoc.Swatches {
+		if !newSwatches[swatch.Name] {
+			changes.SwatchesRemoved = append(changes.SwatchesRemoved, swatch)
+		}
+	}
+
+	// Images are matched by content; the same image placed twice counts twice
+	remaining := make(map[string]int)
+	for _, image := range oldDoc.Images {
+		remaining[image.Hash]++
+	}
+	for _, image := range newDoc.Images {
+		if remaining[image.Hash] > 0 {
+			remaining[image.Hash]--
+			continue
+		}
+		changes.ImagesAdded = append(changes.ImagesAdded, image)
+	}
+	for _, image := range oldDoc.Images {
+		if remaining[image.Hash] > 0 {
+			remaining[image.Hash]--
+			changes.ImagesRemoved = append(changes.ImagesRemoved, image)
+		}
+	}
+
+	changes.Summary = changes.summarize()
+	return changes
+}
+
+// compareLayers finds added, removed, renamed and reordered layers
+// A layer is renamed when a removed and an added name occupy the same position
+func compareLayers(oldLayers, newLayers []string, changes *DocumentChanges) {
+	added, removed := diffNames(oldLayers, newLayers)
+	isAdded, isRemoved := toSet(added), toSet(removed)
+	for i := 0; i < min(len(oldLayers), len(newLayers)); i++ {
+		if isRemoved[oldLayers[i]] && isAdded[newLayers[i]] {
+			changes.LayersRenamed = append(changes.LayersRenamed, LayerRename{Old: oldLayers[i], New: newLayers[i]})
+			delete(isRemoved, oldLayers[i])
+			delete(isAdded, newLayers[i])
+		}
+	}
+	for _, name := range added {
+		if isAdded[name] {
+			changes.LayersAdded = append(changes.LayersAdded, name)
+		}
+	}
+	for _, name := range removed {
+		if isRemoved[name] {
+			changes.LayersRemoved = append(changes.LayersRemoved, name)
+		}
+	}
+
+	// Layers present in both versions that fall outside their longest common order were moved
+	inNew, inOld := toSet(newLayers), toSet(oldLayers)
+	var oldOrder, newOrder []string
+	for _, name := range oldLayers {
+		if inNew[name] {
+			oldOrder = append(oldOrder, name)
+		}
+	}
+	for _, name := range newLayers {
+		if inOld[name] {
+			newOrder = append(newOrder, name)
+		}
+	}
+	kept := toSet(longestCommonOrder(oldOrder, newOrder))
+	for _, name := range newOrder {
+		if !kept[name] {
+			changes.LayersReordered = append(changes.LayersReordered, name)
+		}
+	}
+}
+
+// longestCommonOrder returns the longest common subsequence of two name lists
+func longestCommonOrder(a, b []string) []string {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	var common []string
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common = append(common, a[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}
+
+// HasChanges reports whether any difference was found
+func (c *DocumentChanges) HasChanges() bool {
+	return len(c.ArtboardsAdded)+len(c.ArtboardsRemoved)+len(c.ArtboardsResized)+
+		len(c.LayersAdded)+len(c.LayersRemoved)+len(c.LayersRenamed)+len(c.LayersReordered)+
+		len(c.FontsAdded)+len(c.FontsRemoved)+
+		len(c.SwatchesAdded)+len(c.SwatchesRemoved)+len(c.SwatchesChanged)+
+		len(c.ImagesAdded)+len(c.ImagesRemoved) > 0
+}
+
+// summarize describes the changes in one line
+func (c *DocumentChanges) summarize() string {
+	var parts []string
+	add := func(count int, label string) {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, label))
+		}
+	}
+	add(len(c.ArtboardsAdded), "artboard(s) added")
+	add(len(c.ArtboardsRemoved), "artboard(s) removed")
+	add(len(c.ArtboardsResized), "artboard(s) resized")
+	add(len(c.LayersAdded), "layer(s) added")
+	add(len(c.LayersRemoved), "layer(s) removed")
+	add(len(c.LayersRenamed), "layer(s) renamed")
+	add(len(c.LayersReordered), "layer(s) reordered")
+	add(len(c.FontsAdded), "font(s) added")
+	add(len(c.FontsRemoved), "font(s) removed")
+	add(len(c.SwatchesAdded), "swatch(es) added")
+	add(len(c.SwatchesRemoved), "swatch(es) removed")
+	add(len(c.SwatchesChanged), "swatch(es) changed")
+	add(len(c.ImagesAdded), "image(s) added")
+	add(len(c.ImagesRemoved), "image(s) removed")
+	if len(parts) == 0 {
+		return "No document changes detected"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// diffNames returns the names only in b and the names only in a, keeping their order
+func diffNames(a, b []string) ([]string, []string) {
+	inA, inB := toSet(a), toSet(b)
+	var added, removed []string
+	for _, name := range b {
+		if !inA[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range a {
+		if !inB[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
+
+// toSet turns a list of names into a set
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// fontName strips the subset prefix ("ABCDEF+") from an embedded font name
+func fontName(name string) string {
+	name = decodePDFName(name)
+	if prefix, rest, ok := strings.Cut(name, "+"); ok && len(prefix) == 6 && strings.ToUpper(prefix) == prefix {
+		return rest
+	}
+	return name
+}
+
+// decodePDFName resolves #xx escapes in a PDF name
+func decodePDFName(name string) string {
+	if !strings.Contains(name, "#") {
+		return name
+	}
+	var out strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if b, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				out.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		out.WriteByte(name[i])
+	}
+	return out.String()
+}
+
+// unescapeXML resolves the entities XMP uses in text values
+func unescapeXML(s string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(s)
+}
+
+// componentLabels abbreviate colour component names
+var componentLabels = map[string]string{
+	"cyan": "C", "magenta": "M", "yellow": "Y", "black": "K",
+	"red": "R", "green": "G", "blue": "B",
+}
+
+// componentLabel abbreviates a colour component name
+func componentLabel(component string) string {
+	if label, ok := componentLabels[component]; ok {
+		return label
+	}
+	return component
+}
+
+// trimNumber drops trailing zeros from a decimal value
+func trimNumber(value string) string {
+	if !strings.Contains(value, ".") {
+		return value
+	}
+	return strings.TrimSuffix(strings.TrimRight(value, "0"), ".")
+}
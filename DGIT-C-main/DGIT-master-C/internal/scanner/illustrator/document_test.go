@@ -0,0 +1,104 @@
+package illustrator
+
+import (
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// swatchXMP is the swatch group of Illustrator's XMP metadata with a process and a spot colour
+const swatchXMP = `<xmpTPg:SwatchGroups><rdf:Seq><rdf:li rdf:parseType="Resource">
+<xmpG:groupName>Default Swatch Group</xmpG:groupName>
+<xmpG:Colorants><rdf:Seq>
+<rdf:li rdf:parseType="Resource"><xmpG:swatchName>C=0 M=100 Y=100 K=0</xmpG:swatchName><xmpG:mode>CMYK</xmpG:mode><xmpG:type>PROCESS</xmpG:type><xmpG:cyan>0.000000</xmpG:cyan><xmpG:magenta>100.000000</xmpG:magenta><xmpG:yellow>100.000000</xmpG:yellow><xmpG:black>0.000000</xmpG:black></rdf:li>
+<rdf:li rdf:parseType="Resource"><xmpG:swatchName>Brand &amp; Co</xmpG:swatchName><xmpG:type>SPOT</xmpG:type><xmpG:tint>100.000000</xmpG:tint><xmpG:mode>RGB</xmpG:mode><xmpG:red>10</xmpG:red><xmpG:green>20</xmpG:green><xmpG:blue>30</xmpG:blue></rdf:li>
+</rdf:Seq></xmpG:Colorants></rdf:li></rdf:Seq></xmpTPg:SwatchGroups>`
+
+func TestXMPSwatches(t *testing.T) {
+	want := []Swatch{
+		{Name: "C=0 M=100 Y=100 K=0", Type: "process", Mode: "CMYK", Value: "C=0 M=100 Y=100 K=0"},
+		{Name: "Brand & Co", Type: "spot", Mode: "RGB", Value: "tint=100 R=10 G=20 B=30"},
+	}
+	if got := xmpSwatches(swatchXMP); !reflect.DeepEqual(got, want) {
+		t.Errorf("swatches = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	content := swatchXMP + `
+1 0 obj << /Type /Page /MediaBox [0 0 612 792] >> endobj
+2 0 obj << /Type /Page /MediaBox [0 0 1920 1080] >> endobj
+3 0 obj << /Type /XObject /Subtype /Image /Width 64 /Height 32 /Length 4 >>
+stream
+abcd
+endstream endobj
+4 0 obj << /Type /Font /BaseFont /ABCDEF+Inter-Bold >> endobj
+5 0 obj [/Separation /PANTONE#20185#20C /DeviceCMYK 6 0 R] endobj
+`
+	doc := parseDocument(content)
+	wantArtboards := []Artboard{{Index: 1, Bounds: [4]float64{0, 0, 612, 792}}, {Index: 2, Bounds: [4]float64{0, 0, 1920, 1080}}}
+	if !reflect.DeepEqual(doc.Artboards, wantArtboards) {
+		t.Errorf("artboards = %+v", doc.Artboards)
+	}
+	if len(doc.Images) != 1 || doc.Images[0].Width != 64 || doc.Images[0].Height != 32 || doc.Images[0].Hash == "" {
+		t.Errorf("images = %+v", doc.Images)
+	}
+	if !reflect.DeepEqual(doc.Fonts, []string{"Inter-Bold"}) {
+		t.Errorf("fonts = %v", doc.Fonts)
+	}
+	if len(doc.Swatches) != 3 || doc.Swatches[2] != (Swatch{Name: "PANTONE 185 C", Type: "spot"}) {
+		t.Errorf("swatches = %+v", doc.Swatches)
+	}
+}
+
+func TestCompareDocuments(t *testing.T) {
+	red := Swatch{Name: "Red", Type: "process", Mode: "RGB", Value: "R=255 G=0 B=0"}
+	darkRed := Swatch{Name: "Red", Type: "process", Mode: "RGB", Value: "R=200 G=0 B=0"}
+	logo := EmbeddedImage{Width: 10, Height: 10, Hash: "aaaa"}
+	photo := EmbeddedImage{Width: 20, Height: 20, Hash: "bbbb"}
+
+	oldDoc := &Document{
+		Artboards: []Artboard{{Index: 1, Bounds: [4]float64{0, 0, 100, 100}}, {Index: 2, Bounds: [4]float64{0, 0, 50, 50}}},
+		Layers:    []string{"Background", "Logo", "Text", "Draft"},
+		Fonts:     []string{"Inter"},
+		Swatches:  []Swatch{red, {Name: "Old", Type: "spot"}},
+		Images:    []EmbeddedImage{logo, logo},
+	}
+	newDoc := &Document{
+		Artboards: []Artboard{{Index: 1, Bounds: [4]float64{0, 0, 200, 100}}},
+		Layers:    []string{"Text", "Background", "Logo", "Final"},
+		Fonts:     []string{"Inter", "Roboto"},
+		Swatches:  []Swatch{darkRed, {Name: "New", Type: "spot"}},
+		Images:    []EmbeddedImage{logo, photo},
+	}
+
+	changes := CompareDocuments(oldDoc, newDoc)
+	want := &DocumentChanges{
+		ArtboardsRemoved: []Artboard{{Index: 2, Bounds: [4]float64{0, 0, 50, 50}}},
+		ArtboardsResized: []ArtboardChange{{Index: 1, Old: [4]float64{0, 0, 100, 100}, New: [4]float64{0, 0, 200, 100}}},
+		LayersRenamed:    []LayerRename{{Old: "Draft", New: "Final"}},
+		LayersReordered:  []string{"Text"},
+		FontsAdded:       []string{"Roboto"},
+		SwatchesAdded:    []Swatch{{Name: "New", Type: "spot"}},
+		SwatchesRemoved:  []Swatch{{Name: "Old", Type: "spot"}},
+		SwatchesChanged:  []SwatchChange{{Name: "Red", Old: red, New: darkRed}},
+		ImagesAdded:      []EmbeddedImage{photo},
+		ImagesRemoved:    []EmbeddedImage{logo},
+	}
+	want.Summary = want.summarize()
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v\nwant %+v", changes, want)
+	}
+	if !changes.HasChanges() {
+		t.Error("HasChanges = false")
+	}
+	if CompareDocuments(oldDoc, oldDoc).HasChanges() {
+		t.Error("a document differs from itself")
+	}
+}
+
+func TestParseDocumentDoesNotPanicOnTruncation(t *testing.T) {
+	content := swatchXMP + "\n1 0 obj << /Type /Page /MediaBox [0 0 612 792] >> endobj\n"
+	scantest.Truncations(t, []byte(content), func(prefix []byte) { parseDocument(string(prefix)) })
+}
@@ -2,6 +2,7 @@ package illustrator
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"regexp"
@@ -19,6 +20,7 @@ type AIInfo struct {
 	Version        string   // Adobe Illustrator version (e.g., "CC 2025 (29.x)")
 	LayerCount     int      // Total number of layers in the document
 	LayerNames     []string // Names of all layers
+	LayerTree      []Layer  // Layer hierarchy with sublayers and visibility
 	ArtboardCount  int      // Number of artboards/pages
 	ObjectCount    int      // Estimated number of design objects
 	FontCount      int      // Number of unique fonts used
@@ -28,11 +30,16 @@ type AIInfo struct {
 // GetAIInfo extracts comprehensive metadata from Adobe Illustrator files
 // Analyzes AI file structure and returns detailed design information
 func GetAIInfo(filePath string) (*AIInfo, error) {
-	file, err := os.Open(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open AI file: %w", err)
 	}
-	defer file.Close()
+
+	// PDF-compatible files are read through their object structure, which also covers
+	// compressed object streams; the text scan below handles legacy and damaged files
+	if structure, err := readPDFStructure(data); err == nil {
+		return structure.aiInfo(), nil
+	}
 
 	// Initialize AI info structure with default values
 	aiInfo := &AIInfo{
@@ -47,7 +54,7 @@ func GetAIInfo(filePath string) (*AIInfo, error) {
 	}
 
 	// Scan file content efficiently (first 1000 lines for performance)
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	var content strings.Builder
 	lineCount := 0
 	const maxLines = 1000 // Increased from original for better metadata extraction
@@ -0,0 +1,959 @@
+package illustrator
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// pdfName is a PDF name object without its leading slash
+type pdfName string
+
+// pdfKeyword is a bare word such as obj, stream or trailer
+type pdfKeyword string
+
+// pdfRef is an indirect reference to a numbered object
+type pdfRef struct {
+	Num int
+	Gen int
+}
+
+// pdfDict is a PDF dictionary
+type pdfDict map[pdfName]interface{}
+
+// pdfStream is a stream object: its dictionary and its still encoded data
+type pdfStream struct {
+	Dict pdfDict
+	Raw  []byte
+}
+
+// xrefEntry locates an object, either at a file offset or inside an object stream
+type xrefEntry struct {
+	Offset int64
+	Stream int // Object number of the containing object stream; 0 for objects stored directly
+	Index  int // Position within the object stream
+}
+
+// objectStream is a decoded object stream (PDF 1.5) holding several compressed objects
+type objectStream struct {
+	data    []byte
+	nums    []int
+	offsets []int
+}
+
+// pdfFile gives access to the objects of a PDF held in memory
+type pdfFile struct {
+	data          []byte
+	version       string
+	xref          map[int]xrefEntry
+	trailer       pdfDict
+	cache         map[int]interface{}
+	objectStreams map[int]*objectStream
+	rebuilt       bool
+}
+
+// objectHeaderRe finds object headers when the cross-reference data has to be rebuilt
+var objectHeaderRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// openPDF reads the cross-reference data of a PDF, rebuilding it from the objects when it is damaged
+func openPDF(data []byte) (*pdfFile, error) {
+	header := bytes.Index(data[:min(len(data), 1024)], []byte("%PDF-"))
+	if header < 0 {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	f := &pdfFile{
+		data:          data,
+		version:       (&pdfLexer{data: data, pos: header + 5}).token(),
+		xref:          make(map[int]xrefEntry),
+		trailer:       make(pdfDict),
+		cache:         make(map[int]interface{}),
+		objectStreams: make(map[int]*objectStream),
+	}
+
+	if err := f.readXrefChain(); err != nil || f.trailer["Root"] == nil {
+		if err := f.rebuildXref(); err != nil {
+			return nil, err
+		}
+	}
+	if f.trailer["Encrypt"] != nil {
+		return nil, fmt.Errorf("encrypted PDF files are not supported")
+	}
+	return f, nil
+}
+
+// readXrefChain reads the cross-reference sections from the last one back through /Prev
+func (f *pdfFile) readXrefChain() error {
+	start := bytes.LastIndex(f.data, []byte("startxref"))
+	if start < 0 {
+		return fmt.Errorf("startxref not found")
+	}
+	offset, err := strconv.ParseInt((&pdfLexer{data: f.data, pos: start + len("startxref")}).token(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid startxref: %w", err)
+	}
+
+	seen := make(map[int64]bool)
+	for !seen[offset] {
+		seen[offset] = true
+		trailer, err := f.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		f.mergeTrailer(trailer)
+		// Hybrid files keep compressed objects in a cross-reference stream next to the table
+		if stm, ok := pdfInt(trailer["XRefStm"]); ok {
+			if _, err := f.readXrefSection(int64(stm)); err != nil {
+				return err
+			}
+		}
+		prev, ok := pdfInt(trailer["Prev"])
+		if !ok {
+			break
+		}
+		offset = int64(prev)
+	}
+	return nil
+}
+
+// readXrefSection reads a cross-reference table or stream and returns its trailer dictionary
+// Sections are read newest first, so entries already known are not overwritten.
+func (f *pdfFile) readXrefSection(offset int64) (pdfDict, error) {
+	if offset < 0 || offset >= int64(len(f.data)) {
+		return nil, fmt.Errorf("xref offset %d is outside the file", offset)
+	}
+	if bytes.HasPrefix(f.data[offset:], []byte("xref")) {
+		return f.readXrefTable(int(offset) + len("xref"))
+	}
+	return f.readXrefStream(offset)
+}
+
+// readXrefTable reads the subsections of a classic cross-reference table
+func (f *pdfFile) readXrefTable(pos int) (pdfDict, error) {
+	l := &pdfLexer{data: f.data, pos: pos}
+	for {
+		token := l.token()
+		if token == "trailer" {
+			obj, err := l.object()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read trailer: %w", err)
+			}
+			trailer, ok := obj.(pdfDict)
+			if !ok {
+				return nil, fmt.Errorf("trailer is not a dictionary")
+			}
+			return trailer, nil
+		}
+		first, err1 := strconv.Atoi(token)
+		count, err2 := strconv.Atoi(l.token())
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("malformed xref table near offset %d", l.pos)
+		}
+		for i := 0; i < count; i++ {
+			offset, _ := strconv.ParseInt(l.token(), 10, 64)
+			l.token() // Generation
+			switch l.token() {
+			case "n":
+				if _, known := f.xref[first+i]; !known {
+					f.xref[first+i] = xrefEntry{Offset: offset}
+				}
+			case "f":
+			default:
+				return nil, fmt.Errorf("malformed xref entry for object %d", first+i)
+			}
+		}
+	}
+}
+
+// readXrefStream reads a cross-reference stream (PDF 1.5)
+func (f *pdfFile) readXrefStream(offset int64) (pdfDict, error) {
+	_, obj, _, err := f.readIndirect(offset)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(*pdfStream)
+	if !ok || stream.Dict["Type"] != pdfName("XRef") {
+		return nil, fmt.Errorf("no cross-reference data at offset %d", offset)
+	}
+	data, err := f.decode(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode xref stream: %w", err)
+	}
+
+	widths := f.intArray(stream.Dict["W"])
+	if len(widths) != 3 || widths[0]+widths[1]+widths[2] == 0 {
+		return nil, fmt.Errorf("invalid /W in xref stream")
+	}
+	for _, width := range widths {
+		if width < 0 || width > 8 {
+			return nil, fmt.Errorf("invalid /W in xref stream")
+		}
+	}
+	index := f.intArray(stream.Dict["Index"])
+	if len(index) == 0 {
+		size, _ := pdfInt(stream.Dict["Size"])
+		index = []int{0, size}
+	}
+
+	pos := 0
+	field := func(width, fallback int) int {
+		if width == 0 {
+			return fallback
+		}
+		value := 0
+		for i := 0; i < width && pos < len(data); i++ {
+			value = value<<8 | int(data[pos])
+			pos++
+		}
+		return value
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		for num := index[i]; num < index[i]+index[i+1] && pos < len(data); num++ {
+			kind, second, third := field(widths[0], 1), field(widths[1], 0), field(widths[2], 0)
+			if _, known := f.xref[num]; known {
+				continue
+			}
+			switch kind {
+			case 1:
+				f.xref[num] = xrefEntry{Offset: int64(second)}
+			case 2:
+				f.xref[num] = xrefEntry{Stream: second, Index: third}
+			}
+		}
+	}
+	return stream.Dict, nil
+}
+
+// mergeTrailer adds the trailer keys not already set by a newer section
+func (f *pdfFile) mergeTrailer(trailer pdfDict) {
+	for key, value := range trailer {
+		if _, ok := f.trailer[key]; !ok {
+			f.trailer[key] = value
+		}
+	}
+}
+
+// rebuildXref locates every object by scanning the file, for files with missing or wrong offsets
+func (f *pdfFile) rebuildXref() error {
+	f.rebuilt = true
+	f.xref = make(map[int]xrefEntry)
+	f.cache = make(map[int]interface{})
+	f.objectStreams = make(map[int]*objectStream)
+
+	for pos := 0; pos < len(f.data); {
+		match := objectHeaderRe.FindIndex(f.data[pos:])
+		if match == nil {
+			break
+		}
+		start := pos + match[0]
+		if start > 0 && !isPDFSpace(f.data[start-1]) && !isPDFDelimiter(f.data[start-1]) {
+			pos = pos + match[1]
+			continue
+		}
+		num, obj, end, err := f.readIndirect(int64(start))
+		if err != nil {
+			pos = pos + match[1]
+			continue
+		}
+		f.xref[num] = xrefEntry{Offset: int64(start)}
+		if stream, ok := obj.(*pdfStream); ok && stream.Dict["Type"] == pdfName("XRef") {
+			f.mergeTrailer(stream.Dict)
+		}
+		pos = end // Skip stream data, which may contain text that looks like object headers
+	}
+	if len(f.xref) == 0 {
+		return fmt.Errorf("no PDF objects found")
+	}
+
+	for _, num := range f.objectNumbers() {
+		stream, ok := f.object(num).(*pdfStream)
+		if !ok || stream.Dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		objStm, err := f.objectStream(num)
+		if err != nil {
+			continue
+		}
+		for i, member := range objStm.nums {
+			if _, known := f.xref[member]; !known {
+				f.xref[member] = xrefEntry{Stream: num, Index: i}
+			}
+		}
+	}
+
+	if f.trailer["Root"] == nil {
+		if start := bytes.LastIndex(f.data, []byte("trailer")); start >= 0 {
+			l := &pdfLexer{data: f.data, pos: start + len("trailer")}
+			if trailer, err := l.object(); err == nil {
+				if dict, ok := trailer.(pdfDict); ok {
+					f.mergeTrailer(dict)
+				}
+			}
+		}
+	}
+	if f.trailer["Root"] == nil {
+		for _, num := range f.objectNumbers() {
+			if dict, ok := f.object(num).(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+				f.trailer["Root"] = pdfRef{Num: num}
+				break
+			}
+		}
+	}
+	if f.trailer["Root"] == nil {
+		return fmt.Errorf("no document catalog found")
+	}
+	return nil
+}
+
+// objectNumbers returns the numbers of all known objects in ascending order
+func (f *pdfFile) objectNumbers() []int {
+	nums := make([]int, 0, len(f.xref))
+	for num := range f.xref {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// object returns a numbered object, or nil when it cannot be read
+func (f *pdfFile) object(num int) interface{} {
+	if obj, ok := f.cache[num]; ok {
+		return obj
+	}
+	f.cache[num] = nil // Breaks reference cycles while the object is loading
+	obj, err := f.loadObject(num)
+	if err != nil {
+		return nil
+	}
+	f.cache[num] = obj
+	return obj
+}
+
+// loadObject reads a numbered object from the file or from its object stream
+func (f *pdfFile) loadObject(num int) (interface{}, error) {
+	entry, ok := f.xref[num]
+	if !ok {
+		return nil, fmt.Errorf("object %d not found", num)
+	}
+	if entry.Stream > 0 {
+		objStm, err := f.objectStream(entry.Stream)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Index < 0 || entry.Index >= len(objStm.offsets) {
+			return nil, fmt.Errorf("object %d is missing from object stream %d", num, entry.Stream)
+		}
+		return (&pdfLexer{data: objStm.data, pos: objStm.offsets[entry.Index]}).object()
+	}
+
+	found, obj, _, err := f.readIndirect(entry.Offset)
+	if err == nil && found != num {
+		err = fmt.Errorf("expected object %d at offset %d, found %d", num, entry.Offset, found)
+	}
+	if err != nil && !f.rebuilt {
+		if f.rebuildXref() == nil {
+			return f.loadObject(num)
+		}
+	}
+	return obj, err
+}
+
+// objectStream decodes an object stream and indexes the objects it holds
+func (f *pdfFile) objectStream(num int) (*objectStream, error) {
+	if objStm, ok := f.objectStreams[num]; ok {
+		return objStm, nil
+	}
+	stream, ok := f.object(num).(*pdfStream)
+	if !ok || stream.Dict["Type"] != pdfName("ObjStm") {
+		return nil, fmt.Errorf("object %d is not an object stream", num)
+	}
+	data, err := f.decode(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode object stream %d: %w", num, err)
+	}
+	count, _ := pdfInt(f.resolve(stream.Dict["N"]))
+	first, _ := pdfInt(f.resolve(stream.Dict["First"]))
+	if first < 0 || first > len(data) {
+		return nil, fmt.Errorf("object stream %d has an invalid /First offset %d", num, first)
+	}
+
+	objStm := &objectStream{data: data}
+	header := &pdfLexer{data: data[:first]}
+	for i := 0; i < count; i++ {
+		member, err1 := strconv.Atoi(header.token())
+		offset, err2 := strconv.Atoi(header.token())
+		if err1 != nil || err2 != nil {
+			break
+		}
+		if offset < 0 || offset >= len(data)-first {
+			return nil, fmt.Errorf("object %d lies outside object stream %d", member, num)
+		}
+		objStm.nums = append(objStm.nums, member)
+		objStm.offsets = append(objStm.offsets, first+offset)
+	}
+	f.objectStreams[num] = objStm
+	return objStm, nil
+}
+
+// readIndirect parses "num gen obj ... endobj" at an offset
+// It returns the object number, the object and the offset just past it.
+func (f *pdfFile) readIndirect(offset int64) (int, interface{}, int, error) {
+	if offset < 0 || offset >= int64(len(f.data)) {
+		return 0, nil, 0, fmt.Errorf("object offset %d is outside the file", offset)
+	}
+	l := &pdfLexer{data: f.data, pos: int(offset)}
+	num, err := strconv.Atoi(l.token())
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("no object at offset %d", offset)
+	}
+	if _, err := strconv.Atoi(l.token()); err != nil || l.token() != "obj" {
+		return 0, nil, 0, fmt.Errorf("no object at offset %d", offset)
+	}
+	obj, err := l.object()
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("failed to parse object %d: %w", num, err)
+	}
+
+	l.skipSpace()
+	dict, isDict := obj.(pdfDict)
+	if !isDict || !bytes.HasPrefix(f.data[l.pos:], []byte("stream")) {
+		return num, obj, l.pos, nil
+	}
+	start := l.pos + len("stream")
+	if start < len(f.data) && f.data[start] == '\r' {
+		start++
+	}
+	if start < len(f.data) && f.data[start] == '\n' {
+		start++
+	}
+	raw := f.streamData(dict, start)
+	return num, &pdfStream{Dict: dict, Raw: raw}, start + len(raw), nil
+}
+
+// streamData returns the raw data of a stream starting at an offset, using /Length when it is right
+func (f *pdfFile) streamData(dict pdfDict, start int) []byte {
+	if length, ok := pdfInt(f.resolve(dict["Length"])); ok && length >= 0 && length <= len(f.data)-start {
+		rest := f.data[start+length:]
+		rest = bytes.TrimLeft(rest[:min(len(rest), 32)], " \t\r\n\x00")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return f.data[start : start+length]
+		}
+	}
+
+	// Wrong or indirect lengths that cannot be resolved yet: fall back to the endstream keyword
+	end := bytes.Index(f.data[start:], []byte("endstream"))
+	if end < 0 {
+		return f.data[start:]
+	}
+	raw := f.data[start : start+end]
+	if bytes.HasSuffix(raw, []byte("\r\n")) {
+		return raw[:len(raw)-2]
+	}
+	return bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte("\n")), []byte("\r"))
+}
+
+// resolve follows an indirect reference to the object it points to
+func (f *pdfFile) resolve(obj interface{}) interface{} {
+	for depth := 0; depth < 8; depth++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.object(ref.Num)
+	}
+	return nil
+}
+
+// dict resolves an object to a dictionary, taking the dictionary of streams
+func (f *pdfFile) dict(obj interface{}) pdfDict {
+	switch v := f.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.Dict
+	}
+	return nil
+}
+
+// array resolves an object to an array
+func (f *pdfFile) array(obj interface{}) []interface{} {
+	array, _ := f.resolve(obj).([]interface{})
+	return array
+}
+
+// intArray resolves an object to an array of integers
+func (f *pdfFile) intArray(obj interface{}) []int {
+	var values []int
+	for _, item := range f.array(obj) {
+		value, ok := pdfInt(f.resolve(item))
+		if !ok {
+			return nil
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// rect resolves an object to a rectangle, normalized so the first corner is the lower left one
+func (f *pdfFile) rect(obj interface{}) ([4]float64, bool) {
+	var rect [4]float64
+	items := f.array(obj)
+	if len(items) != 4 {
+		return rect, false
+	}
+	for i, item := range items {
+		value, ok := pdfNumber(f.resolve(item))
+		if !ok {
+			return rect, false
+		}
+		rect[i] = value
+	}
+	if rect[0] > rect[2] {
+		rect[0], rect[2] = rect[2], rect[0]
+	}
+	if rect[1] > rect[3] {
+		rect[1], rect[3] = rect[3], rect[1]
+	}
+	return rect, true
+}
+
+// text resolves an object to a text string, decoding UTF-16 and PDFDocEncoding
+func (f *pdfFile) text(obj interface{}) string {
+	s, ok := f.resolve(obj).(string)
+	if !ok {
+		return ""
+	}
+	switch {
+	case len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF:
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case len(s) >= 3 && s[:3] == "\xEF\xBB\xBF":
+		return s[3:]
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// stream resolves an object to a stream and decodes its data
+func (f *pdfFile) stream(obj interface{}) ([]byte, error) {
+	stream, ok := f.resolve(obj).(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("not a stream")
+	}
+	return f.decode(stream)
+}
+
+// decode applies the filters of a stream to its raw data
+func (f *pdfFile) decode(stream *pdfStream) ([]byte, error) {
+	var filters, params []interface{}
+	switch v := f.resolve(stream.Dict["Filter"]).(type) {
+	case nil:
+		return stream.Raw, nil
+	case pdfName:
+		filters = []interface{}{v}
+		params = []interface{}{stream.Dict["DecodeParms"]}
+	case []interface{}:
+		filters = v
+		params = f.array(stream.Dict["DecodeParms"])
+	}
+
+	data := stream.Raw
+	for i, filter := range filters {
+		var parms pdfDict
+		if i < len(params) {
+			parms = f.dict(params[i])
+		}
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			inflated, err := inflate(data)
+			if err != nil {
+				return nil, err
+			}
+			if data, err = f.unpredict(inflated, parms); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, accepting raw deflate data and keeping what was read from truncated streams
+func inflate(data []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		reader = zr
+	} else {
+		reader = flate.NewReader(bytes.NewReader(data))
+	}
+	defer reader.Close()
+	out, err := io.ReadAll(reader)
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	return out, nil
+}
+
+// Limits on the predictor parameters of Flate streams
+const (
+	maxPredictorColors  = 32
+	maxPredictorColumns = 1 << 20
+)
+
+// unpredict reverses the PNG predictors applied before Flate compression
+func (f *pdfFile) unpredict(data []byte, parms pdfDict) ([]byte, error) {
+	predictor, _ := pdfInt(f.resolve(parms["Predictor"]))
+	if predictor <= 1 {
+		return data, nil
+	}
+	if predictor < 10 {
+		return nil, fmt.Errorf("unsupported predictor %d", predictor)
+	}
+	colors, columns, bits := 1, 1, 8
+	if v, ok := pdfInt(f.resolve(parms["Colors"])); ok && v > 0 {
+		colors = v
+	}
+	if v, ok := pdfInt(f.resolve(parms["Columns"])); ok && v > 0 {
+		columns = v
+	}
+	if v, ok := pdfInt(f.resolve(parms["BitsPerComponent"])); ok && v > 0 {
+		bits = v
+	}
+	// The parameters are checked before they size the row buffer
+	if colors > maxPredictorColors || columns > maxPredictorColumns || (bits != 1 && bits != 2 && bits != 4 && bits != 8 && bits != 16) {
+		return nil, fmt.Errorf("unsupported predictor parameters: %d colors, %d columns, %d bits", colors, columns, bits)
+	}
+	bpp := max(1, colors*bits/8)
+	rowLength := (colors*bits*columns + 7) / 8
+	if rowLength >= len(data) {
+		return nil, fmt.Errorf("predictor rows of %d bytes exceed the %d-byte stream", rowLength, len(data))
+	}
+
+	out := make([]byte, 0, len(data))
+	previous := make([]byte, rowLength)
+	for pos := 0; pos+1+rowLength <= len(data); pos += 1 + rowLength {
+		kind, row := data[pos], append([]byte(nil), data[pos+1:pos+1+rowLength]...)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], previous[i-bpp]
+			}
+			up := previous[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		previous = row
+	}
+	return out, nil
+}
+
+// paeth is the Paeth predictor of the PNG specification
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// pdfInt converts a numeric object to an int
+func pdfInt(obj interface{}) (int, bool) {
+	switch v := obj.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// pdfNumber converts a numeric object to a float
+func pdfNumber(obj interface{}) (float64, bool) {
+	switch v := obj.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// maxPDFNesting limits how deeply arrays and dictionaries may nest
+const maxPDFNesting = 256
+
+// pdfLexer reads PDF objects from a byte slice
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token reads a run of regular characters, such as a number or keyword
+func (l *pdfLexer) token() string {
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// object reads the next object
+func (l *pdfLexer) object() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	switch c := l.data[l.pos]; c {
+	case '/':
+		l.pos++
+		return l.name(), nil
+	case '(':
+		return l.literalString()
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			return l.dictionary()
+		}
+		return l.hexString()
+	case '[':
+		return l.array()
+	case ']', '>', ')', '{', '}':
+		l.pos++
+		return nil, fmt.Errorf("unexpected %q at offset %d", c, l.pos-1)
+	}
+
+	token := l.token()
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if n, err := strconv.Atoi(token); err == nil {
+		// Two integers followed by R are an indirect reference
+		save := l.pos
+		if gen, err := strconv.Atoi(l.token()); err == nil && gen >= 0 && l.token() == "R" {
+			return pdfRef{Num: n, Gen: gen}, nil
+		}
+		l.pos = save
+		return n, nil
+	}
+	if v, err := strconv.ParseFloat(token, 64); err == nil {
+		return v, nil
+	}
+	return pdfKeyword(token), nil
+}
+
+// name reads a name after its slash, decoding #xx escapes
+func (l *pdfLexer) name() pdfName {
+	var name []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		l.pos++
+	}
+	return pdfName(name)
+}
+
+// literalString reads a (string) with balanced parentheses and backslash escapes
+func (l *pdfLexer) literalString() (string, error) {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return string(out), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				break
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(value)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+// hexString reads a <hex string>
+func (l *pdfLexer) hexString() (string, error) {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			if len(digits)%2 != 0 {
+				digits = append(digits, '0')
+			}
+			out := make([]byte, len(digits)/2)
+			for i := range out {
+				v, err := strconv.ParseUint(string(digits[i*2:i*2+2]), 16, 8)
+				if err != nil {
+					return "", fmt.Errorf("invalid hex string: %w", err)
+				}
+				out[i] = byte(v)
+			}
+			return string(out), nil
+		}
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+// dictionary reads a << dictionary >>
+func (l *pdfLexer) dictionary() (pdfDict, error) {
+	if l.depth++; l.depth > maxPDFNesting {
+		return nil, fmt.Errorf("objects are nested too deeply at offset %d", l.pos)
+	}
+	defer func() { l.depth-- }()
+	l.pos += 2
+	dict := make(pdfDict)
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		key, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, fmt.Errorf("dictionary key %v is not a name", key)
+		}
+		value, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = value
+	}
+}
+
+// array reads an [array]
+func (l *pdfLexer) array() ([]interface{}, error) {
+	if l.depth++; l.depth > maxPDFNesting {
+		return nil, fmt.Errorf("objects are nested too deeply at offset %d", l.pos)
+	}
+	defer func() { l.depth-- }()
+	l.pos++
+	array := []interface{}{}
+	for {
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == ']' {
+			l.pos++
+			return array, nil
+		}
+		item, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, item)
+	}
+}
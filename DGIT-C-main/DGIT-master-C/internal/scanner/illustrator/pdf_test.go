@@ -0,0 +1,229 @@
+package illustrator
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// privateLayers is Illustrator's native layer data: a visible layer with a hidden sublayer, then a second layer
+const privateLayers = `%AI5_BeginLayer
+1 1 1 1 0 0 0 79 128 255 0 50 Lb
+(Background) Ln
+%AI5_BeginLayer
+0 1 1 1 0 0 0 79 128 255 0 50 Lb
+(Hidden child) Ln
+%AI5_EndLayer
+%AI5_EndLayer
+%AI5_BeginLayer
+1 1 1 1 0 0 0 79 128 255 0 50 Lb
+(Text) Ln
+%AI5_EndLayer
+`
+
+func deflate(data []byte) []byte {
+	var out bytes.Buffer
+	w := zlib.NewWriter(&out)
+	w.Write(data)
+	w.Close()
+	return out.Bytes()
+}
+
+// fixtureObjects returns the objects of a one-page Illustrator file, keyed by number
+// Objects 7, 8 and 9 are streams; the others are plain objects.
+func fixtureObjects() map[int]string {
+	private := append([]byte("%AI12_CompressedData"), deflate([]byte(privateLayers))...)
+	stream := func(dict string, data []byte) string {
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+	return map[int]string{
+		1:  "<< /Type /Catalog /Pages 2 0 R >>",
+		2:  "<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 800 600] >>",
+		3:  "<< /Type /Page /Parent 2 0 R /PieceInfo << /Illustrator 4 0 R >> /Resources << /Font << /F1 6 0 R >> /XObject << /Im1 7 0 R >> /ColorSpace << /CS0 10 0 R >> >> >>",
+		4:  "<< /Private 5 0 R >>",
+		5:  "<< /AIMetaData 8 0 R /AIPrivateData1 9 0 R >>",
+		6:  "<< /Type /Font /Subtype /Type1 /BaseFont /ABCDEF+Helvetica-Bold >>",
+		7:  stream("/Type /XObject /Subtype /Image /Width 2 /Height 1 /BitsPerComponent 8 /ColorSpace /DeviceGray", []byte{0, 255}),
+		8:  stream("", []byte("%%Creator: Adobe Illustrator(R) 24.0\n%AI9_ColorModel: 2\n")),
+		9:  stream("", private),
+		10: "[/Separation /Spot#20Red /DeviceCMYK null]",
+	}
+}
+
+// buildClassicPDF writes the fixture with a cross-reference table
+func buildClassicPDF() []byte {
+	objects := fixtureObjects()
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects)+1)
+	for num := 1; num <= len(objects); num++ {
+		offsets[num] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", num, objects[num])
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, offset := range offsets[1:] {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+	return out.Bytes()
+}
+
+// buildCompressedPDF writes the fixture with its plain objects in an object stream (object 11)
+// and a cross-reference stream (object 12) using the PNG Up predictor
+func buildCompressedPDF(first string, memberOffset func(i, offset int) int) []byte {
+	objects := fixtureObjects()
+	packed := []int{1, 2, 3, 4, 5, 6, 10}
+
+	var header, body bytes.Buffer
+	for i, num := range packed {
+		fmt.Fprintf(&header, "%d %d ", num, memberOffset(i, body.Len()))
+		body.WriteString(objects[num] + "\n")
+	}
+	if first == "" {
+		first = fmt.Sprint(header.Len())
+	}
+	objStm := deflate(append(header.Bytes(), body.Bytes()...))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.5\n")
+	offsets := make(map[int]int)
+	for _, num := range []int{7, 8, 9} {
+		offsets[num] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", num, objects[num])
+	}
+	offsets[11] = out.Len()
+	fmt.Fprintf(&out, "11 0 obj\n<< /Type /ObjStm /N %d /First %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n",
+		len(packed), first, len(objStm), objStm)
+	offsets[12] = out.Len()
+
+	// Rows of type, offset or stream number, and index; each row is stored as its difference from the previous one
+	var rows []byte
+	previous := make([]byte, 7)
+	for num := 0; num <= 12; num++ {
+		row := make([]byte, 7)
+		if index := indexOf(packed, num); index >= 0 {
+			row[0], row[4], row[6] = 2, 11, byte(index)
+		} else if offset, ok := offsets[num]; ok {
+			row[0], row[3], row[4] = 1, byte(offset>>8), byte(offset)
+		}
+		rows = append(rows, 2)
+		for i := range row {
+			rows = append(rows, row[i]-previous[i])
+		}
+		previous = row
+	}
+	xref := deflate(rows)
+	fmt.Fprintf(&out, "12 0 obj\n<< /Type /XRef /Size 13 /Root 1 0 R /W [1 4 2] /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 7 >> /Length %d >>\nstream\n%s\nendstream\nendobj\n",
+		len(xref), xref)
+	fmt.Fprintf(&out, "startxref\n%d\n%%%%EOF\n", offsets[12])
+	return out.Bytes()
+}
+
+func indexOf(nums []int, num int) int {
+	for i, n := range nums {
+		if n == num {
+			return i
+		}
+	}
+	return -1
+}
+
+func keepOffset(_, offset int) int { return offset }
+
+func TestReadPDFStructure(t *testing.T) {
+	fixtures := map[string][]byte{
+		"classic":    buildClassicPDF(),
+		"compressed": buildCompressedPDF("", keepOffset),
+	}
+	for name, data := range fixtures {
+		s, err := readPDFStructure(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		info := s.aiInfo()
+		if info.Width != 800 || info.Height != 600 || info.ArtboardCount != 1 {
+			t.Errorf("%s: %dx%d, %d artboards", name, info.Width, info.Height, info.ArtboardCount)
+		}
+		if info.Version != "CC 2020 (24.x)" || info.ColorMode != "CMYK" {
+			t.Errorf("%s: version %q, color mode %q", name, info.Version, info.ColorMode)
+		}
+		wantTree := []Layer{
+			{Name: "Background", Visible: true, Sublayers: []Layer{{Name: "Hidden child", Visible: false}}},
+			{Name: "Text", Visible: true},
+		}
+		if !reflect.DeepEqual(info.LayerTree, wantTree) {
+			t.Errorf("%s: layer tree = %+v", name, info.LayerTree)
+		}
+		if !reflect.DeepEqual(s.Fonts, []string{"Helvetica-Bold"}) || !reflect.DeepEqual(s.SpotColors, []string{"Spot Red"}) {
+			t.Errorf("%s: fonts %v, spot colors %v", name, s.Fonts, s.SpotColors)
+		}
+		if len(s.Images) != 1 || s.Images[0].Width != 2 || s.Images[0].Height != 1 {
+			t.Errorf("%s: images = %+v", name, s.Images)
+		}
+	}
+}
+
+func TestReadPDFStructureRebuildsDamagedXref(t *testing.T) {
+	data := buildClassicPDF()
+	start := bytes.LastIndex(data, []byte("startxref"))
+	damaged := append(append([]byte(nil), data[:start]...), "startxref\n999999\n%%EOF\n"...)
+	s, err := readPDFStructure(damaged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Pages) != 1 || len(s.Layers) != 2 {
+		t.Errorf("pages = %v, layers = %+v", s.Pages, s.Layers)
+	}
+}
+
+func TestObjectStreamRejectsInvalidOffsets(t *testing.T) {
+	cases := map[string][]byte{
+		"negative /First":        buildCompressedPDF("-3", keepOffset),
+		"/First past the end":    buildCompressedPDF("100000", keepOffset),
+		"negative member offset": buildCompressedPDF("", func(i, offset int) int { return offset - 1000 }),
+		"member offset past end": buildCompressedPDF("", func(i, offset int) int { return offset + 100000 }),
+	}
+	for name, data := range cases {
+		f, err := openPDF(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := f.objectStream(11); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		readPDFStructure(data) // Must not panic
+	}
+}
+
+func TestUnpredictRejectsOversizedRows(t *testing.T) {
+	f := &pdfFile{}
+	for _, parms := range []pdfDict{
+		{"Predictor": 12, "Columns": 1 << 40},
+		{"Predictor": 12, "Colors": 1 << 30, "Columns": 1 << 20},
+		{"Predictor": 12, "BitsPerComponent": 1 << 40},
+		{"Predictor": 12, "Columns": 100},
+	} {
+		if _, err := f.unpredict(make([]byte, 16), parms); err == nil {
+			t.Errorf("%v: expected an error", parms)
+		}
+	}
+}
+
+func TestLexerLimitsNesting(t *testing.T) {
+	l := &pdfLexer{data: []byte(strings.Repeat("[", 100000))}
+	if _, err := l.object(); err == nil {
+		t.Error("expected an error for deeply nested arrays")
+	}
+}
+
+func TestReadPDFStructureDoesNotPanicOnTruncation(t *testing.T) {
+	for _, fixture := range [][]byte{buildClassicPDF(), buildCompressedPDF("", keepOffset)} {
+		scantest.Truncations(t, fixture, func(prefix []byte) { readPDFStructure(prefix) })
+	}
+}
@@ -0,0 +1,432 @@
+package illustrator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layer is a layer of an Illustrator document with its sublayers
+type Layer struct {
+	Name      string  `json:"name"`
+	Visible   bool    `json:"visible"`
+	Sublayers []Layer `json:"sublayers,omitempty"`
+}
+
+// pdfStructure is what the PDF side of an AI file tells about the document
+// Illustrator saves its files as PDFs with the native artwork kept in private streams.
+type pdfStructure struct {
+	PDFVersion  string
+	Pages       [][4]float64 // MediaBox of each page
+	Layers      []Layer
+	Fonts       []string
+	Images      []EmbeddedImage
+	SpotColors  []string
+	ObjectCount int    // Objects other than object and cross-reference streams
+	ColorSpace  string // Blending color space of the first page
+	XMP         string
+	Creator     string // /Creator of the document information dictionary
+	AIMetaData  string // Illustrator's header comments: %%Creator, %AI9_ColorModel, ...
+	PrivateData string // Illustrator's native data; empty when stored in a format that cannot be decoded
+}
+
+var (
+	aiCreatorRe    = regexp.MustCompile(`%%Creator:\s*Adobe Illustrator\(R\)\s*(\d+\.\d+)`)
+	aiColorModelRe = regexp.MustCompile(`%AI9_ColorModel:\s*(\d)`)
+	xmpPagesRe     = regexp.MustCompile(`<xmpTPg:NPages>(\d+)</xmpTPg:NPages>`)
+	versionNumRe   = regexp.MustCompile(`(\d+\.\d+)`)
+)
+
+// processColorants are the DeviceN colorants that are not spot colors
+var processColorants = map[string]bool{
+	"Cyan": true, "Magenta": true, "Yellow": true, "Black": true, "All": true, "None": true,
+}
+
+// readPDFStructure parses an AI file as a PDF
+func readPDFStructure(data []byte) (*pdfStructure, error) {
+	f, err := openPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	root := f.dict(f.trailer["Root"])
+	if root == nil {
+		return nil, fmt.Errorf("no document catalog")
+	}
+
+	var pages []pdfDict
+	s := &pdfStructure{PDFVersion: f.version}
+	f.collectPages(f.dict(root["Pages"]), [4]float64{0, 0, 612, 792}, 0, &pages, &s.Pages)
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages")
+	}
+
+	if xmp, err := f.stream(root["Metadata"]); err == nil {
+		s.XMP = string(xmp)
+	}
+	s.Creator = f.text(f.dict(f.trailer["Info"])["Creator"])
+	if cs, ok := f.resolve(f.dict(pages[0]["Group"])["CS"]).(pdfName); ok {
+		s.ColorSpace = string(cs)
+	}
+
+	s.AIMetaData, s.PrivateData = f.illustratorData(pages[0])
+	if s.Layers = parsePrivateLayers(s.PrivateData); len(s.Layers) == 0 {
+		s.Layers = f.optionalContentLayers(root)
+	}
+	f.scanObjects(s)
+	return s, nil
+}
+
+// collectPages walks the page tree, passing inherited MediaBoxes down to the pages
+func (f *pdfFile) collectPages(node pdfDict, mediaBox [4]float64, depth int, pages *[]pdfDict, boxes *[][4]float64) {
+	if node == nil || depth > 64 {
+		return
+	}
+	if box, ok := f.rect(node["MediaBox"]); ok {
+		mediaBox = box
+	}
+	kids := f.array(node["Kids"])
+	if node["Type"] != pdfName("Pages") && kids == nil {
+		*pages = append(*pages, node)
+		*boxes = append(*boxes, mediaBox)
+		return
+	}
+	for _, kid := range kids {
+		f.collectPages(f.dict(kid), mediaBox, depth+1, pages, boxes)
+	}
+}
+
+// illustratorData returns the AIMetaData and the joined AIPrivateData blocks stored with a page
+func (f *pdfFile) illustratorData(page pdfDict) (string, string) {
+	private := f.dict(f.dict(f.dict(page["PieceInfo"])["Illustrator"])["Private"])
+	if private == nil {
+		return "", ""
+	}
+	var metaData string
+	if data, err := f.stream(private["AIMetaData"]); err == nil {
+		metaData = string(data)
+	}
+
+	var privateData bytes.Buffer
+	for i := 1; ; i++ {
+		block, ok := private[pdfName(fmt.Sprintf("AIPrivateData%d", i))]
+		if !ok {
+			break
+		}
+		data, err := f.stream(block)
+		if err != nil {
+			return metaData, ""
+		}
+		privateData.Write(data)
+	}
+	return metaData, decodePrivateData(privateData.Bytes())
+}
+
+// decodePrivateData undoes the compression Illustrator applies to its native data
+// Versions since CS2 deflate it; recent versions may use Zstandard, which is left undecoded.
+func decodePrivateData(data []byte) string {
+	const compressedMarker = "%AI12_CompressedData"
+	switch {
+	case bytes.HasPrefix(data, []byte(compressedMarker)):
+		inflated, err := inflate(data[len(compressedMarker):])
+		if err != nil {
+			return ""
+		}
+		return string(inflated)
+	case bytes.HasPrefix(data, []byte("%AI")) && bytes.Contains(data[:min(len(data), 32)], []byte("ZStandard")):
+		return ""
+	}
+	return string(data)
+}
+
+// parsePrivateLayers reads the layer blocks of Illustrator's native data
+// Each layer is "%AI5_BeginLayer", an Lb line whose first operand is visibility, "(name) Ln",
+// its artwork and sublayers, then "%AI5_EndLayer".
+func parsePrivateLayers(data string) []Layer {
+	var layers, stack []Layer
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "%AI5_BeginLayer"):
+			stack = append(stack, Layer{Visible: true})
+		case strings.HasPrefix(line, "%AI5_EndLayer") && len(stack) > 0:
+			layer := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				stack[len(stack)-1].Sublayers = append(stack[len(stack)-1].Sublayers, layer)
+			} else {
+				layers = append(layers, layer)
+			}
+		case len(stack) > 0 && strings.HasSuffix(line, " Lb"):
+			fields := strings.Fields(line)
+			stack[len(stack)-1].Visible = fields[0] != "0"
+		case len(stack) > 0 && strings.HasPrefix(line, "(") && strings.HasSuffix(line, ") Ln"):
+			if name, err := (&pdfLexer{data: []byte(line)}).literalString(); err == nil {
+				stack[len(stack)-1].Name = name
+			}
+		}
+	}
+	return layers
+}
+
+// optionalContentLayers reads the layers Illustrator exports as optional content groups
+// The /Order array of the default configuration holds the hierarchy: an array after a
+// group lists its children, and an array starting with a string is a labelled group.
+func (f *pdfFile) optionalContentLayers(root pdfDict) []Layer {
+	properties := f.dict(root["OCProperties"])
+	if properties == nil {
+		return nil
+	}
+	names := make(map[pdfRef]string)
+	var groups []interface{}
+	for _, item := range f.array(properties["OCGs"]) {
+		if ref, ok := item.(pdfRef); ok {
+			names[ref] = f.text(f.dict(ref)["Name"])
+			groups = append(groups, ref)
+		}
+	}
+
+	config := f.dict(properties["D"])
+	hidden := make(map[pdfRef]bool)
+	for _, item := range f.array(config["OFF"]) {
+		if ref, ok := item.(pdfRef); ok {
+			hidden[ref] = true
+		}
+	}
+	order := f.array(config["Order"])
+	if len(order) == 0 {
+		order = groups
+	}
+	return f.orderLayers(order, names, hidden, 0)
+}
+
+// orderLayers turns an optional content /Order array into a layer tree
+func (f *pdfFile) orderLayers(order []interface{}, names map[pdfRef]string, hidden map[pdfRef]bool, depth int) []Layer {
+	var layers []Layer
+	if depth > 32 {
+		return layers
+	}
+	for _, item := range order {
+		switch v := item.(type) {
+		case pdfRef:
+			if name, ok := names[v]; ok {
+				layers = append(layers, Layer{Name: name, Visible: !hidden[v]})
+				continue
+			}
+			// A reference to a nested array rather than to a group
+			if nested := f.array(v); nested != nil {
+				layers = f.appendNested(layers, nested, names, hidden, depth)
+			}
+		case []interface{}:
+			layers = f.appendNested(layers, v, names, hidden, depth)
+		}
+	}
+	return layers
+}
+
+// appendNested adds a nested /Order array as the children of the previous layer, or as a labelled group
+func (f *pdfFile) appendNested(layers []Layer, nested []interface{}, names map[pdfRef]string, hidden map[pdfRef]bool, depth int) []Layer {
+	if len(nested) > 0 {
+		if label, ok := f.resolve(nested[0]).(string); ok {
+			return append(layers, Layer{
+				Name:      f.text(label),
+				Visible:   true,
+				Sublayers: f.orderLayers(nested[1:], names, hidden, depth+1),
+			})
+		}
+	}
+	children := f.orderLayers(nested, names, hidden, depth+1)
+	if len(layers) == 0 {
+		return children
+	}
+	parent := &layers[len(layers)-1]
+	parent.Sublayers = append(parent.Sublayers, children...)
+	return layers
+}
+
+// scanObjects counts the objects of the file and collects its fonts, images and spot colors
+func (f *pdfFile) scanObjects(s *pdfStructure) {
+	fonts := make(map[string]bool)
+	spots := make(map[string]bool)
+	masks := make(map[int]bool)
+	var images []int
+
+	for _, num := range f.objectNumbers() {
+		var dict pdfDict
+		switch v := f.object(num).(type) {
+		case nil:
+			continue
+		case pdfDict:
+			dict = v
+		case *pdfStream:
+			dict = v.Dict
+			if dict["Type"] == pdfName("ObjStm") || dict["Type"] == pdfName("XRef") {
+				continue
+			}
+			if dict["Subtype"] == pdfName("Image") {
+				images = append(images, num)
+				if mask, ok := dict["SMask"].(pdfRef); ok {
+					masks[mask.Num] = true
+				}
+			}
+		case []interface{}:
+			f.collectSpotColors(v, spots, 0)
+		}
+		s.ObjectCount++
+		if dict == nil {
+			continue
+		}
+		if dict["Type"] == pdfName("Font") {
+			if name, ok := f.resolve(dict["BaseFont"]).(pdfName); ok {
+				fonts[fontName(string(name))] = true
+			}
+		}
+		f.collectSpotColors(dict, spots, 0)
+	}
+
+	// Soft masks are the alpha channels of other images, not images of their own
+	for _, num := range images {
+		if masks[num] {
+			continue
+		}
+		stream := f.object(num).(*pdfStream)
+		width, _ := pdfInt(f.resolve(stream.Dict["Width"]))
+		height, _ := pdfInt(f.resolve(stream.Dict["Height"]))
+		s.Images = append(s.Images, EmbeddedImage{
+			Width:  width,
+			Height: height,
+			Hash:   fmt.Sprintf("%x", sha256.Sum256(stream.Raw))[:16],
+		})
+	}
+	for font := range fonts {
+		s.Fonts = append(s.Fonts, font)
+	}
+	sort.Strings(s.Fonts)
+	for spot := range spots {
+		s.SpotColors = append(s.SpotColors, spot)
+	}
+	sort.Strings(s.SpotColors)
+}
+
+// collectSpotColors finds Separation and DeviceN color spaces inside an object
+func (f *pdfFile) collectSpotColors(obj interface{}, spots map[string]bool, depth int) {
+	if depth > 16 {
+		return
+	}
+	switch v := obj.(type) {
+	case pdfDict:
+		for _, value := range v {
+			f.collectSpotColors(value, spots, depth+1)
+		}
+	case []interface{}:
+		if len(v) >= 2 {
+			switch v[0] {
+			case pdfName("Separation"):
+				if name, ok := v[1].(pdfName); ok && !processColorants[string(name)] {
+					spots[string(name)] = true
+				}
+			case pdfName("DeviceN"):
+				for _, colorant := range f.array(v[1]) {
+					if name, ok := colorant.(pdfName); ok && !processColorants[string(name)] {
+						spots[string(name)] = true
+					}
+				}
+			}
+		}
+		for _, item := range v {
+			f.collectSpotColors(item, spots, depth+1)
+		}
+	}
+}
+
+// aiInfo summarizes the structure as AIInfo
+func (s *pdfStructure) aiInfo() *AIInfo {
+	info := &AIInfo{
+		Width:          int(s.Pages[0][2] - s.Pages[0][0]),
+		Height:         int(s.Pages[0][3] - s.Pages[0][1]),
+		ColorMode:      s.colorMode(),
+		Version:        s.version(),
+		LayerNames:     flattenLayers(s.Layers),
+		LayerTree:      s.Layers,
+		ArtboardCount:  len(s.Pages),
+		ObjectCount:    s.ObjectCount,
+		FontCount:      len(s.Fonts),
+		EmbeddedImages: len(s.Images),
+	}
+	// Only the first artboard becomes a PDF page unless all are exported; XMP counts them all
+	if m := xmpPagesRe.FindStringSubmatch(s.XMP); m != nil {
+		if count, err := strconv.Atoi(m[1]); err == nil && count > info.ArtboardCount {
+			info.ArtboardCount = count
+		}
+	}
+	info.LayerCount = len(info.LayerNames)
+	if info.LayerCount == 0 {
+		info.LayerCount = 1
+		info.LayerNames = []string{"Layer 1"}
+	}
+	return info
+}
+
+// document returns the document contents used for comparisons
+func (s *pdfStructure) document() *Document {
+	doc := &Document{
+		Artboards: make([]Artboard, len(s.Pages)),
+		Layers:    flattenLayers(s.Layers),
+		Fonts:     append([]string{}, s.Fonts...),
+		Swatches:  mergeSpotColors(xmpSwatches(s.XMP), s.SpotColors),
+		Images:    append([]EmbeddedImage{}, s.Images...),
+	}
+	for i, box := range s.Pages {
+		doc.Artboards[i] = Artboard{Index: i + 1, Bounds: box}
+	}
+	return doc
+}
+
+// colorMode reads the document color mode from Illustrator's header, then from the page blending space
+func (s *pdfStructure) colorMode() string {
+	if m := aiColorModelRe.FindStringSubmatch(s.AIMetaData); m != nil {
+		switch m[1] {
+		case "1":
+			return "RGB"
+		case "2":
+			return "CMYK"
+		}
+	}
+	switch s.ColorSpace {
+	case "DeviceCMYK":
+		return "CMYK"
+	case "DeviceGray":
+		return "Grayscale"
+	}
+	return "RGB"
+}
+
+// version reads the Illustrator version from XMP, Illustrator's header or the document information
+func (s *pdfStructure) version() string {
+	if version := extractCreatorVersion(s.XMP); version != "" {
+		return version
+	}
+	if m := aiCreatorRe.FindStringSubmatch(s.AIMetaData); m != nil {
+		return mapVersionToName(m[1])
+	}
+	if strings.Contains(s.Creator, "Adobe Illustrator") {
+		if m := versionNumRe.FindStringSubmatch(s.Creator); m != nil {
+			return mapVersionToName(m[1])
+		}
+		return "Adobe Illustrator"
+	}
+	return "Adobe Illustrator (PDF " + s.PDFVersion + ")"
+}
+
+// flattenLayers lists the names of a layer tree depth first
+func flattenLayers(layers []Layer) []string {
+	names := []string{}
+	for _, layer := range layers {
+		names = append(names, layer.Name)
+		names = append(names, flattenLayers(layer.Sublayers)...)
+	}
+	return names
+}
@@ -0,0 +1,35 @@
+package illustrator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ExtractThumbnail returns the JPEG preview Illustrator stores in the file's XMP metadata
+func ExtractThumbnail(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AI file: %w", err)
+	}
+
+	// <xmpGImg:image> holds base64 JPEG data, wrapped with escaped newlines (&#xA;)
+	start := bytes.Index(data, []byte("<xmpGImg:image>"))
+	if start < 0 {
+		return nil, fmt.Errorf("no embedded preview")
+	}
+	start += len("<xmpGImg:image>")
+	end := bytes.Index(data[start:], []byte("</xmpGImg:image>"))
+	if end < 0 {
+		return nil, fmt.Errorf("embedded preview is truncated")
+	}
+
+	encoded := strings.NewReplacer("&#xA;", "", "&#xD;", "", "\n", "", "\r", "", " ", "").Replace(string(data[start : start+end]))
+	preview, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode embedded preview: %w", err)
+	}
+	return preview, nil
+}
@@ -0,0 +1,186 @@
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OBJInfo contains metadata extracted from Wavefront OBJ files and their MTL libraries
+// OBJ is line-based text: "v" vertices, "vt" texture coordinates, "vn" normals, "f" faces,
+// "o" objects, "g" groups, "mtllib" material libraries and "usemtl" material references.
+type OBJInfo struct {
+	Objects     []Object   // Named objects with their geometry counts, or one unnamed object
+	Groups      []string   // Group names in order of first use
+	Vertices    int        // Total "v" lines
+	TexCoords   int        // Total "vt" lines
+	Normals     int        // Total "vn" lines
+	Faces       int        // Total "f" lines
+	Min         [3]float64 // Lowest corner of the bounding box of all vertices
+	Max         [3]float64 // Highest corner of the bounding box
+	Libraries   []string   // Material libraries named by mtllib
+	Materials   []string   // Materials referenced by usemtl, in order of first use
+	Defined     []string   // Materials defined in the libraries that could be read
+	Textures    []string   // Texture maps referenced by those materials
+	MissingLibs []string   // Material libraries that could not be read
+}
+
+// Object is an "o" section of an OBJ file
+type Object struct {
+	Name     string
+	Vertices int
+	Faces    int
+}
+
+// GetOBJInfo extracts objects, groups, geometry counts, the bounding box and materials from an OBJ file
+// Material libraries are read from the OBJ file's directory when present.
+func GetOBJInfo(filePath string) (*OBJInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OBJ file: %w", err)
+	}
+	defer file.Close()
+
+	info := &OBJInfo{
+		Objects:     []Object{},
+		Groups:      []string{},
+		Libraries:   []string{},
+		Materials:   []string{},
+		Defined:     []string{},
+		Textures:    []string{},
+		MissingLibs: []string{},
+	}
+	seenGroups := make(map[string]bool)
+	seenMaterials := make(map[string]bool)
+	current := -1
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword := fields[0]
+		rest := strings.TrimSpace(line[len(keyword):])
+		switch keyword {
+		case "v":
+			coords := strings.Fields(rest)
+			if len(coords) < 3 {
+				continue
+			}
+			var point [3]float64
+			valid := true
+			for i := range point {
+				if point[i], err = strconv.ParseFloat(coords[i], 64); err != nil {
+					valid = false
+				}
+			}
+			if !valid {
+				continue
+			}
+			if info.Vertices == 0 {
+				info.Min, info.Max = point, point
+			}
+			for i := range point {
+				info.Min[i] = math.Min(info.Min[i], point[i])
+				info.Max[i] = math.Max(info.Max[i], point[i])
+			}
+			info.Vertices++
+			if current < 0 {
+				info.Objects = append(info.Objects, Object{})
+				current = 0
+			}
+			info.Objects[current].Vertices++
+		case "vt":
+			info.TexCoords++
+		case "vn":
+			info.Normals++
+		case "f":
+			info.Faces++
+			if current < 0 {
+				info.Objects = append(info.Objects, Object{})
+				current = 0
+			}
+			info.Objects[current].Faces++
+		case "o":
+			// An unnamed object holding geometry written before the first "o" is kept
+			if current >= 0 && info.Objects[current].Name == "" && info.Objects[current].Vertices == 0 && info.Objects[current].Faces == 0 {
+				info.Objects[current].Name = rest
+			} else {
+				info.Objects = append(info.Objects, Object{Name: rest})
+				current = len(info.Objects) - 1
+			}
+		case "g":
+			for _, group := range strings.Fields(rest) {
+				if !seenGroups[group] && group != "default" {
+					seenGroups[group] = true
+					info.Groups = append(info.Groups, group)
+				}
+			}
+		case "usemtl":
+			if rest != "" && !seenMaterials[rest] {
+				seenMaterials[rest] = true
+				info.Materials = append(info.Materials, rest)
+			}
+		case "mtllib":
+			info.Libraries = append(info.Libraries, strings.Fields(rest)...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read OBJ file: %w", err)
+	}
+	if info.Vertices == 0 && info.Faces == 0 && len(info.Objects) == 0 {
+		return nil, fmt.Errorf("OBJ file has no geometry")
+	}
+
+	for _, library := range info.Libraries {
+		if err := info.readMaterialLibrary(filepath.Join(filepath.Dir(filePath), library)); err != nil {
+			info.MissingLibs = append(info.MissingLibs, library)
+		}
+	}
+	return info, nil
+}
+
+// readMaterialLibrary collects the materials and texture maps of an MTL file
+func (info *OBJInfo) readMaterialLibrary(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	seenTextures := make(map[string]bool)
+	for _, texture := range info.Textures {
+		seenTextures[texture] = true
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case fields[0] == "newmtl":
+			info.Defined = append(info.Defined, strings.Join(fields[1:], " "))
+		case strings.HasPrefix(fields[0], "map_") || fields[0] == "bump" || fields[0] == "disp" || fields[0] == "decal":
+			// Options such as "-s 1 1 1" precede the file name, which comes last
+			texture := fields[len(fields)-1]
+			if !seenTextures[texture] {
+				seenTextures[texture] = true
+				info.Textures = append(info.Textures, texture)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Size returns the extent of the bounding box along each axis
+func (info *OBJInfo) Size() [3]float64 {
+	return [3]float64{info.Max[0] - info.Min[0], info.Max[1] - info.Min[1], info.Max[2] - info.Min[2]}
+}
@@ -0,0 +1,130 @@
+package obj
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+const model = `# Blender OBJ
+mtllib model.mtl missing.mtl
+o Cube
+v 1.0 1.0 -1.0
+v 1.0 -1.0 -1.0
+v 1.0 1.0 1.0
+v -1.0 -1.0 1.0
+vt 0.0 0.0
+vn 0 1 0
+g body
+usemtl Metal
+f 1/1/1 2/1/1 3/1/1
+f 2/1/1 3/1/1 4/1/1
+o Plane
+v	-3.0 0.0 2.5
+g floor default
+usemtl Wood
+f 1 4 5
+`
+
+const library = `newmtl Metal
+Kd 0.8 0.8 0.8
+newmtl Wood
+map_Kd -s 1 1 1 textures/wood.png
+bump textures/wood.png
+`
+
+func writeModel(t *testing.T, data string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "model.mtl"), []byte(library), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "model.obj")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGetOBJInfo(t *testing.T) {
+	info, err := GetOBJInfo(writeModel(t, model))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Object{{"Cube", 4, 2}, {"Plane", 1, 1}}; !reflect.DeepEqual(info.Objects, want) {
+		t.Errorf("objects = %+v, want %+v", info.Objects, want)
+	}
+	if info.Vertices != 5 || info.Faces != 3 || info.TexCoords != 1 || info.Normals != 1 {
+		t.Errorf("counts = %d vertices, %d faces, %d texcoords, %d normals", info.Vertices, info.Faces, info.TexCoords, info.Normals)
+	}
+	if want := [3]float64{4, 2, 3.5}; info.Size() != want {
+		t.Errorf("size = %v, want %v", info.Size(), want)
+	}
+	if want := []string{"body", "floor"}; !reflect.DeepEqual(info.Groups, want) {
+		t.Errorf("groups = %v, want %v", info.Groups, want)
+	}
+	if want := []string{"Metal", "Wood"}; !reflect.DeepEqual(info.Materials, want) || !reflect.DeepEqual(info.Defined, want) {
+		t.Errorf("materials = %v, defined = %v", info.Materials, info.Defined)
+	}
+	if want := []string{"textures/wood.png"}; !reflect.DeepEqual(info.Textures, want) {
+		t.Errorf("textures = %v, want %v", info.Textures, want)
+	}
+	if want := []string{"missing.mtl"}; !reflect.DeepEqual(info.MissingLibs, want) {
+		t.Errorf("missing libraries = %v, want %v", info.MissingLibs, want)
+	}
+}
+
+func TestGetOBJInfoWithoutGeometry(t *testing.T) {
+	if _, err := GetOBJInfo(writeModel(t, "garbage")); err == nil {
+		t.Error("expected an error for a file without geometry")
+	}
+}
+
+func TestGetOBJInfoMeshCounts(t *testing.T) {
+	cases := map[string]struct {
+		data            string
+		objects         []Object
+		vertices, faces int
+	}{
+		"geometry before the first object": {
+			data:     "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\no Named\nv 0 0 1\nf 1 2 4\n",
+			objects:  []Object{{"", 3, 1}, {"Named", 1, 1}},
+			vertices: 4, faces: 2,
+		},
+		"object declared before its geometry": {
+			data:     "o First\no Second\nv 0 0 0\nf 1 1 1\n",
+			objects:  []Object{{"First", 0, 0}, {"Second", 1, 1}},
+			vertices: 1, faces: 1,
+		},
+		"malformed vertices are skipped": {
+			data:     "o Mesh\nv 1 2\nv a b c\nv 1 2 3 1.0\nf 1 1 1\n",
+			objects:  []Object{{"Mesh", 1, 1}},
+			vertices: 1, faces: 1,
+		},
+		"faces without vertices": {
+			data:     "f 1 2 3\nf -1 -2 -3\n",
+			objects:  []Object{{"", 0, 2}},
+			vertices: 0, faces: 2,
+		},
+	}
+	for name, c := range cases {
+		info, err := GetOBJInfo(writeModel(t, c.data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(info.Objects, c.objects) {
+			t.Errorf("%s: objects = %+v, want %+v", name, info.Objects, c.objects)
+		}
+		if info.Vertices != c.vertices || info.Faces != c.faces {
+			t.Errorf("%s: %d vertices and %d faces, want %d and %d", name, info.Vertices, info.Faces, c.vertices, c.faces)
+		}
+	}
+}
+
+func TestGetOBJInfoDoesNotPanicOnTruncation(t *testing.T) {
+	scantest.TruncatedFiles(t, "model.obj", []byte(model), func(path string) { GetOBJInfo(path) })
+}
@@ -0,0 +1,248 @@
+package photoshop
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+// Color modes of the PSD header that the composite decoder understands
+const (
+	colorModeGrayscale = 1
+	colorModeRGB       = 3
+	colorModeCMYK      = 4
+)
+
+// Size limits of the PSD header: documents are at most 30000 pixels on a side, large documents (PSB) 300000,
+// and no file has more than 56 channels
+const (
+	maxPSDDimension = 30000
+	maxPSBDimension = 300000
+	maxChannels     = 56
+)
+
+// maxCompositePixels bounds the canvas area that is decoded, keeping the RGBA image within 1 GB
+const maxCompositePixels = 1 << 28
+
+// ReadComposite decodes the merged image of a PSD file
+func ReadComposite(filePath string) (*image.RGBA, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PSD file: %w", err)
+	}
+	return DecodeComposite(data)
+}
+
+// DecodeComposite decodes the merged image data section of a PSD or PSB held in memory
+// Raw and RLE (PackBits) compressed data is supported at 8 and 16 bits per channel in RGB,
+// grayscale and CMYK. Extra channels are ignored; Photoshop mattes the merged image against white.
+func DecodeComposite(data []byte) (*image.RGBA, error) {
+	r := &byteReader{data: data}
+	header, err := r.bytes(26)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PSD header: %w", err)
+	}
+	if string(header[0:4]) != "8BPS" {
+		return nil, fmt.Errorf("not a PSD file")
+	}
+	version := binary.BigEndian.Uint16(header[4:6])
+	if version != 1 && version != 2 {
+		return nil, fmt.Errorf("unsupported PSD file version: %d", version)
+	}
+	channels := int(binary.BigEndian.Uint16(header[12:14]))
+	height := int(binary.BigEndian.Uint32(header[14:18]))
+	width := int(binary.BigEndian.Uint32(header[18:22]))
+	depth := int(binary.BigEndian.Uint16(header[22:24]))
+	colorMode := int(binary.BigEndian.Uint16(header[24:26]))
+
+	if depth != 8 && depth != 16 {
+		return nil, fmt.Errorf("%d-bit composites are not supported", depth)
+	}
+	colorChannels := map[int]int{colorModeGrayscale: 1, colorModeRGB: 3, colorModeCMYK: 4}[colorMode]
+	if colorChannels == 0 {
+		return nil, fmt.Errorf("color mode %d is not supported", colorMode)
+	}
+	if channels < colorChannels {
+		return nil, fmt.Errorf("composite has %d channels, color mode needs %d", channels, colorChannels)
+	}
+	if channels > maxChannels {
+		return nil, fmt.Errorf("invalid channel count %d", channels)
+	}
+	maxDimension := maxPSDDimension
+	if version == 2 {
+		maxDimension = maxPSBDimension
+	}
+	if width <= 0 || height <= 0 || width > maxDimension || height > maxDimension {
+		return nil, fmt.Errorf("invalid canvas size %dx%d", width, height)
+	}
+	if uint64(width)*uint64(height) > maxCompositePixels {
+		return nil, fmt.Errorf("canvas of %dx%d pixels is too large to decode", width, height)
+	}
+
+	// Skip color mode data, image resources and the layer and mask section (8-byte length in PSB)
+	for i, section := range []string{"color mode data", "image resources", "layer and mask info"} {
+		var length uint64
+		if i == 2 && version == 2 {
+			b, err := r.bytes(8)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s length: %w", section, err)
+			}
+			length = binary.BigEndian.Uint64(b)
+		} else {
+			l, err := r.uint32()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s length: %w", section, err)
+			}
+			length = uint64(l)
+		}
+		if length > uint64(r.remaining()) {
+			return nil, fmt.Errorf("%s exceeds file size", section)
+		}
+		r.bytes(int(length))
+	}
+
+	compression, err := r.uint16()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data compression: %w", err)
+	}
+
+	rowBytes := width * depth / 8
+	planes := make([][]byte, colorChannels)
+	switch compression {
+	case 0:
+		for c := range planes {
+			if planes[c], err = r.bytes(rowBytes * height); err != nil {
+				return nil, fmt.Errorf("failed to read channel %d: %w", c, err)
+			}
+		}
+	case 1:
+		planes, err = decodeRLEPlanes(r, channels, colorChannels, height, rowBytes, version == 2)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("image data compression %d is not supported", compression)
+	}
+
+	// 16-bit samples are reduced to their high byte
+	sample := func(plane []byte, i int) uint8 {
+		if depth == 16 {
+			return plane[i*2]
+		}
+		return plane[i]
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		var px color.RGBA
+		switch colorMode {
+		case colorModeGrayscale:
+			v := sample(planes[0], i)
+			px = color.RGBA{v, v, v, 255}
+		case colorModeRGB:
+			px = color.RGBA{sample(planes[0], i), sample(planes[1], i), sample(planes[2], i), 255}
+		case colorModeCMYK:
+			// PSD stores CMYK inverted: 255 means no ink
+			k := uint16(sample(planes[3], i))
+			px = color.RGBA{
+				uint8(uint16(sample(planes[0], i)) * k / 255),
+				uint8(uint16(sample(planes[1], i)) * k / 255),
+				uint8(uint16(sample(planes[2], i)) * k / 255),
+				255,
+			}
+		}
+		img.SetRGBA(i%width, i/width, px)
+	}
+	return img, nil
+}
+
+// decodeRLEPlanes unpacks the PackBits rows of the color channels
+// The row byte counts of every channel come first, as uint16 in PSD and uint32 in PSB
+func decodeRLEPlanes(r *byteReader, channels, colorChannels, height, rowBytes int, large bool) ([][]byte, error) {
+	countSize := 2
+	if large {
+		countSize = 4
+	}
+	if channels*height*countSize > r.remaining() {
+		return nil, fmt.Errorf("RLE row lengths exceed file size")
+	}
+	counts := make([]int, channels*height)
+	for i := range counts {
+		if large {
+			n, err := r.uint32()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read RLE row lengths: %w", err)
+			}
+			counts[i] = int(n)
+		} else {
+			n, err := r.uint16()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read RLE row lengths: %w", err)
+			}
+			counts[i] = int(n)
+		}
+	}
+
+	// PackBits expands two bytes to at most 128, so the rows must hold enough data before the planes are allocated
+	packedTotal := 0
+	for _, count := range counts[:colorChannels*height] {
+		packedTotal += count
+	}
+	if packedTotal > r.remaining() {
+		return nil, fmt.Errorf("RLE data exceeds file size")
+	}
+	if uint64(packedTotal)*64 < uint64(colorChannels)*uint64(rowBytes)*uint64(height) {
+		return nil, fmt.Errorf("RLE data is too short for the canvas")
+	}
+
+	planes := make([][]byte, colorChannels)
+	for c := range planes {
+		plane := make([]byte, 0, rowBytes*height)
+		for y := 0; y < height; y++ {
+			packed, err := r.bytes(counts[c*height+y])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read row %d of channel %d: %w", y, c, err)
+			}
+			row, err := unpackBits(packed, rowBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode row %d of channel %d: %w", y, c, err)
+			}
+			plane = append(plane, row...)
+		}
+		planes[c] = plane
+	}
+	return planes, nil
+}
+
+// unpackBits decodes one PackBits-compressed row of the given length
+func unpackBits(packed []byte, length int) ([]byte, error) {
+	row := make([]byte, 0, length)
+	for i := 0; i < len(packed) && len(row) < length; {
+		header := int(int8(packed[i]))
+		i++
+		switch {
+		case header >= 0:
+			n := header + 1
+			if i+n > len(packed) {
+				return nil, fmt.Errorf("literal run exceeds row data")
+			}
+			row = append(row, packed[i:i+n]...)
+			i += n
+		case header > -128:
+			if i >= len(packed) {
+				return nil, fmt.Errorf("repeat run exceeds row data")
+			}
+			for n := 1 - header; n > 0; n-- {
+				row = append(row, packed[i])
+			}
+			i++
+		}
+		// -128 is a no-op
+	}
+	if len(row) < length {
+		return nil, fmt.Errorf("row is %d bytes short", length-len(row))
+	}
+	return row[:length], nil
+}
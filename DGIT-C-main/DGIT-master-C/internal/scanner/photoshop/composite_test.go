@@ -0,0 +1,142 @@
+package photoshop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// psdHeader returns a PSD header followed by empty color mode data, image resources
+// and layer and mask sections, ready for the image data
+func psdHeader(version, channels int, height, width uint32, depth, colorMode int) []byte {
+	var b bytes.Buffer
+	b.WriteString("8BPS")
+	binary.Write(&b, binary.BigEndian, uint16(version))
+	b.Write(make([]byte, 6))
+	binary.Write(&b, binary.BigEndian, uint16(channels))
+	binary.Write(&b, binary.BigEndian, height)
+	binary.Write(&b, binary.BigEndian, width)
+	binary.Write(&b, binary.BigEndian, uint16(depth))
+	binary.Write(&b, binary.BigEndian, uint16(colorMode))
+	b.Write(make([]byte, 8)) // Color mode data and image resources
+	if version == 2 {
+		b.Write(make([]byte, 8))
+	} else {
+		b.Write(make([]byte, 4))
+	}
+	return b.Bytes()
+}
+
+// rgbComposite is a 3x2 RGB document with RLE image data: a red top row over a
+// bottom row of green, blue and white, plus an alpha channel
+func rgbComposite() []byte {
+	rows := [][]byte{
+		{0xFE, 0xFF},                   // R top: 255 255 255
+		{0x02, 0x00, 0x00, 0xFF},       // R bottom: 0 0 255
+		{0xFE, 0x00},                   // G top
+		{0x02, 0xFF, 0x00, 0xFF},       // G bottom: 255 0 255
+		{0xFE, 0x00},                   // B top
+		{0x80, 0x02, 0x00, 0xFF, 0xFF}, // B bottom, after a no-op header: 0 255 255
+		{0xFE, 0xFF},                   // Alpha
+		{0xFE, 0xFF},
+	}
+	var b bytes.Buffer
+	b.Write(psdHeader(1, 4, 2, 3, 8, colorModeRGB))
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	for _, row := range rows {
+		binary.Write(&b, binary.BigEndian, uint16(len(row)))
+	}
+	for _, row := range rows {
+		b.Write(row)
+	}
+	return b.Bytes()
+}
+
+func TestDecodeCompositeRLE(t *testing.T) {
+	img, err := DecodeComposite(rgbComposite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]color.RGBA{
+		{{255, 0, 0, 255}, {255, 0, 0, 255}, {255, 0, 0, 255}},
+		{{0, 255, 0, 255}, {0, 0, 255, 255}, {255, 255, 255, 255}},
+	}
+	for y, row := range want {
+		for x, px := range row {
+			if got := img.RGBAAt(x, y); got != px {
+				t.Errorf("pixel %d,%d = %v, want %v", x, y, got, px)
+			}
+		}
+	}
+}
+
+func TestDecodeCompositeRaw(t *testing.T) {
+	// 16-bit grayscale: only the high byte of each sample is used
+	gray := append(psdHeader(1, 1, 1, 2, 16, colorModeGrayscale), 0, 0, 0x12, 0x34, 0xAB, 0xCD)
+	img, err := DecodeComposite(gray)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.RGBAAt(0, 0) != (color.RGBA{0x12, 0x12, 0x12, 255}) || img.RGBAAt(1, 0) != (color.RGBA{0xAB, 0xAB, 0xAB, 255}) {
+		t.Errorf("grayscale pixels = %v %v", img.RGBAAt(0, 0), img.RGBAAt(1, 0))
+	}
+
+	// CMYK is stored inverted: 255 is no ink, so C=255 M=0 Y=255 K=255 is pure magenta
+	cmyk := append(psdHeader(1, 4, 1, 1, 8, colorModeCMYK), 0, 0, 255, 0, 255, 255)
+	img, err = DecodeComposite(cmyk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{255, 0, 255, 255}) {
+		t.Errorf("CMYK pixel = %v", got)
+	}
+}
+
+func TestDecodeCompositeRejectsOversizedHeaders(t *testing.T) {
+	withRLE := func(header []byte) []byte { return append(header, 0, 1) }
+	cases := map[string][]byte{
+		"65535 channels":        withRLE(psdHeader(1, 65535, 0xFFFFFFFF, 1, 8, colorModeRGB)),
+		"PSD taller than limit": withRLE(psdHeader(1, 3, maxPSDDimension+1, 1, 8, colorModeRGB)),
+		"PSB wider than limit":  withRLE(psdHeader(2, 3, 1, maxPSBDimension+1, 8, colorModeRGB)),
+		"too many pixels":       withRLE(psdHeader(2, 3, maxPSBDimension, maxPSBDimension, 16, colorModeRGB)),
+		"row lengths missing":   withRLE(psdHeader(1, 3, 20000, 20000, 8, colorModeRGB)),
+		"rows too short":        append(withRLE(psdHeader(1, 1, 2, 20000, 8, colorModeGrayscale)), 0, 2, 0, 2, 0x81, 0, 0x81, 0),
+		"raw data missing":      append(psdHeader(1, 3, 20000, 20000, 8, colorModeRGB), 0, 0),
+	}
+	for name, data := range cases {
+		if _, err := DecodeComposite(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUnpackBits(t *testing.T) {
+	cases := []struct {
+		packed []byte
+		length int
+		want   []byte
+		ok     bool
+	}{
+		{[]byte{0x02, 1, 2, 3}, 3, []byte{1, 2, 3}, true},
+		{[]byte{0xFD, 7}, 4, []byte{7, 7, 7, 7}, true},
+		{[]byte{0x80, 0x00, 9, 0xFF, 8}, 3, []byte{9, 8, 8}, true},
+		{[]byte{0xFD, 7}, 2, []byte{7, 7}, true}, // Runs past the row end are cut off
+		{[]byte{0x05, 1, 2}, 6, nil, false},      // Literal run past the data
+		{[]byte{0xFD}, 4, nil, false},            // Repeat run without its byte
+		{[]byte{0x00, 1}, 2, nil, false},         // Row too short
+	}
+	for _, c := range cases {
+		got, err := unpackBits(c.packed, c.length)
+		if (err == nil) != c.ok || !bytes.Equal(got, c.want) {
+			t.Errorf("unpackBits(%x, %d) = %x, %v", c.packed, c.length, got, err)
+		}
+	}
+}
+
+func TestDecodeCompositeDoesNotPanicOnTruncation(t *testing.T) {
+	fixture := rgbComposite()
+	scantest.Truncations(t, fixture, func(prefix []byte) { DecodeComposite(prefix) })
+}
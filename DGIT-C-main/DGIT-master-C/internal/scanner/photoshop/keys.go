@@ -0,0 +1,73 @@
+package photoshop
+
+import "fmt"
+
+// LayerIdentity holds what identifies a layer across versions of a document
+type LayerIdentity struct {
+	ID      int    // Persistent layer ID, 0 when the file has none
+	Name    string // Layer name
+	Section int    // Section divider type, SectionNone for ordinary layers
+}
+
+// Identity returns the layer's identifying fields
+func (b LayerBlock) Identity() LayerIdentity {
+	return LayerIdentity{ID: b.ID, Name: b.Name, Section: b.Section}
+}
+
+// Identity returns the layer's identifying fields
+func (l DetailedLayer) Identity() LayerIdentity {
+	return LayerIdentity{ID: l.LayerID, Name: l.Name, Section: l.Section}
+}
+
+// identified is a layer representation that LayerKeys can key
+type identified interface {
+	Identity() LayerIdentity
+}
+
+// HasLayerIDs reports whether every layer of every version carries its own persistent layer ID
+// IDs only identify layers when all compared versions have them; repeated IDs cannot.
+func HasLayerIDs[L identified](versions ...[]L) bool {
+	for _, layers := range versions {
+		seen := make(map[int]bool)
+		for _, layer := range layers {
+			id := layer.Identity().ID
+			if id == 0 || seen[id] {
+				return false
+			}
+			seen[id] = true
+		}
+	}
+	return true
+}
+
+// LayerKeys identifies each layer across the versions of a document
+// Layers are keyed by their persistent ID when byID is set (see HasLayerIDs). Otherwise the
+// name and its occurrence count are used, so duplicate names stay distinct; group end markers,
+// which all share one name, take the key of the group they close so the two stay paired.
+func LayerKeys[L identified](layers []L, byID bool) []string {
+	keys := make([]string, len(layers))
+	occurrences := make(map[string]int)
+	var ends []int // End markers waiting for their group, innermost last
+	for i, layer := range layers {
+		identity := layer.Identity()
+		switch {
+		case byID:
+			keys[i] = fmt.Sprintf("id:%d", identity.ID)
+			continue
+		case identity.Section == SectionEnd:
+			ends = append(ends, i)
+			continue
+		}
+		keys[i] = fmt.Sprintf("name:%s#%d", identity.Name, occurrences[identity.Name])
+		occurrences[identity.Name]++
+		if (identity.Section == SectionOpen || identity.Section == SectionClosed) && len(ends) > 0 {
+			keys[ends[len(ends)-1]] = "end:" + keys[i]
+			ends = ends[:len(ends)-1]
+		}
+	}
+	// End markers without a group are keyed by their position among themselves
+	for n, i := range ends {
+		keys[i] = fmt.Sprintf("end:#%d", n)
+	}
+	return keys
+}
@@ -0,0 +1,91 @@
+package photoshop
+
+import (
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// nestedGroups lists, bottom to top, two groups with the same name and layer names,
+// the second holding a nested group
+var nestedGroups = []LayerBlock{
+	{Name: "Background"},
+	{Name: "</Layer group>", Section: SectionEnd},
+	{Name: "Shadow"},
+	{Name: "Card", Section: SectionOpen},
+	{Name: "</Layer group>", Section: SectionEnd},
+	{Name: "</Layer group>", Section: SectionEnd},
+	{Name: "Shadow"},
+	{Name: "Icon", Section: SectionClosed},
+	{Name: "Card", Section: SectionOpen},
+}
+
+func TestLayerKeysWithoutIDs(t *testing.T) {
+	want := []string{
+		"name:Background#0",
+		"end:name:Card#0",
+		"name:Shadow#0",
+		"name:Card#0",
+		"end:name:Card#1",
+		"end:name:Icon#0",
+		"name:Shadow#1",
+		"name:Icon#0",
+		"name:Card#1",
+	}
+	if keys := LayerKeys(nestedGroups, false); !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %q\nwant %q", keys, want)
+	}
+
+	// A stray end marker keeps a key of its own
+	stray := append([]LayerBlock{{Name: "</Layer group>", Section: SectionEnd}}, nestedGroups[:4]...)
+	if keys := LayerKeys(stray, false); keys[0] != "end:#0" || keys[2] != "end:name:Card#0" {
+		t.Errorf("keys = %q", keys)
+	}
+}
+
+func TestLayerKeysWithIDs(t *testing.T) {
+	layers := []LayerBlock{{Name: "Shadow", ID: 7}, {Name: "Shadow", ID: 3}}
+	if !HasLayerIDs(layers, []LayerBlock{{ID: 3}}) {
+		t.Fatal("HasLayerIDs = false")
+	}
+	if keys := LayerKeys(layers, true); !reflect.DeepEqual(keys, []string{"id:7", "id:3"}) {
+		t.Errorf("keys = %q", keys)
+	}
+
+	// Missing or repeated IDs in any version fall back to names
+	cases := map[string][][]LayerBlock{
+		"missing":  {layers, {{ID: 7}, {ID: 0}}},
+		"repeated": {{{ID: 7}, {ID: 7}}, layers},
+	}
+	for name, versions := range cases {
+		if HasLayerIDs(versions...) {
+			t.Errorf("%s: HasLayerIDs = true", name)
+		}
+	}
+}
+
+func TestLayerKeysMatchAcrossReaders(t *testing.T) {
+	data := scantest.LayeredPSD([]scantest.PSDLayer{
+		{Name: "Background", Pixel: 10},
+		{Name: "</Layer group>", Section: SectionEnd},
+		{Name: "Shadow", Pixel: 20},
+		{Name: "Shadow", Unicode: "Schatten", Pixel: 30},
+		{Name: "Card", Section: SectionOpen},
+	})
+	path := scantest.WriteFile(t, "card.psd", data)
+	sections, err := ReadLayerSections(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := GetDetailedPSDInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blame and comparison key the detailed layers, merging keys the layer blocks
+	want := LayerKeys(sections.Layers, HasLayerIDs(sections.Layers))
+	if got := LayerKeys(info.Layers, HasLayerIDs(info.Layers)); !reflect.DeepEqual(got, want) {
+		t.Errorf("detailed layer keys = %q\nlayer block keys   = %q", got, want)
+	}
+}
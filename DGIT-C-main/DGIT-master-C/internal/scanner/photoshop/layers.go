@@ -0,0 +1,321 @@
+package photoshop
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// LayerBlock is a single layer as stored in a PSD: its layer record and its channel image data
+type LayerBlock struct {
+	Name        string
+	ID          int // Persistent layer ID from the "lyid" block, 0 when the file has none
+	Section     int // Section divider type from the "lsct" block, SectionNone for ordinary layers
+	Record      []byte
+	ChannelData []byte
+}
+
+// ContentHash returns a hash of the layer's record and pixel data
+// Two blocks with the same hash are byte-for-byte identical layers
+func (b LayerBlock) ContentHash() string {
+	h := sha256.New()
+	h.Write(b.Record)
+	h.Write(b.ChannelData)
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// PixelHash returns a hash of the layer's channel image data only
+// Unlike ContentHash it is unaffected by property changes such as opacity or position
+func (b LayerBlock) PixelHash() string {
+	h := sha256.Sum256(b.ChannelData)
+	return fmt.Sprintf("%x", h)[:16]
+}
+
+// Section divider types of the "lsct" block, which turn layer records into group markers
+// Records are stored bottom to top, so a group's end marker comes before its layers and the
+// open or closed group record after them.
+const (
+	SectionNone   = 0
+	SectionOpen   = 1
+	SectionClosed = 2
+	SectionEnd    = 3
+)
+
+// LayerSections splits a PSD into the parts surrounding its layers so layers can be recombined
+type LayerSections struct {
+	Prefix    []byte // Header, color mode data and image resources
+	Negative  bool   // Layer count was stored negative (first alpha channel holds merged transparency)
+	Layers    []LayerBlock
+	Tail      []byte // Global layer mask info and additional layer information
+	ImageData []byte // Merged composite image
+}
+
+// ReadLayerSections parses the layer structure of an 8-bit PSD held in memory
+func ReadLayerSections(data []byte) (*LayerSections, error) {
+	r := &byteReader{data: data}
+
+	header, err := r.bytes(26)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PSD header: %w", err)
+	}
+	if string(header[0:4]) != "8BPS" {
+		return nil, fmt.Errorf("not a PSD file")
+	}
+	if binary.BigEndian.Uint16(header[4:6]) != 1 {
+		return nil, fmt.Errorf("large document (PSB) format is not supported")
+	}
+	if depth := binary.BigEndian.Uint16(header[22:24]); depth != 8 {
+		return nil, fmt.Errorf("%d-bit documents are not supported", depth)
+	}
+
+	// Color mode data and image resources are carried over unchanged
+	for _, section := range []string{"color mode data", "image resources"} {
+		length, err := r.uint32()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s length: %w", section, err)
+		}
+		if _, err := r.bytes(int(length)); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", section, err)
+		}
+	}
+	sections := &LayerSections{Prefix: data[:r.pos]}
+
+	layerAndMaskLength, err := r.uint32()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layer and mask section length: %w", err)
+	}
+	layerAndMaskEnd := r.pos + int(layerAndMaskLength)
+	if layerAndMaskEnd > len(data) {
+		return nil, fmt.Errorf("layer and mask section exceeds file size")
+	}
+
+	if layerAndMaskLength > 0 {
+		layerInfoLength, err := r.uint32()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer info length: %w", err)
+		}
+		layerInfoEnd := r.pos + int(layerInfoLength)
+		if layerInfoLength > 0 {
+			if err := sections.readLayers(r); err != nil {
+				return nil, err
+			}
+		}
+		if layerInfoEnd > layerAndMaskEnd || r.pos > layerInfoEnd {
+			return nil, fmt.Errorf("corrupt layer info section")
+		}
+		sections.Tail = data[layerInfoEnd:layerAndMaskEnd]
+	}
+
+	sections.ImageData = data[layerAndMaskEnd:]
+	return sections, nil
+}
+
+// readLayers reads the layer records followed by the channel image data of every layer
+func (s *LayerSections) readLayers(r *byteReader) error {
+	rawCount, err := r.uint16()
+	if err != nil {
+		return fmt.Errorf("failed to read layer count: %w", err)
+	}
+	count := int(int16(rawCount))
+	if count < 0 {
+		s.Negative = true
+		count = -count
+	}
+
+	channelLengths := make([][]uint32, count)
+	for i := 0; i < count; i++ {
+		start := r.pos
+
+		if _, err := r.bytes(16); err != nil {
+			return fmt.Errorf("failed to read bounds of layer %d: %w", i, err)
+		}
+		channels, err := r.uint16()
+		if err != nil {
+			return fmt.Errorf("failed to read channels of layer %d: %w", i, err)
+		}
+		for c := 0; c < int(channels); c++ {
+			if _, err := r.bytes(2); err != nil {
+				return fmt.Errorf("failed to read channel info of layer %d: %w", i, err)
+			}
+			length, err := r.uint32()
+			if err != nil {
+				return fmt.Errorf("failed to read channel info of layer %d: %w", i, err)
+			}
+			channelLengths[i] = append(channelLengths[i], length)
+		}
+
+		// Blend mode signature and key, opacity, clipping, flags, filler
+		if _, err := r.bytes(12); err != nil {
+			return fmt.Errorf("failed to read blend mode of layer %d: %w", i, err)
+		}
+		extraLength, err := r.uint32()
+		if err != nil {
+			return fmt.Errorf("failed to read extra data length of layer %d: %w", i, err)
+		}
+		extra, err := r.bytes(int(extraLength))
+		if err != nil {
+			return fmt.Errorf("failed to read extra data of layer %d: %w", i, err)
+		}
+
+		name, id, section := parseLayerExtraData(extra, i)
+		s.Layers = append(s.Layers, LayerBlock{
+			Name:    name,
+			ID:      id,
+			Section: section,
+			Record:  r.data[start:r.pos],
+		})
+	}
+
+	for i := 0; i < count; i++ {
+		total := 0
+		for _, length := range channelLengths[i] {
+			total += int(length)
+		}
+		channelData, err := r.bytes(total)
+		if err != nil {
+			return fmt.Errorf("failed to read image data of layer %d: %w", i, err)
+		}
+		s.Layers[i].ChannelData = channelData
+	}
+	return nil
+}
+
+// Bytes reassembles the PSD from its sections
+// Lengths are recomputed, so layers may be added, removed or replaced before writing
+func (s *LayerSections) Bytes() []byte {
+	var layerInfo bytes.Buffer
+	if len(s.Layers) > 0 {
+		count := int16(len(s.Layers))
+		if s.Negative {
+			count = -count
+		}
+		binary.Write(&layerInfo, binary.BigEndian, count)
+		for _, layer := range s.Layers {
+			layerInfo.Write(layer.Record)
+		}
+		for _, layer := range s.Layers {
+			layerInfo.Write(layer.ChannelData)
+		}
+		if layerInfo.Len()%2 != 0 {
+			layerInfo.WriteByte(0)
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write(s.Prefix)
+	binary.Write(&out, binary.BigEndian, uint32(4+layerInfo.Len()+len(s.Tail)))
+	binary.Write(&out, binary.BigEndian, uint32(layerInfo.Len()))
+	out.Write(layerInfo.Bytes())
+	out.Write(s.Tail)
+	out.Write(s.ImageData)
+	return out.Bytes()
+}
+
+// parseLayerExtraData returns the layer name, persistent layer ID and section type from a layer's extra data
+// The Unicode name is preferred over the Pascal name; the ID is 0 when no "lyid" block exists
+func parseLayerExtraData(extra []byte, layerIndex int) (string, int, int) {
+	fallback := fmt.Sprintf("Layer %d", layerIndex+1)
+	r := &byteReader{data: extra}
+
+	// Skip layer mask data and blending ranges
+	for i := 0; i < 2; i++ {
+		length, err := r.uint32()
+		if err != nil {
+			return fallback, 0, SectionNone
+		}
+		if _, err := r.bytes(int(length)); err != nil {
+			return fallback, 0, SectionNone
+		}
+	}
+
+	// Pascal string padded to a multiple of 4 bytes
+	nameLength, err := r.bytes(1)
+	if err != nil {
+		return fallback, 0, SectionNone
+	}
+	nameBytes, err := r.bytes(int(nameLength[0]))
+	if err != nil {
+		return fallback, 0, SectionNone
+	}
+	name := string(nameBytes)
+	padded := (1 + int(nameLength[0]) + 3) &^ 3
+	r.bytes(padded - 1 - int(nameLength[0]))
+
+	// Additional layer information blocks carry the Unicode name ("luni") and layer ID ("lyid")
+	unicodeName, id, section := "", 0, SectionNone
+	for r.remaining() >= 12 {
+		signature, _ := r.bytes(4)
+		key, _ := r.bytes(4)
+		length, _ := r.uint32()
+		if string(signature) != "8BIM" && string(signature) != "8B64" {
+			break
+		}
+		block, err := r.bytes(int(length))
+		if err != nil {
+			break
+		}
+		switch string(key) {
+		case "luni":
+			if len(block) < 4 {
+				continue
+			}
+			chars := int(binary.BigEndian.Uint32(block[:4]))
+			if 4+chars*2 <= len(block) {
+				units := make([]uint16, chars)
+				for i := range units {
+					units[i] = binary.BigEndian.Uint16(block[4+i*2:])
+				}
+				unicodeName = string(utf16.Decode(units))
+			}
+		case "lyid":
+			if len(block) >= 4 {
+				id = int(binary.BigEndian.Uint32(block[:4]))
+			}
+		case "lsct":
+			if len(block) >= 4 {
+				section = int(binary.BigEndian.Uint32(block[:4]))
+			}
+		}
+	}
+	if unicodeName != "" {
+		name = unicodeName
+	}
+	return name, id, section
+}
+
+// byteReader reads big-endian values from an in-memory PSD
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *byteReader) uint16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (r *byteReader) uint32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *byteReader) remaining() int {
+	return len(r.data) - r.pos
+}
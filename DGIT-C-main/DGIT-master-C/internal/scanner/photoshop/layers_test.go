@@ -0,0 +1,81 @@
+package photoshop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// groupLayers is a background with a group holding one layer
+var groupLayers = []scantest.PSDLayer{
+	{Name: "Background", ID: 2, Pixel: 10},
+	{Name: "</Layer group>", ID: 5, Section: SectionEnd},
+	{Name: "Shadow", Unicode: "Schatten ✓", ID: 4, Pixel: 20},
+	{Name: "Card", ID: 3, Section: SectionOpen},
+}
+
+func TestReadLayerSections(t *testing.T) {
+	data := scantest.LayeredPSD(groupLayers)
+	sections, err := ReadLayerSections(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var ids, kinds []int
+	for _, layer := range sections.Layers {
+		names = append(names, layer.Name)
+		ids = append(ids, layer.ID)
+		kinds = append(kinds, layer.Section)
+	}
+	if want := []string{"Background", "</Layer group>", "Schatten ✓", "Card"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q", names)
+	}
+	if !reflect.DeepEqual(ids, []int{2, 5, 4, 3}) || !reflect.DeepEqual(kinds, []int{SectionNone, SectionEnd, SectionNone, SectionOpen}) {
+		t.Errorf("ids %v, sections %v", ids, kinds)
+	}
+	if !bytes.Equal(sections.Layers[2].ChannelData, []byte{0, 0, 20}) {
+		t.Errorf("channel data = %v", sections.Layers[2].ChannelData)
+	}
+	if !bytes.Equal(sections.Bytes(), data) {
+		t.Error("Bytes does not reproduce the document")
+	}
+
+	// Layers can be dropped and the document rebuilt
+	sections.Layers = sections.Layers[:1]
+	rebuilt, err := ReadLayerSections(sections.Bytes())
+	if err != nil || len(rebuilt.Layers) != 1 || rebuilt.Layers[0].Name != "Background" {
+		t.Errorf("rebuilt document: %+v, %v", rebuilt, err)
+	}
+}
+
+func TestReadLayerSectionsRejectsOversizedLengths(t *testing.T) {
+	valid := scantest.LayeredPSD(groupLayers)
+	layerAndMask := 34
+	layerInfo := layerAndMask + 4
+	firstChannel := layerInfo + 4 + 2 + 16 + 2 + 2
+
+	cases := map[string]struct {
+		offset int
+		value  uint32
+	}{
+		"layer and mask section": {layerAndMask, math.MaxUint32},
+		"layer info":             {layerInfo, math.MaxInt32},
+		"channel data":           {firstChannel, math.MaxUint32},
+	}
+	for name, c := range cases {
+		data := append([]byte{}, valid...)
+		binary.BigEndian.PutUint32(data[c.offset:], c.value)
+		if _, err := ReadLayerSections(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReadLayerSectionsDoesNotPanicOnTruncation(t *testing.T) {
+	fixture := scantest.LayeredPSD(groupLayers)
+	scantest.Truncations(t, fixture, func(prefix []byte) { ReadLayerSections(prefix) })
+}
@@ -55,14 +55,16 @@ type DetailedPSDInfo struct {
 // DetailedLayer contains comprehensive information about individual layers
 // Provides detailed analysis of layer properties, position, and content
 type DetailedLayer struct {
-	ID          int      `json:"id"`           // Unique layer identifier
-	Name        string   `json:"name"`         // Layer name as set by user
-	Position    [4]int32 `json:"position"`     // Layer bounds: top, left, bottom, right
-	BlendMode   string   `json:"blend_mode"`   // Layer blending mode
-	Opacity     uint8    `json:"opacity"`      // Layer opacity (0-255)
-	Visible     bool     `json:"visible"`      // Layer visibility state
-	ContentHash string   `json:"content_hash"` // Hash of layer content for change detection
-	LayerType   string   `json:"layer_type"`   // Layer type: "normal", "text", "adjustment", etc.
+	ID          int      `json:"id"`                 // Unique layer identifier
+	LayerID     int      `json:"layer_id,omitempty"` // Photoshop's persistent layer ID, stable across saves; 0 when not stored
+	Name        string   `json:"name"`               // Layer name as set by user
+	Position    [4]int32 `json:"position"`           // Layer bounds: top, left, bottom, right
+	BlendMode   string   `json:"blend_mode"`         // Layer blending mode
+	Opacity     uint8    `json:"opacity"`            // Layer opacity (0-255)
+	Visible     bool     `json:"visible"`            // Layer visibility state
+	ContentHash string   `json:"content_hash"`       // Hash of layer content for change detection
+	LayerType   string   `json:"layer_type"`         // Layer type: "normal", "text", "adjustment", etc.
+	Section     int      `json:"section,omitempty"`  // Group divider type from the "lsct" block, SectionNone for ordinary layers
 }
 
 // CanvasInfo contains document-level canvas information
@@ -459,10 +461,29 @@ func GetDetailedPSDInfo(filePath string) (*DetailedPSDInfo, error) {
 	}
 
 	detailedInfo.Layers = layers
+	applyLayerSections(filePath, detailedInfo.Layers)
 
 	return detailedInfo, nil
 }
 
+// applyLayerSections fills in persistent layer IDs, group dividers and pixel-based content hashes
+// Files the section reader cannot parse (16-bit, PSB) keep the header-based values
+func applyLayerSections(filePath string, layers []DetailedLayer) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	sections, err := ReadLayerSections(data)
+	if err != nil || len(sections.Layers) != len(layers) {
+		return
+	}
+	for i, block := range sections.Layers {
+		layers[i].LayerID = block.ID
+		layers[i].Section = block.Section
+		layers[i].ContentHash = block.PixelHash()
+	}
+}
+
 // parseDetailedLayers parses comprehensive layer information including positions, blend modes, and content hashes
 // This is the core function for detailed layer analysis and change detection
 func parseDetailedLayers(file *os.File, layerCount int, filePath string) ([]DetailedLayer, error) {
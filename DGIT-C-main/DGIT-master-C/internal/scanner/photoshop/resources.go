@@ -0,0 +1,103 @@
+package photoshop
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// Image resource IDs holding the thumbnail Photoshop embeds for file browsers
+const (
+	resourceThumbnail       = 1036 // JPEG thumbnail, Photoshop 5.0 and later
+	resourceThumbnailLegacy = 1033 // JPEG thumbnail with red and blue swapped, Photoshop 4.0
+)
+
+// thumbnailHeaderSize is the size of the header preceding the JFIF data of a thumbnail resource
+const thumbnailHeaderSize = 28
+
+// ReadImageResources returns the blocks of the image resources section of a PSD held in memory, keyed by resource ID
+func ReadImageResources(data []byte) (map[uint16][]byte, error) {
+	r := &byteReader{data: data}
+	header, err := r.bytes(26)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PSD header: %w", err)
+	}
+	if string(header[0:4]) != "8BPS" {
+		return nil, fmt.Errorf("not a PSD file")
+	}
+
+	colorModeLength, err := r.uint32()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read color mode data length: %w", err)
+	}
+	if _, err := r.bytes(int(colorModeLength)); err != nil {
+		return nil, fmt.Errorf("failed to read color mode data: %w", err)
+	}
+	sectionLength, err := r.uint32()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image resources length: %w", err)
+	}
+	section, err := r.bytes(int(sectionLength))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image resources: %w", err)
+	}
+
+	// Each block: "8BIM", ID, Pascal name padded to even length, data size, data padded to even length
+	resources := make(map[uint16][]byte)
+	blocks := &byteReader{data: section}
+	for blocks.remaining() >= 12 {
+		signature, _ := blocks.bytes(4)
+		if string(signature) != "8BIM" {
+			return nil, fmt.Errorf("invalid image resource signature %q", signature)
+		}
+		id, _ := blocks.uint16()
+		nameLength, err := blocks.bytes(1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read name of resource %d: %w", id, err)
+		}
+		padded := (1 + int(nameLength[0]) + 1) &^ 1
+		if _, err := blocks.bytes(padded - 1); err != nil {
+			return nil, fmt.Errorf("failed to read name of resource %d: %w", id, err)
+		}
+		size, err := blocks.uint32()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read size of resource %d: %w", id, err)
+		}
+		block, err := blocks.bytes(int(size))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource %d: %w", id, err)
+		}
+		if size%2 != 0 {
+			blocks.bytes(1)
+		}
+		resources[id] = block
+	}
+	return resources, nil
+}
+
+// ExtractThumbnail returns the JPEG thumbnail embedded in a PSD's image resources
+func ExtractThumbnail(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PSD file: %w", err)
+	}
+	resources, err := ReadImageResources(data)
+	if err != nil {
+		return nil, err
+	}
+
+	block, ok := resources[resourceThumbnail]
+	if !ok {
+		// The legacy resource stores BGR data, which still decodes, only with red and blue swapped
+		if block, ok = resources[resourceThumbnailLegacy]; !ok {
+			return nil, fmt.Errorf("no embedded thumbnail")
+		}
+	}
+	if len(block) <= thumbnailHeaderSize {
+		return nil, fmt.Errorf("thumbnail resource is truncated")
+	}
+	if format := binary.BigEndian.Uint32(block[0:4]); format != 1 {
+		return nil, fmt.Errorf("thumbnail format %d is not JPEG", format)
+	}
+	return block[thumbnailHeaderSize:], nil
+}
@@ -0,0 +1,87 @@
+package photoshop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// psdWithResources returns a 1x1 grayscale PSD whose image resources section holds the given blocks
+func psdWithResources(blocks map[uint16][]byte, ids ...uint16) []byte {
+	var section bytes.Buffer
+	for _, id := range ids {
+		section.WriteString("8BIM")
+		binary.Write(&section, binary.BigEndian, id)
+		section.Write([]byte{0, 0}) // Empty Pascal name, padded to even length
+		binary.Write(&section, binary.BigEndian, uint32(len(blocks[id])))
+		section.Write(blocks[id])
+		if len(blocks[id])%2 != 0 {
+			section.WriteByte(0)
+		}
+	}
+
+	var b bytes.Buffer
+	b.Write(psdHeader(1, 1, 1, 1, 8, colorModeGrayscale)[:26])
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	binary.Write(&b, binary.BigEndian, uint32(section.Len()))
+	b.Write(section.Bytes())
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	b.Write([]byte{0, 0, 0x80})
+	return b.Bytes()
+}
+
+// thumbnailResource wraps JPEG data in the header of a thumbnail resource
+func thumbnailResource(format uint32, jpeg []byte) []byte {
+	header := make([]byte, thumbnailHeaderSize)
+	binary.BigEndian.PutUint32(header, format)
+	return append(header, jpeg...)
+}
+
+func TestReadImageResources(t *testing.T) {
+	data := psdWithResources(map[uint16][]byte{1005: {1, 2, 3}, 1036: {9}}, 1005, 1036)
+	resources, err := ReadImageResources(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resources[1005], []byte{1, 2, 3}) || !bytes.Equal(resources[1036], []byte{9}) {
+		t.Errorf("resources = %v", resources)
+	}
+
+	// DecodeComposite skips the resources to reach the image data
+	if _, err := DecodeComposite(data); err != nil {
+		t.Errorf("composite: %v", err)
+	}
+}
+
+func TestExtractThumbnail(t *testing.T) {
+	jpeg := []byte("\xff\xd8 jpeg data \xff\xd9")
+	cases := []struct {
+		name      string
+		resources map[uint16][]byte
+		want      []byte
+	}{
+		{"current", map[uint16][]byte{resourceThumbnail: thumbnailResource(1, jpeg)}, jpeg},
+		{"legacy", map[uint16][]byte{resourceThumbnailLegacy: thumbnailResource(1, jpeg)}, jpeg},
+		{"raw format", map[uint16][]byte{resourceThumbnail: thumbnailResource(0, jpeg)}, nil},
+		{"truncated", map[uint16][]byte{resourceThumbnail: make([]byte, thumbnailHeaderSize)}, nil},
+		{"missing", map[uint16][]byte{1005: {0}}, nil},
+	}
+	for _, c := range cases {
+		var ids []uint16
+		for id := range c.resources {
+			ids = append(ids, id)
+		}
+		path := scantest.WriteFile(t, "image.psd", psdWithResources(c.resources, ids...))
+		got, err := ExtractThumbnail(path)
+		if (err == nil) != (c.want != nil) || !bytes.Equal(got, c.want) {
+			t.Errorf("%s: got %q, %v", c.name, got, err)
+		}
+	}
+}
+
+func TestReadImageResourcesDoesNotPanicOnTruncation(t *testing.T) {
+	fixture := psdWithResources(map[uint16][]byte{1005: {1, 2, 3}, resourceThumbnail: thumbnailResource(1, []byte{0xff, 0xd8})}, 1005, resourceThumbnail)
+	scantest.Truncations(t, fixture, func(prefix []byte) { ReadImageResources(prefix) })
+}
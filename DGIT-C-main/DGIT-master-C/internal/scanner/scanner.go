@@ -8,8 +8,14 @@ import (
 	"strings"
 	"time"
 
+	"dgit/internal/scanner/blender"
+	"dgit/internal/scanner/fbx"
+	"dgit/internal/scanner/figma"
 	"dgit/internal/scanner/illustrator"
+	"dgit/internal/scanner/obj"
 	"dgit/internal/scanner/photoshop"
+	"dgit/internal/scanner/sketch"
+	"dgit/internal/scanner/xd"
 )
 
 // DesignFile contains metadata for detected design files
@@ -26,6 +32,21 @@ type DesignFile struct {
 	LayerNames []string `json:"layer_names"` // Names of all layers
 	FileSize   int64    `json:"file_size"`   // File size in bytes
 
+	// Document Structure (component-based formats)
+	// Counts are serialized even when zero, so that a first component or text layer reads as a change
+	Components int `json:"components"`  // Components or symbols defined in the document
+	Instances  int `json:"instances"`   // Component or symbol instances placed in the document
+	TextLayers int `json:"text_layers"` // Number of text layers with content
+	Assets     int `json:"assets"`      // Images and linked resources
+
+	// Scene Contents (3D formats)
+	Meshes     int `json:"meshes"`     // Mesh data blocks
+	Vertices   int `json:"vertices"`   // Total vertex count of all meshes
+	Polygons   int `json:"polygons"`   // Total face count of all meshes
+	Materials  int `json:"materials"`  // Materials defined in the file
+	Textures   int `json:"textures"`   // Images used as textures, packed or linked
+	Animations int `json:"animations"` // Animation stacks or takes
+
 	// Cache Integration
 	Hash       string        `json:"hash"`               // File hash for cache key generation
 	CacheLevel string        `json:"cache_level"`        // Cache tier: hot/warm/cold
@@ -33,6 +54,38 @@ type DesignFile struct {
 	ScanTime   time.Duration `json:"scan_time"`          // Time taken to scan file
 }
 
+// formatCounts lists the document and scene counts that each file type's analyzer fills in
+var formatCounts = map[string][]string{
+	"sketch": {"components", "instances", "text_layers", "assets"},
+	"fig":    {"components", "instances", "text_layers", "assets"},
+	"xd":     {"components", "instances", "text_layers", "assets"},
+	"blend":  {"meshes", "vertices", "polygons", "materials", "textures", "assets"},
+	"fbx":    {"meshes", "vertices", "polygons", "materials", "textures", "animations"},
+	"obj":    {"meshes", "vertices", "polygons", "materials", "assets"},
+}
+
+// Counts returns the counts recorded for the file's format, keyed by their JSON names
+// Zero counts of the format are included; counts other formats record are left out.
+func (df *DesignFile) Counts() map[string]int {
+	values := map[string]int{
+		"components":  df.Components,
+		"instances":   df.Instances,
+		"text_layers": df.TextLayers,
+		"assets":      df.Assets,
+		"meshes":      df.Meshes,
+		"vertices":    df.Vertices,
+		"polygons":    df.Polygons,
+		"materials":   df.Materials,
+		"textures":    df.Textures,
+		"animations":  df.Animations,
+	}
+	counts := make(map[string]int)
+	for _, key := range formatCounts[df.Type] {
+		counts[key] = values[key]
+	}
+	return counts
+}
+
 // FileMetadata contains pre-extracted design file metadata
 type FileMetadata struct {
 	Dimensions  string    `json:"dimensions,omitempty"`   // Canvas dimensions: "1920x1080"
@@ -236,6 +289,12 @@ func (fs *FileScanner) ScanFile(filePath string) (*DesignFile, error) {
 		return fs.analyzeFigmaFile(filePath, designFile)
 	case "xd":
 		return fs.analyzeXDFile(filePath, designFile)
+	case "blend":
+		return fs.analyzeBlenderFile(filePath, designFile)
+	case "fbx":
+		return fs.analyzeFBXFile(filePath, designFile)
+	case "obj":
+		return fs.analyzeOBJFile(filePath, designFile)
 	default:
 		return designFile, nil
 	}
@@ -296,39 +355,76 @@ func (fs *FileScanner) analyzePSDFile(filePath string, designFile *DesignFile) (
 
 // analyzeSketchFile performs Sketch file analysis
 func (fs *FileScanner) analyzeSketchFile(filePath string, designFile *DesignFile) (*DesignFile, error) {
-	// Sketch files are ZIP archives requiring complex parsing
-	designFile.Dimensions = "Unknown"
-	designFile.ColorMode = "RGB"
-	designFile.Version = "Sketch App"
-	designFile.Layers = 1
-	designFile.LayerNames = []string{"Sketch Layer"}
+	sketchInfo, err := sketch.GetSketchInfo(filePath)
+	if err != nil {
+		return designFile, err
+	}
+
+	// Sketch has no canvas size; the first artboard stands for the document
+	if len(sketchInfo.Artboards) > 0 {
+		first := sketchInfo.Artboards[0]
+		designFile.Dimensions = fmt.Sprintf("%.0fx%.0f px", first.Width, first.Height)
+	}
+	designFile.ColorMode = sketchColorMode(sketchInfo)
+	designFile.Version = sketchInfo.AppVersion
+	designFile.Layers = len(sketchInfo.LayerNames)
+	designFile.Artboards = len(sketchInfo.Artboards)
+	designFile.Objects = sketchInfo.ObjectCount
+	designFile.LayerNames = sketchInfo.LayerNames
+	designFile.Components = len(sketchInfo.Symbols)
+	designFile.Instances = sketchInfo.SymbolInstances
+	designFile.TextLayers = len(sketchInfo.Texts)
+	designFile.Assets = len(sketchInfo.Images)
 
 	designFile.Metadata = &FileMetadata{
-		Dimensions:  "Unknown",
-		ColorMode:   "RGB",
+		Dimensions:  designFile.Dimensions,
+		ColorMode:   designFile.ColorMode,
 		Resolution:  72,
-		LayerCount:  1,
-		FileVersion: "Sketch App",
+		LayerCount:  designFile.Layers,
+		FileVersion: designFile.Version,
 		ExtractedAt: time.Now(),
 	}
 
 	return designFile, nil
 }
 
+// sketchColorMode returns the color profile of a Sketch document
+func sketchColorMode(info *sketch.SketchInfo) string {
+	if info.ColorSpace == "" {
+		return "RGB"
+	}
+	return info.ColorSpace
+}
+
 // analyzeFigmaFile performs Figma file analysis
 func (fs *FileScanner) analyzeFigmaFile(filePath string, designFile *DesignFile) (*DesignFile, error) {
-	designFile.Dimensions = "Unknown"
+	figmaInfo, err := figma.GetFigmaInfo(filePath)
+	if err != nil {
+		return designFile, err
+	}
+
+	// Figma pages are unbounded; the first top-level frame stands for the document
+	if len(figmaInfo.Frames) > 0 {
+		first := figmaInfo.Frames[0]
+		designFile.Dimensions = fmt.Sprintf("%.0fx%.0f px", first.Width, first.Height)
+	}
 	designFile.ColorMode = "RGB"
-	designFile.Version = "Figma"
-	designFile.Layers = 1
-	designFile.LayerNames = []string{"Figma Frame"}
+	designFile.Version = fmt.Sprintf("Figma (fig-kiwi v%d)", figmaInfo.Version)
+	designFile.Layers = len(figmaInfo.LayerNames)
+	designFile.Artboards = len(figmaInfo.Frames)
+	designFile.Objects = figmaInfo.ObjectCount
+	designFile.LayerNames = figmaInfo.LayerNames
+	designFile.Components = len(figmaInfo.Components)
+	designFile.Instances = figmaInfo.Instances
+	designFile.TextLayers = len(figmaInfo.Texts)
+	designFile.Assets = len(figmaInfo.Images)
 
 	designFile.Metadata = &FileMetadata{
-		Dimensions:  "Unknown",
-		ColorMode:   "RGB",
+		Dimensions:  designFile.Dimensions,
+		ColorMode:   designFile.ColorMode,
 		Resolution:  72,
-		LayerCount:  1,
-		FileVersion: "Figma",
+		LayerCount:  designFile.Layers,
+		FileVersion: designFile.Version,
 		ExtractedAt: time.Now(),
 	}
 
@@ -337,24 +433,160 @@ func (fs *FileScanner) analyzeFigmaFile(filePath string, designFile *DesignFile)
 
 // analyzeXDFile performs Adobe XD file analysis
 func (fs *FileScanner) analyzeXDFile(filePath string, designFile *DesignFile) (*DesignFile, error) {
-	designFile.Dimensions = "Unknown"
+	xdInfo, err := xd.GetXDInfo(filePath)
+	if err != nil {
+		return designFile, err
+	}
+
+	// XD has no canvas size; the first artboard stands for the document
+	if len(xdInfo.Artboards) > 0 {
+		first := xdInfo.Artboards[0]
+		designFile.Dimensions = fmt.Sprintf("%.0fx%.0f px", first.Width, first.Height)
+	}
 	designFile.ColorMode = "RGB"
-	designFile.Version = "Adobe XD"
-	designFile.Layers = 1
-	designFile.LayerNames = []string{"XD Artboard"}
+	designFile.Version = xdInfo.Version
+	designFile.Layers = len(xdInfo.LayerNames)
+	designFile.Artboards = len(xdInfo.Artboards)
+	designFile.Objects = xdInfo.ObjectCount
+	designFile.LayerNames = xdInfo.LayerNames
+	designFile.Components = len(xdInfo.Components)
+	designFile.Instances = len(xdInfo.ComponentInstances)
+	designFile.TextLayers = len(xdInfo.Texts)
+	designFile.Assets = len(xdInfo.Assets)
 
 	designFile.Metadata = &FileMetadata{
-		Dimensions:  "Unknown",
-		ColorMode:   "RGB",
+		Dimensions:  designFile.Dimensions,
+		ColorMode:   designFile.ColorMode,
 		Resolution:  72,
-		LayerCount:  1,
-		FileVersion: "Adobe XD",
+		LayerCount:  designFile.Layers,
+		FileVersion: designFile.Version,
 		ExtractedAt: time.Now(),
 	}
 
 	return designFile, nil
 }
 
+// analyzeBlenderFile performs Blender file analysis
+func (fs *FileScanner) analyzeBlenderFile(filePath string, designFile *DesignFile) (*DesignFile, error) {
+	blenderInfo, err := blender.GetBlenderInfo(filePath)
+	if err != nil {
+		return designFile, err
+	}
+
+	// The render resolution of the first scene stands for the document size
+	if len(blenderInfo.Scenes) > 0 && blenderInfo.Scenes[0].Width > 0 {
+		first := blenderInfo.Scenes[0]
+		designFile.Dimensions = fmt.Sprintf("%dx%d px", first.Width, first.Height)
+	}
+	designFile.Version = "Blender " + blenderInfo.Version
+	designFile.Layers = len(blenderInfo.Collections)
+	designFile.LayerNames = blenderInfo.Collections
+	designFile.Artboards = len(blenderInfo.Scenes)
+	designFile.Objects = len(blenderInfo.Objects)
+	designFile.Meshes = len(blenderInfo.Meshes)
+	for _, mesh := range blenderInfo.Meshes {
+		designFile.Vertices += mesh.Vertices
+		designFile.Polygons += mesh.Faces
+	}
+	designFile.Materials = len(blenderInfo.Materials)
+	designFile.Textures = len(blenderInfo.Images)
+	for _, image := range blenderInfo.Images {
+		if !image.Packed {
+			designFile.Assets++
+		}
+	}
+
+	designFile.Metadata = &FileMetadata{
+		Dimensions:  designFile.Dimensions,
+		ColorMode:   designFile.ColorMode,
+		LayerCount:  designFile.Layers,
+		FileVersion: designFile.Version,
+		ExtractedAt: time.Now(),
+	}
+
+	return designFile, nil
+}
+
+// analyzeFBXFile performs FBX file analysis
+func (fs *FileScanner) analyzeFBXFile(filePath string, designFile *DesignFile) (*DesignFile, error) {
+	fbxInfo, err := fbx.GetFBXInfo(filePath)
+	if err != nil {
+		return designFile, err
+	}
+
+	designFile.Version = fbxVersion(fbxInfo)
+	designFile.Layers = len(fbxInfo.Models)
+	designFile.Objects = len(fbxInfo.Models)
+	for _, model := range fbxInfo.Models {
+		designFile.LayerNames = append(designFile.LayerNames, model.Name)
+	}
+	designFile.Meshes = len(fbxInfo.Meshes)
+	for _, mesh := range fbxInfo.Meshes {
+		designFile.Vertices += mesh.Vertices
+		designFile.Polygons += mesh.Polygons
+	}
+	designFile.Materials = len(fbxInfo.Materials)
+	designFile.Textures = len(fbxInfo.Textures)
+	designFile.Animations = len(fbxInfo.AnimationStacks)
+
+	designFile.Metadata = &FileMetadata{
+		Dimensions:  designFile.Dimensions,
+		ColorMode:   designFile.ColorMode,
+		LayerCount:  designFile.Layers,
+		FileVersion: designFile.Version,
+		ExtractedAt: time.Now(),
+	}
+
+	return designFile, nil
+}
+
+// fbxVersion formats the format version and encoding of an FBX file, e.g. "FBX 7.4 (binary)"
+func fbxVersion(info *fbx.FBXInfo) string {
+	encoding := "ASCII"
+	if info.Binary {
+		encoding = "binary"
+	}
+	return fmt.Sprintf("FBX %d.%d (%s)", info.Version/1000, info.Version%1000/100, encoding)
+}
+
+// analyzeOBJFile performs Wavefront OBJ file analysis
+func (fs *FileScanner) analyzeOBJFile(filePath string, designFile *DesignFile) (*DesignFile, error) {
+	objInfo, err := obj.GetOBJInfo(filePath)
+	if err != nil {
+		return designFile, err
+	}
+
+	designFile.Version = "Wavefront OBJ"
+	if objInfo.Vertices > 0 {
+		designFile.Dimensions = objDimensions(objInfo)
+	}
+	designFile.Layers = len(objInfo.Groups)
+	designFile.LayerNames = objInfo.Groups
+	designFile.Objects = len(objInfo.Objects)
+	designFile.Meshes = len(objInfo.Objects)
+	designFile.Vertices = objInfo.Vertices
+	designFile.Polygons = objInfo.Faces
+	designFile.Materials = len(objInfo.Materials)
+	// Textures come from the MTL libraries beside the file, which stored versions lack
+	designFile.Assets = len(objInfo.Libraries)
+
+	designFile.Metadata = &FileMetadata{
+		Dimensions:  designFile.Dimensions,
+		ColorMode:   designFile.ColorMode,
+		LayerCount:  designFile.Layers,
+		FileVersion: designFile.Version,
+		ExtractedAt: time.Now(),
+	}
+
+	return designFile, nil
+}
+
+// objDimensions formats the bounding box size of an OBJ file in model units
+func objDimensions(info *obj.OBJInfo) string {
+	size := info.Size()
+	return fmt.Sprintf("%.4gx%.4gx%.4g units", size[0], size[1], size[2])
+}
+
 // generateFileHash creates hash for file identification
 func (fs *FileScanner) generateFileHash(filePath string, info os.FileInfo) string {
 	hashInput := fmt.Sprintf("%s:%d:%d", filePath, info.Size(), info.ModTime().Unix())
@@ -0,0 +1,23 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountsOnlyIncludeTheFormatsCounts(t *testing.T) {
+	cases := []struct {
+		file DesignFile
+		want map[string]int
+	}{
+		{DesignFile{Type: "psd", Layers: 3, Meshes: 1}, map[string]int{}},
+		{DesignFile{Type: "xd", Components: 2, Meshes: 1}, map[string]int{"components": 2, "instances": 0, "text_layers": 0, "assets": 0}},
+		{DesignFile{Type: "obj", Meshes: 1, Vertices: 8, Polygons: 6}, map[string]int{"meshes": 1, "vertices": 8, "polygons": 6, "materials": 0, "assets": 0}},
+		{DesignFile{Type: "fbx", Animations: 2}, map[string]int{"meshes": 0, "vertices": 0, "polygons": 0, "materials": 0, "textures": 0, "animations": 2}},
+	}
+	for _, c := range cases {
+		if got := c.file.Counts(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: counts = %v, want %v", c.file.Type, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package scantest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// PSDLayer is a one-pixel gray layer of a layered test document
+type PSDLayer struct {
+	Name    string
+	Unicode string // Written as a "luni" block when set
+	ID      int    // Written as a "lyid" block when non-zero
+	Section int    // Written as a "lsct" block when non-zero
+	Pixel   byte
+	Opacity byte   // 255 when zero
+	Blend   string // Blend mode key, "norm" when empty
+	Hidden  bool
+}
+
+// LayeredPSD returns a 1x1 grayscale PSD with the given layers, listed bottom to top
+func LayeredPSD(layers []PSDLayer) []byte {
+	var records, channels bytes.Buffer
+	for _, layer := range layers {
+		records.Write(make([]byte, 8))
+		binary.Write(&records, binary.BigEndian, []uint32{1, 1}) // Bottom and right
+		binary.Write(&records, binary.BigEndian, uint16(1))
+		binary.Write(&records, binary.BigEndian, int16(0))
+		binary.Write(&records, binary.BigEndian, uint32(3))
+		blend, opacity, flags := layer.Blend, layer.Opacity, byte(0)
+		if blend == "" {
+			blend = "norm"
+		}
+		if opacity == 0 {
+			opacity = 255
+		}
+		if layer.Hidden {
+			flags = 0x02
+		}
+		records.WriteString("8BIM" + blend)
+		records.Write([]byte{opacity, 0, flags, 0})
+
+		var extra bytes.Buffer
+		extra.Write(make([]byte, 8)) // No mask or blending ranges
+		name := append([]byte{byte(len(layer.Name))}, layer.Name...)
+		for len(name)%4 != 0 {
+			name = append(name, 0)
+		}
+		extra.Write(name)
+		if layer.Unicode != "" {
+			units := utf16.Encode([]rune(layer.Unicode))
+			data := binary.BigEndian.AppendUint32(nil, uint32(len(units)))
+			for _, unit := range units {
+				data = binary.BigEndian.AppendUint16(data, unit)
+			}
+			extra.Write(additionalInfo("luni", data))
+		}
+		if layer.ID != 0 {
+			extra.Write(additionalInfo("lyid", binary.BigEndian.AppendUint32(nil, uint32(layer.ID))))
+		}
+		if layer.Section != 0 {
+			extra.Write(additionalInfo("lsct", binary.BigEndian.AppendUint32(nil, uint32(layer.Section))))
+		}
+		binary.Write(&records, binary.BigEndian, uint32(extra.Len()))
+		records.Write(extra.Bytes())
+
+		channels.Write([]byte{0, 0, layer.Pixel}) // Raw compression and the pixel
+	}
+
+	layerInfo := binary.BigEndian.AppendUint16(nil, uint16(len(layers)))
+	layerInfo = append(append(layerInfo, records.Bytes()...), channels.Bytes()...)
+	if len(layerInfo)%2 != 0 {
+		layerInfo = append(layerInfo, 0)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("8BPS")
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	b.Write(make([]byte, 6))
+	binary.Write(&b, binary.BigEndian, uint16(1))      // One channel
+	binary.Write(&b, binary.BigEndian, []uint32{1, 1}) // Height and width
+	binary.Write(&b, binary.BigEndian, []uint16{8, 1}) // Depth and grayscale color mode
+	b.Write(make([]byte, 8))                           // Color mode data and image resources
+	binary.Write(&b, binary.BigEndian, uint32(4+len(layerInfo)+4))
+	binary.Write(&b, binary.BigEndian, uint32(len(layerInfo)))
+	b.Write(layerInfo)
+	b.Write(make([]byte, 4)) // Empty global layer mask info
+	b.Write([]byte{0, 0, 0x80})
+	return b.Bytes()
+}
+
+// additionalInfo encodes an additional layer information block
+func additionalInfo(key string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("8BIM" + key)
+	binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
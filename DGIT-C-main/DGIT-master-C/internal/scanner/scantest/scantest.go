@@ -0,0 +1,50 @@
+// Package scantest holds test fixtures and helpers shared by the design file analyzers
+package scantest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WriteFile writes data to a file with the given name in a temporary directory of the test
+func WriteFile(t testing.TB, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Truncations calls parse with every proper prefix of data
+// Analyzers read files that may be cut off, so any prefix has to fail with an error rather
+// than a panic; the test fails with the length of the first prefix that panics.
+func Truncations(t testing.TB, data []byte, parse func(prefix []byte)) {
+	t.Helper()
+	for i := 0; i < len(data); i++ {
+		if recovered := tryParse(data[:i], parse); recovered != nil {
+			t.Fatalf("panic on the first %d of %d bytes: %v", i, len(data), recovered)
+		}
+	}
+}
+
+// TruncatedFiles is Truncations for analyzers that read a file: each prefix is written to
+// a file with the given name, whose path is passed to parse
+func TruncatedFiles(t testing.TB, name string, data []byte, parse func(path string)) {
+	t.Helper()
+	path := WriteFile(t, name, nil)
+	Truncations(t, data, func(prefix []byte) {
+		if err := os.WriteFile(path, prefix, 0644); err != nil {
+			t.Fatal(err)
+		}
+		parse(path)
+	})
+}
+
+// tryParse runs parse on data and returns the value of a panic, or nil
+func tryParse(data []byte, parse func([]byte)) (recovered interface{}) {
+	defer func() { recovered = recover() }()
+	parse(data)
+	return nil
+}
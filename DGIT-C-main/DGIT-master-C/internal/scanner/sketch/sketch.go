@@ -0,0 +1,228 @@
+package sketch
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SketchInfo contains metadata extracted from Sketch documents
+// Sketch 43 and later save documents as ZIP archives of JSON files:
+// document.json, meta.json and one pages/<id>.json per page.
+type SketchInfo struct {
+	AppVersion      string     // Sketch version that saved the file, e.g. "Sketch 99.1"
+	ColorSpace      string     // Unmanaged, sRGB or Display P3
+	Pages           []string   // Page names in document order
+	Artboards       []Artboard // Artboards of all pages
+	Layers          []Layer    // Layer tree of each page, artboards at the top
+	LayerNames      []string   // Names of all layers inside artboards and on the canvas
+	Symbols         []string   // Names of symbol masters, including those from libraries
+	SymbolInstances int        // Number of symbol instances placed in the document
+	Texts           []string   // Content of the text layers
+	SharedStyles    []string   // Names of shared layer and text styles
+	Images          []string   // Bitmaps stored in the archive
+	ObjectCount     int        // Total number of layers, containers included
+}
+
+// Artboard is an artboard of a Sketch page
+type Artboard struct {
+	Name   string
+	Page   string
+	Width  float64
+	Height float64
+}
+
+// Layer is a layer of a Sketch page with its children
+type Layer struct {
+	Name     string
+	Type     string // Sketch layer class: artboard, group, text, shapePath, bitmap, symbolInstance, ...
+	Children []Layer
+}
+
+// layerJSON is the part of a Sketch layer object read by the analyzer
+type layerJSON struct {
+	Class string `json:"_class"`
+	Name  string `json:"name"`
+	Frame struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	} `json:"frame"`
+	Layers           []layerJSON `json:"layers"`
+	AttributedString *struct {
+		String string `json:"string"`
+	} `json:"attributedString"`
+}
+
+// sharedStylesJSON is a container of shared styles in document.json
+type sharedStylesJSON struct {
+	Objects []struct {
+		Name string `json:"name"`
+	} `json:"objects"`
+}
+
+// documentJSON is the part of document.json read by the analyzer
+type documentJSON struct {
+	ColorSpace int `json:"colorSpace"`
+	Pages      []struct {
+		Ref string `json:"_ref"`
+	} `json:"pages"`
+	LayerStyles     sharedStylesJSON `json:"layerStyles"`
+	LayerTextStyles sharedStylesJSON `json:"layerTextStyles"`
+	ForeignSymbols  []struct {
+		SymbolMaster layerJSON `json:"symbolMaster"`
+	} `json:"foreignSymbols"`
+}
+
+// metaJSON is the part of meta.json read by the analyzer
+type metaJSON struct {
+	AppVersion string `json:"appVersion"`
+}
+
+// colorSpaces maps document.json color space values to names
+var colorSpaces = map[int]string{
+	0: "Unmanaged",
+	1: "sRGB",
+	2: "Display P3",
+}
+
+// GetSketchInfo extracts pages, artboards, layers, symbols, shared styles and images from a Sketch file
+func GetSketchInfo(filePath string) (*SketchInfo, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("not a Sketch 43+ document (legacy files are not supported): %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var document documentJSON
+	if err := readJSON(files, "document.json", &document); err != nil {
+		return nil, err
+	}
+	var meta metaJSON
+	if err := readJSON(files, "meta.json", &meta); err != nil {
+		return nil, err
+	}
+
+	info := &SketchInfo{
+		AppVersion:   "Sketch",
+		ColorSpace:   colorSpaces[document.ColorSpace],
+		Pages:        []string{},
+		Artboards:    []Artboard{},
+		LayerNames:   []string{},
+		Symbols:      []string{},
+		Texts:        []string{},
+		SharedStyles: []string{},
+		Images:       []string{},
+	}
+	if meta.AppVersion != "" {
+		info.AppVersion = "Sketch " + meta.AppVersion
+	}
+
+	for _, pagePath := range pagePaths(document, files) {
+		var page layerJSON
+		if err := readJSON(files, pagePath, &page); err != nil {
+			return nil, err
+		}
+		info.Pages = append(info.Pages, page.Name)
+		for _, layer := range page.Layers {
+			info.Layers = append(info.Layers, info.walk(layer, page.Name))
+		}
+	}
+
+	for _, foreign := range document.ForeignSymbols {
+		info.Symbols = append(info.Symbols, foreign.SymbolMaster.Name)
+	}
+	for _, style := range document.LayerStyles.Objects {
+		info.SharedStyles = append(info.SharedStyles, style.Name)
+	}
+	for _, style := range document.LayerTextStyles.Objects {
+		info.SharedStyles = append(info.SharedStyles, style.Name)
+	}
+	for name := range files {
+		if strings.HasPrefix(name, "images/") && !strings.HasSuffix(name, "/") {
+			info.Images = append(info.Images, strings.TrimPrefix(name, "images/"))
+		}
+	}
+	sort.Strings(info.Images)
+	return info, nil
+}
+
+// walk records a layer and its children, returning its place in the layer tree
+func (info *SketchInfo) walk(layer layerJSON, page string) Layer {
+	info.ObjectCount++
+	switch layer.Class {
+	case "artboard":
+		info.Artboards = append(info.Artboards, Artboard{
+			Name: layer.Name, Page: page, Width: layer.Frame.Width, Height: layer.Frame.Height,
+		})
+	case "symbolMaster":
+		info.Symbols = append(info.Symbols, layer.Name)
+	case "symbolInstance":
+		info.SymbolInstances++
+		info.LayerNames = append(info.LayerNames, layer.Name)
+	case "text":
+		if layer.AttributedString != nil && layer.AttributedString.String != "" {
+			info.Texts = append(info.Texts, layer.AttributedString.String)
+		}
+		info.LayerNames = append(info.LayerNames, layer.Name)
+	default:
+		info.LayerNames = append(info.LayerNames, layer.Name)
+	}
+
+	node := Layer{Name: layer.Name, Type: layer.Class}
+	for _, child := range layer.Layers {
+		node.Children = append(node.Children, info.walk(child, page))
+	}
+	return node
+}
+
+// pagePaths returns the archive paths of the pages in document order
+// Pages missing from document.json are appended in name order.
+func pagePaths(document documentJSON, files map[string]*zip.File) []string {
+	var paths []string
+	listed := make(map[string]bool)
+	for _, page := range document.Pages {
+		path := page.Ref + ".json"
+		if _, ok := files[path]; ok && !listed[path] {
+			listed[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	var unlisted []string
+	for name := range files {
+		if strings.HasPrefix(name, "pages/") && strings.HasSuffix(name, ".json") && !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	return append(paths, unlisted...)
+}
+
+// readJSON decodes a JSON file of the archive
+func readJSON(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s not found in Sketch document", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
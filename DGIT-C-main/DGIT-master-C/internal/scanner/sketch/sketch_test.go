@@ -0,0 +1,168 @@
+package sketch
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// fixtureFiles is a Sketch document with one listed and one unlisted page
+var fixtureFiles = map[string]string{
+	"meta.json": `{"appVersion": "99.1"}`,
+	"document.json": `{
+		"colorSpace": 1,
+		"pages": [{"_ref": "pages/B"}],
+		"layerStyles": {"objects": [{"name": "Card"}]},
+		"layerTextStyles": {"objects": [{"name": "Heading"}]},
+		"foreignSymbols": [{"symbolMaster": {"_class": "symbolMaster", "name": "Library/Button"}}]
+	}`,
+	"pages/B.json": `{"name": "Home", "layers": [
+		{"_class": "artboard", "name": "Desktop", "frame": {"width": 1440, "height": 900}, "layers": [
+			{"_class": "text", "name": "Title", "attributedString": {"string": "Welcome"}},
+			{"_class": "group", "name": "Nav", "layers": [{"_class": "symbolInstance", "name": "Button"}]}
+		]}
+	]}`,
+	"pages/A.json":    `{"name": "Symbols", "layers": [{"_class": "symbolMaster", "name": "Button"}]}`,
+	"images/logo.png": "png",
+}
+
+func buildSketch(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	for _, name := range []string{"meta.json", "document.json", "pages/B.json", "pages/A.json", "images/logo.png"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestGetSketchInfo(t *testing.T) {
+	info, err := GetSketchInfo(scantest.WriteFile(t, "design.sketch", buildSketch(t, fixtureFiles)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.AppVersion != "Sketch 99.1" || info.ColorSpace != "sRGB" {
+		t.Errorf("app version %q, color space %q", info.AppVersion, info.ColorSpace)
+	}
+	if want := []string{"Home", "Symbols"}; !reflect.DeepEqual(info.Pages, want) {
+		t.Errorf("pages = %v, want %v", info.Pages, want)
+	}
+	if want := []Artboard{{Name: "Desktop", Page: "Home", Width: 1440, Height: 900}}; !reflect.DeepEqual(info.Artboards, want) {
+		t.Errorf("artboards = %+v", info.Artboards)
+	}
+	if want := []string{"Title", "Nav", "Button"}; !reflect.DeepEqual(info.LayerNames, want) {
+		t.Errorf("layer names = %v, want %v", info.LayerNames, want)
+	}
+	if want := []string{"Button", "Library/Button"}; !reflect.DeepEqual(info.Symbols, want) {
+		t.Errorf("symbols = %v, want %v", info.Symbols, want)
+	}
+	if info.SymbolInstances != 1 || info.ObjectCount != 5 {
+		t.Errorf("%d symbol instances, %d objects", info.SymbolInstances, info.ObjectCount)
+	}
+	if !reflect.DeepEqual(info.Texts, []string{"Welcome"}) || !reflect.DeepEqual(info.SharedStyles, []string{"Card", "Heading"}) {
+		t.Errorf("texts %v, shared styles %v", info.Texts, info.SharedStyles)
+	}
+	if !reflect.DeepEqual(info.Images, []string{"logo.png"}) {
+		t.Errorf("images = %v", info.Images)
+	}
+	if len(info.Layers) != 2 || info.Layers[0].Type != "artboard" || len(info.Layers[0].Children) != 2 {
+		t.Errorf("layer tree = %+v", info.Layers)
+	}
+}
+
+func TestGetSketchInfoRejectsIncompleteDocuments(t *testing.T) {
+	for _, missing := range []string{"document.json", "meta.json"} {
+		files := make(map[string]string)
+		for name, content := range fixtureFiles {
+			if name != missing {
+				files[name] = content
+			}
+		}
+		if _, err := GetSketchInfo(scantest.WriteFile(t, "design.sketch", buildSketch(t, files))); err == nil {
+			t.Errorf("expected an error without %s", missing)
+		}
+	}
+
+	broken := map[string]string{"meta.json": "{}", "document.json": "{}", "pages/A.json": `{"layers": [`}
+	if _, err := GetSketchInfo(scantest.WriteFile(t, "design.sketch", buildSketch(t, broken))); err == nil {
+		t.Error("expected an error for a truncated page")
+	}
+	if _, err := GetSketchInfo(scantest.WriteFile(t, "design.sketch", []byte("not a zip archive"))); err == nil {
+		t.Error("expected an error for a legacy document")
+	}
+}
+
+func TestGetSketchInfoSymbols(t *testing.T) {
+	cases := []struct {
+		name      string
+		page      string
+		foreign   string
+		symbols   []string
+		instances int
+		texts     []string
+	}{
+		{
+			name:      "masters on any page",
+			page:      `[{"_class": "symbolMaster", "name": "Icon"}, {"_class": "artboard", "name": "A", "layers": [{"_class": "symbolMaster", "name": "Badge"}]}]`,
+			symbols:   []string{"Icon", "Badge"},
+			instances: 0,
+			texts:     []string{},
+		},
+		{
+			name: "nested instances with overrides",
+			page: `[{"_class": "artboard", "name": "A", "layers": [{"_class": "group", "name": "List", "layers": [
+				{"_class": "symbolInstance", "name": "Row", "symbolID": "S1", "overrideValues": [{"overrideName": "T1_stringValue", "value": "Overridden label"}]},
+				{"_class": "symbolInstance", "name": "Row", "symbolID": "S1", "layers": []}
+			]}]}]`,
+			symbols:   []string{},
+			instances: 2,
+			texts:     []string{}, // Override values belong to the instance, not to text layers
+		},
+		{
+			name:      "master text and library symbols",
+			page:      `[{"_class": "symbolMaster", "name": "Button", "layers": [{"_class": "text", "name": "Label", "attributedString": {"string": "OK"}}]}]`,
+			foreign:   `[{"symbolMaster": {"name": "Library/Icon"}}, {"symbolMaster": {"name": "Library/Avatar"}}]`,
+			symbols:   []string{"Button", "Library/Icon", "Library/Avatar"},
+			instances: 0,
+			texts:     []string{"OK"},
+		},
+	}
+	for _, c := range cases {
+		foreign := c.foreign
+		if foreign == "" {
+			foreign = "[]"
+		}
+		files := map[string]string{
+			"meta.json":     `{"appVersion": "99.1"}`,
+			"document.json": `{"pages": [{"_ref": "pages/A"}], "foreignSymbols": ` + foreign + `}`,
+			"pages/A.json":  `{"name": "Page", "layers": ` + c.page + `}`,
+		}
+		info, err := GetSketchInfo(scantest.WriteFile(t, "design.sketch", buildSketch(t, files)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(info.Symbols, c.symbols) || info.SymbolInstances != c.instances || !reflect.DeepEqual(info.Texts, c.texts) {
+			t.Errorf("%s: symbols %q, %d instances, texts %q", c.name, info.Symbols, info.SymbolInstances, info.Texts)
+		}
+	}
+}
+
+func TestGetSketchInfoDoesNotPanicOnTruncation(t *testing.T) {
+	scantest.TruncatedFiles(t, "design.sketch", buildSketch(t, fixtureFiles), func(path string) {
+		GetSketchInfo(path)
+	})
+}
@@ -0,0 +1,223 @@
+package xd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// XDInfo contains metadata extracted from Adobe XD documents
+// XD files are ZIP packages: a JSON manifest lists the artboards, and each artboard's
+// content is a JSON scene graph at artwork/<artboard>/graphics/graphicContent.agc.
+type XDInfo struct {
+	Version            string     // XD version that saved the file, when recorded
+	Artboards          []Artboard // Artboards in manifest order
+	Layers             []Layer    // Scene graph of each artboard, artboards at the top
+	LayerNames         []string   // Names of all layers inside artboards
+	Components         []string   // Names of the components defined in the document
+	ComponentInstances []string   // Names of the component instances placed on artboards
+	Texts              []string   // Content of the text layers
+	Assets             []string   // Images and other resources stored in or linked from the package
+	ObjectCount        int        // Total number of scene graph nodes
+}
+
+// Artboard is an XD artboard
+type Artboard struct {
+	Name   string
+	Width  float64
+	Height float64
+}
+
+// Layer is a node of an XD scene graph with its children
+type Layer struct {
+	Name     string
+	Type     string // Scene graph node type: artboard, group, shape, text, ...
+	Children []Layer
+}
+
+// manifestEntry is a node of the package manifest
+type manifestEntry struct {
+	Name     string          `json:"name"`
+	Path     string          `json:"path"`
+	Type     string          `json:"type"`
+	Bounds   *boundsJSON     `json:"uxdesign#bounds"`
+	Children []manifestEntry `json:"children"`
+}
+
+// boundsJSON is an artboard frame in the manifest
+type boundsJSON struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// manifestJSON is the part of the package manifest read by the analyzer
+type manifestJSON struct {
+	Version    string          `json:"uxdesign#version"`
+	Children   []manifestEntry `json:"children"`
+	Components []manifestEntry `json:"components"`
+}
+
+// GetXDInfo extracts artboards, the scene graph, components, texts and assets from an XD file
+func GetXDInfo(filePath string) (*XDInfo, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("not an XD package: %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var manifest manifestJSON
+	if err := readJSON(files, "manifest", &manifest); err != nil {
+		return nil, err
+	}
+
+	info := &XDInfo{
+		Version:            "Adobe XD",
+		Artboards:          []Artboard{},
+		LayerNames:         []string{},
+		Components:         []string{},
+		ComponentInstances: []string{},
+		Texts:              []string{},
+		Assets:             []string{},
+	}
+	if manifest.Version != "" {
+		info.Version = "Adobe XD " + manifest.Version
+	}
+
+	// Component definitions live in the shared resources graphic
+	var resources map[string]interface{}
+	if readJSON(files, "resources/graphics/graphicContent.agc", &resources) == nil {
+		for _, symbol := range list(lookup(resources, "resources", "meta", "ux", "symbols")) {
+			if name := text(lookup(symbol, "name")); name != "" {
+				info.Components = append(info.Components, name)
+			}
+		}
+	}
+
+	assets := make(map[string]bool)
+	for _, entry := range manifest.Children {
+		if entry.Path != "artwork" && entry.Name != "artwork" {
+			continue
+		}
+		for _, artboard := range entry.Children {
+			if artboard.Name == "pasteboard" {
+				continue // Holds objects outside any artboard
+			}
+			if artboard.Bounds != nil {
+				info.Artboards = append(info.Artboards, Artboard{
+					Name: artboard.Name, Width: artboard.Bounds.Width, Height: artboard.Bounds.Height,
+				})
+			}
+			var graphic map[string]interface{}
+			if err := readJSON(files, path.Join("artwork", artboard.Path, "graphics", "graphicContent.agc"), &graphic); err != nil {
+				continue
+			}
+			for _, node := range list(graphic["children"]) {
+				info.Layers = append(info.Layers, info.walk(node, assets))
+			}
+		}
+	}
+
+	for _, component := range manifest.Components {
+		if component.Path != "" && component.Type != "application/json" {
+			assets[path.Base(component.Path)] = true
+		}
+	}
+	for name := range files {
+		if strings.HasPrefix(name, "resources/") && !strings.HasSuffix(name, "/") && !strings.HasPrefix(name, "resources/graphics/") {
+			assets[path.Base(name)] = true
+		}
+	}
+	for asset := range assets {
+		info.Assets = append(info.Assets, asset)
+	}
+	sort.Strings(info.Assets)
+	return info, nil
+}
+
+// walk records a scene graph node and its children, returning its place in the layer tree
+func (info *XDInfo) walk(node interface{}, assets map[string]bool) Layer {
+	nodeType := text(lookup(node, "type"))
+	name := text(lookup(node, "name"))
+	info.ObjectCount++
+
+	var children []interface{}
+	switch nodeType {
+	case "artboard":
+		children = list(lookup(node, "artboard", "children"))
+	case "group":
+		children = list(lookup(node, "group", "children"))
+	case "text":
+		if content := text(lookup(node, "text", "rawText")); content != "" {
+			info.Texts = append(info.Texts, content)
+		}
+	}
+	if nodeType != "artboard" {
+		info.LayerNames = append(info.LayerNames, name)
+	}
+	if lookup(node, "meta", "ux", "symbolId") != nil || nodeType == "syncRef" {
+		info.ComponentInstances = append(info.ComponentInstances, name)
+	}
+	if href := text(lookup(node, "style", "fill", "pattern", "href")); href != "" {
+		assets[path.Base(href)] = true
+	}
+
+	layer := Layer{Name: name, Type: nodeType}
+	for _, child := range children {
+		layer.Children = append(layer.Children, info.walk(child, assets))
+	}
+	return layer
+}
+
+// lookup follows a path of keys through nested JSON objects
+func lookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// list returns a JSON array, or nil for other values
+func list(value interface{}) []interface{} {
+	array, _ := value.([]interface{})
+	return array
+}
+
+// text returns a JSON string, or "" for other values
+func text(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// readJSON decodes a JSON file of the package
+func readJSON(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s not found in XD package", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
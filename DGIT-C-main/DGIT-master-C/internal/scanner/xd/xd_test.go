@@ -0,0 +1,158 @@
+package xd
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"dgit/internal/scanner/scantest"
+)
+
+// fixtureFiles is an XD package with one artboard, the pasteboard and a component definition
+var fixtureFiles = [][2]string{
+	{"manifest", `{
+		"uxdesign#version": "57.1.12",
+		"children": [{"path": "artwork", "children": [
+			{"name": "Home", "path": "artboard-1", "uxdesign#bounds": {"width": 375, "height": 812}},
+			{"name": "pasteboard", "path": "pasteboard"}
+		]}],
+		"components": [
+			{"path": "a1b2.png", "type": "image/png"},
+			{"path": "resources/graphics/graphicContent.agc", "type": "application/json"}
+		]
+	}`},
+	{"artwork/artboard-1/graphics/graphicContent.agc", `{"children": [
+		{"type": "artboard", "name": "Home", "artboard": {"children": [
+			{"type": "text", "name": "Title", "text": {"rawText": "Hello"}},
+			{"type": "group", "name": "Card", "group": {"children": [
+				{"type": "shape", "name": "Photo", "style": {"fill": {"pattern": {"href": "/photo.jpg"}}}},
+				{"type": "syncRef", "name": "Button"}
+			]}}
+		]}}
+	]}`},
+	{"artwork/pasteboard/graphics/graphicContent.agc", `{"children": [{"type": "shape", "name": "Scratch"}]}`},
+	{"resources/graphics/graphicContent.agc", `{"resources": {"meta": {"ux": {"symbols": [{"name": "Button"}]}}}}`},
+	{"resources/a1b2.png", "png"},
+}
+
+func buildXD(t *testing.T, files [][2]string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	for _, file := range files {
+		f, err := w.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(file[1]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestGetXDInfo(t *testing.T) {
+	info, err := GetXDInfo(scantest.WriteFile(t, "design.xd", buildXD(t, fixtureFiles)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "Adobe XD 57.1.12" {
+		t.Errorf("version = %q", info.Version)
+	}
+	if want := []Artboard{{Name: "Home", Width: 375, Height: 812}}; !reflect.DeepEqual(info.Artboards, want) {
+		t.Errorf("artboards = %+v", info.Artboards)
+	}
+	if want := []string{"Title", "Card", "Photo", "Button"}; !reflect.DeepEqual(info.LayerNames, want) {
+		t.Errorf("layer names = %v, want %v", info.LayerNames, want)
+	}
+	if !reflect.DeepEqual(info.Components, []string{"Button"}) || !reflect.DeepEqual(info.ComponentInstances, []string{"Button"}) {
+		t.Errorf("components %v, instances %v", info.Components, info.ComponentInstances)
+	}
+	if !reflect.DeepEqual(info.Texts, []string{"Hello"}) {
+		t.Errorf("texts = %v", info.Texts)
+	}
+	if want := []string{"a1b2.png", "photo.jpg"}; !reflect.DeepEqual(info.Assets, want) {
+		t.Errorf("assets = %v, want %v", info.Assets, want)
+	}
+	if info.ObjectCount != 5 || len(info.Layers) != 1 || len(info.Layers[0].Children) != 2 {
+		t.Errorf("%d objects, layer tree = %+v", info.ObjectCount, info.Layers)
+	}
+}
+
+func TestGetXDInfoRejectsInvalidPackages(t *testing.T) {
+	if _, err := GetXDInfo(scantest.WriteFile(t, "design.xd", buildXD(t, fixtureFiles[1:]))); err == nil {
+		t.Error("expected an error without a manifest")
+	}
+	if _, err := GetXDInfo(scantest.WriteFile(t, "design.xd", buildXD(t, [][2]string{{"manifest", `{"children": [`}}))); err == nil {
+		t.Error("expected an error for a truncated manifest")
+	}
+	if _, err := GetXDInfo(scantest.WriteFile(t, "design.xd", []byte("not a zip archive"))); err == nil {
+		t.Error("expected an error for a file that is not a package")
+	}
+}
+
+func TestGetXDInfoSkipsUnreadableArtboards(t *testing.T) {
+	files := append([][2]string{}, fixtureFiles...)
+	files[1][1] = `{"children": [{"type": "artboard", "artboard": `
+	info, err := GetXDInfo(scantest.WriteFile(t, "design.xd", buildXD(t, files)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Artboards) != 1 || len(info.Layers) != 0 {
+		t.Errorf("artboards %+v, layers %+v", info.Artboards, info.Layers)
+	}
+}
+
+func TestGetXDInfoArtboards(t *testing.T) {
+	cases := []struct {
+		name     string
+		artwork  string
+		expected []Artboard
+	}{
+		{
+			name: "manifest order and fractional sizes",
+			artwork: `{"path": "artwork", "children": [
+				{"name": "Mobile", "path": "a1", "uxdesign#bounds": {"width": 375, "height": 812.5}},
+				{"name": "Desktop", "path": "a2", "uxdesign#bounds": {"width": 1440, "height": 1024}}
+			]}`,
+			expected: []Artboard{{Name: "Mobile", Width: 375, Height: 812.5}, {Name: "Desktop", Width: 1440, Height: 1024}},
+		},
+		{
+			name: "pasteboard and entries without bounds",
+			artwork: `{"path": "artwork", "children": [
+				{"name": "pasteboard", "path": "pasteboard", "uxdesign#bounds": {"width": 10, "height": 10}},
+				{"name": "Loose", "path": "a1"},
+				{"name": "Tablet", "path": "a2", "uxdesign#bounds": {"width": 768, "height": 1024}}
+			]}`,
+			expected: []Artboard{{Name: "Tablet", Width: 768, Height: 1024}},
+		},
+		{
+			name:     "artwork entry found by name",
+			artwork:  `{"name": "artwork", "children": [{"name": "Watch", "path": "a1", "uxdesign#bounds": {"width": 184, "height": 224}}]}`,
+			expected: []Artboard{{Name: "Watch", Width: 184, Height: 224}},
+		},
+		{
+			name:     "no artwork",
+			artwork:  `{"path": "resources", "children": [{"name": "Other", "uxdesign#bounds": {"width": 1, "height": 1}}]}`,
+			expected: []Artboard{},
+		},
+	}
+	for _, c := range cases {
+		manifest := `{"uxdesign#version": "57.1.12", "children": [` + c.artwork + `]}`
+		info, err := GetXDInfo(scantest.WriteFile(t, "design.xd", buildXD(t, [][2]string{{"manifest", manifest}})))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(info.Artboards, c.expected) {
+			t.Errorf("%s: artboards = %+v, want %+v", c.name, info.Artboards, c.expected)
+		}
+	}
+}
+
+func TestGetXDInfoDoesNotPanicOnTruncation(t *testing.T) {
+	scantest.TruncatedFiles(t, "design.xd", buildXD(t, fixtureFiles), func(path string) {
+		GetXDInfo(path)
+	})
+}
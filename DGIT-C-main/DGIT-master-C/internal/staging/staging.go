@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -37,6 +38,9 @@ type StagedFile struct {
 	CacheLevel    string        `json:"cache_level"`        // "versions", "cache"
 	PreCompressed bool          `json:"pre_compressed"`     // LZ4 pre-compression status
 	Metadata      *FileMetadata `json:"metadata,omitempty"` // Pre-extracted metadata
+
+	// Previous path when the file was staged by 'dgit mv'
+	RenamedFrom string `json:"renamed_from,omitempty"`
 }
 
 // FileMetadata contains pre-extracted design file metadata
@@ -70,7 +74,9 @@ type CacheStats struct {
 type StagingArea struct {
 	DgitDir     string
 	StagingFile string
+	RemovedFile string // Paths staged for removal (.dgit/staging/removed.json)
 	files       map[string]*StagedFile
+	removed     map[string]bool // Repository-relative paths removed in the next commit
 
 	// Simplified storage directories
 	versionsDir string // 메인 버전 저장소 (.dgit/versions/)
@@ -96,7 +102,9 @@ func NewStagingArea(dgitDir string) *StagingArea {
 	return &StagingArea{
 		DgitDir:     dgitDir,
 		StagingFile: filepath.Join(stagingDir, "staged.json"),
+		RemovedFile: filepath.Join(stagingDir, "removed.json"),
 		files:       make(map[string]*StagedFile),
+		removed:     make(map[string]bool),
 		versionsDir: versionsDir,
 		commitsDir:  commitsDir,
 		cacheDir:    cacheDir,
@@ -106,6 +114,9 @@ func NewStagingArea(dgitDir string) *StagingArea {
 
 // LoadStaging loads the current staging area from disk with cache validation
 func (s *StagingArea) LoadStaging() error {
+	if err := s.loadRemovals(); err != nil {
+		return err
+	}
 	if _, err := os.Stat(s.StagingFile); os.IsNotExist(err) {
 		return nil // No staging file exists yet
 	}
@@ -126,6 +137,26 @@ func (s *StagingArea) LoadStaging() error {
 	return nil
 }
 
+// loadRemovals reads the paths staged for removal
+func (s *StagingArea) loadRemovals() error {
+	data, err := os.ReadFile(s.RemovedFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read removal list: %w", err)
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return fmt.Errorf("failed to parse removal list: %w", err)
+	}
+	for _, path := range paths {
+		s.removed[path] = true
+	}
+	return nil
+}
+
 // validateCacheIntegrity ensures all cached files are accessible
 func (s *StagingArea) validateCacheIntegrity() {
 	for _, file := range s.files {
@@ -150,6 +181,21 @@ func (s *StagingArea) SaveStaging() error {
 		return fmt.Errorf("failed to write staging file: %w", err)
 	}
 
+	// The removal list only exists while removals are staged
+	if len(s.removed) == 0 {
+		if err := os.Remove(s.RemovedFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove removal list: %w", err)
+		}
+		return nil
+	}
+	data, err = json.MarshalIndent(s.GetRemovedFiles(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal removal list: %w", err)
+	}
+	if err := os.WriteFile(s.RemovedFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write removal list: %w", err)
+	}
+
 	return nil
 }
 
@@ -174,9 +220,8 @@ func (s *StagingArea) AddFile(path string) error {
 		return fmt.Errorf("not a design file: %s", path)
 	}
 
-	// Get relative path from current directory
-	currentDir, _ := os.Getwd()
-	relPath, err := filepath.Rel(currentDir, absPath)
+	// Paths are recorded relative to the repository root so commits are independent of the working directory
+	relPath, err := filepath.Rel(s.WorkTreeRoot(), absPath)
 	if err != nil {
 		relPath = absPath
 	}
@@ -208,6 +253,7 @@ func (s *StagingArea) AddFile(path string) error {
 	}
 
 	s.files[absPath] = stagedFile
+	delete(s.removed, filepath.ToSlash(relPath))
 
 	processingTime := time.Since(startTime)
 	fmt.Printf("Added %s to %s (processed in %v)\n",
@@ -407,6 +453,11 @@ func (s *StagingArea) getCachePath(hash, level string) string {
 	return filepath.Join(cacheDir, hash)
 }
 
+// CacheFilePath returns the cache entry created for a staged file
+func (s *StagingArea) CacheFilePath(file *StagedFile) string {
+	return s.getCachePath(file.Hash, file.CacheLevel)
+}
+
 // createCacheEntry creates a cache entry (symlink or copy)
 func (s *StagingArea) createCacheEntry(sourcePath, cachePath string) error {
 	// Create symlink for efficiency
@@ -604,6 +655,11 @@ func (s *StagingArea) addAllDesignFiles(dir string) (*AddResult, error) {
 	return result, nil
 }
 
+// WorkTreeRoot returns the repository root directory containing .dgit
+func (s *StagingArea) WorkTreeRoot() string {
+	return filepath.Dir(s.DgitDir)
+}
+
 // GetCacheStats returns current cache performance statistics
 func (s *StagingArea) GetCacheStats() *CacheStats {
 	return s.cacheStats
@@ -618,6 +674,10 @@ func (s *StagingArea) RemoveFile(path string) error {
 
 	file, exists := s.files[absPath]
 	if !exists {
+		if relPath, err := filepath.Rel(s.WorkTreeRoot(), absPath); err == nil && s.removed[filepath.ToSlash(relPath)] {
+			delete(s.removed, filepath.ToSlash(relPath))
+			return nil
+		}
 		return fmt.Errorf("file not in staging area: %s", path)
 	}
 
@@ -642,7 +702,48 @@ func (s *StagingArea) GetStagedFiles() []*StagedFile {
 
 // IsEmpty returns true if the staging area is empty
 func (s *StagingArea) IsEmpty() bool {
-	return len(s.files) == 0
+	return len(s.files) == 0 && len(s.removed) == 0
+}
+
+// StageRemoval records that a tracked file is removed in the next commit
+// relPath is relative to the repository root
+func (s *StagingArea) StageRemoval(relPath string) {
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	for absPath, file := range s.files {
+		if filepath.ToSlash(file.Path) == relPath {
+			delete(s.files, absPath)
+		}
+	}
+	s.removed[relPath] = true
+}
+
+// IsRemoved checks if a repository-relative path is staged for removal
+func (s *StagingArea) IsRemoved(relPath string) bool {
+	return s.removed[filepath.ToSlash(filepath.Clean(relPath))]
+}
+
+// GetRemovedFiles returns the repository-relative paths staged for removal, sorted
+func (s *StagingArea) GetRemovedFiles() []string {
+	paths := make([]string, 0, len(s.removed))
+	for path := range s.removed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// MarkRenamed records the previous path of a staged file
+func (s *StagingArea) MarkRenamed(path, oldRelPath string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	file, exists := s.files[absPath]
+	if !exists {
+		return fmt.Errorf("file not in staging area: %s", path)
+	}
+	file.RenamedFrom = filepath.ToSlash(oldRelPath)
+	return nil
 }
 
 // ClearStaging clears all files from staging area and cache
@@ -656,13 +757,14 @@ func (s *StagingArea) ClearStaging() error {
 	}
 
 	s.files = make(map[string]*StagedFile)
+	s.removed = make(map[string]bool)
 	s.cacheStats = &CacheStats{}
 	return s.SaveStaging()
 }
 
-// GetFileCount returns the number of staged files
+// GetFileCount returns the number of staged files, including removals
 func (s *StagingArea) GetFileCount() int {
-	return len(s.files)
+	return len(s.files) + len(s.removed)
 }
 
 // HasFile checks if a file is in the staging area
@@ -0,0 +1,101 @@
+package staging
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestStaging returns a staging area whose work tree holds the given files
+func newTestStaging(t *testing.T, files ...string) *StagingArea {
+	workTree := t.TempDir()
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(workTree, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewStagingArea(filepath.Join(workTree, ".dgit"))
+}
+
+// state reloads the staging area from disk and lists staged and removed paths
+func state(t *testing.T, s *StagingArea) ([]string, []string) {
+	t.Helper()
+	loaded := NewStagingArea(s.DgitDir)
+	if err := loaded.LoadStaging(); err != nil {
+		t.Fatal(err)
+	}
+	staged := []string{}
+	for _, file := range loaded.GetStagedFiles() {
+		staged = append(staged, filepath.ToSlash(file.Path))
+	}
+	sort.Strings(staged)
+	return staged, loaded.GetRemovedFiles()
+}
+
+func TestUnstage(t *testing.T) {
+	cases := []struct {
+		name    string
+		unstage []string // Work tree relative paths; nil clears the staging area
+		staged  []string
+		removed []string
+		wantErr bool
+	}{
+		{"everything", nil, []string{}, []string{}, false},
+		{"one file", []string{"hero.psd"}, []string{"logo.ai"}, []string{"old.sketch"}, false},
+		{"a removal", []string{"old.sketch"}, []string{"hero.psd", "logo.ai"}, []string{}, false},
+		{"a file that is not staged", []string{"scene.ma"}, []string{"hero.psd", "logo.ai"}, []string{"old.sketch"}, true},
+	}
+	for _, c := range cases {
+		s := newTestStaging(t, "hero.psd", "logo.ai", "scene.ma")
+		workTree := s.WorkTreeRoot()
+		for _, name := range []string{"hero.psd", "logo.ai"} {
+			if err := s.AddFile(filepath.Join(workTree, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.StageRemoval("old.sketch")
+		if err := s.SaveStaging(); err != nil {
+			t.Fatal(err)
+		}
+
+		var err error
+		if c.unstage == nil {
+			err = s.ClearStaging()
+		}
+		for _, name := range c.unstage {
+			if removeErr := s.RemoveFile(filepath.Join(workTree, name)); removeErr != nil {
+				err = removeErr
+			}
+		}
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: %v", c.name, err)
+		}
+		if err := s.SaveStaging(); err != nil {
+			t.Fatal(err)
+		}
+		staged, removed := state(t, s)
+		if !reflect.DeepEqual(staged, c.staged) || !reflect.DeepEqual(removed, c.removed) {
+			t.Errorf("%s: staged %v, removed %v; want %v, %v", c.name, staged, removed, c.staged, c.removed)
+		}
+	}
+}
+
+func TestStagingAFileCancelsItsRemoval(t *testing.T) {
+	s := newTestStaging(t, "hero.psd")
+	s.StageRemoval("./hero.psd")
+	if !s.IsRemoved("hero.psd") {
+		t.Fatal("removal not staged")
+	}
+	if err := s.AddFile(filepath.Join(s.WorkTreeRoot(), "hero.psd")); err != nil {
+		t.Fatal(err)
+	}
+	if s.IsRemoved("hero.psd") || s.GetFileCount() != 1 {
+		t.Errorf("removed %v, %d files", s.GetRemovedFiles(), s.GetFileCount())
+	}
+	s.StageRemoval("hero.psd")
+	if s.HasFile(filepath.Join(s.WorkTreeRoot(), "hero.psd")) || !s.IsRemoved("hero.psd") {
+		t.Errorf("staged removal kept the file: removed %v, %d files", s.GetRemovedFiles(), s.GetFileCount())
+	}
+}
@@ -0,0 +1,475 @@
+package stash
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dgit/internal/log"
+	"dgit/internal/restore"
+	"dgit/internal/staging"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// File states recorded in a stash entry
+const (
+	FileModified = "modified"
+	FileAdded    = "added"
+	FileDeleted  = "deleted"
+)
+
+// StashedFile records one working tree file saved in a stash entry
+type StashedFile struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`              // "modified", "added" or "deleted"
+	Hash     string `json:"hash,omitempty"`      // Content hash of the stashed file; empty when deleted
+	BaseHash string `json:"base_hash,omitempty"` // Content hash at the base commit; empty when added
+}
+
+// StashEntry is a shelved set of working tree changes and staging state
+type StashEntry struct {
+	ID         string          `json:"id"`
+	Message    string          `json:"message"`
+	Branch     string          `json:"branch,omitempty"`
+	Base       string          `json:"base"` // HEAD commit when the changes were stashed
+	Files      []StashedFile   `json:"files"`
+	Staged     json.RawMessage `json:"staged,omitempty"`      // Contents of staging/staged.json
+	Removed    []string        `json:"removed,omitempty"`     // Paths staged for removal
+	CacheFiles []string        `json:"cache_files,omitempty"` // Staging cache entries, relative to .dgit
+	Archive    string          `json:"archive"`               // LZ4 archive holding file and cache contents
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ApplyResult reports the outcome of re-applying a stash entry
+type ApplyResult struct {
+	Restored  []string
+	Removed   []string
+	Conflicts map[string]string // Path -> reason
+}
+
+// StashManager stores stash entries in the object store (.dgit/objects/stash/)
+type StashManager struct {
+	DgitDir  string
+	StashDir string
+
+	restoreManager *restore.RestoreManager
+}
+
+// NewStashManager creates a new stash manager
+func NewStashManager(dgitDir string) *StashManager {
+	return &StashManager{
+		DgitDir:        dgitDir,
+		StashDir:       filepath.Join(dgitDir, "objects", "stash"),
+		restoreManager: restore.NewRestoreManager(dgitDir),
+	}
+}
+
+// Push saves the given changed files and the staging area, then resets them to HEAD
+// changed lists modified and deleted tracked files; staged files are always included
+func (sm *StashManager) Push(message, branch string, head *log.Commit, changed []string, stagingArea *staging.StagingArea) (*StashEntry, error) {
+	workTree := filepath.Dir(sm.DgitDir)
+	headFiles := head.TrackedFiles()
+
+	paths := make(map[string]bool)
+	for _, path := range changed {
+		paths[path] = true
+	}
+	stagedFiles := stagingArea.GetStagedFiles()
+	for _, file := range stagedFiles {
+		paths[file.Path] = true
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no local changes to save")
+	}
+
+	if err := os.MkdirAll(sm.StashDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create stash directory: %w", err)
+	}
+
+	createdAt := time.Now()
+	entry := &StashEntry{
+		ID:        fmt.Sprintf("%x", sha256.Sum256([]byte(head.Hash+createdAt.Format(time.RFC3339Nano))))[:12],
+		Message:   message,
+		Branch:    branch,
+		Base:      head.Hash,
+		CreatedAt: createdAt,
+	}
+	entry.Archive = entry.ID + ".lz4"
+	if entry.Message == "" {
+		entry.Message = fmt.Sprintf("WIP on %s: %s %s", sm.branchLabel(branch), head.Hash[:8], strings.SplitN(head.Message, "\n", 2)[0])
+	}
+
+	archive := make(map[string][]byte)
+	for _, path := range sortedKeys(paths) {
+		file := StashedFile{Path: path}
+		if _, tracked := headFiles[path]; tracked {
+			baseHash, err := sm.commitFileHash(head, path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s from HEAD: %w", path, err)
+			}
+			file.BaseHash = baseHash
+		}
+
+		data, err := os.ReadFile(filepath.Join(workTree, path))
+		switch {
+		case err == nil:
+			file.Hash = contentHash(data)
+			file.Status = FileModified
+			if file.BaseHash == "" {
+				file.Status = FileAdded
+			}
+			archive[path] = data
+		case os.IsNotExist(err) && file.BaseHash != "":
+			file.Status = FileDeleted
+		default:
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		entry.Files = append(entry.Files, file)
+	}
+
+	// The staging area is saved as-is so it can be reinstated exactly
+	if data, err := os.ReadFile(stagingArea.StagingFile); err == nil && len(stagedFiles) > 0 {
+		entry.Staged = json.RawMessage(data)
+	}
+	entry.Removed = stagingArea.GetRemovedFiles()
+	for _, file := range stagedFiles {
+		cachePath := stagingArea.CacheFilePath(file)
+		data, err := os.ReadFile(cachePath)
+		if err != nil {
+			continue
+		}
+		relPath, err := filepath.Rel(sm.DgitDir, cachePath)
+		if err != nil {
+			continue
+		}
+		key := filepath.ToSlash(filepath.Join(".dgit", relPath))
+		archive[key] = data
+		entry.CacheFiles = append(entry.CacheFiles, key)
+	}
+
+	if err := writeArchive(filepath.Join(sm.StashDir, entry.Archive), archive); err != nil {
+		return nil, err
+	}
+	if err := sm.saveEntry(entry); err != nil {
+		os.Remove(filepath.Join(sm.StashDir, entry.Archive))
+		return nil, err
+	}
+
+	// Reset the stashed files and the staging area to HEAD
+	if err := stagingArea.ClearStaging(); err != nil {
+		return entry, fmt.Errorf("failed to clear staging area: %w", err)
+	}
+	for _, file := range entry.Files {
+		target := filepath.Join(workTree, file.Path)
+		if file.BaseHash == "" {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return entry, fmt.Errorf("failed to remove %s: %w", file.Path, err)
+			}
+			continue
+		}
+		data, err := sm.restoreManager.ReadCommitFile(head, file.Path)
+		if err != nil {
+			return entry, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return entry, err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return entry, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+	}
+
+	return entry, nil
+}
+
+// Apply re-applies a stash entry on top of HEAD
+// Nothing is written when a conflict is found: a file changed in HEAD since the stash
+// was made, or a working tree file with local changes that the stash would overwrite.
+func (sm *StashManager) Apply(entry *StashEntry, head *log.Commit, stagingArea *staging.StagingArea) (*ApplyResult, error) {
+	workTree := filepath.Dir(sm.DgitDir)
+	result := &ApplyResult{Conflicts: make(map[string]string)}
+
+	if (len(entry.Staged) > 0 || len(entry.Removed) > 0) && !stagingArea.IsEmpty() {
+		result.Conflicts["staging area"] = "staged changes would be overwritten by the stashed staging area"
+	}
+
+	headFiles := map[string]log.TreeEntry{}
+	if head != nil {
+		headFiles = head.TrackedFiles()
+	}
+
+	for _, file := range entry.Files {
+		headHash := ""
+		if _, tracked := headFiles[file.Path]; tracked {
+			hash, err := sm.commitFileHash(head, file.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s from HEAD: %w", file.Path, err)
+			}
+			headHash = hash
+		}
+
+		worktreeHash := ""
+		if data, err := os.ReadFile(filepath.Join(workTree, file.Path)); err == nil {
+			worktreeHash = contentHash(data)
+		}
+
+		switch {
+		case headHash != file.BaseHash && headHash != file.Hash:
+			result.Conflicts[file.Path] = "changed in HEAD since the stash was created"
+		case worktreeHash != headHash && worktreeHash != file.Hash:
+			result.Conflicts[file.Path] = "local changes would be overwritten"
+		}
+	}
+	if len(result.Conflicts) > 0 {
+		return result, nil
+	}
+
+	archive, err := readArchive(filepath.Join(sm.StashDir, entry.Archive))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range entry.Files {
+		target := filepath.Join(workTree, file.Path)
+		if file.Status == FileDeleted {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return result, fmt.Errorf("failed to remove %s: %w", file.Path, err)
+			}
+			result.Removed = append(result.Removed, file.Path)
+			continue
+		}
+		data, ok := archive[file.Path]
+		if !ok {
+			return result, fmt.Errorf("stash archive is missing %s", file.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return result, err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+		result.Restored = append(result.Restored, file.Path)
+	}
+
+	// Reinstate the staging cache first so the restored staging file finds its entries
+	for _, key := range entry.CacheFiles {
+		data, ok := archive[key]
+		if !ok {
+			continue
+		}
+		target := filepath.Join(workTree, filepath.FromSlash(key))
+		os.Remove(target)
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return result, fmt.Errorf("failed to restore staging cache: %w", err)
+		}
+	}
+	if len(entry.Staged) > 0 {
+		if err := os.WriteFile(stagingArea.StagingFile, entry.Staged, 0644); err != nil {
+			return result, fmt.Errorf("failed to restore staging area: %w", err)
+		}
+	}
+	if len(entry.Removed) > 0 {
+		if err := stagingArea.LoadStaging(); err != nil {
+			return result, err
+		}
+		for _, path := range entry.Removed {
+			stagingArea.StageRemoval(path)
+		}
+		if err := stagingArea.SaveStaging(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// List returns stash entries, newest first (stash@{0} is the first entry)
+func (sm *StashManager) List() ([]*StashEntry, error) {
+	files, err := os.ReadDir(sm.StashDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read stash directory: %w", err)
+	}
+
+	var entries []*StashEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(sm.StashDir, file.Name()))
+		if err != nil {
+			continue
+		}
+		var entry StashEntry
+		if json.Unmarshal(data, &entry) != nil || entry.ID == "" {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// Get returns a stash entry by reference: "stash@{N}", "N", or an entry ID
+// An empty reference selects the latest entry
+func (sm *StashManager) Get(ref string) (*StashEntry, int, error) {
+	entries, err := sm.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(entries) == 0 {
+		return nil, 0, fmt.Errorf("no stash entries found")
+	}
+	if ref == "" {
+		return entries[0], 0, nil
+	}
+
+	// A full ID is matched first, as IDs made only of digits would read as indexes
+	for i, entry := range entries {
+		if entry.ID == ref {
+			return entry, i, nil
+		}
+	}
+	indexRef := strings.TrimSuffix(strings.TrimPrefix(ref, "stash@{"), "}")
+	if index, err := strconv.Atoi(indexRef); err == nil {
+		if index < 0 || index >= len(entries) {
+			return nil, 0, fmt.Errorf("stash@{%d} does not exist", index)
+		}
+		return entries[index], index, nil
+	}
+	for i, entry := range entries {
+		if strings.HasPrefix(entry.ID, ref) {
+			return entry, i, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("stash entry '%s' not found", ref)
+}
+
+// Drop deletes a stash entry and its archive
+func (sm *StashManager) Drop(entry *StashEntry) error {
+	if err := os.Remove(filepath.Join(sm.StashDir, entry.Archive)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete stash archive: %w", err)
+	}
+	if err := os.Remove(filepath.Join(sm.StashDir, entry.ID+".json")); err != nil {
+		return fmt.Errorf("failed to delete stash entry: %w", err)
+	}
+	return nil
+}
+
+// saveEntry writes a stash entry's metadata
+func (sm *StashManager) saveEntry(entry *StashEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stash entry: %w", err)
+	}
+	return os.WriteFile(filepath.Join(sm.StashDir, entry.ID+".json"), data, 0644)
+}
+
+// commitFileHash returns the content hash of a file in a commit, reading the content for legacy trees
+func (sm *StashManager) commitFileHash(commit *log.Commit, path string) (string, error) {
+	if entry := commit.TrackedFiles()[path]; entry.Hash != "" {
+		return entry.Hash, nil
+	}
+	data, err := sm.restoreManager.ReadCommitFile(commit, path)
+	if err != nil {
+		return "", err
+	}
+	return contentHash(data), nil
+}
+
+// branchLabel names the branch in default stash messages
+func (sm *StashManager) branchLabel(branch string) string {
+	if branch == "" {
+		return "(no branch)"
+	}
+	return branch
+}
+
+// writeArchive stores files in the structured "FILE:path:size\n[data]" LZ4 format used for snapshots
+func writeArchive(path string, files map[string][]byte) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create stash archive: %w", err)
+	}
+	defer out.Close()
+
+	writer := lz4.NewWriter(out)
+	for _, name := range sortedKeys(files) {
+		if _, err := fmt.Fprintf(writer, "FILE:%s:%d\n", name, len(files[name])); err != nil {
+			return fmt.Errorf("failed to write stash archive: %w", err)
+		}
+		if _, err := writer.Write(files[name]); err != nil {
+			return fmt.Errorf("failed to write stash archive: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finalize stash archive: %w", err)
+	}
+	return nil
+}
+
+// readArchive reads every file from a stash archive
+func readArchive(path string) (map[string][]byte, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stash archive: %w", err)
+	}
+	defer in.Close()
+
+	reader := bufio.NewReader(lz4.NewReader(in))
+	files := make(map[string][]byte)
+	for {
+		header, err := reader.ReadString('\n')
+		if err == io.EOF && header == "" {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stash archive: %w", err)
+		}
+
+		header = strings.TrimSuffix(strings.TrimPrefix(header, "FILE:"), "\n")
+		sep := strings.LastIndex(header, ":")
+		if sep < 0 {
+			return nil, fmt.Errorf("corrupt stash archive header: %q", header)
+		}
+		size, err := strconv.ParseInt(header[sep+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt stash archive header: %q", header)
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("failed to read %s from stash archive: %w", header[:sep], err)
+		}
+		files[header[:sep]] = data
+	}
+}
+
+// contentHash returns the SHA256 hash used for tree entries
+func contentHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// sortedKeys returns map keys in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
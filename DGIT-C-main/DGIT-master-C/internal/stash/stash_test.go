@@ -0,0 +1,258 @@
+package stash
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"dgit/internal/commit"
+	"dgit/internal/log"
+	"dgit/internal/refs"
+	"dgit/internal/staging"
+)
+
+// testRepo is a work tree whose HEAD commit holds logo.ma, scene.ma and notes.ma
+type testRepo struct {
+	t       *testing.T
+	workDir string
+	dgitDir string
+	sm      *StashManager
+	head    *log.Commit
+}
+
+// padded repeats a line so that files are large enough to compress
+func padded(line string) string {
+	return strings.Repeat(line+"\n", 64)
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	workDir := t.TempDir()
+	dgitDir := filepath.Join(workDir, ".dgit")
+	if err := os.MkdirAll(dgitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := refs.NewRefManager(dgitDir).SetHeadToBranch(refs.DefaultBranch); err != nil {
+		t.Fatal(err)
+	}
+	r := &testRepo{t: t, workDir: workDir, dgitDir: dgitDir, sm: NewStashManager(dgitDir)}
+	r.commit("Initial", map[string]string{"logo.ma": "logo1", "scene.ma": "scene1", "notes.ma": "notes1"})
+	return r
+}
+
+// commit writes files to the work tree and commits them on top of HEAD
+func (r *testRepo) commit(message string, files map[string]string) {
+	r.t.Helper()
+	var staged []*staging.StagedFile
+	for path, line := range files {
+		r.write(path, line)
+		staged = append(staged, &staging.StagedFile{
+			Path: path, AbsolutePath: filepath.Join(r.workDir, path), FileType: "ma", Size: int64(len(padded(line))),
+		})
+	}
+	c, err := commit.NewCommitManager(r.dgitDir).CreateCommit(message, staged, nil)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if r.head, err = log.NewLogManager(r.dgitDir).GetCommitByHash(c.Hash); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) write(path, line string) {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.workDir, path), []byte(padded(line)), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) remove(path string) {
+	r.t.Helper()
+	if err := os.Remove(filepath.Join(r.workDir, path)); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// workTree returns the first line of every .ma file in the work tree
+func (r *testRepo) workTree() map[string]string {
+	r.t.Helper()
+	files := make(map[string]string)
+	paths, _ := filepath.Glob(filepath.Join(r.workDir, "*.ma"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			r.t.Fatal(err)
+		}
+		files[filepath.Base(path)] = strings.SplitN(string(data), "\n", 2)[0]
+	}
+	return files
+}
+
+func (r *testRepo) staging() *staging.StagingArea {
+	r.t.Helper()
+	stagingArea := staging.NewStagingArea(r.dgitDir)
+	if err := stagingArea.LoadStaging(); err != nil {
+		r.t.Fatal(err)
+	}
+	return stagingArea
+}
+
+func TestPushAndApply(t *testing.T) {
+	r := newTestRepo(t)
+	committed := r.workTree()
+	r.write("logo.ma", "logo2")
+	r.write("draft.ma", "draft1")
+	r.remove("notes.ma")
+	stagingArea := r.staging()
+	if err := stagingArea.AddFile(filepath.Join(r.workDir, "draft.ma")); err != nil {
+		t.Fatal(err)
+	}
+	stagingArea.StageRemoval("notes.ma")
+	if err := stagingArea.SaveStaging(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := r.sm.Push("", refs.DefaultBranch, r.head, []string{"logo.ma", "notes.ma"}, stagingArea)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]string)
+	for _, file := range entry.Files {
+		statuses[file.Path] = file.Status
+	}
+	if want := map[string]string{"logo.ma": FileModified, "draft.ma": FileAdded, "notes.ma": FileDeleted}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("stashed files = %v, want %v", statuses, want)
+	}
+	if !strings.HasPrefix(entry.Message, "WIP on main: "+r.head.Hash[:8]+" Initial") {
+		t.Errorf("message = %q", entry.Message)
+	}
+	if got := r.workTree(); !reflect.DeepEqual(got, committed) {
+		t.Errorf("work tree after push = %v, want %v", got, committed)
+	}
+	if !r.staging().IsEmpty() {
+		t.Error("staging area not cleared by push")
+	}
+
+	result, err := r.sm.Apply(entry, r.head, r.staging())
+	if err != nil || len(result.Conflicts) > 0 {
+		t.Fatalf("apply: %v, conflicts %v", err, result)
+	}
+	sort.Strings(result.Restored)
+	if !reflect.DeepEqual(result.Restored, []string{"draft.ma", "logo.ma"}) || !reflect.DeepEqual(result.Removed, []string{"notes.ma"}) {
+		t.Errorf("restored %v, removed %v", result.Restored, result.Removed)
+	}
+	if want := map[string]string{"logo.ma": "logo2", "draft.ma": "draft1", "scene.ma": "scene1"}; !reflect.DeepEqual(r.workTree(), want) {
+		t.Errorf("work tree after apply = %v, want %v", r.workTree(), want)
+	}
+	restored := r.staging()
+	if !restored.HasFile(filepath.Join(r.workDir, "draft.ma")) || !restored.IsRemoved("notes.ma") {
+		t.Errorf("staging area not restored: %d files, removed %v", restored.GetFileCount(), restored.GetRemovedFiles())
+	}
+}
+
+func TestApplyConflicts(t *testing.T) {
+	cases := []struct {
+		name      string
+		change    func(r *testRepo)
+		conflicts []string
+	}{
+		{"clean", func(r *testRepo) {}, nil},
+		{"changed in HEAD", func(r *testRepo) { r.commit("Logo v2", map[string]string{"logo.ma": "logo3"}) }, []string{"logo.ma"}},
+		{"same change committed", func(r *testRepo) { r.commit("Logo v2", map[string]string{"logo.ma": "logo2"}) }, nil},
+		{"other file changed in HEAD", func(r *testRepo) { r.commit("Scene v2", map[string]string{"scene.ma": "scene2"}) }, nil},
+		{"local changes", func(r *testRepo) { r.write("logo.ma", "local") }, []string{"logo.ma"}},
+		{"untracked file in the way", func(r *testRepo) { r.write("draft.ma", "other") }, []string{"draft.ma"}},
+		{"staged changes", func(r *testRepo) {
+			stagingArea := r.staging()
+			stagingArea.StageRemoval("scene.ma")
+			stagingArea.SaveStaging()
+		}, []string{"staging area"}},
+	}
+	for _, c := range cases {
+		r := newTestRepo(t)
+		r.write("logo.ma", "logo2")
+		r.write("draft.ma", "draft1")
+		stagingArea := r.staging()
+		stagingArea.StageRemoval("notes.ma")
+		if err := stagingArea.SaveStaging(); err != nil {
+			t.Fatal(err)
+		}
+		entry, err := r.sm.Push("Work in progress", refs.DefaultBranch, r.head, []string{"logo.ma", "draft.ma"}, stagingArea)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.change(r)
+		before := r.workTree()
+		result, err := r.sm.Apply(entry, r.head, r.staging())
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		var conflicts []string
+		for path := range result.Conflicts {
+			conflicts = append(conflicts, path)
+		}
+		sort.Strings(conflicts)
+		if !reflect.DeepEqual(conflicts, c.conflicts) {
+			t.Errorf("%s: conflicts = %v, want %v", c.name, result.Conflicts, c.conflicts)
+		}
+		if len(conflicts) > 0 && !reflect.DeepEqual(r.workTree(), before) {
+			t.Errorf("%s: work tree changed despite conflicts", c.name)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	r := newTestRepo(t)
+	if _, _, err := r.sm.Get(""); err == nil {
+		t.Error("expected an error without stash entries")
+	}
+	var ids []string
+	for _, line := range []string{"logo2", "logo3", "logo4"} {
+		r.write("logo.ma", line)
+		entry, err := r.sm.Push(line, refs.DefaultBranch, r.head, []string{"logo.ma"}, r.staging())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	cases := []struct {
+		ref     string
+		message string
+		index   int
+	}{
+		{"", "logo4", 0},
+		{"stash@{0}", "logo4", 0},
+		{"stash@{2}", "logo2", 2},
+		{"1", "logo3", 1},
+		{ids[0], "logo2", 2},
+		{"stash@{3}", "", 0},
+		{"-1", "", 0},
+		{"zzzz", "", 0},
+	}
+	for _, c := range cases {
+		entry, index, err := r.sm.Get(c.ref)
+		if c.message == "" {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", c.ref, entry.Message)
+			}
+			continue
+		}
+		if err != nil || entry.Message != c.message || index != c.index {
+			t.Errorf("%q: got %v at %d, %v; want %s at %d", c.ref, entry, index, err, c.message, c.index)
+		}
+	}
+
+	entry, _, _ := r.sm.Get("stash@{1}")
+	if err := r.sm.Drop(entry); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := r.sm.List()
+	if err != nil || len(entries) != 2 || entries[0].Message != "logo4" || entries[1].Message != "logo2" {
+		t.Errorf("after drop: %v, %v", entries, err)
+	}
+}
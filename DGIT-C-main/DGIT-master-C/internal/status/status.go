@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"dgit/internal/log"
+	"dgit/internal/restore"
 	"github.com/kr/binarydist"
 )
 
@@ -58,6 +59,35 @@ func (sm *StatusManager) GetSnapshotFileHashes(commitVersion int) (map[string]st
 	return make(map[string]string), nil
 }
 
+// GetCommitFileHashes returns a map of every file tracked at a commit to its SHA256 hash
+// Commits without a recorded tree fall back to reading their snapshot
+func (sm *StatusManager) GetCommitFileHashes(commit *log.Commit) (map[string]string, error) {
+	if commit == nil {
+		return make(map[string]string), nil
+	}
+	if len(commit.Tree) == 0 {
+		return sm.GetSnapshotFileHashes(commit.Version)
+	}
+
+	var restoreManager *restore.RestoreManager
+	fileHashes := make(map[string]string, len(commit.Tree))
+	for path, entry := range commit.Tree {
+		if entry.Hash == "" {
+			// Carried over from a commit created before content hashes were recorded
+			if restoreManager == nil {
+				restoreManager = restore.NewRestoreManager(sm.DgitDir)
+			}
+			data, err := restoreManager.ReadCommitFile(commit, path)
+			if err != nil {
+				continue
+			}
+			entry.Hash = fmt.Sprintf("%x", sha256.Sum256(data))
+		}
+		fileHashes[path] = entry.Hash
+	}
+	return fileHashes, nil
+}
+
 // extractHashesFromZip extracts file hashes from a ZIP file
 func (sm *StatusManager) extractHashesFromZip(zipFileName string) (map[string]string, error) {
 	zipPath := filepath.Join(sm.ObjectsDir, zipFileName)
@@ -340,17 +370,11 @@ type FileStatusResult struct {
 }
 
 // CompareWithCommit compares current working directory with a specific commit
-func (sm *StatusManager) CompareWithCommit(commitVersion int, currentDirFiles map[string]string) (*FileStatusResult, error) {
-	var lastCommitFileHashes map[string]string
-	var err error
-
-	if commitVersion > 0 {
-		lastCommitFileHashes, err = sm.GetSnapshotFileHashes(commitVersion)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load commit snapshot files (v%d): %w", commitVersion, err)
-		}
-	} else {
-		lastCommitFileHashes = make(map[string]string) // Empty map if no commits
+// A nil commit means the current branch has no commits yet
+func (sm *StatusManager) CompareWithCommit(commit *log.Commit, currentDirFiles map[string]string) (*FileStatusResult, error) {
+	lastCommitFileHashes, err := sm.GetCommitFileHashes(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit snapshot files (v%d): %w", commit.Version, err)
 	}
 
 	result := &FileStatusResult{
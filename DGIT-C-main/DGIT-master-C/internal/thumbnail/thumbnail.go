@@ -0,0 +1,208 @@
+package thumbnail
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dgit/internal/log"
+	"dgit/internal/restore"
+	"dgit/internal/scanner/illustrator"
+	"dgit/internal/scanner/photoshop"
+)
+
+// MaxSize is the longest side of thumbnails rendered from a PSD composite
+const MaxSize = 256
+
+// maxStoredSize is the longest side accepted for an embedded preview
+const maxStoredSize = 4096
+
+// Thumbnail is a stored preview of one version of a file
+type Thumbnail struct {
+	Path   string `json:"path"` // JPEG file in .dgit/thumbnails
+	Hash   string `json:"hash"` // Content hash of the file version it previews
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ThumbnailManager keeps JPEG previews of committed file versions, keyed by content hash
+// Identical versions share one thumbnail, so history galleries can be shown without decoding full files
+type ThumbnailManager struct {
+	DgitDir        string
+	Dir            string
+	restoreManager *restore.RestoreManager
+}
+
+// NewThumbnailManager creates a new thumbnail manager
+func NewThumbnailManager(dgitDir string) *ThumbnailManager {
+	return &ThumbnailManager{
+		DgitDir:        dgitDir,
+		Dir:            filepath.Join(dgitDir, "thumbnails"),
+		restoreManager: restore.NewRestoreManager(dgitDir),
+	}
+}
+
+// Supported reports whether thumbnails can be made for a file type
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".psd", ".ai":
+		return true
+	}
+	return false
+}
+
+// Extract returns a JPEG preview of a design file on disk
+// PSDs use their embedded thumbnail, or else a downscaled render of the merged image;
+// AI files use the preview stored in their XMP metadata.
+func Extract(path string) ([]byte, error) {
+	data, err := ExtractEmbedded(path)
+	if err == nil || strings.ToLower(filepath.Ext(path)) != ".psd" {
+		return data, err
+	}
+	composite, err := photoshop.ReadComposite(path)
+	if err != nil {
+		return nil, fmt.Errorf("no embedded thumbnail and %w", err)
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, downscale(composite, MaxSize), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// ExtractEmbedded returns the JPEG preview a design file stores itself, without decoding its image data
+func ExtractEmbedded(path string) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".psd":
+		return photoshop.ExtractThumbnail(path)
+	case ".ai":
+		return illustrator.ExtractThumbnail(path)
+	}
+	return nil, fmt.Errorf("thumbnails are not supported for %s files", filepath.Ext(path))
+}
+
+// Store saves the thumbnail of a file version unless one is already stored for its content hash
+func (tm *ThumbnailManager) Store(contentHash, path string) (*Thumbnail, error) {
+	return tm.store(contentHash, path, Extract)
+}
+
+// StoreEmbedded saves the thumbnail of a file version only when the file embeds one
+// It is cheap enough for every commit; rendering composites is left to Store.
+func (tm *ThumbnailManager) StoreEmbedded(contentHash, path string) (*Thumbnail, error) {
+	return tm.store(contentHash, path, ExtractEmbedded)
+}
+
+// store saves a thumbnail made by extract, after checking that it is a JPEG of a sensible size
+func (tm *ThumbnailManager) store(contentHash, path string, extract func(string) ([]byte, error)) (*Thumbnail, error) {
+	if thumb, err := tm.Lookup(contentHash); err == nil {
+		return thumb, nil
+	}
+	data, err := extract(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid thumbnail: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxStoredSize || config.Height > maxStoredSize {
+		return nil, fmt.Errorf("invalid thumbnail size %dx%d", config.Width, config.Height)
+	}
+	if err := os.MkdirAll(tm.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail directory: %w", err)
+	}
+	if err := os.WriteFile(tm.thumbnailPath(contentHash), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	return tm.Lookup(contentHash)
+}
+
+// Lookup returns the stored thumbnail for a content hash
+func (tm *ThumbnailManager) Lookup(contentHash string) (*Thumbnail, error) {
+	path := tm.thumbnailPath(contentHash)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no thumbnail stored for %s", contentHash)
+	}
+	defer file.Close()
+	config, err := jpeg.DecodeConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail %s: %w", path, err)
+	}
+	return &Thumbnail{Path: path, Hash: contentHash, Width: config.Width, Height: config.Height}, nil
+}
+
+// ForCommitFile returns the thumbnail of a file as recorded in a commit
+// Versions committed before thumbnails existed are extracted once and then kept in the cache
+func (tm *ThumbnailManager) ForCommitFile(commit *log.Commit, path string) (*Thumbnail, error) {
+	entry, ok := commit.TrackedFiles()[path]
+	if !ok {
+		return nil, fmt.Errorf("file '%s' is not tracked in v%d", path, commit.Version)
+	}
+	if !Supported(path) {
+		return nil, fmt.Errorf("thumbnails are not supported for %s files", filepath.Ext(path))
+	}
+	if entry.Hash != "" {
+		if thumb, err := tm.Lookup(entry.Hash); err == nil {
+			return thumb, nil
+		}
+	}
+
+	data, err := tm.restoreManager.ReadCommitFile(commit, path)
+	if err != nil {
+		return nil, err
+	}
+	contentHash := entry.Hash
+	if contentHash == "" {
+		contentHash = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+
+	tempDir, err := os.MkdirTemp("", "dgit-thumbnail-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	tempPath := filepath.Join(tempDir, filepath.Base(path))
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", path, err)
+	}
+	return tm.Store(contentHash, tempPath)
+}
+
+// thumbnailPath returns where the thumbnail of a content hash is stored
+func (tm *ThumbnailManager) thumbnailPath(contentHash string) string {
+	return filepath.Join(tm.Dir, contentHash+".jpg")
+}
+
+// downscale shrinks an image so its longest side is at most size pixels, averaging the source pixels
+func downscale(src *image.RGBA, size int) image.Image {
+	b := src.Bounds()
+	scale := float64(max(b.Dx(), b.Dy())) / float64(size)
+	if scale <= 1 {
+		return src
+	}
+	width, height := max(1, int(float64(b.Dx())/scale)), max(1, int(float64(b.Dy())/scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+		for x := 0; x < width; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+			var r, g, bl, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.RGBAAt(sx, sy)
+					r, g, bl, n = r+int(c.R), g+int(c.G), bl+int(c.B), n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), 255})
+		}
+	}
+	return dst
+}
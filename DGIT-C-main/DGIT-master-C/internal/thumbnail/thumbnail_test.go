@@ -0,0 +1,144 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testJPEG encodes a solid image of the given size
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// testPSD returns a 2x2 RGB PSD with raw image data, embedding a thumbnail resource when jpegData is set
+func testPSD(jpegData []byte) []byte {
+	var resources bytes.Buffer
+	if jpegData != nil {
+		block := make([]byte, 28)
+		binary.BigEndian.PutUint32(block, 1)
+		block = append(block, jpegData...)
+		resources.WriteString("8BIM")
+		binary.Write(&resources, binary.BigEndian, uint16(1036))
+		resources.Write([]byte{0, 0})
+		binary.Write(&resources, binary.BigEndian, uint32(len(block)))
+		resources.Write(block)
+		if len(block)%2 != 0 {
+			resources.WriteByte(0)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("8BPS")
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	b.Write(make([]byte, 6))
+	binary.Write(&b, binary.BigEndian, uint16(3))      // Channels
+	binary.Write(&b, binary.BigEndian, []uint32{2, 2}) // Height and width
+	binary.Write(&b, binary.BigEndian, []uint16{8, 3}) // Depth and RGB color mode
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	binary.Write(&b, binary.BigEndian, uint32(resources.Len()))
+	b.Write(resources.Bytes())
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	binary.Write(&b, binary.BigEndian, uint16(0))
+	b.Write(bytes.Repeat([]byte{255, 0, 0, 255}, 3))
+	return b.Bytes()
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtract(t *testing.T) {
+	embedded := testJPEG(t, 16, 16)
+	if data, err := Extract(writeFile(t, "a.psd", testPSD(embedded))); err != nil || !bytes.Equal(data, embedded) {
+		t.Errorf("embedded PSD thumbnail: %v", err)
+	}
+
+	// Without an embedded thumbnail the composite is rendered
+	data, err := Extract(writeFile(t, "b.psd", testPSD(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, err := jpeg.DecodeConfig(bytes.NewReader(data)); err != nil || config.Width != 2 || config.Height != 2 {
+		t.Errorf("rendered thumbnail: %+v, %v", config, err)
+	}
+
+	// AI files keep a base64 JPEG in their XMP, wrapped with escaped newlines
+	encoded := base64.StdEncoding.EncodeToString(embedded)
+	xmp := "%PDF-1.6\n<xmpGImg:image>" + encoded[:20] + "&#xA;" + encoded[20:] + "</xmpGImg:image>\n"
+	if data, err := Extract(writeFile(t, "c.ai", []byte(xmp))); err != nil || !bytes.Equal(data, embedded) {
+		t.Errorf("AI thumbnail: %v", err)
+	}
+}
+
+func TestStoreEmbeddedSkipsComposites(t *testing.T) {
+	tm := NewThumbnailManager(t.TempDir())
+	if _, err := tm.StoreEmbedded("plain", writeFile(t, "a.psd", testPSD(nil))); err == nil {
+		t.Error("expected no thumbnail for a PSD without an embedded one")
+	}
+	thumb, err := tm.StoreEmbedded("embedded", writeFile(t, "b.psd", testPSD(testJPEG(t, 24, 12))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumb.Width != 24 || thumb.Height != 12 {
+		t.Errorf("thumbnail size = %dx%d", thumb.Width, thumb.Height)
+	}
+	if _, err := tm.Lookup("embedded"); err != nil {
+		t.Errorf("stored thumbnail not found: %v", err)
+	}
+}
+
+func TestStoreRejectsInvalidThumbnails(t *testing.T) {
+	tm := NewThumbnailManager(t.TempDir())
+	cases := map[string][]byte{
+		"not a JPEG": []byte("\xff\xd8 not really a jpeg"),
+		"oversized":  testJPEG(t, maxStoredSize+1, 1),
+	}
+	for name, jpegData := range cases {
+		if _, err := tm.Store(name, writeFile(t, "a.psd", testPSD(jpegData))); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, err := os.Stat(tm.thumbnailPath(name)); err == nil {
+			t.Errorf("%s: thumbnail was written", name)
+		}
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 512, 256))
+	for x := 0; x < 256; x++ {
+		for y := 0; y < 256; y++ {
+			src.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	dst := downscale(src, MaxSize)
+	if b := dst.Bounds(); b.Dx() != 256 || b.Dy() != 128 {
+		t.Fatalf("size = %v", b)
+	}
+	if r, _, _, _ := dst.At(10, 10).RGBA(); r>>8 != 255 {
+		t.Errorf("left half is not white")
+	}
+	if r, _, _, _ := dst.At(200, 10).RGBA(); r>>8 != 0 {
+		t.Errorf("right half is not black")
+	}
+}
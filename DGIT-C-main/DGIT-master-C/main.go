@@ -33,6 +33,21 @@ func init() {
 	rootCmd.AddCommand(cmd.RestoreCmd)
 	rootCmd.AddCommand(cmd.ScanCmd)
 	rootCmd.AddCommand(cmd.ShowCmd) // 새로 추가
+	rootCmd.AddCommand(cmd.BranchCmd)
+	rootCmd.AddCommand(cmd.SwitchCmd)
+	rootCmd.AddCommand(cmd.TagCmd)
+	rootCmd.AddCommand(cmd.MergeCmd)
+	rootCmd.AddCommand(cmd.RevertCmd)
+	rootCmd.AddCommand(cmd.RewordCmd)
+	rootCmd.AddCommand(cmd.StashCmd)
+	rootCmd.AddCommand(cmd.ResetCmd)
+	rootCmd.AddCommand(cmd.UnstageCmd)
+	rootCmd.AddCommand(cmd.RmCmd)
+	rootCmd.AddCommand(cmd.MvCmd)
+	rootCmd.AddCommand(cmd.BlameCmd)
+	rootCmd.AddCommand(cmd.BisectCmd)
+	rootCmd.AddCommand(cmd.ReflogCmd)
+	rootCmd.AddCommand(cmd.DiffCmd)
 }
 func main() {
 	if err := rootCmd.Execute(); err != nil {
@@ -444,6 +444,7 @@ class DGitIntegration {
 
     /**
      * 특정 커밋의 파일들 복원
+     * options.confirmed는 호출하는 쪽에서 확인 대화상자를 보여준 뒤에만 설정한다
      */
     async reset(projectPath, target = 'HEAD', options = {}) {
         const args = [];
@@ -456,6 +457,13 @@ class DGitIntegration {
             args.push('--mixed');
         }
 
+        // 자식 프로세스에는 터미널이 없어 CLI가 확인을 물을 수 없으므로,
+        // 사용자가 확인한 경우에만 --yes를 넘긴다. 확인 없이는 CLI가 위험한 reset을 거부한다.
+        // 파일 언스테이지는 확인이 필요 없으므로 --yes를 붙이지 않는다
+        if (options.confirmed && !options.files) {
+            args.push('--yes');
+        }
+
         args.push(target);
 
         if (options.files) {